@@ -173,10 +173,7 @@ func executeScript(host *host.Host, script string) error {
 	defer cancel()
 
 	// Create the CommandConfig
-	config := commandmanager.CommandConfig{
-		Command: script,
-		Sudo:    false,
-	}
+	config := shellCommand(script)
 
 	// Use the CommandManager embedded in the host.Host struct
 	result, err := host.CommandManager.Run(ctx, config)
@@ -195,6 +192,15 @@ func executeScript(host *host.Host, script string) error {
 	return nil
 }
 
+// shellCommand returns the config that runs command through sh, so that its
+// quoting, escapes and operators mean the same locally and over SSH.
+func shellCommand(command string) commandmanager.CommandConfig {
+	return commandmanager.CommandConfig{
+		Command: "sh",
+		Args:    []string{"-c", command},
+	}
+}
+
 func processHosts(hg *hostgroup.HostGroup, action func(h *host.Host) error, maxConcurrency int) error {
 	sem := make(chan struct{}, maxConcurrency) // Create semaphore with buffer size equal to max concurrency
 	errCh := make(chan error, len(hg.Hosts))
@@ -306,10 +312,7 @@ func executeCommandOnHost(host *host.Host, command string) error {
 	defer cancel()
 
 	// Create the CommandConfig
-	config := commandmanager.CommandConfig{
-		Command: command,
-		Sudo:    false,
-	}
+	config := shellCommand(command)
 
 	if host.SSHClient == nil {
 		slog.Error("SSHClient is nil in executeCommandOnHost")
@@ -482,7 +485,7 @@ func buildHostOptions(f *flags, password, keyPass string) []host.HostOption {
 		fmt.Print("Enter the sudo password: ")
 		sudoPasswordBytes, err := term.ReadPassword(int(os.Stdin.Fd()))
 		if err != nil {
-			slog.Error("Failed to read sudo password: %v", err)
+			slog.Error("Failed to read sudo password", "error", err)
 		}
 		sudoPassword := string(sudoPasswordBytes)
 		fmt.Println()
@@ -490,7 +493,7 @@ func buildHostOptions(f *flags, password, keyPass string) []host.HostOption {
 			options = append(options, host.WithSudoPassword(sudoPassword))
 		}
 	}
-	options = append(options, host.WithSSHClient(&host.RealSSHClient{}))
+	options = append(options, host.WithSSHClient(&host.RealSSHClient{}), host.WithLogger(slog.Default()))
 	slog.Debug("SSHClient set in options")
 	return options
 }
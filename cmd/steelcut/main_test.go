@@ -1,10 +1,13 @@
 package main
 
 import (
+	"context"
 	"io/ioutil"
 	"os"
 	"reflect"
 	"testing"
+
+	"github.com/steelcutops/steelcut/steelcut/commandmanager"
 )
 
 func TestReadHostsFromFile(t *testing.T) {
@@ -42,3 +45,27 @@ host3=127.0.0.3`
 		t.Errorf("Expected %v, got %v", expected, hosts)
 	}
 }
+
+func TestShellCommand(t *testing.T) {
+	manager := commandmanager.UnixCommandManager{Hostname: "localhost"}
+	tests := []struct {
+		command  string
+		expected string
+	}{
+		{`printf '%s|' "hello world"`, "hello world|"},
+		{`printf '%s|' one   two`, "one|two|"},
+		{`printf '%s|' 'it'\''s' "say \"hi\""`, `it's|say "hi"|`},
+		{`printf '%s|' a\ b c\\d`, `a b|c\d|`},
+		{`echo one | tr o 0`, "0ne\n"},
+	}
+	for _, tt := range tests {
+		result, err := manager.RunLocal(context.Background(), shellCommand(tt.command))
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.command, err)
+			continue
+		}
+		if result.STDOUT != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.command, tt.expected, result.STDOUT)
+		}
+	}
+}
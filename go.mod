@@ -4,13 +4,16 @@ go 1.21
 
 require (
 	github.com/hashicorp/go-multierror v1.1.1
+	github.com/pkg/sftp v1.13.6
 	golang.org/x/crypto v0.24.0
 	golang.org/x/term v0.21.0
 	gopkg.in/ini.v1 v1.67.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/hashicorp/errwrap v1.0.0 // indirect
+	github.com/kr/fs v0.1.0 // indirect
 	github.com/stretchr/testify v1.8.4 // indirect
 	golang.org/x/sys v0.21.0 // indirect
 )
@@ -0,0 +1,44 @@
+package commandmanager
+
+import (
+	"os"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// usesAgent reports whether the manager authenticates with keys from the
+// SSH agent, which getSSHConfig does when no password, identity files or
+// key passphrase are set.
+func (u *UnixCommandManager) usesAgent() bool {
+	return u.Password == "" && len(u.IdentityFiles) == 0 && u.KeyPassphrase == ""
+}
+
+// forwardAgent serves the local SSH agent to sessions on client that ask for
+// it with AgentForwarding. It does nothing unless the manager authenticates
+// with the agent and SSH_AUTH_SOCK is set.
+func (u *UnixCommandManager) forwardAgent(client *ssh.Client) {
+	if !u.AgentForwarding || !u.usesAgent() {
+		return
+	}
+	socket := os.Getenv("SSH_AUTH_SOCK")
+	if socket == "" {
+		u.logger().Debug("No SSH agent to forward", "hostname", u.Hostname)
+		return
+	}
+	if err := agent.ForwardToRemote(client, socket); err != nil {
+		u.logger().Debug("Failed to forward SSH agent", "hostname", u.Hostname, "error", err)
+	}
+}
+
+// requestAgentForwarding asks the server to make the forwarded agent
+// available to session. A server that refuses leaves the command to run
+// without it.
+func (u *UnixCommandManager) requestAgentForwarding(session *ssh.Session) {
+	if !u.AgentForwarding || !u.usesAgent() || os.Getenv("SSH_AUTH_SOCK") == "" {
+		return
+	}
+	if err := agent.RequestAgentForwarding(session); err != nil {
+		u.logger().Debug("Server refused SSH agent forwarding", "hostname", u.Hostname, "error", err)
+	}
+}
@@ -0,0 +1,166 @@
+package commandmanager
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+
+	"github.com/steelcutops/steelcut/common"
+)
+
+// startAgentServer starts an SSH server whose commands print how many keys
+// the forwarded agent holds, or "no agent" when forwarding was not
+// requested.
+func startAgentServer(t *testing.T) string {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+	config := &ssh.ServerConfig{NoClientAuth: true}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				sshConn, chans, reqs, err := ssh.NewServerConn(conn, config)
+				if err != nil {
+					conn.Close()
+					return
+				}
+				go ssh.DiscardRequests(reqs)
+				for newChannel := range chans {
+					go serveAgentSession(sshConn, newChannel)
+				}
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func serveAgentSession(conn *ssh.ServerConn, newChannel ssh.NewChannel) {
+	channel, requests, err := newChannel.Accept()
+	if err != nil {
+		return
+	}
+	defer channel.Close()
+
+	forwarded := false
+	for req := range requests {
+		switch req.Type {
+		case "auth-agent-req@openssh.com":
+			forwarded = true
+			req.Reply(true, nil)
+		case "exec":
+			req.Reply(true, nil)
+			output := "no agent"
+			if forwarded {
+				output = listForwardedKeys(conn)
+			}
+			io.WriteString(channel, output+"\n")
+			channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{0}))
+			return
+		default:
+			req.Reply(false, nil)
+		}
+	}
+}
+
+func listForwardedKeys(conn *ssh.ServerConn) string {
+	channel, requests, err := conn.OpenChannel("auth-agent@openssh.com", nil)
+	if err != nil {
+		return "open failed: " + err.Error()
+	}
+	defer channel.Close()
+	go ssh.DiscardRequests(requests)
+
+	keys, err := agent.NewClient(channel).List()
+	if err != nil {
+		return "list failed: " + err.Error()
+	}
+	return fmt.Sprintf("%d keys", len(keys))
+}
+
+// startTestAgent serves an agent holding one key on a socket it points
+// SSH_AUTH_SOCK at.
+func startTestAgent(t *testing.T) {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	keyring := agent.NewKeyring()
+	if err := keyring.Add(agent.AddedKey{PrivateKey: priv}); err != nil {
+		t.Fatalf("Failed to add key: %v", err)
+	}
+
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go agent.ServeAgent(keyring, conn)
+		}
+	}()
+	t.Setenv("SSH_AUTH_SOCK", socket)
+}
+
+func TestAgentForwarding(t *testing.T) {
+	startTestAgent(t)
+	addr := startAgentServer(t)
+
+	run := func(manager *UnixCommandManager) string {
+		t.Helper()
+		manager.SSHClient = addrDialer{addr: addr}
+		manager.HostKeyCallback = ssh.InsecureIgnoreHostKey()
+		result, err := manager.RunRemote(context.Background(), CommandConfig{Command: "ssh-add", Args: []string{"-l"}})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return result.STDOUT
+	}
+
+	forwarding := &UnixCommandManager{Hostname: "remote.example.com", Credentials: common.Credentials{User: "test"}, AgentForwarding: true}
+	if got := run(forwarding); got != "1 keys\n" {
+		t.Errorf("Expected the agent to be forwarded, got %q", got)
+	}
+
+	if got := run(&UnixCommandManager{Hostname: "remote.example.com", Credentials: common.Credentials{User: "test"}}); got != "no agent\n" {
+		t.Errorf("Expected no forwarding by default, got %q", got)
+	}
+
+	// Password authentication does not use the agent
+	password := &UnixCommandManager{Hostname: "remote.example.com", Credentials: common.Credentials{User: "test", Password: "test"}, AgentForwarding: true}
+	if got := run(password); got != "no agent\n" {
+		t.Errorf("Expected no forwarding with password authentication, got %q", got)
+	}
+}
@@ -0,0 +1,72 @@
+package commandmanager
+
+import (
+	"strings"
+)
+
+// askpassRejectedStatus is the exit status askpassScript exits with when sudo
+// asks for the password a second time, meaning the first was wrong. It is
+// reported together with askpassRejectedMarker on stderr, so that commands
+// exiting with the same status are not mistaken for a rejected password.
+const (
+	askpassRejectedStatus = 125
+	askpassRejectedMarker = "steelcut: sudo rejected the password"
+)
+
+// askpassScript runs "$@" with sudo -A. It reads the password from the first
+// line of stdin, whether sudo ends up asking for it or not, and leaves the
+// rest of stdin to the command. The password never touches the disk: a
+// background writer hands it to the askpass helper through a FIFO in a
+// private directory, and is killed on exit if sudo never asked. The helper
+// serves it once, so a second prompt fails instead of looping.
+const askpassScript = `umask 077
+d=$(mktemp -d) || exit 1
+trap 'kill "$w" 2>/dev/null; rm -rf "$d"' EXIT
+mkfifo "$d/password" || exit 1
+IFS= read -r p
+{ printf '%s\n' "$p" > "$d/password"; } >/dev/null 2>&1 &
+w=$!
+unset p
+cat > "$d/askpass" <<EOF
+#!/bin/sh
+[ -e "$d/served" ] && { : > "$d/rejected"; exit 1; }
+: > "$d/served"
+cat "$d/password"
+EOF
+chmod 700 "$d/askpass"
+SUDO_ASKPASS="$d/askpass" sudo -A -- "$@"
+status=$?
+if [ -e "$d/rejected" ]; then
+	echo '` + askpassRejectedMarker + `' >&2
+	exit 125
+fi
+exit $status`
+
+// SudoAskpassStrategy escalates privileges with sudo -A, serving the password
+// from a short-lived askpass helper rather than sudo's own stdin prompt. The
+// password line is always consumed, so command input passed in
+// CommandConfig.Stdin reaches the command intact, sudo prints no prompt into
+// the output, and a wrong password is recognised by exit status rather than
+// by sudo's localised messages. The helper is written under the host's
+// temporary directory, which must allow executing files.
+type SudoAskpassStrategy struct{}
+
+func (SudoAskpassStrategy) Name() string {
+	return "sudo"
+}
+
+func (SudoAskpassStrategy) Wrap(command string, args []string) []string {
+	return append([]string{"sh", "-c", askpassScript, "sh", command}, args...)
+}
+
+func (SudoAskpassStrategy) Stdin(password string) string {
+	// The script reads a line even when no password is set
+	return password + "\n"
+}
+
+func (SudoAskpassStrategy) CheckErrors(result CommandResult) error {
+	if result.ExitCode == askpassRejectedStatus && strings.Contains(result.STDERR, askpassRejectedMarker) {
+		return ErrSudoAuth
+	}
+	return SudoStrategy{}.CheckErrors(result)
+}
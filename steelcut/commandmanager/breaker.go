@@ -0,0 +1,102 @@
+package commandmanager
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without contacting the host while its circuit
+// breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker open: too many recent failures")
+
+// CircuitBreaker limits how often a failing host is retried. Once MaxFailures
+// failures happen within Window, the circuit opens and operations fail fast
+// with ErrCircuitOpen for a cooldown of one Window. After the cooldown a
+// single probe is let through: if it succeeds the circuit closes, otherwise
+// it opens again for another cooldown.
+//
+// A CircuitBreaker is safe for concurrent use.
+type CircuitBreaker struct {
+	MaxFailures int
+	Window      time.Duration
+
+	mu       sync.Mutex
+	failures []time.Time
+	openedAt time.Time
+	open     bool
+	probing  bool
+
+	now func() time.Time // overridden in tests
+}
+
+// NewCircuitBreaker returns a closed CircuitBreaker.
+func NewCircuitBreaker(maxFailures int, window time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{MaxFailures: maxFailures, Window: window}
+}
+
+func (b *CircuitBreaker) clock() time.Time {
+	if b.now != nil {
+		return b.now()
+	}
+	return time.Now()
+}
+
+// Allow returns ErrCircuitOpen if the operation should not be attempted. An
+// allowed operation's outcome must be passed to Record, as it may be the probe.
+func (b *CircuitBreaker) Allow() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.open {
+		return nil
+	}
+	if b.probing || b.clock().Sub(b.openedAt) < b.Window {
+		return ErrCircuitOpen
+	}
+	// Cooldown elapsed: let one probe through
+	b.probing = true
+	return nil
+}
+
+// Record reports the outcome of an operation. A nil breaker ignores it.
+func (b *CircuitBreaker) Record(err error) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.clock()
+	if b.probing {
+		b.probing = false
+		if err == nil {
+			b.open = false
+			b.failures = nil
+		} else {
+			b.openedAt = now
+		}
+		return
+	}
+	if err == nil || b.open {
+		return
+	}
+
+	// Drop failures that have aged out of the window
+	recent := b.failures[:0]
+	for _, t := range b.failures {
+		if now.Sub(t) < b.Window {
+			recent = append(recent, t)
+		}
+	}
+	b.failures = append(recent, now)
+
+	if len(b.failures) >= b.MaxFailures {
+		b.open = true
+		b.openedAt = now
+		b.failures = nil
+	}
+}
@@ -0,0 +1,95 @@
+package commandmanager
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/steelcutops/steelcut/common"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	breaker := NewCircuitBreaker(3, time.Minute)
+	breaker.now = func() time.Time { return now }
+	failure := errors.New("connection refused")
+
+	// Failures spread wider than the window do not open the circuit
+	for i := 0; i < 4; i++ {
+		if err := breaker.Allow(); err != nil {
+			t.Fatalf("Expected closed circuit, got: %v", err)
+		}
+		breaker.Record(failure)
+		now = now.Add(40 * time.Second)
+	}
+	now = now.Add(time.Minute)
+
+	for i := 0; i < 3; i++ {
+		if err := breaker.Allow(); err != nil {
+			t.Fatalf("Expected closed circuit, got: %v", err)
+		}
+		breaker.Record(failure)
+	}
+	if err := breaker.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected ErrCircuitOpen, got: %v", err)
+	}
+
+	// Still open during the cooldown
+	now = now.Add(30 * time.Second)
+	if err := breaker.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected ErrCircuitOpen during cooldown, got: %v", err)
+	}
+
+	// A failed probe reopens the circuit
+	now = now.Add(31 * time.Second)
+	if err := breaker.Allow(); err != nil {
+		t.Fatalf("Expected a probe after the cooldown, got: %v", err)
+	}
+	if err := breaker.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected only one probe at a time, got: %v", err)
+	}
+	breaker.Record(failure)
+	if err := breaker.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected ErrCircuitOpen after a failed probe, got: %v", err)
+	}
+
+	// A successful probe closes it
+	now = now.Add(time.Minute)
+	if err := breaker.Allow(); err != nil {
+		t.Fatalf("Expected a probe after the cooldown, got: %v", err)
+	}
+	breaker.Record(nil)
+	if err := breaker.Allow(); err != nil {
+		t.Errorf("Expected closed circuit after a successful probe, got: %v", err)
+	}
+	breaker.Record(failure)
+	if err := breaker.Allow(); err != nil {
+		t.Errorf("Expected failure count to restart after closing, got: %v", err)
+	}
+}
+
+func TestRunRemoteCircuitOpen(t *testing.T) {
+	dialer := &MockFamilyDialer{errors: map[string]error{"tcp": errors.New("connection refused")}}
+	manager := UnixCommandManager{
+		Hostname:    "example.com",
+		SSHClient:   dialer,
+		Credentials: common.Credentials{User: "user", Password: "password"},
+		Breaker:     NewCircuitBreaker(2, time.Minute),
+	}
+
+	for i := 0; i < 2; i++ {
+		_, err := manager.RunRemote(context.Background(), CommandConfig{Command: "ls"})
+		if err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("Expected dial error, got: %v", err)
+		}
+	}
+
+	_, err := manager.RunRemote(context.Background(), CommandConfig{Command: "ls"})
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected ErrCircuitOpen, got: %v", err)
+	}
+	if len(dialer.networks) != 2 {
+		t.Errorf("Expected no dial while the circuit is open, got %d dials", len(dialer.networks))
+	}
+}
@@ -0,0 +1,48 @@
+package commandmanager
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// CommandError is returned when a command runs but exits with a non-zero
+// code. The same code is reported in CommandResult.ExitCode; use errors.As
+// to tell a failed command from one that could not be run at all.
+type CommandError struct {
+	Command  string
+	ExitCode int
+	Stderr   string
+
+	// Err is the underlying *exec.ExitError or *ssh.ExitError, if any.
+	Err error
+}
+
+func (e *CommandError) Error() string {
+	msg := fmt.Sprintf("command %s exited with code %d", e.Command, e.ExitCode)
+	if stderr := strings.TrimSpace(e.Stderr); stderr != "" {
+		msg += ": " + stderr
+	}
+	return msg
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// IsExitError reports whether err is a *CommandError: the command ran and
+// exited non-zero. Any other error, such as ErrSudoAuth or ErrDirNotFound,
+// means the command did not run, even when CommandResult.ExitCode is set.
+func IsExitError(err error) bool {
+	var cmdErr *CommandError
+	return errors.As(err, &cmdErr)
+}
+
+// commandError returns a *CommandError for result if it exited non-zero,
+// and nil otherwise.
+func commandError(command string, result CommandResult, err error) error {
+	if result.ExitCode == 0 {
+		return nil
+	}
+	return &CommandError{Command: command, ExitCode: result.ExitCode, Stderr: result.STDERR, Err: err}
+}
@@ -2,6 +2,7 @@ package commandmanager
 
 import (
 	"context"
+	"io"
 	"time"
 )
 
@@ -20,7 +21,33 @@ type CommandConfig struct {
 	Command string
 	Args    []string
 	Sudo    bool
-	Env     []string
+
+	// Env sets environment variables for the command, each "NAME=value".
+	// Remote variables are passed with Setenv, falling back to a quoted env
+	// prefix when the server refuses them.
+	Env []string
+
+	// Dir is the directory to run the command in. Remote commands otherwise
+	// run in the login home directory, which relative paths are resolved
+	// against. A missing directory fails with ErrDirNotFound.
+	Dir string
+
+	// Stdin, if set, is read as the command's standard input. With Sudo and
+	// the default escalation, the command runs under SudoAskpassStrategy,
+	// which reads the password line ahead of Stdin and needs an executable
+	// temporary directory on the host. An explicit SudoStrategy reads the
+	// password from the same stream, so NOPASSWD rules or cached credentials
+	// leave the password line for the command.
+	Stdin io.Reader
+
+	// Limits sets resource limits for the command, keyed by name ("nofile",
+	// "nproc", "as", ...), each an integer or "unlimited".
+	Limits map[string]string
+
+	// OnLine, if set, is called with StreamStdout or StreamStderr and each
+	// line of output as the command produces it. The output is still
+	// returned in the CommandResult.
+	OnLine func(stream, line string)
 }
 
 // CommandManager provides methods to execute commands, both locally and remotely.
@@ -0,0 +1,204 @@
+package commandmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Address families accepted by UnixCommandManager.AddressFamily.
+const (
+	AddressFamilyIPv4 = "ip4"
+	AddressFamilyIPv6 = "ip6"
+	AddressFamilyAuto = "auto"
+)
+
+// ValidAddressFamily reports whether family is a supported address family.
+// The empty string leaves the choice to the system resolver.
+func ValidAddressFamily(family string) bool {
+	switch family {
+	case "", AddressFamilyIPv4, AddressFamilyIPv6, AddressFamilyAuto:
+		return true
+	}
+	return false
+}
+
+// dialNetworks returns the networks to dial for the given address family.
+func dialNetworks(family string) []string {
+	switch family {
+	case AddressFamilyIPv4:
+		return []string{"tcp4"}
+	case AddressFamilyIPv6:
+		return []string{"tcp6"}
+	case AddressFamilyAuto:
+		return []string{"tcp6", "tcp4"}
+	default:
+		return []string{"tcp"}
+	}
+}
+
+// dial connects to addr using the configured address family. With "auto",
+// only the TCP connections over IPv6 and IPv4 are raced, as in happy
+// eyeballs, and the SSH handshake runs once, on the first to connect, so the
+// server sees a single authentication. SSHClient is not used in that case.
+func (u *UnixCommandManager) dial(addr string, config *ssh.ClientConfig, timeout time.Duration) (*ssh.Client, error) {
+	networks := dialNetworks(u.AddressFamily)
+	if len(networks) == 1 {
+		return u.SSHClient.Dial(networks[0], addr, config, timeout)
+	}
+
+	conn, err := raceConnect(networks, func(network string) (net.Conn, error) {
+		return net.DialTimeout(network, addr, timeout)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newClientConn(conn, addr, config, timeout)
+}
+
+// raceConnect calls connect for every network concurrently and returns the
+// first connection to succeed. Connections that succeed later are closed.
+func raceConnect(networks []string, connect func(network string) (net.Conn, error)) (net.Conn, error) {
+	type connResult struct {
+		network string
+		conn    net.Conn
+		err     error
+	}
+
+	results := make(chan connResult, len(networks))
+	for _, network := range networks {
+		go func(network string) {
+			conn, err := connect(network)
+			results <- connResult{network: network, conn: conn, err: err}
+		}(network)
+	}
+
+	var errs []error
+	for i := range networks {
+		r := <-results
+		if r.err == nil {
+			go func(remaining int) {
+				for j := 0; j < remaining; j++ {
+					if late := <-results; late.err == nil {
+						late.conn.Close()
+					}
+				}
+			}(len(networks) - i - 1)
+			return r.conn, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", r.network, r.err))
+	}
+
+	return nil, errors.Join(errs...)
+}
+
+// newClientConn runs the SSH handshake on conn, giving up if it stalls past
+// timeout. conn is closed if the handshake fails.
+func newClientConn(conn net.Conn, addr string, config *ssh.ClientConfig, timeout time.Duration) (*ssh.Client, error) {
+	if timeout > 0 {
+		conn.SetDeadline(time.Now().Add(timeout))
+	}
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	return ssh.NewClient(sshConn, chans, reqs), nil
+}
+
+// dialJump connects to addr through the jump host. The jump host is dialed
+// with its own timeout, after which timeout applies to the hop from the jump
+// host to addr alone. The jump connection is closed on error, and otherwise
+// once the returned client is closed.
+func (u *UnixCommandManager) dialJump(ctx context.Context, addr string, config *ssh.ClientConfig, timeout time.Duration) (*ssh.Client, error) {
+	jump, err := u.JumpHost.connect(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("jump host %s: %w", u.JumpHost.Hostname, err)
+	}
+
+	// Tunneled connections have no deadlines, so a stalled hop is aborted by
+	// closing the jump connection under it
+	timer := time.AfterFunc(timeout, func() { jump.Close() })
+	client, err := dialThrough(jump, addr, config)
+	if !timer.Stop() {
+		if client != nil {
+			client.Close()
+		}
+		return nil, fmt.Errorf("%w: dial %s through %s: timed out after %s", ErrHostUnreachable, addr, u.JumpHost.Hostname, timeout)
+	}
+	if err != nil {
+		jump.Close()
+		return nil, fmt.Errorf("dial %s through %s: %w", addr, u.JumpHost.Hostname, err)
+	}
+
+	go func() {
+		client.Wait()
+		jump.Close()
+	}()
+	return client, nil
+}
+
+// dialThrough opens an SSH connection to addr over a TCP channel of jump.
+func dialThrough(jump *ssh.Client, addr string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	conn, err := jump.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return newClientConn(conn, addr, config, 0)
+}
+
+// dialWithRetry calls dial until it succeeds, fails with an error that is
+// not transient, or DialAttempts is reached, backing off exponentially
+// between attempts.
+func (u *UnixCommandManager) dialWithRetry(ctx context.Context, addr string, dial func() (*ssh.Client, error)) (*ssh.Client, error) {
+	if u.DialAttempts <= 1 {
+		return dial()
+	}
+
+	backoff := u.DialBackoff
+	for attempt := 1; ; attempt++ {
+		client, err := dial()
+		if err == nil {
+			return client, nil
+		}
+		if !transientDialError(err) {
+			return nil, err
+		}
+		if attempt == u.DialAttempts {
+			return nil, fmt.Errorf("dial %s failed after %d attempts: %w", addr, attempt, err)
+		}
+
+		u.logger().Warn("SSH dial failed, retrying", "hostname", u.Hostname, "attempt", attempt, "backoff", backoff, "error", err)
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("dial %s failed after %d attempts: %w", addr, attempt, err)
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// transientDialError reports whether a dial error may resolve by itself, as
+// when sshd is not yet listening on a host that is still booting. Failed
+// authentication and host key verification are never transient.
+func transientDialError(err error) bool {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED),
+		errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.EHOSTUNREACH),
+		errors.Is(err, syscall.ENETUNREACH),
+		errors.Is(err, io.EOF):
+		return true
+	case errors.As(err, &netErr):
+		return netErr.Timeout()
+	}
+	return false
+}
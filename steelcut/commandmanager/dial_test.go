@@ -0,0 +1,376 @@
+package commandmanager
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/steelcutops/steelcut/common"
+)
+
+// testSSHServer accepts SSH connections without authentication. Commands
+// succeed without output, channels for TCP forwarding are connected to
+// forward, whatever address they ask for, and closed connections are reported
+// on closed.
+type testSSHServer struct {
+	addr    string
+	forward string
+	closed  chan struct{}
+}
+
+func startTestSSHServer(t *testing.T, forward string) *testSSHServer {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+	config := &ssh.ServerConfig{NoClientAuth: true}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	server := &testSSHServer{addr: listener.Addr().String(), forward: forward, closed: make(chan struct{}, 8)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn, config)
+		}
+	}()
+	return server
+}
+
+func (s *testSSHServer) serve(conn net.Conn, config *ssh.ServerConfig) {
+	sshConn, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	go func() {
+		for newChannel := range chans {
+			if newChannel.ChannelType() == "session" {
+				go serveTestSession(newChannel)
+				continue
+			}
+			if newChannel.ChannelType() != "direct-tcpip" || s.forward == "" {
+				newChannel.Reject(ssh.Prohibited, "forwarding disabled")
+				continue
+			}
+			target, err := net.Dial("tcp", s.forward)
+			if err != nil {
+				newChannel.Reject(ssh.ConnectionFailed, err.Error())
+				continue
+			}
+			channel, requests, err := newChannel.Accept()
+			if err != nil {
+				target.Close()
+				continue
+			}
+			go ssh.DiscardRequests(requests)
+			go func() {
+				io.Copy(channel, target)
+				channel.Close()
+			}()
+			go func() {
+				io.Copy(target, channel)
+				target.Close()
+			}()
+		}
+	}()
+	sshConn.Wait()
+	s.closed <- struct{}{}
+}
+
+// serveTestSession runs every command successfully without output, except
+// echo, env, which prints its command line, sleep, which runs until the
+// session is closed, and "exit N", which fails with status N. Environment
+// requests are refused.
+func serveTestSession(newChannel ssh.NewChannel) {
+	channel, requests, err := newChannel.Accept()
+	if err != nil {
+		return
+	}
+	defer channel.Close()
+	for req := range requests {
+		if req.Type != "exec" {
+			req.Reply(false, nil)
+			continue
+		}
+		req.Reply(true, nil)
+		var exec struct{ Command string }
+		if ssh.Unmarshal(req.Payload, &exec) == nil && strings.HasPrefix(exec.Command, "sleep ") {
+			continue
+		}
+		if text, ok := strings.CutPrefix(exec.Command, "echo "); ok {
+			io.WriteString(channel, text+"\n")
+		}
+		if strings.HasPrefix(exec.Command, "env ") {
+			io.WriteString(channel, exec.Command+"\n")
+		}
+		var status uint32
+		if code, ok := strings.CutPrefix(exec.Command, "exit "); ok {
+			n, _ := strconv.Atoi(strings.TrimSpace(code))
+			status = uint32(n)
+			io.WriteString(channel.Stderr(), "failed\n")
+		}
+		channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{status}))
+		return
+	}
+}
+
+// addrDialer dials addr regardless of the address it is asked for.
+type addrDialer struct {
+	addr string
+}
+
+func (d addrDialer) Dial(network, _ string, config *ssh.ClientConfig, timeout time.Duration) (*ssh.Client, error) {
+	conn, err := net.DialTimeout(network, d.addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, d.addr, config)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return ssh.NewClient(sshConn, chans, reqs), nil
+}
+
+func testJumpManager(hostname string) *UnixCommandManager {
+	return &UnixCommandManager{
+		Hostname:        hostname,
+		Credentials:     common.Credentials{User: "test", Password: "test"},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	}
+}
+
+func TestConnectThroughJumpHosts(t *testing.T) {
+	target := startTestSSHServer(t, "")
+	inner := startTestSSHServer(t, target.addr)
+	outer := startTestSSHServer(t, inner.addr)
+
+	outerJump := testJumpManager("outer.example.com")
+	outerJump.SSHClient = addrDialer{addr: outer.addr}
+	innerJump := testJumpManager("inner.example.com")
+	innerJump.JumpHost = outerJump
+	manager := testJumpManager("target.example.com")
+	manager.JumpHost = innerJump
+	// Only the outermost hop is dialed directly
+	manager.SSHClient = &MockSSHClient{dialError: errors.New("dialed directly")}
+
+	client, err := manager.connect(context.Background())
+	if err != nil {
+		t.Fatalf("connect failed: %v", err)
+	}
+	client.Close()
+
+	for name, server := range map[string]*testSSHServer{"target": target, "inner": inner, "outer": outer} {
+		select {
+		case <-server.closed:
+		case <-time.After(5 * time.Second):
+			t.Errorf("%s connection was not closed", name)
+		}
+	}
+}
+
+func TestConnectJumpHostDialError(t *testing.T) {
+	dialErr := errors.New("connection refused")
+	jump := testJumpManager("bastion.example.com")
+	jump.SSHClient = &MockSSHClient{dialError: dialErr}
+	manager := testJumpManager("target.example.com")
+	manager.SSHClient = &MockSSHClient{dialError: errors.New("dialed directly")}
+	manager.JumpHost = jump
+
+	_, err := manager.connect(context.Background())
+	if !errors.Is(err, dialErr) {
+		t.Fatalf("Expected jump host dial error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "bastion.example.com") {
+		t.Errorf("Expected error to name the jump host, got %v", err)
+	}
+}
+
+func TestConnectJumpHostTimeout(t *testing.T) {
+	// A target that accepts connections but never completes the handshake
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		var conns []net.Conn
+		defer func() {
+			for _, conn := range conns {
+				conn.Close()
+			}
+		}()
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conns = append(conns, conn)
+		}
+	}()
+	bastion := startTestSSHServer(t, listener.Addr().String())
+
+	jump := testJumpManager("bastion.example.com")
+	jump.SSHClient = addrDialer{addr: bastion.addr}
+	manager := testJumpManager("target.example.com")
+	manager.JumpHost = jump
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	_, err = manager.connect(ctx)
+	if !errors.Is(err, ErrHostUnreachable) || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("Expected timeout error, got %v", err)
+	}
+
+	select {
+	case <-bastion.closed:
+	case <-time.After(5 * time.Second):
+		t.Error("Jump host connection was not closed after timeout")
+	}
+}
+
+// flakyDialer fails the first failures dials with err.
+type flakyDialer struct {
+	addrDialer
+	failures int
+	err      error
+	dials    int
+}
+
+func (d *flakyDialer) Dial(network, addr string, config *ssh.ClientConfig, timeout time.Duration) (*ssh.Client, error) {
+	d.dials++
+	if d.dials <= d.failures {
+		return nil, d.err
+	}
+	return d.addrDialer.Dial(network, addr, config, timeout)
+}
+
+func TestDialRetryTransientErrors(t *testing.T) {
+	server := startTestSSHServer(t, "")
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+	dialer := &flakyDialer{addrDialer: addrDialer{addr: server.addr}, failures: 2, err: refused}
+	manager := testJumpManager("booting.example.com")
+	manager.SSHClient = dialer
+	manager.DialAttempts = 3
+	manager.DialBackoff = time.Millisecond
+
+	client, err := manager.connect(context.Background())
+	if err != nil {
+		t.Fatalf("connect failed: %v", err)
+	}
+	client.Close()
+	if dialer.dials != 3 {
+		t.Errorf("Expected 3 dials, got %d", dialer.dials)
+	}
+
+	dialer = &flakyDialer{failures: 10, err: refused}
+	manager.SSHClient = dialer
+	manager.DialAttempts = 2
+	_, err = manager.connect(context.Background())
+	if !errors.Is(err, syscall.ECONNREFUSED) || !strings.Contains(err.Error(), "after 2 attempts") {
+		t.Errorf("Expected the error to report 2 attempts, got %v", err)
+	}
+}
+
+func TestDialRetrySkipsPermanentErrors(t *testing.T) {
+	for _, dialErr := range []error{
+		errors.New("ssh: handshake failed: ssh: unable to authenticate, attempted methods [none password], no supported methods remain"),
+		fmt.Errorf("ssh: handshake failed: %w", ErrHostKeyMismatch),
+	} {
+		dialer := &flakyDialer{failures: 10, err: dialErr}
+		manager := testJumpManager("broken.example.com")
+		manager.SSHClient = dialer
+		manager.DialAttempts = 5
+		manager.DialBackoff = time.Millisecond
+
+		if _, err := manager.connect(context.Background()); !errors.Is(err, dialErr) {
+			t.Errorf("Expected %v, got %v", dialErr, err)
+		}
+		if dialer.dials != 1 {
+			t.Errorf("Expected %q not to be retried, got %d dials", dialErr, dialer.dials)
+		}
+	}
+}
+
+func TestConnectErrorKinds(t *testing.T) {
+	// A port nothing listens on
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	closedAddr := listener.Addr().String()
+	listener.Close()
+
+	manager := testJumpManager("remote.example.com")
+	manager.SSHClient = addrDialer{addr: closedAddr}
+	if _, err := manager.connect(context.Background()); !errors.Is(err, ErrHostUnreachable) || !errors.Is(err, syscall.ECONNREFUSED) {
+		t.Errorf("Expected ErrHostUnreachable wrapping the refusal, got %v", err)
+	}
+
+	// A server that rejects every password
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(ssh.ConnMetadata, []byte) (*ssh.Permissions, error) {
+			return nil, errors.New("denied")
+		},
+		KeyboardInteractiveCallback: func(ssh.ConnMetadata, ssh.KeyboardInteractiveChallenge) (*ssh.Permissions, error) {
+			return nil, errors.New("denied")
+		},
+	}
+	config.AddHostKey(signer)
+	listener, err = net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				ssh.NewServerConn(conn, config)
+				conn.Close()
+			}()
+		}
+	}()
+
+	manager.SSHClient = addrDialer{addr: listener.Addr().String()}
+	if _, err := manager.connect(context.Background()); !errors.Is(err, ErrSSHAuth) {
+		t.Errorf("Expected ErrSSHAuth, got %v", err)
+	}
+}
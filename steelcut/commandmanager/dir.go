@@ -0,0 +1,46 @@
+package commandmanager
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ErrDirNotFound is returned when a command's working directory, set with
+// CommandConfig.Dir, does not exist.
+var ErrDirNotFound = errors.New("working directory does not exist")
+
+// dirMissingMarker starts the line a remote command prints to stderr when its
+// working directory is missing, telling that apart from the command failing.
+const dirMissingMarker = "steelcut: working directory does not exist: "
+
+// dirPrefix returns the shell commands that change to dir, or fail with
+// dirMissingMarker if it does not exist, followed by " && ".
+func dirPrefix(dir string) string {
+	quoted := ShellQuote(dir)
+	return fmt.Sprintf("{ [ -d %s ] || { echo %s >&2; exit 1; }; } && cd %s && ",
+		quoted, ShellQuote(dirMissingMarker+dir), quoted)
+}
+
+// dirError returns ErrDirNotFound if result is from a remote command whose
+// working directory does not exist.
+func dirError(dir string, result CommandResult) error {
+	if dir != "" && result.ExitCode == 1 && strings.HasPrefix(result.STDERR, dirMissingMarker) {
+		return fmt.Errorf("%w: %s", ErrDirNotFound, dir)
+	}
+	return nil
+}
+
+// checkLocalDir returns ErrDirNotFound if dir is set and is not an existing
+// local directory.
+func checkLocalDir(dir string) error {
+	if dir == "" {
+		return nil
+	}
+	info, err := os.Stat(dir)
+	if errors.Is(err, os.ErrNotExist) || (err == nil && !info.IsDir()) {
+		return fmt.Errorf("%w: %s", ErrDirNotFound, dir)
+	}
+	return err
+}
@@ -0,0 +1,36 @@
+package commandmanager
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var envName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// checkEnv rejects CommandConfig.Env entries that are not NAME=value with a
+// valid variable name, which env would otherwise take for the command.
+func checkEnv(env []string) error {
+	for _, e := range env {
+		name, _, ok := strings.Cut(e, "=")
+		if !ok || !envName.MatchString(name) {
+			return fmt.Errorf("invalid environment variable: %q", e)
+		}
+	}
+	return nil
+}
+
+// envCommand rewrites config to set its Env through env(1), for when the
+// variables can't be passed to the process directly: sudo resets the
+// environment, and SSH servers accept only the variables allowed by their
+// AcceptEnv setting.
+func envCommand(config CommandConfig) CommandConfig {
+	if len(config.Env) == 0 {
+		return config
+	}
+	wrapped := config
+	wrapped.Command = "env"
+	wrapped.Args = append(append(append([]string(nil), config.Env...), config.Command), config.Args...)
+	wrapped.Env = nil
+	return wrapped
+}
@@ -0,0 +1,48 @@
+package commandmanager
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Errors for the ways a command can fail before or instead of running to
+// completion, for use with errors.Is. The underlying error stays wrapped.
+// See also ErrSudoAuth, ErrHostKeyMismatch and ErrUnknownHost.
+var (
+	// ErrHostUnreachable is returned when no connection to the host could
+	// be established: the name did not resolve, the connection was refused
+	// or reset, or it timed out.
+	ErrHostUnreachable = errors.New("host unreachable")
+
+	// ErrSSHAuth is returned when the SSH server rejected every
+	// authentication method offered, or the keys to offer could not be
+	// loaded.
+	ErrSSHAuth = errors.New("SSH authentication failed")
+
+	// ErrCommandTimeout is returned when a command is stopped because its
+	// context's deadline passed. context.DeadlineExceeded is wrapped too.
+	ErrCommandTimeout = errors.New("command timed out")
+)
+
+// dialError wraps an error from connecting to addr in ErrHostUnreachable or
+// ErrSSHAuth, depending on its cause. Other errors, such as host key
+// failures, are returned unchanged.
+func dialError(addr string, err error) error {
+	var dnsErr *net.DNSError
+	switch {
+	case err == nil,
+		errors.Is(err, ErrHostUnreachable),
+		errors.Is(err, ErrSSHAuth),
+		errors.Is(err, ErrHostKeyMismatch),
+		errors.Is(err, ErrUnknownHost):
+		return err
+	// x/crypto/ssh reports authentication failures only in the message
+	case strings.Contains(err.Error(), "ssh: unable to authenticate"):
+		return fmt.Errorf("%w: %s: %w", ErrSSHAuth, addr, err)
+	case transientDialError(err), errors.As(err, &dnsErr):
+		return fmt.Errorf("%w: %s: %w", ErrHostUnreachable, addr, err)
+	}
+	return err
+}
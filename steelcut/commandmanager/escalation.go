@@ -0,0 +1,239 @@
+package commandmanager
+
+import (
+	"context"
+	"errors"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// ErrNoEscalationTool is returned when neither sudo nor doas is installed.
+var ErrNoEscalationTool = errors.New("no privilege escalation tool found")
+
+// ErrSudoAuth is returned when sudo rejects the configured password.
+var ErrSudoAuth = errors.New("sudo: incorrect password provided")
+
+// EscalationStrategy describes how a command is run with elevated privileges
+// and how failures of the escalation tool are recognised.
+type EscalationStrategy interface {
+	// Name returns the name of the escalation tool, e.g. "sudo".
+	Name() string
+
+	// Wrap returns the command line that runs command with args as root.
+	Wrap(command string, args []string) []string
+
+	// Stdin returns the data to feed the escalation tool for authentication.
+	// An empty string means nothing is written to stdin.
+	Stdin(password string) string
+
+	// CheckErrors inspects a command result and returns an error describing
+	// an escalation failure, or nil.
+	CheckErrors(result CommandResult) error
+}
+
+// sudoPrompt replaces sudo's password prompt, which is localised and names
+// the user, with a fixed marker. It is removed from the command's output, and
+// counting it tells a rejected password apart from a failing command.
+const sudoPrompt = "[steelcut-sudo-prompt]"
+
+// sudoPromptPattern matches sudo's prompts, both the marker and the default
+// one shown when sudoers sets passprompt_override.
+var sudoPromptPattern = regexp.MustCompile(`\[steelcut-sudo-prompt\]|\[sudo\] password for [^:\n]*: ?`)
+
+// SudoStrategy escalates privileges with sudo, passing the password on stdin.
+type SudoStrategy struct{}
+
+func (SudoStrategy) Name() string {
+	return "sudo"
+}
+
+func (SudoStrategy) Wrap(command string, args []string) []string {
+	return append([]string{"sudo", "-S", "-p", sudoPrompt, "--", command}, args...)
+}
+
+func (SudoStrategy) Stdin(password string) string {
+	return password + "\n"
+}
+
+func (SudoStrategy) CheckErrors(result CommandResult) error {
+	// sudo prompts again only after a wrong password, and exits 1 once it
+	// runs out of attempts or input
+	if result.ExitCode == 1 && strings.Count(result.STDERR, sudoPrompt) > 1 {
+		return ErrSudoAuth
+	}
+	if strings.Contains(result.STDERR, "is not in the sudoers file") {
+		return errors.New("sudo: user is not in the sudoers file")
+	}
+	if strings.Contains(result.STDERR, "timed out reading password") {
+		return errors.New("sudo: password prompt timed out")
+	}
+	if strings.Contains(result.STDERR, "no tty present and no askpass program specified") {
+		return errors.New("sudo: cannot prompt for password due to missing terminal or askpass program")
+	}
+	if strings.Contains(result.STDERR, "unknown user") {
+		return errors.New("sudo: specified user is unknown")
+	}
+	if strings.Contains(result.STDERR, "unable to execute") {
+		return errors.New("sudo: unable to execute the specified command")
+	}
+	if strings.Contains(result.STDERR, "Permission denied") {
+		return errors.New("permission denied: consider using sudo for this command")
+	}
+	return nil
+}
+
+// stripSudoPrompts removes sudo's password prompts from output. sudo writes
+// them without a newline, so whatever follows on the line is kept.
+func stripSudoPrompts(output string) string {
+	return sudoPromptPattern.ReplaceAllString(output, "")
+}
+
+// PkexecStrategy escalates privileges through polkit's pkexec. Authentication
+// is handled by the polkit agent, so no password is written to stdin.
+type PkexecStrategy struct{}
+
+func (PkexecStrategy) Name() string {
+	return "pkexec"
+}
+
+func (PkexecStrategy) Wrap(command string, args []string) []string {
+	return append([]string{"pkexec", command}, args...)
+}
+
+func (PkexecStrategy) Stdin(_ string) string {
+	return ""
+}
+
+func (PkexecStrategy) CheckErrors(result CommandResult) error {
+	if strings.Contains(result.STDERR, "Not authorized") {
+		return errors.New("pkexec: not authorized by polkit")
+	}
+	if strings.Contains(result.STDERR, "No authentication agent found") {
+		return errors.New("pkexec: no polkit authentication agent available")
+	}
+	if strings.Contains(result.STDERR, "Request dismissed") {
+		return errors.New("pkexec: authentication dialog was dismissed")
+	}
+	if strings.Contains(result.STDERR, "Permission denied") {
+		return errors.New("permission denied: consider using pkexec for this command")
+	}
+	return nil
+}
+
+// DoasStrategy escalates privileges with OpenBSD's doas. doas reads passwords
+// only from a terminal, so it runs non-interactively and needs a nopass or
+// persist rule for the user.
+type DoasStrategy struct{}
+
+func (DoasStrategy) Name() string {
+	return "doas"
+}
+
+func (DoasStrategy) Wrap(command string, args []string) []string {
+	return append([]string{"doas", "-n", "--", command}, args...)
+}
+
+func (DoasStrategy) Stdin(_ string) string {
+	return ""
+}
+
+func (DoasStrategy) CheckErrors(result CommandResult) error {
+	if strings.Contains(result.STDERR, "doas: Authentication required") {
+		return errors.New("doas: authentication required; doas cannot read a password from stdin, add a nopass rule")
+	}
+	if strings.Contains(result.STDERR, "doas: Authentication failed") {
+		return errors.New("doas: authentication failed")
+	}
+	if strings.Contains(result.STDERR, "doas: Operation not permitted") {
+		return errors.New("doas: user is not permitted by doas.conf")
+	}
+	if strings.Contains(result.STDERR, "doas: doas is not enabled") {
+		return errors.New("doas: no doas.conf found on the host")
+	}
+	if strings.Contains(result.STDERR, "Permission denied") {
+		return errors.New("permission denied: consider using doas for this command")
+	}
+	return nil
+}
+
+// DetectEscalation picks the escalation strategy for the host behind manager.
+// sudo is preferred; doas is used only when sudo is not installed.
+func DetectEscalation(ctx context.Context, manager CommandManager) (EscalationStrategy, error) {
+	result, err := manager.Run(ctx, CommandConfig{
+		Command: "which",
+		Args:    []string{"sudo", "doas"},
+	})
+	// which exits non-zero when any tool is missing, so only fail when it
+	// could not run at all
+	if err != nil && !IsExitError(err) {
+		return nil, err
+	}
+
+	found := make(map[string]bool)
+	for _, line := range Lines(result.STDOUT) {
+		found[path.Base(line)] = true
+	}
+
+	switch {
+	case found["sudo"]:
+		return SudoStrategy{}, nil
+	case found["doas"]:
+		return DoasStrategy{}, nil
+	}
+	return nil, ErrNoEscalationTool
+}
+
+// EscalationDetector picks the escalation strategy with DetectEscalation the
+// first time a privileged command runs, so hosts that never escalate are not
+// probed. Only doas is remembered: sudo, or no tool at all, keeps the
+// manager's default, and a failed probe is retried by the next privileged
+// command. A nil EscalationDetector detects nothing.
+//
+// An EscalationDetector is safe for concurrent use.
+type EscalationDetector struct {
+	// probe serialises probes; mu guards the result, which the probe's own
+	// command reads
+	probe    sync.Mutex
+	mu       sync.Mutex
+	done     bool
+	strategy EscalationStrategy
+}
+
+// detect probes the host behind manager unless it was already probed.
+func (d *EscalationDetector) detect(ctx context.Context, manager CommandManager) {
+	if d == nil {
+		return
+	}
+	d.probe.Lock()
+	defer d.probe.Unlock()
+
+	d.mu.Lock()
+	done := d.done
+	d.mu.Unlock()
+	if done {
+		return
+	}
+
+	strategy, err := DetectEscalation(ctx, manager)
+	if err != nil && !errors.Is(err, ErrNoEscalationTool) {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.done = true
+	if err == nil && strategy.Name() != "sudo" {
+		d.strategy = strategy
+	}
+}
+
+// detected returns the strategy found by detect, or nil to use the default.
+func (d *EscalationDetector) detected() EscalationStrategy {
+	if d == nil {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.strategy
+}
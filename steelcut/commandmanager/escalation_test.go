@@ -0,0 +1,363 @@
+package commandmanager
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steelcutops/steelcut/common"
+)
+
+func TestSudoStrategyWrap(t *testing.T) {
+	got := SudoStrategy{}.Wrap("apt-get", []string{"install", "-y", "curl"})
+	expected := []string{"sudo", "-S", "-p", sudoPrompt, "--", "apt-get", "install", "-y", "curl"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+
+	if stdin := (SudoStrategy{}).Stdin("secret"); stdin != "secret\n" {
+		t.Errorf("Expected password on stdin, got %q", stdin)
+	}
+}
+
+func TestPkexecStrategyWrap(t *testing.T) {
+	got := PkexecStrategy{}.Wrap("systemctl", []string{"restart", "nginx"})
+	expected := []string{"pkexec", "systemctl", "restart", "nginx"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+
+	if stdin := (PkexecStrategy{}).Stdin("secret"); stdin != "" {
+		t.Errorf("Expected no stdin for pkexec, got %q", stdin)
+	}
+}
+
+func TestEscalationCheckErrors(t *testing.T) {
+	if err := (SudoStrategy{}).CheckErrors(CommandResult{STDERR: "user is not in the sudoers file"}); err == nil {
+		t.Errorf("Expected sudoers error from SudoStrategy")
+	}
+	if err := (PkexecStrategy{}).CheckErrors(CommandResult{STDERR: "Error executing command as another user: Not authorized"}); err == nil {
+		t.Errorf("Expected authorization error from PkexecStrategy")
+	}
+	if err := (PkexecStrategy{}).CheckErrors(CommandResult{STDOUT: "ok"}); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+}
+
+func TestDefaultEscalationIsSudo(t *testing.T) {
+	manager := UnixCommandManager{}
+	if name := manager.escalation().Name(); name != "sudo" {
+		t.Errorf("Expected default escalation to be sudo, got %s", name)
+	}
+
+	manager.Escalation = PkexecStrategy{}
+	if name := manager.escalation().Name(); name != "pkexec" {
+		t.Errorf("Expected pkexec escalation, got %s", name)
+	}
+}
+
+func TestDoasStrategy(t *testing.T) {
+	got := DoasStrategy{}.Wrap("rc-service", []string{"nginx", "restart"})
+	expected := []string{"doas", "-n", "--", "rc-service", "nginx", "restart"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+
+	if stdin := (DoasStrategy{}).Stdin("secret"); stdin != "" {
+		t.Errorf("Expected no stdin for doas, got %q", stdin)
+	}
+
+	for _, stderr := range []string{
+		"doas: Authentication required\n",
+		"doas: Authentication failed\n",
+		"doas: Operation not permitted\n",
+		"doas: doas is not enabled, /etc/doas.conf: No such file or directory\n",
+	} {
+		if err := (DoasStrategy{}).CheckErrors(CommandResult{STDERR: stderr}); err == nil {
+			t.Errorf("Expected error for %q", stderr)
+		}
+	}
+	if err := (DoasStrategy{}).CheckErrors(CommandResult{STDERR: "sudo: incorrect password"}); err != nil {
+		t.Errorf("Expected sudo messages to be ignored by doas, got: %v", err)
+	}
+}
+
+type whichCommandManager struct {
+	result CommandResult
+	err    error
+}
+
+func (w *whichCommandManager) RunLocal(ctx context.Context, config CommandConfig) (CommandResult, error) {
+	return w.result, w.err
+}
+
+func (w *whichCommandManager) RunRemote(ctx context.Context, config CommandConfig) (CommandResult, error) {
+	return w.result, w.err
+}
+
+func (w *whichCommandManager) Run(ctx context.Context, config CommandConfig) (CommandResult, error) {
+	return w.result, w.err
+}
+
+func TestDetectEscalation(t *testing.T) {
+	tests := []struct {
+		stdout   string
+		exitCode int
+		expected string
+	}{
+		{"/usr/bin/sudo\n/usr/bin/doas\n", 0, "sudo"},
+		{"/usr/bin/sudo\n", 1, "sudo"},
+		{"/usr/local/bin/doas\n", 1, "doas"},
+	}
+	for _, tt := range tests {
+		manager := &whichCommandManager{result: CommandResult{STDOUT: tt.stdout, ExitCode: tt.exitCode}}
+		strategy, err := DetectEscalation(context.Background(), manager)
+		if err != nil || strategy.Name() != tt.expected {
+			t.Errorf("Expected %s for %q, got %v, %v", tt.expected, tt.stdout, strategy, err)
+		}
+	}
+
+	manager := &whichCommandManager{result: CommandResult{ExitCode: 1}, err: &CommandError{Command: "which", ExitCode: 1}}
+	if _, err := DetectEscalation(context.Background(), manager); !errors.Is(err, ErrNoEscalationTool) {
+		t.Errorf("Expected ErrNoEscalationTool, got: %v", err)
+	}
+
+	manager = &whichCommandManager{err: errors.New("connection reset")}
+	if _, err := DetectEscalation(context.Background(), manager); err == nil || errors.Is(err, ErrNoEscalationTool) {
+		t.Errorf("Expected connection error, got: %v", err)
+	}
+}
+
+func TestEscalationDetector(t *testing.T) {
+	var detector *EscalationDetector
+	detector.detect(context.Background(), &whichCommandManager{})
+	if strategy := detector.detected(); strategy != nil {
+		t.Errorf("Expected a nil detector to detect nothing, got %v", strategy)
+	}
+
+	detector = &EscalationDetector{}
+	detector.detect(context.Background(), &whichCommandManager{err: errors.New("connection reset")})
+	detector.detect(context.Background(), &whichCommandManager{result: CommandResult{STDOUT: "/usr/bin/doas\n"}})
+	if strategy := detector.detected(); strategy == nil || strategy.Name() != "doas" {
+		t.Errorf("Expected doas after a failed probe was retried, got %v", strategy)
+	}
+	detector.detect(context.Background(), &whichCommandManager{result: CommandResult{STDOUT: "/usr/bin/sudo\n"}})
+	if strategy := detector.detected(); strategy == nil || strategy.Name() != "doas" {
+		t.Errorf("Expected the host to be probed once, got %v", strategy)
+	}
+
+	detector = &EscalationDetector{}
+	detector.detect(context.Background(), &whichCommandManager{result: CommandResult{STDOUT: "/usr/bin/sudo\n/usr/bin/doas\n"}})
+	if strategy := detector.detected(); strategy != nil {
+		t.Errorf("Expected sudo to keep the default, got %v", strategy)
+	}
+}
+
+// fakeWhich stands in for which on a host with only doas, counting its runs
+// in the file named by $WHICH_RUNS.
+const fakeWhich = `#!/bin/sh
+echo run >> "$WHICH_RUNS"
+echo /usr/bin/doas
+exit 1
+`
+
+// fakeDoas stands in for doas with a nopass rule, running the command after
+// "--".
+const fakeDoas = `#!/bin/sh
+[ "$1" = -n ] && [ "$2" = -- ] || exit 99
+shift 2
+exec "$@"
+`
+
+func TestUnixCommandManagerDetectsEscalation(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{"which": fakeWhich, "doas": fakeDoas} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o755); err != nil {
+			t.Fatalf("Failed to write fake %s: %v", name, err)
+		}
+	}
+	runs := filepath.Join(dir, "runs")
+	t.Setenv("WHICH_RUNS", runs)
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	manager := UnixCommandManager{Hostname: "localhost", EscalationDetector: &EscalationDetector{}}
+	if _, err := manager.Run(context.Background(), CommandConfig{Command: "true"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := os.Stat(runs); !os.IsNotExist(err) {
+		t.Errorf("Expected no probe before a privileged command, got %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		result, err := manager.Run(context.Background(), CommandConfig{
+			Command: "cat",
+			Sudo:    true,
+			Stdin:   strings.NewReader("input\n"),
+		})
+		if err != nil || result.STDOUT != "input\n" {
+			t.Fatalf("Expected the command to run with doas, got %v (%+v)", err, result)
+		}
+	}
+	if data, _ := os.ReadFile(runs); string(data) != "run\n" {
+		t.Errorf("Expected one probe, got %q", data)
+	}
+}
+
+// fakeAskpassSudo stands in for sudo -A, asking for the password until it
+// gets "secret" and giving up when the askpass helper fails. It refuses to
+// run unless the password is served through a FIFO rather than a file.
+const fakeAskpassSudo = `#!/bin/sh
+[ "$1" = -A ] && [ "$2" = -- ] || exit 99
+shift 2
+[ -p "${SUDO_ASKPASS%/*}/password" ] || { echo "password stored in a file" >&2; exit 98; }
+while :; do
+	password=$("$SUDO_ASKPASS") || { echo "sudo: no password was provided" >&2; exit 1; }
+	[ "$password" = secret ] && exec "$@"
+	echo "Sorry, try again." >&2
+done
+`
+
+func TestSudoAskpassStrategy(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "sudo"), []byte(fakeAskpassSudo), 0o755); err != nil {
+		t.Fatalf("Failed to write fake sudo: %v", err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	manager := UnixCommandManager{
+		Hostname:    "localhost",
+		Credentials: common.Credentials{SudoPassword: "secret"},
+		Escalation:  SudoAskpassStrategy{},
+	}
+	result, err := manager.RunLocal(context.Background(), CommandConfig{
+		Command: "cat",
+		Sudo:    true,
+		Stdin:   strings.NewReader("first line\nsecond line\n"),
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v (%+v)", err, result)
+	}
+	if result.STDOUT != "first line\nsecond line\n" {
+		t.Errorf("Expected stdin to reach the command intact, got %q", result.STDOUT)
+	}
+
+	manager.SudoPassword = "wrong"
+	result, err = manager.RunLocal(context.Background(), CommandConfig{Command: "true", Sudo: true})
+	if !errors.Is(err, ErrSudoAuth) {
+		t.Errorf("Expected ErrSudoAuth, got %v (%+v)", err, result)
+	}
+
+	// Under NOPASSWD the password is never read, and the blocked writer must
+	// not hold the command open
+	if err := os.WriteFile(filepath.Join(dir, "sudo"), []byte(fakeNopasswdSudo), 0o755); err != nil {
+		t.Fatalf("Failed to write fake sudo: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	result, err = manager.RunLocal(ctx, CommandConfig{Command: "echo", Args: []string{"ok"}, Sudo: true})
+	if err != nil || result.STDOUT != "ok\n" {
+		t.Errorf("Expected the command to run without a prompt, got %v (%+v)", err, result)
+	}
+
+	if err := (SudoAskpassStrategy{}).CheckErrors(CommandResult{ExitCode: askpassRejectedStatus}); err != nil {
+		t.Errorf("Expected a plain exit status 125 not to be an auth error, got %v", err)
+	}
+}
+
+// fakeNopasswdSudo stands in for sudo under a NOPASSWD rule: it never asks
+// for a password and runs the command after "--".
+const fakeNopasswdSudo = `#!/bin/sh
+while [ "$1" != -- ]; do shift; done
+shift
+exec "$@"
+`
+
+func TestSudoNopasswdStdin(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "sudo"), []byte(fakeNopasswdSudo), 0o755); err != nil {
+		t.Fatalf("Failed to write fake sudo: %v", err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	input := "10.0.0.5 db\n\x00binary"
+	for _, password := range []string{"secret", ""} {
+		manager := UnixCommandManager{Hostname: "localhost", Credentials: common.Credentials{SudoPassword: password}}
+		result, err := manager.RunLocal(context.Background(), CommandConfig{
+			Command: "cat",
+			Sudo:    true,
+			Stdin:   strings.NewReader(input),
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v (%+v)", err, result)
+		}
+		if result.STDOUT != input {
+			t.Errorf("Expected exactly the caller's stdin with password %q, got %q", password, result.STDOUT)
+		}
+	}
+}
+
+// fakeStdinSudo stands in for sudo -S -p, reading up to three passwords from
+// stdin and complaining in French about wrong ones.
+const fakeStdinSudo = `#!/bin/sh
+[ "$1" = -S ] && [ "$2" = -p ] && [ "$4" = -- ] || exit 99
+prompt=$3
+shift 4
+for i in 1 2 3; do
+	printf '%s' "$prompt" >&2
+	IFS= read -r password || { echo "sudo: aucun mot de passe fourni" >&2; exit 1; }
+	[ "$password" = secret ] && exec "$@"
+	echo "Désolé, essayez de nouveau." >&2
+done
+exit 1
+`
+
+func TestSudoStrategyPassword(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "sudo"), []byte(fakeStdinSudo), 0o755); err != nil {
+		t.Fatalf("Failed to write fake sudo: %v", err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	manager := UnixCommandManager{Hostname: "localhost", Credentials: common.Credentials{SudoPassword: "secret"}}
+	result, err := manager.RunLocal(context.Background(), CommandConfig{
+		Command: "sh",
+		Args:    []string{"-c", "echo warning >&2"},
+		Sudo:    true,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v (%+v)", err, result)
+	}
+	if result.STDERR != "warning\n" {
+		t.Errorf("Expected the prompt to be stripped, got %q", result.STDERR)
+	}
+
+	_, err = manager.RunLocal(context.Background(), CommandConfig{Command: "false", Sudo: true})
+	var cmdErr *CommandError
+	if errors.Is(err, ErrSudoAuth) || !errors.As(err, &cmdErr) {
+		t.Errorf("Expected a failing command not to be an auth error, got %v", err)
+	}
+
+	manager.SudoPassword = "wrong"
+	_, err = manager.RunLocal(context.Background(), CommandConfig{Command: "true", Sudo: true})
+	if !errors.Is(err, ErrSudoAuth) {
+		t.Errorf("Expected ErrSudoAuth, got %v", err)
+	}
+}
+
+func TestStripSudoPrompts(t *testing.T) {
+	tests := map[string]string{
+		sudoPrompt + "E: Unable to locate package\n": "E: Unable to locate package\n",
+		"[sudo] password for deploy: warning\n":      "warning\n",
+		"no prompt here\n":                           "no prompt here\n",
+	}
+	for input, expected := range tests {
+		if got := stripSudoPrompts(input); got != expected {
+			t.Errorf("stripSudoPrompts(%q) = %q, expected %q", input, got, expected)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package commandmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrUnexpectedExitCode is returned by MustRun and RunExpectCode when a
+// command exits with a code other than the one expected.
+var ErrUnexpectedExitCode = errors.New("unexpected exit code")
+
+// MustRun runs config on manager and returns its standard output, failing
+// unless the command exits 0.
+func MustRun(ctx context.Context, manager CommandManager, config CommandConfig) (string, error) {
+	return RunExpectCode(ctx, manager, config, 0)
+}
+
+// RunExpectCode runs config on manager and returns its standard output,
+// failing unless the command exits with want, e.g. 1 for a grep that must
+// not match. The error includes the command's stderr.
+func RunExpectCode(ctx context.Context, manager CommandManager, config CommandConfig, want int) (string, error) {
+	result, err := manager.Run(ctx, config)
+	if err != nil && !IsExitError(err) {
+		return result.STDOUT, err
+	}
+	if result.ExitCode != want {
+		command := strings.TrimSpace(config.Command + " " + shellJoin(config.Args))
+		return result.STDOUT, fmt.Errorf("%w: %s exited with %d, expected %d: %s",
+			ErrUnexpectedExitCode, command, result.ExitCode, want, strings.TrimSpace(result.STDERR))
+	}
+	return result.STDOUT, nil
+}
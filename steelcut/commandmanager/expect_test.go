@@ -0,0 +1,60 @@
+package commandmanager
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMustRun(t *testing.T) {
+	manager := &UnixCommandManager{Hostname: "localhost"}
+	ctx := context.Background()
+
+	output, err := MustRun(ctx, manager, CommandConfig{Command: "echo", Args: []string{"ok"}})
+	if err != nil || output != "ok\n" {
+		t.Errorf("Expected ok, got %q, %v", output, err)
+	}
+
+	_, err = MustRun(ctx, manager, CommandConfig{Command: "sh", Args: []string{"-c", "echo broken >&2; exit 3"}})
+	if !errors.Is(err, ErrUnexpectedExitCode) {
+		t.Fatalf("Expected ErrUnexpectedExitCode, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), "exited with 3") || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("Expected exit code and stderr in the error, got: %v", err)
+	}
+}
+
+func TestRunExpectCode(t *testing.T) {
+	manager := &UnixCommandManager{Hostname: "localhost"}
+	ctx := context.Background()
+	config := CommandConfig{Command: "grep", Args: []string{"-q", "needle", "/dev/null"}}
+
+	if _, err := RunExpectCode(ctx, manager, config, 1); err != nil {
+		t.Errorf("Expected grep exiting 1 to pass, got: %v", err)
+	}
+	if _, err := RunExpectCode(ctx, manager, config, 0); !errors.Is(err, ErrUnexpectedExitCode) {
+		t.Errorf("Expected ErrUnexpectedExitCode, got: %v", err)
+	}
+
+	_, err := RunExpectCode(ctx, manager, CommandConfig{Command: "steelcut-no-such-command"}, 0)
+	if err == nil || errors.Is(err, ErrUnexpectedExitCode) {
+		t.Errorf("Expected the exec error for a missing command, got: %v", err)
+	}
+}
+
+func TestRunExpectCodeNotRun(t *testing.T) {
+	ctx := context.Background()
+	for _, want := range []error{ErrDirNotFound, ErrSudoAuth} {
+		manager := &whichCommandManager{result: CommandResult{ExitCode: 1}, err: want}
+		if _, err := RunExpectCode(ctx, manager, CommandConfig{Command: "grep"}, 1); !errors.Is(err, want) {
+			t.Errorf("Expected %v for a command that did not run, got: %v", want, err)
+		}
+	}
+
+	manager := &UnixCommandManager{Hostname: "localhost"}
+	config := CommandConfig{Command: "true", Dir: "/steelcut-no-such-dir"}
+	if _, err := RunExpectCode(ctx, manager, config, 1); !errors.Is(err, ErrDirNotFound) {
+		t.Errorf("Expected ErrDirNotFound, got: %v", err)
+	}
+}
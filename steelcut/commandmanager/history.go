@@ -0,0 +1,133 @@
+package commandmanager
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNoCommandHistory is returned when no command has been recorded, either
+// because none has run yet or because history is not enabled.
+var ErrNoCommandHistory = errors.New("no command history recorded")
+
+// CommandTrace records a command that was run and what it produced, with the
+// host's credentials masked.
+type CommandTrace struct {
+	Host      string
+	Command   string
+	Args      []string
+	Sudo      bool
+	Env       []string
+	Dir       string
+	STDOUT    string
+	STDERR    string
+	ExitCode  int
+	Err       error
+	Duration  time.Duration
+	Timestamp time.Time
+}
+
+// CommandHistory keeps the most recent commands run on a host in a ring
+// buffer. A nil CommandHistory records nothing.
+//
+// A CommandHistory is safe for concurrent use.
+type CommandHistory struct {
+	mu     sync.Mutex
+	traces []CommandTrace
+	next   int
+	full   bool
+}
+
+// NewCommandHistory returns a CommandHistory retaining the last n commands.
+// n is at least one.
+func NewCommandHistory(n int) *CommandHistory {
+	return &CommandHistory{traces: make([]CommandTrace, max(n, 1))}
+}
+
+// Record adds trace, evicting the oldest once the history is full.
+func (h *CommandHistory) Record(trace CommandTrace) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.traces[h.next] = trace
+	h.next = (h.next + 1) % len(h.traces)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// Last returns the most recently recorded command.
+func (h *CommandHistory) Last() (CommandTrace, error) {
+	if h == nil {
+		return CommandTrace{}, ErrNoCommandHistory
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.full && h.next == 0 {
+		return CommandTrace{}, ErrNoCommandHistory
+	}
+	return h.traces[(h.next-1+len(h.traces))%len(h.traces)], nil
+}
+
+// Traces returns the recorded commands, oldest first.
+func (h *CommandHistory) Traces() []CommandTrace {
+	if h == nil {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.full {
+		return append([]CommandTrace(nil), h.traces[:h.next]...)
+	}
+	return append(append([]CommandTrace(nil), h.traces[h.next:]...), h.traces[:h.next]...)
+}
+
+// runRecorded runs config with run and records the outcome in u.History.
+func (u *UnixCommandManager) runRecorded(ctx context.Context, config CommandConfig, run func(context.Context, CommandConfig) (CommandResult, error)) (CommandResult, error) {
+	start := time.Now()
+	result, err := run(ctx, config)
+	if u.History == nil {
+		return result, err
+	}
+
+	args := make([]string, len(config.Args))
+	for i, arg := range config.Args {
+		args[i] = u.redact(arg)
+	}
+	env := make([]string, len(config.Env))
+	for i, e := range config.Env {
+		env[i] = u.redact(e)
+	}
+	u.History.Record(CommandTrace{
+		Host:      u.Hostname,
+		Command:   config.Command,
+		Args:      args,
+		Sudo:      config.Sudo,
+		Env:       env,
+		Dir:       config.Dir,
+		STDOUT:    u.redact(result.STDOUT),
+		STDERR:    u.redact(result.STDERR),
+		ExitCode:  result.ExitCode,
+		Err:       err,
+		Duration:  time.Since(start),
+		Timestamp: start,
+	})
+	return result, err
+}
+
+// redact masks the manager's credentials in s.
+func (u *UnixCommandManager) redact(s string) string {
+	for _, secret := range []string{u.Password, u.SudoPassword, u.KeyPassphrase} {
+		if secret != "" {
+			s = strings.ReplaceAll(s, secret, redacted)
+		}
+	}
+	return s
+}
@@ -0,0 +1,100 @@
+package commandmanager
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// ErrHostKeyMismatch is returned when a host presents a key other than the
+// one that was expected.
+var ErrHostKeyMismatch = errors.New("host key mismatch")
+
+// ErrUnknownHost is returned when a host has no entry in the known_hosts
+// files used to verify it.
+var ErrUnknownHost = errors.New("unknown host key")
+
+// DefaultKnownHostsPath returns ~/.ssh/known_hosts.
+func DefaultKnownHostsPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".ssh", "known_hosts"), nil
+}
+
+// KnownHostsCallback returns a HostKeyCallback that verifies hosts against
+// the given known_hosts files. A host without an entry fails with
+// ErrUnknownHost and one presenting a different key with ErrHostKeyMismatch.
+func KnownHostsCallback(paths ...string) (ssh.HostKeyCallback, error) {
+	callback, err := knownhosts.New(paths...)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		err := callback(hostname, remote, key)
+		var keyErr *knownhosts.KeyError
+		if !errors.As(err, &keyErr) {
+			return err
+		}
+		if len(keyErr.Want) == 0 {
+			return fmt.Errorf("%w: %s (%s) is not in %s", ErrUnknownHost, hostname, ssh.FingerprintSHA256(key), strings.Join(paths, ", "))
+		}
+		want := keyErr.Want[0]
+		return fmt.Errorf("%w: %s presented %s, %s:%d has %s",
+			ErrHostKeyMismatch, hostname, ssh.FingerprintSHA256(key), want.Filename, want.Line, ssh.FingerprintSHA256(want.Key))
+	}, nil
+}
+
+// defaultHostKeyCallback verifies against ~/.ssh/known_hosts. A missing file
+// is not an error here; every host is then unknown.
+func defaultHostKeyCallback() (ssh.HostKeyCallback, error) {
+	path, err := DefaultKnownHostsPath()
+	if err != nil {
+		return nil, err
+	}
+	callback, err := KnownHostsCallback(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			return fmt.Errorf("%w: %s (%s): %s does not exist", ErrUnknownHost, hostname, ssh.FingerprintSHA256(key), path)
+		}, nil
+	}
+	return callback, err
+}
+
+// FingerprintCallback returns a HostKeyCallback that accepts only the key
+// with the given SHA256 fingerprint, as printed by "ssh-keygen -lf". The
+// "SHA256:" prefix is optional.
+func FingerprintCallback(fingerprint string) (ssh.HostKeyCallback, error) {
+	expected, err := normalizeFingerprint(fingerprint)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		presented := ssh.FingerprintSHA256(key)
+		if presented != expected {
+			return fmt.Errorf("%w: %s presented %s, expected %s", ErrHostKeyMismatch, hostname, presented, expected)
+		}
+		return nil
+	}, nil
+}
+
+// normalizeFingerprint validates a SHA256 fingerprint and returns it in the
+// "SHA256:<base64>" form produced by ssh.FingerprintSHA256.
+func normalizeFingerprint(fingerprint string) (string, error) {
+	encoded := strings.TrimPrefix(strings.TrimSpace(fingerprint), "SHA256:")
+	hash, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil || len(hash) != 32 {
+		return "", fmt.Errorf("invalid SHA256 host key fingerprint: %q", fingerprint)
+	}
+	return "SHA256:" + encoded, nil
+}
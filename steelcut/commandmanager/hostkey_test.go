@@ -0,0 +1,106 @@
+package commandmanager
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func newTestHostKey(t *testing.T) ssh.PublicKey {
+	t.Helper()
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatalf("Failed to convert key: %v", err)
+	}
+	return key
+}
+
+func TestFingerprintCallbackMatch(t *testing.T) {
+	key := newTestHostKey(t)
+	fingerprint := ssh.FingerprintSHA256(key)
+
+	for _, pinned := range []string{fingerprint, strings.TrimPrefix(fingerprint, "SHA256:")} {
+		callback, err := FingerprintCallback(pinned)
+		if err != nil {
+			t.Fatalf("Expected valid fingerprint %q, got: %v", pinned, err)
+		}
+		if err := callback("host:22", nil, key); err != nil {
+			t.Errorf("Expected key to match %q, got: %v", pinned, err)
+		}
+	}
+}
+
+func TestFingerprintCallbackMismatch(t *testing.T) {
+	callback, err := FingerprintCallback(ssh.FingerprintSHA256(newTestHostKey(t)))
+	if err != nil {
+		t.Fatalf("Expected valid fingerprint, got: %v", err)
+	}
+
+	if err := callback("host:22", nil, newTestHostKey(t)); !errors.Is(err, ErrHostKeyMismatch) {
+		t.Errorf("Expected ErrHostKeyMismatch, got: %v", err)
+	}
+}
+
+func TestFingerprintCallbackMalformed(t *testing.T) {
+	for _, fingerprint := range []string{"", "SHA256:", "SHA256:not base64!", "MD5:aa:bb:cc", "SHA256:dGVzdA"} {
+		if _, err := FingerprintCallback(fingerprint); err == nil {
+			t.Errorf("Expected error for malformed fingerprint %q", fingerprint)
+		}
+	}
+}
+
+func TestKnownHostsCallback(t *testing.T) {
+	known := newTestHostKey(t)
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	line := knownhosts.Line([]string{knownhosts.Normalize("web1.example.com:22")}, known)
+	if err := os.WriteFile(path, []byte(line+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	callback, err := KnownHostsCallback(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	remote := &net.TCPAddr{IP: net.ParseIP("192.0.2.10"), Port: 22}
+
+	if err := callback("web1.example.com:22", remote, known); err != nil {
+		t.Errorf("Expected known key to be accepted, got: %v", err)
+	}
+	err = callback("web1.example.com:22", remote, newTestHostKey(t))
+	if !errors.Is(err, ErrHostKeyMismatch) || errors.Is(err, ErrUnknownHost) {
+		t.Errorf("Expected ErrHostKeyMismatch, got: %v", err)
+	}
+	err = callback("web2.example.com:22", remote, known)
+	if !errors.Is(err, ErrUnknownHost) || errors.Is(err, ErrHostKeyMismatch) {
+		t.Errorf("Expected ErrUnknownHost, got: %v", err)
+	}
+
+	if _, err := KnownHostsCallback(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Errorf("Expected error for a missing known_hosts file")
+	}
+}
+
+func TestDefaultHostKeyCallbackWithoutKnownHosts(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	callback, err := defaultHostKeyCallback()
+	if err != nil {
+		t.Fatalf("Expected no error without ~/.ssh/known_hosts, got: %v", err)
+	}
+	remote := &net.TCPAddr{IP: net.ParseIP("192.0.2.10"), Port: 22}
+	if err := callback("web1.example.com:22", remote, newTestHostKey(t)); !errors.Is(err, ErrUnknownHost) {
+		t.Errorf("Expected ErrUnknownHost, got: %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+package commandmanager
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// keepAliveMaxMissed is how many keepalives in a row may go unanswered
+// before the connection is considered dead.
+const keepAliveMaxMissed = 3
+
+var errKeepAliveTimeout = errors.New("keepalive timed out")
+
+// keepAlive sends a keepalive request on client every interval until the
+// connection closes. A request not answered within the interval counts as
+// missed, and after keepAliveMaxMissed in a row the connection is closed so
+// that commands using it fail instead of hanging.
+func keepAlive(client *ssh.Client, hostname string, interval time.Duration, logger *slog.Logger) {
+	done := make(chan struct{})
+	go func() {
+		client.Wait()
+		close(done)
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	missed := 0
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		// Servers that do not know the request reply with a failure, which
+		// still shows the connection is alive
+		reply := make(chan error, 1)
+		go func() {
+			_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+			reply <- err
+		}()
+
+		var err error
+		select {
+		case <-done:
+			return
+		case err = <-reply:
+		case <-time.After(interval):
+			err = errKeepAliveTimeout
+		}
+		if err == nil {
+			missed = 0
+			continue
+		}
+
+		missed++
+		logger.Debug("SSH keepalive failed", "hostname", hostname, "missed", missed, "error", err)
+		if missed >= keepAliveMaxMissed {
+			logger.Warn("Closing SSH connection after missed keepalives", "hostname", hostname, "missed", missed)
+			client.Close()
+			return
+		}
+	}
+}
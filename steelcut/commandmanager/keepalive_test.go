@@ -0,0 +1,80 @@
+package commandmanager
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestKeepAliveKeepsConnection(t *testing.T) {
+	server := startTestSSHServer(t, "")
+	manager := testJumpManager("alive.example.com")
+	manager.SSHClient = addrDialer{addr: server.addr}
+	manager.KeepAlive = 10 * time.Millisecond
+
+	client, err := manager.connect(context.Background())
+	if err != nil {
+		t.Fatalf("connect failed: %v", err)
+	}
+	defer client.Close()
+
+	time.Sleep(100 * time.Millisecond)
+	if _, err := client.NewSession(); err != nil {
+		t.Errorf("Expected the connection to stay open, got %v", err)
+	}
+}
+
+func TestKeepAliveClosesUnresponsiveConnection(t *testing.T) {
+	// A server that completes the handshake and then never answers requests
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+	config := &ssh.ServerConfig{NoClientAuth: true}
+	config.AddHostKey(signer)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		accepted <- conn
+		ssh.NewServerConn(conn, config)
+	}()
+
+	manager := testJumpManager("stalled.example.com")
+	manager.SSHClient = addrDialer{addr: listener.Addr().String()}
+	manager.KeepAlive = 20 * time.Millisecond
+
+	client, err := manager.connect(context.Background())
+	if err != nil {
+		t.Fatalf("connect failed: %v", err)
+	}
+	defer (<-accepted).Close()
+	closed := make(chan struct{})
+	go func() {
+		client.Wait()
+		close(closed)
+	}()
+
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		client.Close()
+		t.Error("Expected the connection to be closed after missed keepalives")
+	}
+}
@@ -0,0 +1,163 @@
+package commandmanager
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// KnownHostEntry is a single key line from a known_hosts file.
+type KnownHostEntry struct {
+	Line        int      // 1-based line number in the file
+	Marker      string   // "cert-authority", "revoked" or empty
+	Hosts       []string // host patterns, or the single hashed token
+	Hashed      bool
+	KeyType     string
+	Fingerprint string // SHA256 fingerprint of the key
+	Comment     string
+}
+
+// ListKnownHosts parses the known_hosts file at path. Comments and blank
+// lines are skipped.
+func ListKnownHosts(path string) ([]KnownHostEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []KnownHostEntry
+	for i, line := range strings.Split(string(data), "\n") {
+		entry, ok, err := parseKnownHostLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, i+1, err)
+		}
+		if ok {
+			entry.Line = i + 1
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// RemoveKnownHostEntry removes every key for host from the known_hosts file
+// at knownHostsPath, like "ssh-keygen -R". host is a hostname or address, with an
+// optional port as in "[host]:2222", and is matched against plaintext and
+// hashed entries. The previous file is kept with an ".old" suffix. Nothing is
+// written if no entry matches or the file does not exist.
+func RemoveKnownHostEntry(knownHostsPath, host string) error {
+	info, err := os.Stat(knownHostsPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(knownHostsPath)
+	if err != nil {
+		return err
+	}
+
+	name := knownhosts.Normalize(host)
+	lines := strings.SplitAfter(string(data), "\n")
+	kept := make([]string, 0, len(lines))
+	for i, line := range lines {
+		entry, ok, err := parseKnownHostLine(line)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %v", knownHostsPath, i+1, err)
+		}
+		if ok && entry.matches(name) {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	if len(kept) == len(lines) {
+		return nil
+	}
+
+	if err := os.WriteFile(knownHostsPath+".old", data, info.Mode().Perm()); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(knownHostsPath), ".known_hosts")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(strings.Join(kept, "")); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(info.Mode().Perm()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), knownHostsPath)
+}
+
+// parseKnownHostLine parses one known_hosts line, reporting false for
+// comments and blank lines.
+func parseKnownHostLine(line string) (KnownHostEntry, bool, error) {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return KnownHostEntry{}, false, nil
+	}
+
+	marker, hosts, key, comment, _, err := ssh.ParseKnownHosts([]byte(trimmed))
+	if err != nil {
+		return KnownHostEntry{}, false, err
+	}
+	return KnownHostEntry{
+		Marker:      marker,
+		Hosts:       hosts,
+		Hashed:      len(hosts) == 1 && strings.HasPrefix(hosts[0], "|1|"),
+		KeyType:     key.Type(),
+		Fingerprint: ssh.FingerprintSHA256(key),
+		Comment:     comment,
+	}, true, nil
+}
+
+// matches reports whether the entry is for name, a host in the normalized
+// form written to known_hosts. Wildcard patterns are not expanded, so an
+// entry such as "*.example.com" is never removed for a single host.
+func (e KnownHostEntry) matches(name string) bool {
+	if e.Hashed {
+		return hashedHostMatches(e.Hosts[0], name)
+	}
+	for _, h := range e.Hosts {
+		if h == name {
+			return true
+		}
+	}
+	return false
+}
+
+// hashedHostMatches checks name against a "|1|salt|hash" token, where hash is
+// the HMAC-SHA1 of the name keyed with salt.
+func hashedHostMatches(token, name string) bool {
+	parts := strings.Split(token, "|")
+	if len(parts) != 4 {
+		return false
+	}
+	salt, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	hash, err := base64.StdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha1.New, salt)
+	mac.Write([]byte(name))
+	return hmac.Equal(mac.Sum(nil), hash)
+}
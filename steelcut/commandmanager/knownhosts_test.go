@@ -0,0 +1,104 @@
+package commandmanager
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func writeKnownHostsFixture(t *testing.T) (string, ssh.PublicKey) {
+	t.Helper()
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := "# managed by steelcut\n" +
+		knownhosts.Line([]string{"web1.example.com", "10.0.0.5"}, key) + "\n" +
+		knownhosts.Line([]string{knownhosts.HashHostname("db1.example.com")}, key) + "\n" +
+		knownhosts.Line([]string{knownhosts.HashHostname("[db1.example.com]:2222")}, key) + "\n" +
+		knownhosts.Line([]string{"*.internal"}, key) + "\n" +
+		"\n" +
+		knownhosts.Line([]string{"web2.example.com"}, key) + "\n"
+
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path, key
+}
+
+func TestListKnownHosts(t *testing.T) {
+	path, key := writeKnownHostsFixture(t)
+
+	entries, err := ListKnownHosts(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(entries) != 5 {
+		t.Fatalf("Expected 5 entries, got: %+v", entries)
+	}
+
+	first := entries[0]
+	if first.Line != 2 || first.Hashed || strings.Join(first.Hosts, ",") != "web1.example.com,10.0.0.5" {
+		t.Errorf("Unexpected plaintext entry: %+v", first)
+	}
+	if first.KeyType != ssh.KeyAlgoED25519 || first.Fingerprint != ssh.FingerprintSHA256(key) {
+		t.Errorf("Unexpected key details: %+v", first)
+	}
+	if !entries[1].Hashed || entries[4].Line != 7 {
+		t.Errorf("Unexpected entries: %+v", entries)
+	}
+}
+
+func TestRemoveKnownHostEntry(t *testing.T) {
+	path, _ := writeKnownHostsFixture(t)
+
+	for _, host := range []string{"db1.example.com", "10.0.0.5"} {
+		if err := RemoveKnownHostEntry(path, host); err != nil {
+			t.Fatalf("Expected no error removing %s, got: %v", host, err)
+		}
+	}
+
+	entries, err := ListKnownHosts(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 remaining entries, got: %+v", entries)
+	}
+	// The db1 key on another port and the wildcard entry are kept
+	if !entries[0].Hashed || entries[1].Hosts[0] != "*.internal" || entries[2].Hosts[0] != "web2.example.com" {
+		t.Errorf("Unexpected remaining entries: %+v", entries)
+	}
+
+	if err := RemoveKnownHostEntry(path, "[db1.example.com]:2222"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "|1|") || !strings.HasPrefix(string(data), "# managed by steelcut\n") {
+		t.Errorf("Expected hashed entry removed and comments kept, got:\n%s", data)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected permissions to be preserved, got %v, %v", info, err)
+	}
+	if _, err := os.Stat(path + ".old"); err != nil {
+		t.Errorf("Expected a backup file, got: %v", err)
+	}
+
+	if err := RemoveKnownHostEntry(filepath.Join(t.TempDir(), "missing"), "web1.example.com"); err != nil {
+		t.Errorf("Expected no error for a missing file, got: %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package commandmanager
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ulimitFlags maps the resource names accepted in CommandConfig.Limits to
+// ulimit flags. Sizes are in ulimit's units: KiB for as, data, stack,
+// memlock and fsize, and seconds for cpu.
+var ulimitFlags = map[string]string{
+	"as":      "-v",
+	"core":    "-c",
+	"cpu":     "-t",
+	"data":    "-d",
+	"fsize":   "-f",
+	"memlock": "-l",
+	"nofile":  "-n",
+	"nproc":   "-u",
+	"stack":   "-s",
+}
+
+// limitsPrefix returns the shell commands that apply limits, each followed by
+// " && ", in a stable order.
+func limitsPrefix(limits map[string]string) (string, error) {
+	names := make([]string, 0, len(limits))
+	for name, value := range limits {
+		if _, ok := ulimitFlags[name]; !ok {
+			return "", fmt.Errorf("unsupported resource limit: %q", name)
+		}
+		if value != "unlimited" {
+			if _, err := strconv.ParseUint(value, 10, 64); err != nil {
+				return "", fmt.Errorf("invalid value for resource limit %s: %q", name, value)
+			}
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var prefix strings.Builder
+	for _, name := range names {
+		value := limits[name]
+		if name == "nproc" {
+			// dash spells the process limit -p where bash and busybox use -u
+			fmt.Fprintf(&prefix, "{ ulimit -u %s 2>/dev/null || ulimit -p %s; } && ", value, value)
+			continue
+		}
+		fmt.Fprintf(&prefix, "ulimit %s %s && ", ulimitFlags[name], value)
+	}
+	return prefix.String(), nil
+}
+
+// applyLimits rewrites config to run its command through sh with its Limits
+// applied. The command is exec'd, so it keeps the shell's PID and its exit
+// status is reported unchanged. When escalating, the limits are set as root,
+// which allows raising hard limits.
+func applyLimits(config CommandConfig) (CommandConfig, error) {
+	if len(config.Limits) == 0 {
+		return config, nil
+	}
+	prefix, err := limitsPrefix(config.Limits)
+	if err != nil {
+		return CommandConfig{}, err
+	}
+
+	limited := config
+	limited.Command = "sh"
+	limited.Args = append([]string{"-c", prefix + `exec "$0" "$@"`, config.Command}, config.Args...)
+	limited.Limits = nil
+	return limited, nil
+}
@@ -0,0 +1,70 @@
+package commandmanager
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestLimitsPrefix(t *testing.T) {
+	prefix, err := limitsPrefix(map[string]string{"nofile": "65536", "as": "unlimited", "nproc": "512"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	want := "ulimit -v unlimited && ulimit -n 65536 && { ulimit -u 512 2>/dev/null || ulimit -p 512; } && "
+	if prefix != want {
+		t.Errorf("Expected %q, got: %q", want, prefix)
+	}
+
+	for _, limits := range []map[string]string{
+		{"files": "1024"},
+		{"nofile": "-1"},
+		{"nofile": "1024; reboot"},
+		{"as": "2G"},
+	} {
+		if _, err := limitsPrefix(limits); err == nil {
+			t.Errorf("Expected error for %v", limits)
+		}
+	}
+}
+
+func TestApplyLimits(t *testing.T) {
+	config, err := applyLimits(CommandConfig{
+		Command: "loadtest",
+		Args:    []string{"--connections", "10000"},
+		Sudo:    true,
+		Limits:  map[string]string{"nofile": "65536"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	got := config.Command + " " + shellJoin(config.Args)
+	want := `sh -c 'ulimit -n 65536 && exec "$0" "$@"' loadtest --connections 10000`
+	if got != want || !config.Sudo || config.Limits != nil {
+		t.Errorf("Expected %s, got: %s (%+v)", want, got, config)
+	}
+}
+
+func TestRunLocalWithLimits(t *testing.T) {
+	manager := &UnixCommandManager{Hostname: "localhost"}
+
+	result, err := manager.RunLocal(context.Background(), CommandConfig{
+		Command: "sh",
+		Args:    []string{"-c", "ulimit -n; exit 4"},
+		Limits:  map[string]string{"nofile": "64"},
+	})
+	if strings.TrimSpace(result.STDOUT) != "64" || result.ExitCode != 4 || err == nil {
+		t.Errorf("Expected limit 64 and exit code 4, got: %+v, %v", result, err)
+	}
+	if result.Command != "sh" {
+		t.Errorf("Expected the original command to be reported, got: %s", result.Command)
+	}
+
+	_, err = manager.RunLocal(context.Background(), CommandConfig{
+		Command: "true",
+		Limits:  map[string]string{"bogus": "1"},
+	})
+	if err == nil {
+		t.Errorf("Expected error for an unsupported limit")
+	}
+}
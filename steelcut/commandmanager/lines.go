@@ -0,0 +1,41 @@
+package commandmanager
+
+import (
+	"context"
+	"strings"
+)
+
+// EachLine calls fn for every non-empty line of output, with surrounding
+// whitespace trimmed. Iteration stops at the first error returned by fn.
+func EachLine(output string, fn func(line string) error) error {
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if err := fn(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Lines returns the non-empty, trimmed lines of output.
+func Lines(output string) []string {
+	var lines []string
+	_ = EachLine(output, func(line string) error {
+		lines = append(lines, line)
+		return nil
+	})
+	return lines
+}
+
+// RunCommandLines runs the command and returns the non-empty, trimmed lines
+// of its standard output.
+func RunCommandLines(ctx context.Context, manager CommandManager, config CommandConfig) ([]string, error) {
+	result, err := manager.Run(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	return Lines(result.STDOUT), nil
+}
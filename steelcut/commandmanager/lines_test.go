@@ -0,0 +1,63 @@
+package commandmanager
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"", nil},
+		{"\n", nil},
+		{"one\ntwo\n", []string{"one", "two"}},
+		{"one\ntwo", []string{"one", "two"}},
+		{"one\n\n  \ntwo\n\n", []string{"one", "two"}},
+		{"  padded  \r\n\tline\t\n", []string{"padded", "line"}},
+	}
+
+	for _, tt := range tests {
+		if got := Lines(tt.input); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("Lines(%q): expected %v, got %v", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestEachLineStopsOnError(t *testing.T) {
+	stop := errors.New("stop")
+	var seen []string
+
+	err := EachLine("a\nb\nc\n", func(line string) error {
+		seen = append(seen, line)
+		if line == "b" {
+			return stop
+		}
+		return nil
+	})
+
+	if !errors.Is(err, stop) {
+		t.Errorf("Expected the callback error, got: %v", err)
+	}
+	if !reflect.DeepEqual(seen, []string{"a", "b"}) {
+		t.Errorf("Expected iteration to stop after b, got %v", seen)
+	}
+}
+
+func TestRunCommandLines(t *testing.T) {
+	manager := UnixCommandManager{Hostname: "localhost"}
+
+	lines, err := RunCommandLines(context.Background(), &manager, CommandConfig{
+		Command: "printf",
+		Args:    []string{"first\n\nsecond\n"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !reflect.DeepEqual(lines, []string{"first", "second"}) {
+		t.Errorf("Expected [first second], got %v", lines)
+	}
+}
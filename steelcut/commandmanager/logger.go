@@ -0,0 +1,30 @@
+package commandmanager
+
+import (
+	"context"
+	"log/slog"
+)
+
+// discardHandler is a slog.Handler that drops every record.
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+var discardLogger = slog.New(discardHandler{})
+
+// DiscardLogger returns a logger that drops everything, used by managers
+// that were not given a Logger.
+func DiscardLogger() *slog.Logger {
+	return discardLogger
+}
+
+// logger returns the configured Logger, defaulting to DiscardLogger.
+func (u *UnixCommandManager) logger() *slog.Logger {
+	if u.Logger == nil {
+		return discardLogger
+	}
+	return u.Logger
+}
@@ -0,0 +1,76 @@
+package commandmanager
+
+import (
+	"errors"
+	"strings"
+	"sync"
+)
+
+// DefaultMaxOutputBytes is the output cap applied when none is configured.
+const DefaultMaxOutputBytes int64 = 64 << 20
+
+// ErrOutputTooLarge is returned when a command produces more output than the
+// configured limit. The command is killed and the output captured up to the
+// limit is returned alongside the error.
+var ErrOutputTooLarge = errors.New("command output exceeded the maximum allowed size")
+
+// outputLimiter enforces a byte budget shared by a command's stdout and stderr.
+type outputLimiter struct {
+	mu        sync.Mutex
+	remaining int64
+	exceeded  bool
+	onExceed  func()
+}
+
+func newOutputLimiter(limit int64, onExceed func()) *outputLimiter {
+	return &outputLimiter{remaining: limit, onExceed: onExceed}
+}
+
+// Writer returns a writer that captures into buf within the shared budget.
+func (l *outputLimiter) Writer(buf *strings.Builder) *limitedWriter {
+	return &limitedWriter{limiter: l, buf: buf}
+}
+
+// Exceeded reports whether the budget was exhausted.
+func (l *outputLimiter) Exceeded() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.exceeded
+}
+
+type limitedWriter struct {
+	limiter *outputLimiter
+	buf     *strings.Builder
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	l := w.limiter
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.exceeded {
+		return 0, ErrOutputTooLarge
+	}
+
+	if int64(len(p)) > l.remaining {
+		n, _ := w.buf.Write(p[:l.remaining])
+		l.remaining = 0
+		l.exceeded = true
+		if l.onExceed != nil {
+			go l.onExceed()
+		}
+		return n, ErrOutputTooLarge
+	}
+
+	n, err := w.buf.Write(p)
+	l.remaining -= int64(n)
+	return n, err
+}
+
+// maxOutputBytes returns the configured output cap, falling back to the default.
+func (u *UnixCommandManager) maxOutputBytes() int64 {
+	if u.MaxOutputBytes <= 0 {
+		return DefaultMaxOutputBytes
+	}
+	return u.MaxOutputBytes
+}
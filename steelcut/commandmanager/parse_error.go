@@ -0,0 +1,34 @@
+package commandmanager
+
+import "fmt"
+
+// ParseError reports a line of command output that a parser did not
+// recognize. It is only returned in strict parsing mode; by default such
+// lines are skipped.
+type ParseError struct {
+	Command string
+	Line    int // 1-based line number in the output
+	Text    string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("unrecognized %s output on line %d: %q", e.Command, e.Line, e.Text)
+}
+
+// StrictParser is implemented by command managers that can ask parsers to
+// reject unrecognized output.
+type StrictParser interface {
+	StrictParsing() bool
+}
+
+// StrictParsing reports whether parsers should fail with a *ParseError on
+// unrecognized lines of manager's output.
+func StrictParsing(manager CommandManager) bool {
+	strict, ok := manager.(StrictParser)
+	return ok && strict.StrictParsing()
+}
+
+// StrictParsing reports whether strict output parsing is enabled.
+func (u *UnixCommandManager) StrictParsing() bool {
+	return u.StrictOutput
+}
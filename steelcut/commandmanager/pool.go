@@ -0,0 +1,138 @@
+package commandmanager
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+)
+
+var errNoConnection = errors.New("SSHClient returned no connection")
+
+// ConnectionPool keeps a single SSH connection to a host open so that
+// commands and SFTP sessions reuse it instead of dialing each time. It is
+// safe for concurrent use; a connection the server has closed is dropped and
+// the next caller dials a new one.
+type ConnectionPool struct {
+	mu     sync.Mutex
+	client *ssh.Client
+}
+
+// NewConnectionPool returns an empty pool. The connection is dialed on first
+// use.
+func NewConnectionPool() *ConnectionPool {
+	return &ConnectionPool{}
+}
+
+// get returns the pooled connection, dialing one if there is none.
+func (p *ConnectionPool) get(ctx context.Context, dial func(context.Context) (*ssh.Client, error)) (*ssh.Client, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.client != nil {
+		return p.client, nil
+	}
+
+	client, err := dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		return nil, errNoConnection
+	}
+	p.client = client
+	go func() {
+		client.Wait()
+		p.discard(client)
+	}()
+	return client, nil
+}
+
+// discard closes client and removes it from the pool, unless it has already
+// been replaced.
+func (p *ConnectionPool) discard(client *ssh.Client) {
+	p.mu.Lock()
+	if p.client == client {
+		p.client = nil
+	}
+	p.mu.Unlock()
+	client.Close()
+}
+
+// Close closes the pooled connection, if any. Later commands dial a new one.
+func (p *ConnectionPool) Close() error {
+	p.mu.Lock()
+	client := p.client
+	p.client = nil
+	p.mu.Unlock()
+	if client == nil {
+		return nil
+	}
+	return client.Close()
+}
+
+// WithConnection calls fn with a manager whose commands share one SSH
+// connection, so that only the first command pays for the dial. A remote
+// UnixCommandManager without a Pool is copied with a temporary pool that is
+// closed when fn returns; other managers are passed to fn unchanged.
+func WithConnection(manager CommandManager, fn func(CommandManager) error) error {
+	u, ok := manager.(*UnixCommandManager)
+	if !ok || u.Pool != nil || u.IsLocal() {
+		return fn(manager)
+	}
+
+	pooled := *u
+	pooled.Pool = NewConnectionPool()
+	defer pooled.Pool.Close()
+	return fn(&pooled)
+}
+
+// acquire returns a connection to the host and a function to release it.
+// Pooled connections stay open on release; others are closed.
+func (u *UnixCommandManager) acquire(ctx context.Context) (*ssh.Client, func() error, error) {
+	if u.Pool != nil {
+		client, err := u.Pool.get(ctx, u.connect)
+		if err != nil {
+			return nil, nil, err
+		}
+		return client, func() error { return nil }, nil
+	}
+
+	client, err := u.connect(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if client == nil {
+		return nil, nil, errNoConnection
+	}
+	return client, client.Close, nil
+}
+
+// newSession opens a session to the host. A pooled connection that fails to
+// open one is assumed dead and replaced once.
+func (u *UnixCommandManager) newSession(ctx context.Context) (*ssh.Session, func() error, error) {
+	client, release, err := u.acquire(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	session, err := client.NewSession()
+	if err != nil && u.Pool != nil {
+		u.Pool.discard(client)
+		if client, release, err = u.acquire(ctx); err != nil {
+			return nil, nil, err
+		}
+		session, err = client.NewSession()
+	}
+	if err != nil {
+		u.Breaker.Record(err)
+		release()
+		return nil, nil, err
+	}
+	return session, release, nil
+}
+
+// closerFunc adapts a function to io.Closer.
+type closerFunc func() error
+
+func (f closerFunc) Close() error { return f() }
@@ -0,0 +1,103 @@
+package commandmanager
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// countingDialer counts the connections it dials.
+type countingDialer struct {
+	addrDialer
+	dials atomic.Int32
+}
+
+func (d *countingDialer) Dial(network, addr string, config *ssh.ClientConfig, timeout time.Duration) (*ssh.Client, error) {
+	d.dials.Add(1)
+	return d.addrDialer.Dial(network, addr, config, timeout)
+}
+
+func TestConnectionPoolReusesConnection(t *testing.T) {
+	server := startTestSSHServer(t, "")
+	dialer := &countingDialer{addrDialer: addrDialer{addr: server.addr}}
+	manager := testJumpManager("pooled.example.com")
+	manager.SSHClient = dialer
+	manager.Pool = NewConnectionPool()
+	defer manager.Pool.Close()
+
+	for i := 0; i < 3; i++ {
+		if _, err := manager.RunRemote(context.Background(), CommandConfig{Command: "true"}); err != nil {
+			t.Fatalf("RunRemote failed: %v", err)
+		}
+	}
+	if n := dialer.dials.Load(); n != 1 {
+		t.Errorf("Expected 1 dial, got %d", n)
+	}
+
+	// A dead connection is replaced transparently
+	manager.Pool.client.Close()
+	if _, err := manager.RunRemote(context.Background(), CommandConfig{Command: "true"}); err != nil {
+		t.Fatalf("RunRemote after connection loss failed: %v", err)
+	}
+	if n := dialer.dials.Load(); n != 2 {
+		t.Errorf("Expected 2 dials after connection loss, got %d", n)
+	}
+
+	if err := manager.Pool.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+	select {
+	case <-server.closed:
+	case <-time.After(5 * time.Second):
+		t.Error("First connection was not closed")
+	}
+}
+
+func TestWithoutConnectionPoolDialsEachCommand(t *testing.T) {
+	server := startTestSSHServer(t, "")
+	dialer := &countingDialer{addrDialer: addrDialer{addr: server.addr}}
+	manager := testJumpManager("unpooled.example.com")
+	manager.SSHClient = dialer
+
+	for i := 0; i < 2; i++ {
+		if _, err := manager.RunRemote(context.Background(), CommandConfig{Command: "true"}); err != nil {
+			t.Fatalf("RunRemote failed: %v", err)
+		}
+	}
+	if n := dialer.dials.Load(); n != 2 {
+		t.Errorf("Expected 2 dials, got %d", n)
+	}
+}
+
+func TestWithConnection(t *testing.T) {
+	server := startTestSSHServer(t, "")
+	dialer := &countingDialer{addrDialer: addrDialer{addr: server.addr}}
+	manager := testJumpManager("shared.example.com")
+	manager.SSHClient = dialer
+
+	err := WithConnection(manager, func(shared CommandManager) error {
+		for i := 0; i < 2; i++ {
+			if _, err := shared.Run(context.Background(), CommandConfig{Command: "true"}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithConnection failed: %v", err)
+	}
+	if n := dialer.dials.Load(); n != 1 {
+		t.Errorf("Expected 1 dial, got %d", n)
+	}
+	if manager.Pool != nil {
+		t.Errorf("Expected the manager to be left without a pool")
+	}
+	select {
+	case <-server.closed:
+	case <-time.After(5 * time.Second):
+		t.Error("Shared connection was not closed")
+	}
+}
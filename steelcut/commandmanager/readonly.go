@@ -0,0 +1,198 @@
+package commandmanager
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrReadOnlyMode is returned when a command that may change the host is run
+// while read-only mode is enabled.
+var ErrReadOnlyMode = errors.New("command blocked: host is in read-only mode")
+
+// readOnlyCommands lists commands that never change the host.
+var readOnlyCommands = map[string]bool{
+	"aa-status":           true,
+	"atq":                 true,
+	"blkid":               true,
+	"cat":                 true,
+	"df":                  true,
+	"dpkg-query":          true,
+	"du":                  true,
+	"echo":                true,
+	"findfs":              true,
+	"findmnt":             true,
+	"free":                true,
+	"getenforce":          true,
+	"getent":              true,
+	"getfattr":            true,
+	"grep":                true,
+	"id":                  true,
+	"lsattr":              true,
+	"lsblk":               true,
+	"ls":                  true,
+	"lsof":                true,
+	"nproc":               true,
+	"pacman-conf":         true,
+	"ping":                true,
+	"ping6":               true,
+	"printenv":            true,
+	"ps":                  true,
+	"quota":               true,
+	"readlink":            true,
+	"repquota":            true,
+	"sestatus":            true,
+	"sha256sum":           true,
+	"shasum":              true,
+	"stat":                true,
+	"sw_vers":             true,
+	"tail":                true,
+	"systemd-detect-virt": true,
+	"true":                true,
+	"uname":               true,
+	"uptime":              true,
+	"vmstat":              true,
+	"which":               true,
+	"whoami":              true,
+}
+
+// readOnlySubcommands lists, for tools that both read and write, the first
+// arguments that only read.
+var readOnlySubcommands = map[string][]string{
+	"apk":         {"info", "version", "search", "policy"},
+	"apt":         {"list", "show", "search", "policy"},
+	"apt-cache":   {"search", "show", "policy", "depends", "rdepends"},
+	"apt-mark":    {"showhold", "showmanual", "showauto"},
+	"brew":        {"list", "outdated", "info", "search", "--cache", "--prefix", "--version"},
+	"chronyc":     {"tracking", "sources", "sourcestats"},
+	"diskutil":    {"list", "info"},
+	"dnf":         {"list", "info", "search", "check-update", "repolist"},
+	"dpkg":        {"--get-selections", "-l", "-s", "-L", "--status", "--list"},
+	"launchctl":   {"print", "print-disabled", "list"},
+	"log":         {"show", "stream"},
+	"pacman":      {"-Q", "-Qi", "-Qu", "-Qs", "-Ss", "-Si"},
+	"rpm":         {"-q", "-qa", "-qi"},
+	"scutil":      {"--get"},
+	"systemsetup": {"-gettimezone", "-listtimezones", "-getusingnetworktime", "-getnetworktimeserver"},
+	"systemctl":   {"is-active", "is-enabled", "is-failed", "status", "show", "cat", "list-units", "list-unit-files", "--failed"},
+	"timedatectl": {"show", "status", "list-timezones"},
+	"xattr":       {"-p", "-l"},
+	"yum":         {"list", "info", "search", "check-update", "repolist"},
+	"zypper":      {"search", "list-updates", "info", "locks"},
+}
+
+// dryRunFlags lists, for package managers, the flags that make any
+// subcommand report its changes without making them.
+var dryRunFlags = map[string][]string{
+	"apt-get": {"--dry-run", "--simulate", "-s"},
+	"brew":    {"--dry-run"},
+	"dnf":     {"--assumeno"},
+	"yum":     {"--assumeno"},
+}
+
+// isReadOnlyCommand reports whether config is known not to change the host.
+// Anything not recognised is treated as mutating so that new operations are
+// blocked by default.
+func isReadOnlyCommand(config CommandConfig) bool {
+	if readOnlyCommands[config.Command] {
+		return true
+	}
+
+	switch config.Command {
+	case "hostname", "hostnamectl":
+		// Without arguments (or with only flags) these only print the name
+		for _, arg := range config.Args {
+			if !strings.HasPrefix(arg, "-") {
+				return false
+			}
+		}
+		return true
+	case "date":
+		for _, arg := range config.Args {
+			if arg == "-s" || strings.HasPrefix(arg, "--set") {
+				return false
+			}
+		}
+		return true
+	case "dmesg":
+		for _, arg := range config.Args {
+			if arg == "-c" || arg == "-C" || arg == "--clear" || arg == "--read-clear" {
+				return false
+			}
+		}
+		return true
+	case "journalctl":
+		for _, arg := range config.Args {
+			if strings.HasPrefix(arg, "--vacuum") || arg == "--rotate" || arg == "--flush" {
+				return false
+			}
+		}
+		return true
+	case "find":
+		return isReadOnlyFind(config.Args)
+	case "sysctl":
+		for _, arg := range config.Args {
+			if strings.Contains(arg, "=") || arg == "-w" {
+				return false
+			}
+		}
+		return true
+	}
+
+	for _, flag := range dryRunFlags[config.Command] {
+		for _, arg := range config.Args {
+			if arg == flag {
+				return true
+			}
+		}
+	}
+
+	args := config.Args
+	if config.Command == "zypper" && len(args) > 0 && (args[0] == "--non-interactive" || args[0] == "-n") {
+		args = args[1:]
+	}
+	if (config.Command == "yum" || config.Command == "dnf") && len(args) > 1 && args[0] == "versionlock" {
+		return args[1] == "list"
+	}
+
+	subcommands, ok := readOnlySubcommands[config.Command]
+	if !ok || len(args) == 0 {
+		// A bare "xattr path" lists attribute names
+		return config.Command == "xattr"
+	}
+	for _, sub := range subcommands {
+		if args[0] == sub {
+			return true
+		}
+	}
+	return config.Command == "xattr" && !strings.HasPrefix(args[0], "-")
+}
+
+// isReadOnlyFind reports whether find with args only searches: it doesn't
+// delete, write its output to a file with -fprint or -fls, or run a command
+// with -exec, -execdir, -ok or -okdir that is not itself read-only.
+func isReadOnlyFind(args []string) bool {
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "-delete" || arg == "-fls" || strings.HasPrefix(arg, "-fprint"):
+			return false
+		case arg == "-exec" || arg == "-execdir" || arg == "-ok" || arg == "-okdir":
+			end := i + 1
+			for end < len(args) && args[end] != ";" && args[end] != "+" {
+				end++
+			}
+			if end == i+1 || !isReadOnlyCommand(CommandConfig{Command: args[i+1], Args: args[i+2 : end]}) {
+				return false
+			}
+			i = end
+		}
+	}
+	return true
+}
+
+// checkReadOnly returns ErrReadOnlyMode if read-only mode blocks config.
+func (u *UnixCommandManager) checkReadOnly(config CommandConfig) error {
+	if u.ReadOnly && !isReadOnlyCommand(config) {
+		return ErrReadOnlyMode
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package commandmanager
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/steelcutops/steelcut/common"
+)
+
+func TestReadOnlyBlocksMutations(t *testing.T) {
+	dialErr := errors.New("mock dial error")
+	manager := UnixCommandManager{
+		Hostname:  "remote",
+		SSHClient: &MockSSHClient{dialError: dialErr},
+		Credentials: common.Credentials{
+			User:     "user",
+			Password: "password",
+		},
+		ReadOnly: true,
+	}
+
+	mutating := []CommandConfig{
+		{Command: "apt-get", Args: []string{"install", "-y", "nginx"}, Sudo: true},
+		{Command: "brew", Args: []string{"upgrade"}},
+		{Command: "dnf", Args: []string{"remove", "-y", "nginx"}, Sudo: true},
+		{Command: "systemctl", Args: []string{"restart", "nginx"}, Sudo: true},
+		{Command: "launchctl", Args: []string{"bootout", "system", "/Library/LaunchDaemons/x.plist"}},
+		{Command: "sudo", Args: []string{"reboot"}},
+		{Command: "sudo", Args: []string{"-n", "reboot"}},
+		{Command: "rm", Args: []string{"-f", "/tmp/file"}},
+		{Command: "useradd", Args: []string{"deploy"}},
+		{Command: "sh", Args: []string{"-c", "echo hi > /etc/motd"}},
+		{Command: "hostname", Args: []string{"newname"}},
+		{Command: "sysctl", Args: []string{"-w", "vm.swappiness=10"}},
+		{Command: "dmesg", Args: []string{"-C"}},
+		{Command: "xattr", Args: []string{"-w", "user.k", "v", "/tmp/file"}},
+		{Command: "zypper", Args: []string{"--non-interactive", "install", "-y", "nginx"}, Sudo: true},
+		{Command: "apt-mark", Args: []string{"hold", "nginx"}, Sudo: true},
+		{Command: "dnf", Args: []string{"versionlock", "add", "nginx"}, Sudo: true},
+		{Command: "find", Args: []string{"/tmp", "-name", "*.log", "-delete"}},
+		{Command: "find", Args: []string{"/var", "-fprint", "/tmp/list"}},
+		{Command: "find", Args: []string{"/tmp", "-exec", "rm", "-f", "{}", ";"}},
+		{Command: "find", Args: []string{"/tmp", "-type", "f", "-execdir", "chmod", "600", "{}", "+"}},
+		{Command: "find", Args: []string{"/tmp", "-ok"}},
+	}
+	for _, config := range mutating {
+		if _, err := manager.Run(context.Background(), config); !errors.Is(err, ErrReadOnlyMode) {
+			t.Errorf("Expected %s %v to be blocked, got: %v", config.Command, config.Args, err)
+		}
+	}
+
+	reads := []CommandConfig{
+		{Command: "cat", Args: []string{"/etc/os-release"}},
+		{Command: "dpkg", Args: []string{"--get-selections"}},
+		{Command: "apt", Args: []string{"list", "--upgradable"}},
+		{Command: "brew", Args: []string{"outdated"}},
+		{Command: "systemctl", Args: []string{"is-active", "nginx"}},
+		{Command: "launchctl", Args: []string{"print", "system/com.example"}},
+		{Command: "dmesg", Sudo: true},
+		{Command: "hostname"},
+		{Command: "xattr", Args: []string{"/tmp/file"}},
+		{Command: "apt-get", Args: []string{"dist-upgrade", "--dry-run"}},
+		{Command: "dnf", Args: []string{"install", "nginx", "--assumeno"}, Sudo: true},
+		{Command: "zypper", Args: []string{"--non-interactive", "list-updates"}},
+		{Command: "apt-mark", Args: []string{"showhold"}},
+		{Command: "yum", Args: []string{"versionlock", "list"}},
+		{Command: "dpkg-query", Args: []string{"-W", "-f", "${Version}", "nginx"}},
+		{Command: "find", Args: []string{"/etc", "-mindepth", "1", "-printf", "%y %p\\n"}},
+		{Command: "find", Args: []string{"/etc/nginx", "-type", "f", "-exec", "sha256sum", "{}", "+"}},
+		{Command: "journalctl", Args: []string{"-u", "nginx", "--no-pager"}},
+	}
+	for _, config := range reads {
+		if _, err := manager.Run(context.Background(), config); err != dialErr {
+			t.Errorf("Expected %s %v to reach the host, got: %v", config.Command, config.Args, err)
+		}
+	}
+}
+
+func TestReadOnlyLocal(t *testing.T) {
+	manager := UnixCommandManager{Hostname: "localhost", ReadOnly: true}
+
+	if _, err := manager.Run(context.Background(), CommandConfig{Command: "touch", Args: []string{t.TempDir() + "/x"}}); !errors.Is(err, ErrReadOnlyMode) {
+		t.Errorf("Expected ErrReadOnlyMode, got: %v", err)
+	}
+
+	result, err := manager.Run(context.Background(), CommandConfig{Command: "uname"})
+	if err != nil || result.STDOUT == "" {
+		t.Errorf("Expected read command to run, got %q, %v", result.STDOUT, err)
+	}
+}
@@ -0,0 +1,44 @@
+package commandmanager
+
+import "regexp"
+
+// redacted replaces secrets in logged and recorded commands.
+const redacted = "[REDACTED]"
+
+// secretPatterns match values that look like secrets on a command line,
+// quoted or not, each with the replacement that masks them.
+var secretPatterns = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	// PASSWORD=x, DB_TOKEN=x, --api-key=x
+	{regexp.MustCompile(`(?i)(\b[\w.-]*(?:passw(?:or)?d|secret|token|api[_-]?key|access[_-]?key|credentials?)[\w.-]*=)(?:'[^']*'|"[^"]*"|[^\s'"]+)`), "${1}" + redacted},
+	// --password x, --token x
+	{regexp.MustCompile(`(?i)(--(?:passw(?:or)?d|secret|token|api-key|access-key)\s+)(?:'[^']*'|"[^"]*"|[^\s'"-][^\s'"]*)`), "${1}" + redacted},
+	// Authorization: Bearer x
+	{regexp.MustCompile(`(?i)(authorization:\s*(?:bearer|basic|token)\s+)[^\s'"]+`), "${1}" + redacted},
+	// https://user:x@host
+	{regexp.MustCompile(`(://[^/\s:@]+:)[^@\s/]+@`), "${1}" + redacted + "@"},
+}
+
+// redactCommand masks the manager's credentials and anything that looks like
+// a secret in a command line or its output, for logging. With RedactCommands
+// set, all of s is masked.
+func (u *UnixCommandManager) redactCommand(s string) string {
+	if u.RedactCommands {
+		if s == "" {
+			return ""
+		}
+		return redacted
+	}
+	return RedactSecrets(u.redact(s))
+}
+
+// RedactSecrets masks anything in s that looks like a secret, such as
+// --password=x, TOKEN=x or the password in a URL.
+func RedactSecrets(s string) string {
+	for _, secret := range secretPatterns {
+		s = secret.pattern.ReplaceAllString(s, secret.replacement)
+	}
+	return s
+}
@@ -0,0 +1,28 @@
+package commandmanager
+
+import "bytes"
+
+// replaceFileScript replaces the file "$1" with stdin. A symlink is resolved
+// first, so its target is replaced and the link kept. The temporary file is
+// created by mktemp next to the target, so concurrent writers don't share it
+// and nothing planted at a predictable name can redirect the write. It takes
+// the mode and ownership of an existing target before being renamed over it;
+// a new file gets mode 644 and its parent directories are created.
+const replaceFileScript = `if [ -e "$1" ]; then f=$(realpath "$1") || exit 1; else f=$1; mkdir -p "$(dirname "$f")" || exit 1; fi
+tmp=$(mktemp "$f.steelcut-XXXXXXXX") || exit 1
+trap 'rm -f "$tmp"' EXIT
+if [ -e "$f" ]; then cp -p "$f" "$tmp"; else chmod 644 "$tmp"; fi || exit 1
+cat > "$tmp" && mv -f "$tmp" "$f"`
+
+// ReplaceFile returns a command that atomically replaces path with data as
+// root. The data is passed on stdin rather than the command line, so its size
+// is not bounded by ARG_MAX, and readers of path see either the old or the
+// new content, never a partial write.
+func ReplaceFile(path string, data []byte) CommandConfig {
+	return CommandConfig{
+		Command: "sh",
+		Args:    []string{"-c", replaceFileScript, "sh", path},
+		Sudo:    true,
+		Stdin:   bytes.NewReader(data),
+	}
+}
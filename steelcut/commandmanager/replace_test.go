@@ -0,0 +1,78 @@
+package commandmanager
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func runReplaceFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	config := ReplaceFile(path, data)
+	config.Sudo = false
+	manager := UnixCommandManager{Hostname: "localhost"}
+	if _, err := manager.Run(context.Background(), config); err != nil {
+		t.Fatalf("Expected %s to be replaced, got: %v", path, err)
+	}
+}
+
+func TestReplaceFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "fstab")
+	if err := os.WriteFile(path, []byte("old\n"), 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	runReplaceFile(t, path, []byte("new\n"))
+
+	if data, _ := os.ReadFile(path); string(data) != "new\n" {
+		t.Errorf("Unexpected content: %q", data)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0640 {
+		t.Errorf("Expected mode 0640 to be kept, got %v", info.Mode().Perm())
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("Expected no temporary file left behind, got %v", entries)
+	}
+}
+
+func TestReplaceFileSymlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "hosts.real")
+	link := filepath.Join(dir, "hosts")
+	if err := os.WriteFile(target, []byte("old\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("hosts.real", link); err != nil {
+		t.Fatal(err)
+	}
+
+	runReplaceFile(t, link, []byte("new\n"))
+
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("Expected %s to stay a symlink, got %v, %v", link, info, err)
+	}
+	if data, _ := os.ReadFile(target); string(data) != "new\n" {
+		t.Errorf("Expected the target to be replaced, got %q", data)
+	}
+	if info, _ := os.Stat(target); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the target's mode 0600 to be kept, got %v", info.Mode().Perm())
+	}
+}
+
+func TestReplaceFileCreates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nginx.service.d", "override.conf")
+
+	// Larger than ARG_MAX, which the content would hit on the command line
+	data := bytes.Repeat([]byte("# padding line\n"), 300000)
+	runReplaceFile(t, path, data)
+
+	if got, _ := os.ReadFile(path); !bytes.Equal(got, data) {
+		t.Errorf("Expected %d bytes, got %d", len(data), len(got))
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0644 {
+		t.Errorf("Expected a new file to get mode 0644, got %v", info.Mode().Perm())
+	}
+}
@@ -0,0 +1,196 @@
+package commandmanager
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"github.com/pkg/sftp"
+)
+
+// SFTPProvider is implemented by command managers that can open an SFTP
+// session to their host.
+type SFTPProvider interface {
+	OpenSFTP(ctx context.Context) (*SFTPClient, error)
+}
+
+// SFTPClient is an SFTP session to a host. Closing it also closes the
+// connection the session runs over.
+type SFTPClient struct {
+	*sftp.Client
+
+	// ReadOnly is set when the host is in read-only mode. The session's
+	// methods that modify the host then fail with ErrReadOnlyMode.
+	ReadOnly bool
+
+	conn io.Closer
+}
+
+// NewSFTPClient wraps client, closing conn along with it. conn may be nil.
+func NewSFTPClient(client *sftp.Client, conn io.Closer) *SFTPClient {
+	return &SFTPClient{Client: client, conn: conn}
+}
+
+func (c *SFTPClient) Close() error {
+	err := c.Client.Close()
+	if c.conn != nil {
+		if connErr := c.conn.Close(); err == nil {
+			err = connErr
+		}
+	}
+	return err
+}
+
+// checkWrite returns ErrReadOnlyMode if the session is read-only.
+func (c *SFTPClient) checkWrite() error {
+	if c.ReadOnly {
+		return ErrReadOnlyMode
+	}
+	return nil
+}
+
+// sftpWriteFlags are the open flags that can modify a file.
+const sftpWriteFlags = os.O_WRONLY | os.O_RDWR | os.O_CREATE | os.O_TRUNC | os.O_APPEND
+
+// The methods below shadow those of sftp.Client that modify the host, so
+// that read-only mode holds for every caller of the session.
+
+func (c *SFTPClient) Create(path string) (*sftp.File, error) {
+	if err := c.checkWrite(); err != nil {
+		return nil, err
+	}
+	return c.Client.Create(path)
+}
+
+func (c *SFTPClient) OpenFile(path string, f int) (*sftp.File, error) {
+	if f&sftpWriteFlags != 0 {
+		if err := c.checkWrite(); err != nil {
+			return nil, err
+		}
+	}
+	return c.Client.OpenFile(path, f)
+}
+
+func (c *SFTPClient) Link(oldname, newname string) error {
+	if err := c.checkWrite(); err != nil {
+		return err
+	}
+	return c.Client.Link(oldname, newname)
+}
+
+func (c *SFTPClient) Symlink(oldname, newname string) error {
+	if err := c.checkWrite(); err != nil {
+		return err
+	}
+	return c.Client.Symlink(oldname, newname)
+}
+
+func (c *SFTPClient) Chtimes(path string, atime, mtime time.Time) error {
+	if err := c.checkWrite(); err != nil {
+		return err
+	}
+	return c.Client.Chtimes(path, atime, mtime)
+}
+
+func (c *SFTPClient) Chown(path string, uid, gid int) error {
+	if err := c.checkWrite(); err != nil {
+		return err
+	}
+	return c.Client.Chown(path, uid, gid)
+}
+
+func (c *SFTPClient) Chmod(path string, mode os.FileMode) error {
+	if err := c.checkWrite(); err != nil {
+		return err
+	}
+	return c.Client.Chmod(path, mode)
+}
+
+func (c *SFTPClient) Truncate(path string, size int64) error {
+	if err := c.checkWrite(); err != nil {
+		return err
+	}
+	return c.Client.Truncate(path, size)
+}
+
+func (c *SFTPClient) Remove(path string) error {
+	if err := c.checkWrite(); err != nil {
+		return err
+	}
+	return c.Client.Remove(path)
+}
+
+func (c *SFTPClient) RemoveDirectory(path string) error {
+	if err := c.checkWrite(); err != nil {
+		return err
+	}
+	return c.Client.RemoveDirectory(path)
+}
+
+func (c *SFTPClient) RemoveAll(path string) error {
+	if err := c.checkWrite(); err != nil {
+		return err
+	}
+	return c.Client.RemoveAll(path)
+}
+
+func (c *SFTPClient) Rename(oldname, newname string) error {
+	if err := c.checkWrite(); err != nil {
+		return err
+	}
+	return c.Client.Rename(oldname, newname)
+}
+
+func (c *SFTPClient) PosixRename(oldname, newname string) error {
+	if err := c.checkWrite(); err != nil {
+		return err
+	}
+	return c.Client.PosixRename(oldname, newname)
+}
+
+func (c *SFTPClient) Mkdir(path string) error {
+	if err := c.checkWrite(); err != nil {
+		return err
+	}
+	return c.Client.Mkdir(path)
+}
+
+func (c *SFTPClient) MkdirAll(path string) error {
+	if err := c.checkWrite(); err != nil {
+		return err
+	}
+	return c.Client.MkdirAll(path)
+}
+
+// OpenSFTP opens an SFTP session to the host, over the pooled connection if
+// there is one and otherwise over a new connection.
+func (u *UnixCommandManager) OpenSFTP(ctx context.Context) (*SFTPClient, error) {
+	if u.IsLocal() {
+		return nil, errors.New("SFTP requires a remote host")
+	}
+
+	conn, release, err := u.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := sftp.NewClient(conn)
+	if err != nil && u.Pool != nil {
+		// The pooled connection may have died; replace it once
+		u.Pool.discard(conn)
+		if conn, release, err = u.acquire(ctx); err != nil {
+			return nil, err
+		}
+		client, err = sftp.NewClient(conn)
+	}
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	session := NewSFTPClient(client, closerFunc(release))
+	session.ReadOnly = u.ReadOnly
+	return session, nil
+}
@@ -0,0 +1,68 @@
+package commandmanager
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/pkg/sftp"
+)
+
+// newTestSFTPClient returns a session to an in-memory SFTP server shared by
+// every session opened on handlers.
+func newTestSFTPClient(t *testing.T, handlers sftp.Handlers) *SFTPClient {
+	t.Helper()
+	serverConn, clientConn := net.Pipe()
+	server := sftp.NewRequestServer(serverConn, handlers)
+	go server.Serve()
+
+	client, err := sftp.NewClientPipe(clientConn, clientConn)
+	if err != nil {
+		t.Fatalf("Failed to start SFTP client: %v", err)
+	}
+	session := NewSFTPClient(client, server)
+	t.Cleanup(func() { session.Close() })
+	return session
+}
+
+func TestSFTPClientReadOnly(t *testing.T) {
+	handlers := sftp.InMemHandler()
+	writable := newTestSFTPClient(t, handlers)
+	f, err := writable.Create("/motd")
+	if err != nil {
+		t.Fatalf("Expected a writable session to create files, got: %v", err)
+	}
+	f.Write([]byte("hello"))
+	f.Close()
+
+	session := newTestSFTPClient(t, handlers)
+	session.ReadOnly = true
+
+	writes := map[string]func() error{
+		"Create":      func() error { _, err := session.Create("/new"); return err },
+		"OpenFile":    func() error { _, err := session.OpenFile("/motd", os.O_WRONLY|os.O_TRUNC); return err },
+		"Mkdir":       func() error { return session.Mkdir("/dir") },
+		"MkdirAll":    func() error { return session.MkdirAll("/a/b") },
+		"Chmod":       func() error { return session.Chmod("/motd", 0777) },
+		"Rename":      func() error { return session.Rename("/motd", "/moved") },
+		"PosixRename": func() error { return session.PosixRename("/motd", "/moved") },
+		"Remove":      func() error { return session.Remove("/motd") },
+		"Symlink":     func() error { return session.Symlink("/motd", "/link") },
+	}
+	for name, write := range writes {
+		if err := write(); !errors.Is(err, ErrReadOnlyMode) {
+			t.Errorf("Expected %s to fail with ErrReadOnlyMode, got: %v", name, err)
+		}
+	}
+
+	f, err = session.Open("/motd")
+	if err != nil {
+		t.Fatalf("Expected reads to work in read-only mode, got: %v", err)
+	}
+	defer f.Close()
+	if data, _ := io.ReadAll(f); string(data) != "hello" {
+		t.Errorf("Expected the file to be unchanged, got %q", data)
+	}
+}
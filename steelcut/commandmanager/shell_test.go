@@ -0,0 +1,50 @@
+package commandmanager
+
+import (
+	"context"
+	"testing"
+)
+
+func TestShellQuote(t *testing.T) {
+	tests := map[string]string{
+		"":            "''",
+		"simple":      "simple",
+		"/etc/hosts":  "/etc/hosts",
+		"%F %Y %a":    "'%F %Y %a'",
+		"<":           "'<'",
+		"it's":        `'it'\''s'`,
+		"$HOME; rm -": "'$HOME; rm -'",
+	}
+
+	for input, expected := range tests {
+		if got := ShellQuote(input); got != expected {
+			t.Errorf("ShellQuote(%q): expected %s, got %s", input, expected, got)
+		}
+	}
+}
+
+func TestShellJoin(t *testing.T) {
+	words := []string{"systemctl", "set-environment", "GREETING=hello world", "", "it's"}
+	expected := `systemctl set-environment 'GREETING=hello world' '' 'it'\''s'`
+	if got := shellJoin(words); got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+}
+
+func TestRunRemoteQuotesArgs(t *testing.T) {
+	server := startTestSSHServer(t, "")
+	manager := testJumpManager("remote.example.com")
+	manager.SSHClient = addrDialer{addr: server.addr}
+
+	result, err := manager.RunRemote(context.Background(), CommandConfig{
+		Command: "echo",
+		Args:    []string{"hello world", "$HOME", "*.conf", "a;b"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := "'hello world' '$HOME' '*.conf' 'a;b'\n"
+	if result.STDOUT != expected {
+		t.Errorf("Expected %q, got %q", expected, result.STDOUT)
+	}
+}
@@ -0,0 +1,200 @@
+package commandmanager
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// maxSSHConfigDepth bounds nested Include directives.
+const maxSSHConfigDepth = 16
+
+// SSHHostConfig holds the connection settings an ssh_config file gives one
+// host. Fields the file does not set are left empty.
+type SSHHostConfig struct {
+	HostName      string
+	Port          int
+	User          string
+	IdentityFiles []string
+}
+
+// DefaultSSHConfigPath returns ~/.ssh/config.
+func DefaultSSHConfigPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".ssh", "config"), nil
+}
+
+// LoadSSHConfig returns the settings the ssh_config file at path gives host,
+// which may be an alias. As with ssh, the first value found for a setting
+// wins, except that every matching IdentityFile is kept. Host patterns and
+// Include are supported; Match blocks are skipped.
+func LoadSSHConfig(path, host string) (SSHHostConfig, error) {
+	var config SSHHostConfig
+	if err := readSSHConfig(path, host, &config, 0); err != nil {
+		return SSHHostConfig{}, err
+	}
+
+	home, _ := os.UserHomeDir()
+	hostname := host
+	if config.HostName != "" {
+		hostname = expandSSHTokens(config.HostName, host, home)
+		config.HostName = hostname
+	}
+	for i, file := range config.IdentityFiles {
+		config.IdentityFiles[i] = expandSSHTokens(file, hostname, home)
+	}
+	return config, nil
+}
+
+func readSSHConfig(file, host string, config *SSHHostConfig, depth int) error {
+	if depth > maxSSHConfigDepth {
+		return fmt.Errorf("%s: too many nested Include directives", file)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	// Settings before the first Host line apply to every host
+	matched := true
+	for i, line := range strings.Split(string(data), "\n") {
+		keyword, args, err := parseSSHConfigLine(line)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %v", file, i+1, err)
+		}
+		if keyword == "" {
+			continue
+		}
+
+		switch keyword {
+		case "host":
+			matched = matchSSHHost(host, args)
+			continue
+		case "match":
+			matched = false
+			continue
+		}
+		if !matched {
+			continue
+		}
+
+		switch keyword {
+		case "include":
+			for _, pattern := range args {
+				if err := includeSSHConfig(file, pattern, host, config, depth); err != nil {
+					return err
+				}
+			}
+		case "hostname":
+			if config.HostName == "" && len(args) > 0 {
+				config.HostName = args[0]
+			}
+		case "user":
+			if config.User == "" && len(args) > 0 {
+				config.User = args[0]
+			}
+		case "port":
+			if config.Port == 0 && len(args) > 0 {
+				port, err := strconv.Atoi(args[0])
+				if err != nil || port < 1 || port > 65535 {
+					return fmt.Errorf("%s:%d: invalid port %q", file, i+1, args[0])
+				}
+				config.Port = port
+			}
+		case "identityfile":
+			if len(args) > 0 {
+				config.IdentityFiles = append(config.IdentityFiles, args[0])
+			}
+		}
+	}
+	return nil
+}
+
+// includeSSHConfig reads the files matching pattern, which is relative to
+// ~/.ssh unless absolute.
+func includeSSHConfig(from, pattern, host string, config *SSHHostConfig, depth int) error {
+	if home, err := os.UserHomeDir(); err == nil && strings.HasPrefix(pattern, "~/") {
+		pattern = filepath.Join(home, pattern[2:])
+	}
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(filepath.Dir(from), pattern)
+	}
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return fmt.Errorf("%s: invalid Include pattern %q", from, pattern)
+	}
+	for _, file := range files {
+		if err := readSSHConfig(file, host, config, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseSSHConfigLine splits a line into its lowercased keyword and
+// arguments. Keywords may be separated from their value by "=", and
+// arguments may be double-quoted.
+func parseSSHConfigLine(line string) (string, []string, error) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", nil, nil
+	}
+
+	end := strings.IndexAny(line, " \t=")
+	if end == -1 {
+		return strings.ToLower(line), nil, nil
+	}
+	keyword := strings.ToLower(line[:end])
+	rest := strings.TrimSpace(line[end:])
+	rest = strings.TrimSpace(strings.TrimPrefix(rest, "="))
+
+	var args []string
+	for rest != "" {
+		var arg string
+		if rest[0] == '"' {
+			closing := strings.IndexByte(rest[1:], '"')
+			if closing == -1 {
+				return "", nil, fmt.Errorf("unterminated quote in %q", line)
+			}
+			arg, rest = rest[1:closing+1], rest[closing+2:]
+		} else if i := strings.IndexAny(rest, " \t"); i != -1 {
+			arg, rest = rest[:i], rest[i:]
+		} else {
+			arg, rest = rest, ""
+		}
+		args = append(args, arg)
+		rest = strings.TrimSpace(rest)
+	}
+	return keyword, args, nil
+}
+
+// matchSSHHost reports whether host matches a Host line's patterns. A
+// matching negated pattern ("!pattern") rules the host out.
+func matchSSHHost(host string, patterns []string) bool {
+	matched := false
+	for _, pattern := range patterns {
+		negated := strings.HasPrefix(pattern, "!")
+		pattern = strings.TrimPrefix(pattern, "!")
+		if ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(host)); ok {
+			if negated {
+				return false
+			}
+			matched = true
+		}
+	}
+	return matched
+}
+
+// expandSSHTokens expands a leading "~" and the %h, %d and %% tokens.
+func expandSSHTokens(value, host, home string) string {
+	if strings.HasPrefix(value, "~/") && home != "" {
+		value = filepath.Join(home, value[2:])
+	}
+	return strings.NewReplacer("%%", "%", "%h", host, "%d", home).Replace(value)
+}
@@ -0,0 +1,98 @@
+package commandmanager
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/steelcutops/steelcut/common"
+)
+
+func TestLoadSSHConfig(t *testing.T) {
+	dir := t.TempDir()
+	extra := filepath.Join(dir, "extra.conf")
+	if err := os.WriteFile(extra, []byte("Host db\n  HostName db.internal\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	config := filepath.Join(dir, "config")
+	content := `# global defaults
+IdentityFile /keys/global
+
+Include extra.conf
+
+Host web web-*
+  HostName %h.example.com
+  Port=2222
+  User deploy
+  IdentityFile "/keys/web key"
+
+Host * !db
+  User fallback
+  Port 22
+  IdentityFile /keys/%h
+
+Match exec "true"
+  User ignored
+`
+	if err := os.WriteFile(config, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		host string
+		want SSHHostConfig
+	}{
+		{"web", SSHHostConfig{
+			HostName:      "web.example.com",
+			Port:          2222,
+			User:          "deploy",
+			IdentityFiles: []string{"/keys/global", "/keys/web key", "/keys/web.example.com"},
+		}},
+		{"db", SSHHostConfig{
+			HostName:      "db.internal",
+			IdentityFiles: []string{"/keys/global"},
+		}},
+		{"other", SSHHostConfig{
+			Port:          22,
+			User:          "fallback",
+			IdentityFiles: []string{"/keys/global", "/keys/other"},
+		}},
+	}
+	for _, tt := range tests {
+		got, err := LoadSSHConfig(config, tt.host)
+		if err != nil {
+			t.Fatalf("LoadSSHConfig(%s) failed: %v", tt.host, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("LoadSSHConfig(%s) = %+v, want %+v", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestLoadSSHConfigInvalidPort(t *testing.T) {
+	config := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(config, []byte("Host *\n  Port ssh\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSSHConfig(config, "web"); err == nil {
+		t.Error("Expected an invalid port to be rejected")
+	}
+}
+
+func TestConnectUsesPort(t *testing.T) {
+	dialer := &MockFamilyDialer{errors: map[string]error{"tcp": errors.New("mock dial error")}}
+	manager := UnixCommandManager{
+		Hostname:    "web.example.com",
+		Port:        2222,
+		SSHClient:   dialer,
+		Credentials: common.Credentials{User: "user", Password: "password"},
+	}
+
+	_, _ = manager.RunRemote(context.Background(), CommandConfig{Command: "ls"})
+	if len(dialer.addrs) != 1 || dialer.addrs[0] != "web.example.com:2222" {
+		t.Errorf("Expected a dial to port 2222, got %v", dialer.addrs)
+	}
+}
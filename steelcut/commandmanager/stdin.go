@@ -0,0 +1,36 @@
+package commandmanager
+
+import (
+	"io"
+	"strings"
+)
+
+// escalationFor returns the escalation strategy for config. sudo -S reads the
+// password from the stdin it shares with the command, and skips it when
+// sudoers has NOPASSWD or credentials are cached, so when no Escalation is
+// set or detected and the command has input, SudoAskpassStrategy, which
+// always consumes the password line, is used instead. It needs a temporary
+// directory that allows executing files; setting Escalation to
+// SudoStrategy{} keeps sudo -S.
+func (u *UnixCommandManager) escalationFor(config CommandConfig) EscalationStrategy {
+	if u.Escalation == nil && u.EscalationDetector.detected() == nil && config.Stdin != nil {
+		return SudoAskpassStrategy{}
+	}
+	return u.escalation()
+}
+
+// escalate returns the command line that runs config's command as root and
+// the reader for its stdin: the escalation tool's password line, if any,
+// followed by config.Stdin. A nil reader leaves stdin empty.
+func (u *UnixCommandManager) escalate(config CommandConfig) ([]string, io.Reader) {
+	strategy := u.escalationFor(config)
+	args := strategy.Wrap(config.Command, config.Args)
+	password := strategy.Stdin(u.SudoPassword)
+	switch {
+	case password == "":
+		return args, config.Stdin
+	case config.Stdin == nil:
+		return args, strings.NewReader(password)
+	}
+	return args, io.MultiReader(strings.NewReader(password), config.Stdin)
+}
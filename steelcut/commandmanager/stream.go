@@ -0,0 +1,95 @@
+package commandmanager
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Stream names passed to CommandConfig.OnLine.
+const (
+	StreamStdout = "stdout"
+	StreamStderr = "stderr"
+)
+
+// RunCommandStream runs the command, calling onLine with StreamStdout or
+// StreamStderr and each line of output as it arrives, e.g. to show progress
+// of a long upgrade. Cancelling ctx stops the command mid-stream.
+func RunCommandStream(ctx context.Context, manager CommandManager, config CommandConfig, onLine func(stream, line string)) error {
+	config.OnLine = onLine
+	_, err := manager.Run(ctx, config)
+	return err
+}
+
+// streamOutput tees stdout and stderr to onLine, a line at a time. Calls are
+// serialized, so onLine need not be safe for concurrent use. With a nil
+// onLine the writers are returned unchanged.
+func streamOutput(onLine func(stream, line string), stdout, stderr io.Writer) (io.Writer, io.Writer, *lineStream) {
+	s := &lineStream{onLine: onLine}
+	if onLine == nil {
+		return stdout, stderr, s
+	}
+	return io.MultiWriter(stdout, s.writer(StreamStdout)), io.MultiWriter(stderr, s.writer(StreamStderr)), s
+}
+
+// lineStream splits a command's output into lines for onLine.
+type lineStream struct {
+	mu      sync.Mutex
+	onLine  func(stream, line string)
+	stopped bool
+	pending map[string][]byte
+}
+
+func (s *lineStream) writer(stream string) io.Writer {
+	return lineWriter{s: s, stream: stream}
+}
+
+// Flush delivers any final lines that lack a newline.
+func (s *lineStream) Flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, stream := range []string{StreamStdout, StreamStderr} {
+		if buf := s.pending[stream]; len(buf) > 0 && !s.stopped {
+			s.onLine(stream, strings.TrimSuffix(string(buf), "\r"))
+		}
+		delete(s.pending, stream)
+	}
+}
+
+// Stop drops any output still to come, for commands that were abandoned.
+func (s *lineStream) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopped = true
+}
+
+type lineWriter struct {
+	s      *lineStream
+	stream string
+}
+
+func (w lineWriter) Write(p []byte) (int, error) {
+	s := w.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		return len(p), nil
+	}
+	if s.pending == nil {
+		s.pending = make(map[string][]byte)
+	}
+
+	buf := append(s.pending[w.stream], p...)
+	for {
+		i := bytes.IndexByte(buf, '\n')
+		if i < 0 {
+			break
+		}
+		s.onLine(w.stream, strings.TrimSuffix(string(buf[:i]), "\r"))
+		buf = buf[i+1:]
+	}
+	s.pending[w.stream] = buf
+	return len(p), nil
+}
@@ -0,0 +1,74 @@
+package commandmanager
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRunCommandStreamLocal(t *testing.T) {
+	manager := &UnixCommandManager{Hostname: "localhost"}
+
+	var stdout, stderr []string
+	var firstAt time.Time
+	start := time.Now()
+	err := RunCommandStream(context.Background(), manager, CommandConfig{
+		Command: "sh",
+		Args:    []string{"-c", "echo one; echo oops >&2; sleep 1; printf 'two\\r\\nthree'"},
+	}, func(stream, line string) {
+		if firstAt.IsZero() {
+			firstAt = time.Now()
+		}
+		if stream == StreamStderr {
+			stderr = append(stderr, line)
+		} else {
+			stdout = append(stdout, line)
+		}
+	})
+	if err != nil {
+		t.Fatalf("RunCommandStream failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(stdout, []string{"one", "two", "three"}) {
+		t.Errorf("Unexpected stdout lines: %q", stdout)
+	}
+	if !reflect.DeepEqual(stderr, []string{"oops"}) {
+		t.Errorf("Unexpected stderr lines: %q", stderr)
+	}
+	if firstAt.Sub(start) > 900*time.Millisecond {
+		t.Errorf("First line arrived after %s, expected it before the command finished", firstAt.Sub(start))
+	}
+}
+
+func TestRunCommandStreamRemote(t *testing.T) {
+	server := startTestSSHServer(t, "")
+	manager := testJumpManager("remote.example.com")
+	manager.SSHClient = addrDialer{addr: server.addr}
+
+	var lines []string
+	err := RunCommandStream(context.Background(), manager, CommandConfig{Command: "echo", Args: []string{"hello"}}, func(stream, line string) {
+		lines = append(lines, stream+": "+line)
+	})
+	if err != nil {
+		t.Fatalf("RunCommandStream failed: %v", err)
+	}
+	if !reflect.DeepEqual(lines, []string{"stdout: hello"}) {
+		t.Errorf("Unexpected lines: %q", lines)
+	}
+}
+
+func TestRunCommandStreamCancelled(t *testing.T) {
+	manager := &UnixCommandManager{Hostname: "localhost"}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	err := RunCommandStream(ctx, manager, CommandConfig{
+		Command: "sh",
+		Args:    []string{"-c", "echo started; sleep 10"},
+	}, func(stream, line string) {
+		cancel()
+	})
+	if err == nil || ctx.Err() == nil {
+		t.Fatalf("Expected the command to be cancelled mid-stream, got %v", err)
+	}
+}
@@ -3,9 +3,12 @@ package commandmanager
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
+	"net"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -23,57 +26,193 @@ type UnixCommandManager struct {
 	Hostname  string
 	SSHClient SSHDialer
 	common.Credentials
+
+	// Escalation selects how privileged commands are run. Defaults to sudo.
+	Escalation EscalationStrategy
+
+	// EscalationDetector, when Escalation is nil, checks which escalation
+	// tool the host has before the first privileged command. Nil uses the
+	// default without probing.
+	EscalationDetector *EscalationDetector
+
+	// AddressFamily restricts dialing to "ip4" or "ip6", or races TCP
+	// connections over both with "auto" and runs the SSH handshake on the
+	// first to connect, without SSHClient. Empty leaves the choice to the
+	// resolver.
+	AddressFamily string
+
+	// MaxOutputBytes caps the combined stdout and stderr captured from a
+	// command. Zero uses DefaultMaxOutputBytes.
+	MaxOutputBytes int64
+
+	// ReadOnly blocks any command not known to be read-only with
+	// ErrReadOnlyMode, before it is executed.
+	ReadOnly bool
+
+	// HostKeyCallback verifies the host's key. Nil verifies against
+	// ~/.ssh/known_hosts.
+	HostKeyCallback ssh.HostKeyCallback
+
+	// Breaker fails remote operations fast with ErrCircuitOpen after
+	// repeated dial or command failures. Nil disables it.
+	Breaker *CircuitBreaker
+
+	// StrictOutput makes parsers return a *ParseError for output lines they
+	// do not recognize instead of skipping them.
+	StrictOutput bool
+
+	// History records each command run through Run. Nil disables it.
+	History *CommandHistory
+
+	// JumpHost is a bastion the host is reached through. Its own JumpHost,
+	// if set, chains a further bastion in front of it. Nil connects directly.
+	JumpHost *UnixCommandManager
+
+	// Pool keeps the connection open between commands. Nil dials a new
+	// connection for each command.
+	Pool *ConnectionPool
+
+	// Port is the SSH port. Zero uses 22.
+	Port int
+
+	// KeepAlive is the interval at which keepalives are sent on open
+	// connections. Zero sends none.
+	KeepAlive time.Duration
+
+	// DialAttempts is how many times a connection is attempted when dialing
+	// fails with a transient error, waiting DialBackoff before the first
+	// retry and twice as long before each one after. Zero or one dials once.
+	DialAttempts int
+	DialBackoff  time.Duration
+
+	// IdentityFiles are the private keys to authenticate with, in place of
+	// the SSH agent or the default ~/.ssh/id_* keys.
+	IdentityFiles []string
+
+	// ConnectTimeout bounds each attempt to establish a connection, including
+	// the SSH handshake. Zero uses DefaultConnectTimeout.
+	ConnectTimeout time.Duration
+
+	// CommandTimeout bounds each command run through Run, connecting
+	// included. Zero waits as long as the context allows.
+	CommandTimeout time.Duration
+
+	// Logger receives the manager's diagnostics, all at debug level except
+	// dial retries and dropped connections. Nil discards them.
+	Logger *slog.Logger
+
+	// AgentForwarding makes the local SSH agent available to remote
+	// commands, e.g. for git over SSH on the host. It applies only when
+	// authenticating with the agent, and is skipped if there is none.
+	AgentForwarding bool
+
+	// RedactCommands leaves command lines and output out of the log
+	// entirely. Otherwise they are logged with the manager's credentials and
+	// likely secrets, such as PASSWORD=... or --token ..., masked.
+	RedactCommands bool
 }
 
-func (u *UnixCommandManager) checkSudoErrors(result CommandResult) error {
-	if strings.Contains(result.STDERR, "incorrect password") {
-		return errors.New("sudo: incorrect password provided")
-	}
-	if strings.Contains(result.STDERR, "is not in the sudoers file") {
-		return errors.New("sudo: user is not in the sudoers file")
-	}
-	if strings.Contains(result.STDERR, "timed out reading password") {
-		return errors.New("sudo: password prompt timed out")
+// DefaultConnectTimeout is the ConnectTimeout used when none is set.
+const DefaultConnectTimeout = 5 * time.Second
+
+// port returns the configured SSH port, defaulting to 22.
+func (u *UnixCommandManager) port() int {
+	if u.Port == 0 {
+		return 22
 	}
-	if strings.Contains(result.STDERR, "no tty present and no askpass program specified") {
-		return errors.New("sudo: cannot prompt for password due to missing terminal or askpass program")
+	return u.Port
+}
+
+// connectTimeout returns the configured connect timeout, defaulting to
+// DefaultConnectTimeout.
+func (u *UnixCommandManager) connectTimeout() time.Duration {
+	if u.ConnectTimeout <= 0 {
+		return DefaultConnectTimeout
 	}
-	if strings.Contains(result.STDERR, "unknown user") {
-		return errors.New("sudo: specified user is unknown")
+	return u.ConnectTimeout
+}
+
+// escalation returns the configured or detected privilege escalation
+// strategy, defaulting to sudo.
+func (u *UnixCommandManager) escalation() EscalationStrategy {
+	if u.Escalation != nil {
+		return u.Escalation
 	}
-	if strings.Contains(result.STDERR, "unable to execute") {
-		return errors.New("sudo: unable to execute the specified command")
+	if strategy := u.EscalationDetector.detected(); strategy != nil {
+		return strategy
 	}
-	if strings.Contains(result.STDERR, "Permission denied") {
-		return errors.New("permission denied: consider using sudo for this command")
+	return SudoStrategy{}
+}
+
+// detectEscalation probes the host for its escalation tool before config's
+// command runs, if it is privileged and no Escalation is set.
+func (u *UnixCommandManager) detectEscalation(ctx context.Context, config CommandConfig) {
+	if config.Sudo && u.Escalation == nil {
+		u.EscalationDetector.detect(ctx, u)
 	}
-	return nil
+}
+
+func (u *UnixCommandManager) checkSudoErrors(config CommandConfig, result CommandResult) error {
+	return u.escalationFor(config).CheckErrors(result)
 }
 
 func (u *UnixCommandManager) RunLocal(ctx context.Context, config CommandConfig) (CommandResult, error) {
+	if err := u.checkReadOnly(config); err != nil {
+		return CommandResult{}, err
+	}
+	command := config.Command
+	if err := checkEnv(config.Env); err != nil {
+		return CommandResult{}, err
+	}
+	if err := checkLocalDir(config.Dir); err != nil {
+		return CommandResult{}, err
+	}
+	config, err := applyLimits(config)
+	if err != nil {
+		return CommandResult{}, err
+	}
+	u.detectEscalation(ctx, config)
+	if config.Sudo {
+		config = envCommand(config)
+	}
+
 	start := time.Now()
 
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	cmd := exec.CommandContext(ctx, config.Command, config.Args...)
+	cmd.Stdin = config.Stdin
 	if config.Sudo {
-		cmdArgs := append([]string{"sudo", "-S", "--", config.Command}, config.Args...)
+		cmdArgs, stdin := u.escalate(config)
 		cmd = exec.CommandContext(ctx, cmdArgs[0], cmdArgs[1:]...)
-		cmd.Stdin = strings.NewReader(u.SudoPassword + "\n")
+		cmd.Stdin = stdin
 	}
 
-	// Set the environment variables
+	cmd.Dir = config.Dir
+
+	// Once the command is killed, don't wait for children that still hold
+	// its output pipes open
+	cmd.WaitDelay = time.Second
+
+	// Set the environment variables; with Sudo they were moved to env above
 	if len(config.Env) > 0 {
 		cmd.Env = append(os.Environ(), config.Env...)
 	}
 
+	// Kill the command once it exceeds the output limit
 	var stdout, stderr strings.Builder
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	limiter := newOutputLimiter(u.maxOutputBytes(), cancel)
+	var lines *lineStream
+	cmd.Stdout, cmd.Stderr, lines = streamOutput(config.OnLine, limiter.Writer(&stdout), limiter.Writer(&stderr))
 
-	err := cmd.Run()
+	err = cmd.Run()
+	lines.Flush()
 
 	duration := time.Since(start)
 	result := CommandResult{
-		Command:   config.Command,
+		Command:   command,
 		STDOUT:    stdout.String(),
 		STDERR:    stderr.String(),
 		ExitCode:  getExitCode(err),
@@ -81,12 +220,25 @@ func (u *UnixCommandManager) RunLocal(ctx context.Context, config CommandConfig)
 		Timestamp: start,
 	}
 
+	if limiter.Exceeded() {
+		return result, ErrOutputTooLarge
+	}
+	if parent.Err() != nil {
+		return result, contextError(parent, command)
+	}
+
 	// Check for sudo-related errors
-	sudoErr := u.checkSudoErrors(result)
+	sudoErr := u.checkSudoErrors(config, result)
+	if config.Sudo {
+		result.STDERR = stripSudoPrompts(result.STDERR)
+	}
 	if sudoErr != nil {
 		return result, sudoErr
 	}
 
+	if cmdErr := commandError(command, result, err); cmdErr != nil {
+		return result, cmdErr
+	}
 	return result, err
 }
 
@@ -95,7 +247,7 @@ func (c UnixCommandManager) getSSHConfig() (*ssh.ClientConfig, error) {
 
 	handleKeyboardInteractive := func(user, instruction string, questions []string, echos []bool) ([]string, error) {
 		for _, question := range questions {
-			slog.Debug("Received keyboard-interactive challenge:", "challenge", question, "hostname", c.Hostname)
+			c.logger().Debug("Received keyboard-interactive challenge", "challenge", question, "hostname", c.Hostname)
 		}
 
 		// Return an empty response to prevent hanging.
@@ -103,12 +255,14 @@ func (c UnixCommandManager) getSSHConfig() (*ssh.ClientConfig, error) {
 	}
 
 	if c.Password != "" {
-		slog.Debug("Using password authentication", "hostname", c.Hostname)
+		c.logger().Debug("Using password authentication", "hostname", c.Hostname)
 		authMethods = append(authMethods, ssh.Password(c.Password))
 	} else {
-		slog.Debug("Using public key authentication", "hostname", c.Hostname)
+		c.logger().Debug("Using public key authentication", "hostname", c.Hostname)
 		var keyManager steelcut.SSHKeyManager
-		if c.KeyPassphrase != "" {
+		if len(c.IdentityFiles) > 0 {
+			keyManager = steelcut.FileSSHKeyManager{Paths: c.IdentityFiles}
+		} else if c.KeyPassphrase != "" {
 			keyManager = steelcut.FileSSHKeyManager{}
 		} else {
 			keyManager = steelcut.AgentSSHKeyManager{}
@@ -116,7 +270,7 @@ func (c UnixCommandManager) getSSHConfig() (*ssh.ClientConfig, error) {
 
 		keys, err := keyManager.ReadPrivateKeys(c.KeyPassphrase)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("%w: %w", ErrSSHAuth, err)
 		}
 
 		authMethods = append(authMethods, ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
@@ -127,76 +281,136 @@ func (c UnixCommandManager) getSSHConfig() (*ssh.ClientConfig, error) {
 	// Add keyboard-interactive authentication method
 	authMethods = append(authMethods, ssh.KeyboardInteractive(handleKeyboardInteractive))
 
+	hostKeyCallback := c.HostKeyCallback
+	if hostKeyCallback == nil {
+		var err error
+		if hostKeyCallback, err = defaultHostKeyCallback(); err != nil {
+			return nil, fmt.Errorf("failed to load known hosts: %w", err)
+		}
+	}
+
 	return &ssh.ClientConfig{
 		User:            c.User,
 		Auth:            authMethods,
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		HostKeyCallback: hostKeyCallback,
 	}, nil
 }
 
+// connect opens an SSH connection to the host.
+func (u *UnixCommandManager) connect(ctx context.Context) (*ssh.Client, error) {
+	// Hosts behind a jump host are dialed through it
+	if u.SSHClient == nil && u.JumpHost == nil {
+		return nil, errors.New("SSHClient is not initialized")
+	}
+
+	sshConfig, err := u.getSSHConfig()
+	if err != nil {
+		return nil, err
+	}
+	if err := u.Breaker.Allow(); err != nil {
+		return nil, err
+	}
+	addr := net.JoinHostPort(u.Hostname, strconv.Itoa(u.port()))
+	client, err := u.dialWithRetry(ctx, addr, func() (*ssh.Client, error) {
+		dialTimeout := u.connectTimeout()
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < dialTimeout {
+			dialTimeout = time.Until(deadline)
+		}
+
+		if u.JumpHost != nil {
+			return u.dialJump(ctx, addr, sshConfig, dialTimeout)
+		}
+		return u.dial(addr, sshConfig, dialTimeout)
+	})
+	err = dialError(addr, err)
+	u.Breaker.Record(err)
+	if err == nil && client != nil {
+		u.forwardAgent(client)
+		if u.KeepAlive > 0 {
+			go keepAlive(client, u.Hostname, u.KeepAlive, u.logger())
+		}
+	}
+	return client, err
+}
+
 func (u *UnixCommandManager) RunRemote(ctx context.Context, config CommandConfig) (CommandResult, error) {
-	slog.Debug("Executing remote command",
+	u.logger().Debug("Executing remote command",
 		"hostname", u.Hostname,
-		"command", config.Command,
-		"args", strings.Join(config.Args, " "),
+		"command", u.redactCommand(config.Command),
+		"args", u.redactCommand(shellJoin(config.Args)),
 		"sudo", config.Sudo,
 	)
 
-	if u.SSHClient == nil {
-		return CommandResult{}, errors.New("SSHClient is not initialized")
-	}
-
-	sshConfig, err := u.getSSHConfig()
-	if err != nil {
+	if err := u.checkReadOnly(config); err != nil {
 		return CommandResult{}, err
 	}
-	var dialTimeout time.Duration
-	if deadline, ok := ctx.Deadline(); ok {
-		dialTimeout = time.Until(deadline)
-	} else {
-		dialTimeout = 15 * time.Minute
+	if err := checkEnv(config.Env); err != nil {
+		return CommandResult{}, err
 	}
-
-	client, err := u.SSHClient.Dial("tcp", u.Hostname+":22", sshConfig, dialTimeout)
-	if err != nil || client == nil {
+	config, err := applyLimits(config)
+	if err != nil {
 		return CommandResult{}, err
 	}
-	defer client.Close()
+	u.detectEscalation(ctx, config)
 
-	session, err := client.NewSession()
-	if err != nil || session == nil {
+	session, release, err := u.newSession(ctx)
+	if err != nil {
 		return CommandResult{}, err
 	}
+	defer release()
 	defer session.Close()
+	u.requestAgentForwarding(session)
 
-	cmdStr := config.Command + " " + strings.Join(config.Args, " ")
+	// Servers refuse variables not allowed by AcceptEnv, and sudo would drop
+	// them anyway, so those are set on the command line instead
+	if config.Sudo {
+		config = envCommand(config)
+	}
+	for _, e := range config.Env {
+		name, value, _ := strings.Cut(e, "=")
+		if err := session.Setenv(name, value); err != nil {
+			u.logger().Debug("Server rejected environment variable, passing it with env", "hostname", u.Hostname, "name", name)
+			config = envCommand(config)
+			break
+		}
+	}
+
+	cmdStr := config.Command + " " + shellJoin(config.Args)
+	session.Stdin = config.Stdin
 
 	if config.Sudo {
-		cmdStr = "sudo -S -- " + cmdStr
-		session.Stdin = strings.NewReader(u.SudoPassword + "\n")
+		cmdArgs, stdin := u.escalate(config)
+		cmdStr = shellJoin(cmdArgs)
+		session.Stdin = stdin
 	}
 
-	// Prepend environment variables
-	if len(config.Env) > 0 {
-		envStr := strings.Join(config.Env, " ") + " "
-		cmdStr = envStr + cmdStr
+	// sudo keeps the working directory, so the cd stays outside it
+	if config.Dir != "" {
+		cmdStr = dirPrefix(config.Dir) + cmdStr
 	}
 
 	start := time.Now()
 
-	outputCh := make(chan CommandResult)
+	// Set up the command to execute remotely, killing it once it exceeds the output limit
+	var stdout, stderr strings.Builder
+	limiter := newOutputLimiter(u.maxOutputBytes(), func() {
+		_ = session.Signal(ssh.SIGKILL)
+		session.Close()
+	})
+	var lines *lineStream
+	session.Stdout, session.Stderr, lines = streamOutput(config.OnLine, limiter.Writer(&stdout), limiter.Writer(&stderr))
+
+	outputCh := make(chan CommandResult, 1)
+	var runErr error
 	go func() {
 		var result CommandResult
 
-		// Set up the command to execute remotely
-		var stdout, stderr strings.Builder
-		session.Stdout = &stdout
-		session.Stderr = &stderr
-
 		// Execute command
 		err := session.Run(cmdStr)
+		lines.Flush()
+		runErr = err
 		if err != nil {
-			slog.Error("Failed to execute command over SSH", "command", cmdStr, "error", err, "stdout", stdout.String(), "stderr", stderr.String())
+			u.logger().Debug("Failed to execute command over SSH", "hostname", u.Hostname, "command", u.redactCommand(cmdStr), "error", err, "stdout", u.redactCommand(stdout.String()), "stderr", u.redactCommand(stderr.String()))
 			result.ExitCode = getExitCode(err)
 		}
 
@@ -213,31 +427,73 @@ func (u *UnixCommandManager) RunRemote(ctx context.Context, config CommandConfig
 		result.Timestamp = start
 		result.Command = cmdStr
 
+		if limiter.Exceeded() {
+			return result, ErrOutputTooLarge
+		}
+
 		// Check for sudo-related errors
-		sudoErr := u.checkSudoErrors(result)
+		sudoErr := u.checkSudoErrors(config, result)
+		if config.Sudo {
+			result.STDERR = stripSudoPrompts(result.STDERR)
+		}
 		if sudoErr != nil {
 			return result, sudoErr
 		}
 
+		if dirErr := dirError(config.Dir, result); dirErr != nil {
+			return result, dirErr
+		}
+
+		if cmdErr := commandError(config.Command, result, runErr); cmdErr != nil {
+			return result, cmdErr
+		}
+		// Any other error means the command's outcome is unknown, e.g. the
+		// connection dropped
+		var exitErr *ssh.ExitError
+		if runErr != nil && !errors.As(runErr, &exitErr) {
+			return result, runErr
+		}
 		return result, nil
 
 	case <-ctx.Done():
-		slog.Error("Command over SSH timed out.", "command_string", cmdStr)
-		return CommandResult{}, ctx.Err()
+		u.logger().Debug("Command over SSH aborted", "hostname", u.Hostname, "command", u.redactCommand(cmdStr), "error", ctx.Err())
+		// Kill the remote command rather than leave it running
+		lines.Stop()
+		_ = session.Signal(ssh.SIGKILL)
+		session.Close()
+		u.Breaker.Record(ctx.Err())
+		return CommandResult{}, contextError(ctx, config.Command)
 	}
 }
 
+// contextError reports that command was stopped because ctx was cancelled or
+// its deadline passed. It wraps context.Canceled, or ErrCommandTimeout and
+// context.DeadlineExceeded.
+func contextError(ctx context.Context, command string) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %s: %w", ErrCommandTimeout, command, ctx.Err())
+	}
+	return fmt.Errorf("command %s cancelled: %w", command, ctx.Err())
+}
+
 func (u *UnixCommandManager) Run(ctx context.Context, config CommandConfig) (CommandResult, error) {
-	if u.isLocal() {
-		slog.Debug("Detected local so running local command", "hostname", u.Hostname, "command", config.Command, "sshclient", u.SSHClient)
-		return u.RunLocal(ctx, config)
+	if u.CommandTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, u.CommandTimeout)
+		defer cancel()
+	}
+
+	if u.IsLocal() {
+		u.logger().Debug("Detected local so running local command", "hostname", u.Hostname, "command", u.redactCommand(config.Command), "sshclient", u.SSHClient)
+		return u.runRecorded(ctx, config, u.RunLocal)
 	}
 
-	slog.Debug("Detected remote command so running remote command", "hostname", u.Hostname, "command", config.Command, "sshclient", u.SSHClient)
-	return u.RunRemote(ctx, config)
+	u.logger().Debug("Detected remote command so running remote command", "hostname", u.Hostname, "command", u.redactCommand(config.Command), "sshclient", u.SSHClient)
+	return u.runRecorded(ctx, config, u.RunRemote)
 }
 
-func (u *UnixCommandManager) isLocal() bool {
+// IsLocal reports whether commands run on this machine rather than over SSH.
+func (u *UnixCommandManager) IsLocal() bool {
 	return u.Hostname == "localhost" || u.Hostname == "127.0.0.1"
 }
 
@@ -247,6 +503,9 @@ func getExitCode(err error) int {
 			status := exitError.Sys().(syscall.WaitStatus)
 			return status.ExitStatus()
 		}
+		if exitError, ok := err.(*ssh.ExitError); ok {
+			return exitError.ExitStatus()
+		}
 	}
 	return 0
 }
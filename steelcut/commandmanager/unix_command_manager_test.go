@@ -3,6 +3,14 @@ package commandmanager
 import (
 	"context"
 	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -54,13 +62,13 @@ func TestIsLocal(t *testing.T) {
 		Hostname: "localhost",
 	}
 
-	if !manager.isLocal() {
-		t.Errorf("Expected isLocal to return true for localhost")
+	if !manager.IsLocal() {
+		t.Errorf("Expected IsLocal to return true for localhost")
 	}
 
 	manager.Hostname = "example.com"
-	if manager.isLocal() {
-		t.Errorf("Expected isLocal to return false for example.com")
+	if manager.IsLocal() {
+		t.Errorf("Expected IsLocal to return false for example.com")
 	}
 }
 
@@ -110,3 +118,455 @@ func TestRun(t *testing.T) {
 		t.Errorf("Expected Run with remote host to fail due to lack of mock, but it didn't")
 	}
 }
+
+type MockFamilyDialer struct {
+	mu       sync.Mutex
+	networks []string
+	addrs    []string
+	errors   map[string]error
+}
+
+func (m *MockFamilyDialer) Dial(network, addr string, config *ssh.ClientConfig, timeout time.Duration) (*ssh.Client, error) {
+	m.mu.Lock()
+	m.networks = append(m.networks, network)
+	m.addrs = append(m.addrs, addr)
+	m.mu.Unlock()
+
+	return nil, m.errors[network]
+}
+
+func TestDialExplicitIPv6(t *testing.T) {
+	dialer := &MockFamilyDialer{errors: map[string]error{"tcp6": errors.New("mock dial error")}}
+	manager := UnixCommandManager{
+		Hostname:      "::1",
+		SSHClient:     dialer,
+		AddressFamily: AddressFamilyIPv6,
+		Credentials:   common.Credentials{User: "user", Password: "password"},
+	}
+
+	_, _ = manager.RunRemote(context.Background(), CommandConfig{Command: "ls"})
+
+	if len(dialer.networks) != 1 || dialer.networks[0] != "tcp6" {
+		t.Errorf("Expected a single tcp6 dial, got %v", dialer.networks)
+	}
+	if dialer.addrs[0] != "[::1]:22" {
+		t.Errorf("Expected bracketed IPv6 address, got %s", dialer.addrs[0])
+	}
+}
+
+func TestDialAutoHandshakesOnce(t *testing.T) {
+	server := startTestSSHServer(t, "")
+	dialer := &MockFamilyDialer{}
+	manager := UnixCommandManager{SSHClient: dialer, AddressFamily: AddressFamilyAuto}
+
+	// Only tcp4 can reach the IPv4 test server
+	client, err := manager.dial(server.addr, &ssh.ClientConfig{HostKeyCallback: ssh.InsecureIgnoreHostKey()}, time.Second)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	client.Close()
+	if len(dialer.networks) != 0 {
+		t.Errorf("Expected no full SSH dials per family, got %v", dialer.networks)
+	}
+}
+
+func TestRaceConnectUsesFirstSuccess(t *testing.T) {
+	fast, fastPeer := net.Pipe()
+	defer fastPeer.Close()
+	slow, slowPeer := net.Pipe()
+	connect := func(network string) (net.Conn, error) {
+		if network == "tcp6" {
+			time.Sleep(50 * time.Millisecond)
+			return slow, nil
+		}
+		return fast, nil
+	}
+
+	conn, err := raceConnect([]string{"tcp6", "tcp4"}, connect)
+	if err != nil || conn != fast {
+		t.Fatalf("Expected the faster tcp4 connection, got %v, %v", conn, err)
+	}
+	// The slower connection is closed once it arrives
+	slowPeer.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := slowPeer.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("Expected the losing connection to be closed, got: %v", err)
+	}
+}
+
+func TestRaceConnectAllFail(t *testing.T) {
+	connect := func(network string) (net.Conn, error) {
+		if network == "tcp6" {
+			return nil, errors.New("network unreachable")
+		}
+		return nil, errors.New("connection refused")
+	}
+
+	_, err := raceConnect([]string{"tcp6", "tcp4"}, connect)
+	if err == nil {
+		t.Fatalf("Expected an error when both families fail")
+	}
+	if !strings.Contains(err.Error(), "tcp4") || !strings.Contains(err.Error(), "tcp6") {
+		t.Errorf("Expected both dial errors to be reported, got: %v", err)
+	}
+}
+
+func TestRunLocalOutputTooLarge(t *testing.T) {
+	manager := UnixCommandManager{
+		Hostname:       "localhost",
+		MaxOutputBytes: 1024,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := manager.RunLocal(ctx, CommandConfig{Command: "yes"})
+	if !errors.Is(err, ErrOutputTooLarge) {
+		t.Fatalf("Expected ErrOutputTooLarge, got: %v", err)
+	}
+	if len(result.STDOUT) != 1024 {
+		t.Errorf("Expected output truncated to 1024 bytes, got %d", len(result.STDOUT))
+	}
+	if ctx.Err() != nil {
+		t.Errorf("Expected the command to be killed before the test deadline")
+	}
+}
+
+func TestMaxOutputBytesDefault(t *testing.T) {
+	manager := UnixCommandManager{}
+	if manager.maxOutputBytes() != DefaultMaxOutputBytes {
+		t.Errorf("Expected default limit %d, got %d", DefaultMaxOutputBytes, manager.maxOutputBytes())
+	}
+}
+
+func TestRunLocalContextErrors(t *testing.T) {
+	manager := UnixCommandManager{Hostname: "localhost"}
+	config := CommandConfig{Command: "sleep", Args: []string{"10"}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := manager.RunLocal(ctx, config)
+	if !errors.Is(err, ErrCommandTimeout) || !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("Expected deadline error, got %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("Command was not stopped at the deadline")
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	_, err = manager.RunLocal(ctx, config)
+	if !errors.Is(err, context.Canceled) || !strings.Contains(err.Error(), "cancelled") {
+		t.Errorf("Expected cancellation error, got %v", err)
+	}
+
+	_, err = manager.RunLocal(context.Background(), CommandConfig{Command: "false"})
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected a plain command failure, got %v", err)
+	}
+}
+
+func TestRunRemoteDeadlineClosesSession(t *testing.T) {
+	server := startTestSSHServer(t, "")
+	manager := testJumpManager("remote.example.com")
+	manager.SSHClient = addrDialer{addr: server.addr}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	_, err := manager.RunRemote(ctx, CommandConfig{Command: "sleep", Args: []string{"10"}})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected deadline error, got %v", err)
+	}
+
+	select {
+	case <-server.closed:
+	case <-time.After(5 * time.Second):
+		t.Error("Connection was not closed after the deadline")
+	}
+}
+
+func TestRunLocalCommandError(t *testing.T) {
+	manager := UnixCommandManager{Hostname: "localhost"}
+
+	result, err := manager.RunLocal(context.Background(), CommandConfig{
+		Command: "sh",
+		Args:    []string{"-c", "echo oops >&2; exit 3"},
+	})
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) {
+		t.Fatalf("Expected a *CommandError, got %v", err)
+	}
+	if cmdErr.ExitCode != 3 || result.ExitCode != 3 || strings.TrimSpace(cmdErr.Stderr) != "oops" {
+		t.Errorf("Unexpected command error: %+v", cmdErr)
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		t.Errorf("Expected the *exec.ExitError to be wrapped")
+	}
+
+	if _, err := manager.RunLocal(context.Background(), CommandConfig{Command: "true"}); err != nil {
+		t.Errorf("Expected nil error for exit 0, got %v", err)
+	}
+}
+
+func TestRunRemoteCommandError(t *testing.T) {
+	server := startTestSSHServer(t, "")
+	manager := testJumpManager("remote.example.com")
+	manager.SSHClient = addrDialer{addr: server.addr}
+
+	result, err := manager.RunRemote(context.Background(), CommandConfig{Command: "exit", Args: []string{"3"}})
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) {
+		t.Fatalf("Expected a *CommandError, got %v", err)
+	}
+	if cmdErr.ExitCode != 3 || result.ExitCode != 3 || strings.TrimSpace(cmdErr.Stderr) != "failed" {
+		t.Errorf("Unexpected command error: %+v", cmdErr)
+	}
+
+	if _, err := manager.RunRemote(context.Background(), CommandConfig{Command: "true"}); err != nil {
+		t.Errorf("Expected nil error for exit 0, got %v", err)
+	}
+}
+
+func TestRunLocalEnv(t *testing.T) {
+	manager := UnixCommandManager{Hostname: "localhost"}
+
+	result, err := manager.RunLocal(context.Background(), CommandConfig{
+		Command: "sh",
+		Args:    []string{"-c", `printf '%s' "$GREETING"`},
+		Env:     []string{"GREETING=hello 'world' $HOME"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.STDOUT != "hello 'world' $HOME" {
+		t.Errorf("Expected the variable unchanged, got %q", result.STDOUT)
+	}
+
+	if _, err := manager.RunLocal(context.Background(), CommandConfig{Command: "true", Env: []string{"1BAD=x"}}); err == nil {
+		t.Error("Expected an error for an invalid variable name")
+	}
+}
+
+func TestRunRemoteEnvFallback(t *testing.T) {
+	server := startTestSSHServer(t, "")
+	manager := testJumpManager("remote.example.com")
+	manager.SSHClient = addrDialer{addr: server.addr}
+
+	result, err := manager.RunRemote(context.Background(), CommandConfig{
+		Command: "printenv",
+		Args:    []string{"GREETING"},
+		Env:     []string{"GREETING=hello world"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := "env 'GREETING=hello world' printenv GREETING\n"
+	if result.STDOUT != expected {
+		t.Errorf("Expected %q, got %q", expected, result.STDOUT)
+	}
+}
+
+func TestEnvCommand(t *testing.T) {
+	config := envCommand(CommandConfig{
+		Command: "systemctl",
+		Args:    []string{"restart", "app"},
+		Sudo:    true,
+		Env:     []string{"A=1", "B=two words"},
+	})
+	expected := []string{"A=1", "B=two words", "systemctl", "restart", "app"}
+	if config.Command != "env" || !reflect.DeepEqual(config.Args, expected) || config.Env != nil || !config.Sudo {
+		t.Errorf("Unexpected config: %+v", config)
+	}
+
+	for _, e := range []string{"NOEQUALS", "=x", "A-B=1", "9A=1"} {
+		if err := checkEnv([]string{e}); err == nil {
+			t.Errorf("Expected an error for %q", e)
+		}
+	}
+	if err := checkEnv([]string{"_OK=", "PATH=/bin:/usr/bin"}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestRunLocalDir(t *testing.T) {
+	manager := UnixCommandManager{Hostname: "localhost"}
+	dir := t.TempDir()
+
+	result, err := manager.RunLocal(context.Background(), CommandConfig{Command: "pwd", Dir: dir})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got, _ := filepath.EvalSymlinks(strings.TrimSpace(result.STDOUT)); got != mustEvalSymlinks(t, dir) {
+		t.Errorf("Expected to run in %s, got %q", dir, result.STDOUT)
+	}
+
+	_, err = manager.RunLocal(context.Background(), CommandConfig{Command: "pwd", Dir: filepath.Join(dir, "missing")})
+	if !errors.Is(err, ErrDirNotFound) {
+		t.Errorf("Expected ErrDirNotFound, got %v", err)
+	}
+}
+
+func mustEvalSymlinks(t *testing.T, path string) string {
+	t.Helper()
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		t.Fatalf("Failed to resolve %s: %v", path, err)
+	}
+	return resolved
+}
+
+func TestDirPrefix(t *testing.T) {
+	dir := t.TempDir()
+	for _, tc := range []struct {
+		dir     string
+		wantErr bool
+	}{
+		{dir: dir},
+		{dir: filepath.Join(dir, "it's missing"), wantErr: true},
+	} {
+		cmd := exec.Command("sh", "-c", dirPrefix(tc.dir)+"pwd")
+		var stdout, stderr strings.Builder
+		cmd.Stdout, cmd.Stderr = &stdout, &stderr
+		runErr := cmd.Run()
+		result := CommandResult{STDOUT: stdout.String(), STDERR: stderr.String(), ExitCode: getExitCode(runErr)}
+
+		err := dirError(tc.dir, result)
+		if tc.wantErr {
+			if !errors.Is(err, ErrDirNotFound) {
+				t.Errorf("%s: expected ErrDirNotFound, got %v (%+v)", tc.dir, err, result)
+			}
+			continue
+		}
+		if err != nil || runErr != nil {
+			t.Errorf("%s: unexpected error: %v, %v", tc.dir, err, runErr)
+		}
+		if got, _ := filepath.EvalSymlinks(strings.TrimSpace(result.STDOUT)); got != mustEvalSymlinks(t, dir) {
+			t.Errorf("Expected to run in %s, got %q", dir, result.STDOUT)
+		}
+	}
+}
+
+func TestRunLocalStdin(t *testing.T) {
+	manager := UnixCommandManager{Hostname: "localhost"}
+
+	result, err := manager.RunLocal(context.Background(), CommandConfig{
+		Command: "cat",
+		Stdin:   strings.NewReader("line one\nline two\n"),
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.STDOUT != "line one\nline two\n" {
+		t.Errorf("Expected stdin to be echoed, got %q", result.STDOUT)
+	}
+}
+
+func TestEscalateStdin(t *testing.T) {
+	manager := UnixCommandManager{Hostname: "localhost", Credentials: common.Credentials{SudoPassword: "secret"}}
+	config := CommandConfig{Command: "tee", Args: []string{"/etc/motd"}, Sudo: true}
+
+	args, stdin := manager.escalate(config)
+	data, _ := io.ReadAll(stdin)
+	if !reflect.DeepEqual(args, []string{"sudo", "-S", "-p", sudoPrompt, "--", "tee", "/etc/motd"}) || string(data) != "secret\n" {
+		t.Errorf("Unexpected escalation without stdin: %q, %q", args, data)
+	}
+
+	config.Stdin = strings.NewReader("hello\n")
+	args, stdin = manager.escalate(config)
+	data, _ = io.ReadAll(stdin)
+	if args[0] != "sh" || args[len(args)-2] != "tee" {
+		t.Errorf("Expected the askpass wrapper with command input, got %q", args)
+	}
+	if string(data) != "secret\nhello\n" {
+		t.Errorf("Expected the password line before stdin, got %q", data)
+	}
+
+	// An explicit sudo strategy is kept, e.g. for hosts mounting /tmp noexec
+	manager.Escalation = SudoStrategy{}
+	config.Stdin = strings.NewReader("hello\n")
+	args, stdin = manager.escalate(config)
+	data, _ = io.ReadAll(stdin)
+	if args[0] != "sudo" || string(data) != "secret\nhello\n" {
+		t.Errorf("Expected sudo -S with the password before stdin, got %q, %q", args, data)
+	}
+
+	manager.Escalation = DoasStrategy{}
+	config.Stdin = strings.NewReader("hello\n")
+	_, stdin = manager.escalate(config)
+	data, _ = io.ReadAll(stdin)
+	if string(data) != "hello\n" {
+		t.Errorf("Expected stdin unchanged, got %q", data)
+	}
+}
+
+func TestCommandTimeout(t *testing.T) {
+	manager := UnixCommandManager{Hostname: "localhost", CommandTimeout: 100 * time.Millisecond}
+
+	start := time.Now()
+	_, err := manager.Run(context.Background(), CommandConfig{Command: "sleep", Args: []string{"5"}})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected a deadline error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("Expected the command to be killed early, took %s", elapsed)
+	}
+}
+
+// timeoutDialer records the timeout it is asked to dial with and fails.
+type timeoutDialer struct {
+	timeout *time.Duration
+}
+
+func (d timeoutDialer) Dial(_, _ string, _ *ssh.ClientConfig, timeout time.Duration) (*ssh.Client, error) {
+	*d.timeout = timeout
+	return nil, errors.New("unreachable")
+}
+
+func TestConnectTimeout(t *testing.T) {
+	var timeout time.Duration
+	manager := testJumpManager("remote.example.com")
+	manager.SSHClient = timeoutDialer{timeout: &timeout}
+
+	manager.connect(context.Background())
+	if timeout != DefaultConnectTimeout {
+		t.Errorf("Expected the default timeout, got %s", timeout)
+	}
+
+	manager.ConnectTimeout = 30 * time.Second
+	manager.connect(context.Background())
+	if timeout != 30*time.Second {
+		t.Errorf("Expected the configured timeout, got %s", timeout)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	manager.connect(ctx)
+	if timeout > time.Second {
+		t.Errorf("Expected the context deadline to cap the timeout, got %s", timeout)
+	}
+}
+
+func TestLogger(t *testing.T) {
+	var global strings.Builder
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&global, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	defer slog.SetDefault(previous)
+
+	manager := UnixCommandManager{Hostname: "localhost"}
+	if _, err := manager.Run(context.Background(), CommandConfig{Command: "true"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if global.Len() != 0 {
+		t.Errorf("Expected nothing logged without a Logger, got %q", global.String())
+	}
+
+	var logs strings.Builder
+	manager.Logger = slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	if _, err := manager.Run(context.Background(), CommandConfig{Command: "true"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(logs.String(), "hostname=localhost") {
+		t.Errorf("Expected the command to be logged, got %q", logs.String())
+	}
+}
@@ -0,0 +1,57 @@
+package commandmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrWaitTimeout is returned by WaitFor when the context deadline passes
+// before the condition is met.
+var ErrWaitTimeout = errors.New("timed out waiting for condition")
+
+// DefaultWaitInterval is the polling interval WaitFor uses when none is given.
+const DefaultWaitInterval = 2 * time.Second
+
+// WaitFor runs config on manager until predicate returns true for its result,
+// checking once immediately and then every interval. Connection failures are
+// retried rather than passed to predicate, so it can wait for a host that is
+// still coming up. It returns ErrWaitTimeout once the context deadline passes
+// and the context's error if it is cancelled.
+func WaitFor(ctx context.Context, manager CommandManager, config CommandConfig, predicate func(CommandResult) bool, interval time.Duration) error {
+	if interval <= 0 {
+		interval = DefaultWaitInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var lastErr error
+	for {
+		result, err := manager.Run(ctx, config)
+		switch {
+		case errors.Is(err, ErrReadOnlyMode):
+			return err
+		case err != nil && !IsExitError(err):
+			// The command did not run, e.g. the host is unreachable
+			lastErr = err
+		case predicate(result):
+			return nil
+		default:
+			lastErr = nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return ctx.Err()
+			}
+			if lastErr != nil {
+				return fmt.Errorf("%w: %s: last error: %v", ErrWaitTimeout, config.Command, lastErr)
+			}
+			return fmt.Errorf("%w: %s", ErrWaitTimeout, config.Command)
+		case <-ticker.C:
+		}
+	}
+}
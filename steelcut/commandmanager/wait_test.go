@@ -0,0 +1,87 @@
+package commandmanager
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// sequenceCommandManager returns its results in order, repeating the last one.
+type sequenceCommandManager struct {
+	results []CommandResult
+	errs    []error
+	calls   int
+}
+
+func (s *sequenceCommandManager) RunLocal(ctx context.Context, config CommandConfig) (CommandResult, error) {
+	return s.Run(ctx, config)
+}
+
+func (s *sequenceCommandManager) RunRemote(ctx context.Context, config CommandConfig) (CommandResult, error) {
+	return s.Run(ctx, config)
+}
+
+func (s *sequenceCommandManager) Run(ctx context.Context, config CommandConfig) (CommandResult, error) {
+	i := min(s.calls, len(s.results)-1)
+	s.calls++
+	return s.results[i], s.errs[i]
+}
+
+func TestWaitFor(t *testing.T) {
+	manager := &sequenceCommandManager{
+		results: []CommandResult{{}, {STDOUT: "activating\n"}, {STDOUT: "active\n"}},
+		errs:    []error{errors.New("connection refused"), nil, nil},
+	}
+	active := func(r CommandResult) bool { return r.STDOUT == "active\n" }
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	start := time.Now()
+	err := WaitFor(ctx, manager, CommandConfig{Command: "systemctl", Args: []string{"is-active", "nginx"}}, active, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if manager.calls != 3 {
+		t.Errorf("Expected 3 polls, got %d", manager.calls)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected polling at the interval, took %v", elapsed)
+	}
+
+	// The first check runs without waiting for the interval
+	manager = &sequenceCommandManager{results: []CommandResult{{STDOUT: "active\n"}}, errs: []error{nil}}
+	start = time.Now()
+	if err := WaitFor(ctx, manager, CommandConfig{Command: "systemctl"}, active, time.Hour); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if time.Since(start) > 100*time.Millisecond {
+		t.Errorf("Expected an immediate first check")
+	}
+}
+
+func TestWaitForTimeout(t *testing.T) {
+	manager := &sequenceCommandManager{
+		results: []CommandResult{{STDOUT: "failed\n", ExitCode: 3}},
+		errs:    []error{nil},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := WaitFor(ctx, manager, CommandConfig{Command: "systemctl"}, func(r CommandResult) bool { return r.ExitCode == 0 }, 10*time.Millisecond)
+	if !errors.Is(err, ErrWaitTimeout) {
+		t.Errorf("Expected ErrWaitTimeout, got: %v", err)
+	}
+	if manager.calls < 2 {
+		t.Errorf("Expected repeated polls, got %d", manager.calls)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	err = WaitFor(ctx, manager, CommandConfig{Command: "systemctl"}, func(r CommandResult) bool { return false }, 0)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got: %v", err)
+	}
+}
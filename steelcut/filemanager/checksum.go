@@ -0,0 +1,125 @@
+package filemanager
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// TreeDiff lists the files that differ between a directory tree and its
+// expected checksums, by path relative to the tree's root.
+type TreeDiff struct {
+	Added   []string // present but not expected
+	Removed []string // expected but missing
+	Changed []string // present with a different checksum
+}
+
+// Empty reports whether the tree matched its expected checksums.
+func (d TreeDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// TreeChecksums returns the SHA-256 of every regular file under root, keyed
+// by path relative to root. All files are hashed by a single find command,
+// which passes them to sha256sum in batches.
+func (ufm *UnixFileManager) TreeChecksums(root string) (map[string]string, error) {
+	return ufm.treeChecksums(root, "sha256sum")
+}
+
+// CompareTree compares the files under root with expected, a map as
+// returned by TreeChecksums.
+func (ufm *UnixFileManager) CompareTree(root string, expected map[string]string) (TreeDiff, error) {
+	actual, err := ufm.TreeChecksums(root)
+	if err != nil {
+		return TreeDiff{}, err
+	}
+	return diffTree(actual, expected), nil
+}
+
+// TreeChecksums hashes with shasum, as macOS has no sha256sum.
+func (dfm *DarwinFileManager) TreeChecksums(root string) (map[string]string, error) {
+	return dfm.treeChecksums(root, "shasum", "-a", "256")
+}
+
+// CompareTree compares the files under root with expected.
+func (dfm *DarwinFileManager) CompareTree(root string, expected map[string]string) (TreeDiff, error) {
+	actual, err := dfm.TreeChecksums(root)
+	if err != nil {
+		return TreeDiff{}, err
+	}
+	return diffTree(actual, expected), nil
+}
+
+func (ufm *UnixFileManager) treeChecksums(root string, hashCommand ...string) (map[string]string, error) {
+	if !path.IsAbs(root) {
+		return nil, fmt.Errorf("root must be an absolute path: %q", root)
+	}
+	root = path.Clean(root)
+
+	args := append([]string{root, "-type", "f", "-exec"}, hashCommand...)
+	result, err := ufm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "find",
+		Args:    append(args, "{}", "+"),
+	})
+	if err != nil && !cm.IsExitError(err) {
+		return nil, err
+	}
+	if result.ExitCode != 0 {
+		return nil, fmt.Errorf("failed to checksum %s: %s", root, strings.TrimSpace(result.STDERR))
+	}
+	return parseChecksums(result.STDOUT, root)
+}
+
+// parseChecksums parses sha256sum output into a map keyed by path relative
+// to root. Names containing a newline or backslash are escaped by sha256sum,
+// which marks the line with a leading backslash.
+func parseChecksums(output, root string) (map[string]string, error) {
+	checksums := make(map[string]string)
+	for _, line := range cm.Lines(output) {
+		escaped := strings.HasPrefix(line, `\`)
+		if escaped {
+			line = line[1:]
+		}
+		hash, name, ok := strings.Cut(line, "  ")
+		if !ok || len(hash) != 64 {
+			return nil, fmt.Errorf("unexpected checksum output: %q", line)
+		}
+		if escaped {
+			name = strings.NewReplacer(`\\`, `\`, `\n`, "\n").Replace(name)
+		}
+
+		rel := strings.TrimPrefix(name, root+"/")
+		if root == "/" {
+			rel = strings.TrimPrefix(name, "/")
+		}
+		checksums[rel] = hash
+	}
+	return checksums, nil
+}
+
+// diffTree returns how actual differs from expected, with each list sorted.
+func diffTree(actual, expected map[string]string) TreeDiff {
+	var diff TreeDiff
+	for name, hash := range actual {
+		want, ok := expected[name]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, name)
+		case !strings.EqualFold(hash, want):
+			diff.Changed = append(diff.Changed, name)
+		}
+	}
+	for name := range expected {
+		if _, ok := actual[name]; !ok {
+			diff.Removed = append(diff.Removed, name)
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Changed)
+	return diff
+}
@@ -0,0 +1,94 @@
+package filemanager
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// DarwinFileManager provides macOS variants of UnixFileManager operations.
+type DarwinFileManager struct {
+	UnixFileManager
+}
+
+func (dfm *DarwinFileManager) GetXattr(path, name string) (string, error) {
+	result, err := dfm.runXattr("-p", name, path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(result.STDOUT, "\n"), nil
+}
+
+func (dfm *DarwinFileManager) SetXattr(path, name, value string) error {
+	_, err := dfm.runXattr("-w", name, value, path)
+	return err
+}
+
+func (dfm *DarwinFileManager) ListXattrs(path string) ([]string, error) {
+	result, err := dfm.runXattr(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, line := range strings.Split(strings.TrimSpace(result.STDOUT), "\n") {
+		if line != "" {
+			names = append(names, line)
+		}
+	}
+	return names, nil
+}
+
+// GetSELinuxContext is not supported on macOS.
+func (dfm *DarwinFileManager) GetSELinuxContext(path string) (string, error) {
+	return "", ErrNotSupported
+}
+
+// SetSELinuxContext is not supported on macOS.
+func (dfm *DarwinFileManager) SetSELinuxContext(path, context string) error {
+	return ErrNotSupported
+}
+
+// SetImmutable is not supported on macOS.
+func (dfm *DarwinFileManager) SetImmutable(path string, immutable bool) error {
+	return ErrNotSupported
+}
+
+// IsImmutable is not supported on macOS.
+func (dfm *DarwinFileManager) IsImmutable(path string) (bool, error) {
+	return false, ErrNotSupported
+}
+
+// MakeFilesystem is not supported on macOS, which formats disks with diskutil.
+func (dfm *DarwinFileManager) MakeFilesystem(device, fstype string, opts MkfsOptions) error {
+	return ErrNotSupported
+}
+
+// Mount is not supported on macOS.
+func (dfm *DarwinFileManager) Mount(device, mountpoint, fstype string, options []string) error {
+	return ErrNotSupported
+}
+
+// Unmount is not supported on macOS.
+func (dfm *DarwinFileManager) Unmount(mountpoint string) error {
+	return ErrNotSupported
+}
+
+func (dfm *DarwinFileManager) runXattr(args ...string) (cm.CommandResult, error) {
+	result, err := dfm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "xattr",
+		Args:    args,
+	})
+	if strings.Contains(result.STDERR, "Operation not supported") {
+		return result, ErrNotSupported
+	}
+	if err != nil {
+		return result, err
+	}
+	if result.ExitCode != 0 {
+		return result, errors.New(result.STDERR)
+	}
+	return result, nil
+}
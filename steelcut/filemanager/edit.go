@@ -0,0 +1,96 @@
+package filemanager
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// EditFile applies transform to the contents of path and writes the result
+// back if it differs, reporting whether the file changed. The file is read
+// with ReadFile, or with sudo where the login user may not. The new content is
+// written to a temporary file next to path and renamed over it, so readers
+// never see a partial file. A symlink is followed and its target replaced,
+// keeping the link. The file is written as the login user with writeAtomic
+// where that user may give it the original mode and ownership; otherwise it
+// is streamed to cm.ReplaceFile, which does the same as root. If transform returns an
+// error the file is left untouched.
+func (ufm *UnixFileManager) EditFile(path string, transform func(current []byte) ([]byte, error)) (bool, error) {
+	current, err := ufm.ReadFile(path)
+	if errors.Is(err, ErrNotSupported) || errors.Is(err, os.ErrPermission) {
+		current, err = ufm.readFileSudo(path)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	updated, err := transform(bytes.Clone(current))
+	if err != nil {
+		return false, err
+	}
+	if bytes.Equal(current, updated) {
+		return false, nil
+	}
+
+	if err := ufm.replaceFile(path, updated); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// readFileSudo returns the contents of path read with sudo cat.
+func (ufm *UnixFileManager) readFileSudo(path string) ([]byte, error) {
+	result, err := ufm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "cat",
+		Args:    []string{path},
+		Sudo:    true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.ExitCode != 0 {
+		return nil, fmt.Errorf("failed to read %s: %s", path, strings.TrimSpace(result.STDERR))
+	}
+	return []byte(result.STDOUT), nil
+}
+
+// replaceFile atomically replaces the content of the existing file filePath
+// with data, falling back to cm.ReplaceFile when the file cannot be written
+// through openFS.
+func (ufm *UnixFileManager) replaceFile(filePath string, data []byte) error {
+	err := ufm.replaceFS(filePath, data)
+	if errors.Is(err, ErrNotSupported) || errors.Is(err, os.ErrPermission) {
+		result, err := ufm.CommandManager.Run(context.TODO(), cm.ReplaceFile(filePath, data))
+		return ufm.writeError(result, err, filePath, path.Dir(filePath))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", filePath, err)
+	}
+	return nil
+}
+
+// replaceFS replaces filePath, or the file it links to, with data through
+// openFS, keeping its mode and ownership.
+func (ufm *UnixFileManager) replaceFS(filePath string, data []byte) error {
+	fs, err := ufm.openFS(true)
+	if err != nil {
+		return err
+	}
+	defer fs.Close()
+
+	target, err := resolveSymlinks(fs, filePath)
+	if err != nil {
+		return err
+	}
+	info, err := fs.Lstat(target)
+	if err != nil {
+		return err
+	}
+	return writeAtomic(fs, target, bytes.NewReader(data), info.Mode(), ownerOf(info))
+}
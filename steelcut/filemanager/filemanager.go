@@ -1,19 +1,29 @@
 package filemanager
 
 import (
+	"errors"
 	"os"
 	"time"
 )
 
+// ErrNotSupported is returned when an operation is not available on the host.
+var ErrNotSupported = errors.New("operation not supported on this host")
+
 // DirOperations represents operations that can be performed on directories.
 type DirOperations interface {
 	CreateDirectory(path string) error
-	DeleteDirectory(path string) error
+	DeleteDirectory(path string) error // Recursive
+	DeleteEmptyDirectory(path string) error
 	MoveDirectory(sourcePath, destPath string) error
-	CopyDirectory(sourcePath, destPath string) error
+	CopyDirectory(localPath, remotePath string) error      // Copy a local tree to the host
+	CopyRemoteDirectory(sourcePath, destPath string) error // Copy a tree within the host
 	ListDirectory(path string) ([]string, error)
+	ListTree(root, pattern string) ([]string, error) // Paths relative to root, recursively
 	GetDirAttributes(path string) (Directory, error)
 	DiskUsage(path string) (DiskUsageInfo, error)
+	DiskUsageByMount() ([]MountUsage, error)
+	TreeChecksums(root string) (map[string]string, error)
+	CompareTree(root string, expected map[string]string) (TreeDiff, error)
 }
 
 // FileOperations represents operations that can be performed on files.
@@ -23,12 +33,61 @@ type FileOperations interface {
 	MoveFile(sourcePath, destPath string) error
 	CopyFile(sourcePath, destPath string) error
 	GetFileAttributes(path string) (File, error)
+	EditFile(path string, transform func(current []byte) ([]byte, error)) (bool, error) // Report whether the file changed
+	ReadFile(path string) ([]byte, error)
+	WriteFile(path string, data []byte, mode os.FileMode) error // Atomic, creates parent directories
+	AppendToFile(path string, data []byte) error
+}
+
+// AttributeOperations represents operations on extended file attributes.
+type AttributeOperations interface {
+	GetXattr(path, name string) (string, error)
+	SetXattr(path, name, value string) error
+	ListXattrs(path string) ([]string, error)
+	GetSELinuxContext(path string) (string, error)
+	SetSELinuxContext(path, context string) error
+	SetImmutable(path string, immutable bool) error
+	IsImmutable(path string) (bool, error)
+}
+
+// PermissionOperations represents changes to file modes and ownership.
+type PermissionOperations interface {
+	SetPermissions(path string, mode os.FileMode) error
+	SetPermissionsRecursive(path string, mode os.FileMode) error
+	SetOwner(path, user, group string, recursive bool) error // Empty user or group is left unchanged
+}
+
+// LinkOperations represents operations on symbolic and hard links.
+type LinkOperations interface {
+	CreateSymlink(target, linkPath string) error
+	CreateHardLink(target, linkPath string) error
+	ReadLink(path string) (string, error)
+	RemoveLink(path string) error
+}
+
+// FstabOperations represents operations on the /etc/fstab mount table.
+type FstabOperations interface {
+	ReadFstab() ([]FstabEntry, error)
+	AddFstabEntry(entry FstabEntry) (bool, error) // Report whether fstab changed
+	RemoveFstabEntry(mountpoint string) error
+}
+
+// MountOperations represents formatting and mounting of filesystems.
+type MountOperations interface {
+	MakeFilesystem(device, fstype string, opts MkfsOptions) error
+	Mount(device, mountpoint, fstype string, options []string) error
+	Unmount(mountpoint string) error
 }
 
 // FileManager encompasses operations on both files and directories.
 type FileManager interface {
 	FileOperations
 	DirOperations
+	AttributeOperations
+	PermissionOperations
+	LinkOperations
+	FstabOperations
+	MountOperations
 }
 
 // File describes basic file attributes.
@@ -46,6 +105,16 @@ type DiskUsageInfo struct {
 	UsePercent float64 // usage percentage
 }
 
+// MountUsage is the usage of one mounted filesystem.
+type MountUsage struct {
+	Filesystem     string
+	MountPoint     string
+	TotalBytes     int64
+	UsedBytes      int64
+	AvailableBytes int64   // space available to unprivileged users
+	UsePercent     float64 // as df reports it, counting reserved blocks as used
+}
+
 // Directory describes basic directory attributes.
 type Directory struct {
 	Path     string
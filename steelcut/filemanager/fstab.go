@@ -0,0 +1,186 @@
+package filemanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+const (
+	fstabPath       = "/etc/fstab"
+	fstabBackupPath = "/etc/fstab.bak"
+)
+
+// FstabEntry is a single mount line of /etc/fstab. Device is kept exactly as
+// written, so UUID= and LABEL= specs round-trip unchanged.
+type FstabEntry struct {
+	Device     string
+	Mountpoint string
+	FSType     string
+	Options    string
+	Dump       int
+	Pass       int
+}
+
+// String formats the entry as a tab-separated fstab line.
+func (e FstabEntry) String() string {
+	options := e.Options
+	if options == "" {
+		options = "defaults"
+	}
+	return fmt.Sprintf("%s\t%s\t%s\t%s\t%d\t%d", e.Device, e.Mountpoint, e.FSType, options, e.Dump, e.Pass)
+}
+
+// ReadFstab parses the entries of /etc/fstab.
+func (ufm *UnixFileManager) ReadFstab() ([]FstabEntry, error) {
+	content, err := ufm.readFstab()
+	if err != nil {
+		return nil, err
+	}
+	return parseFstab(content)
+}
+
+// AddFstabEntry appends entry to /etc/fstab unless its mountpoint is already
+// present. Adding an identical entry again reports changed as false; a
+// different entry for the same mountpoint is an error.
+func (ufm *UnixFileManager) AddFstabEntry(entry FstabEntry) (bool, error) {
+	if entry.Device == "" || entry.Mountpoint == "" || entry.FSType == "" {
+		return false, errors.New("fstab entry requires a device, mountpoint and filesystem type")
+	}
+
+	content, err := ufm.readFstab()
+	if err != nil {
+		return false, err
+	}
+	entries, err := parseFstab(content)
+	if err != nil {
+		return false, err
+	}
+
+	for _, existing := range entries {
+		if existing.Mountpoint != entry.Mountpoint {
+			continue
+		}
+		if existing.String() == entry.String() {
+			return false, nil
+		}
+		return false, fmt.Errorf("fstab already has an entry for %s: %s", entry.Mountpoint, existing.Device)
+	}
+
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	return true, ufm.writeFstab(content + entry.String() + "\n")
+}
+
+// RemoveFstabEntry removes the entries for mountpoint from /etc/fstab. The
+// file is left untouched when there is nothing to remove.
+func (ufm *UnixFileManager) RemoveFstabEntry(mountpoint string) error {
+	content, err := ufm.readFstab()
+	if err != nil {
+		return err
+	}
+
+	lines := strings.SplitAfter(content, "\n")
+	kept := make([]string, 0, len(lines))
+	for _, line := range lines {
+		entry, ok, err := parseFstabLine(line)
+		if err != nil {
+			return err
+		}
+		if ok && entry.Mountpoint == mountpoint {
+			continue
+		}
+		kept = append(kept, line)
+	}
+
+	if len(kept) == len(lines) {
+		return nil
+	}
+	return ufm.writeFstab(strings.Join(kept, ""))
+}
+
+func (ufm *UnixFileManager) readFstab() (string, error) {
+	result, err := ufm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "cat",
+		Args:    []string{fstabPath},
+	})
+	if err != nil {
+		return "", err
+	}
+	if result.ExitCode != 0 {
+		return "", fmt.Errorf("failed to read %s: %s", fstabPath, result.STDERR)
+	}
+	return result.STDOUT, nil
+}
+
+// writeFstab backs up the current fstab and replaces it atomically with
+// cm.ReplaceFile, which streams content over stdin to a temporary file that
+// is renamed over it.
+func (ufm *UnixFileManager) writeFstab(content string) error {
+	result, err := ufm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "cp",
+		Args:    []string{"-p", fstabPath, fstabBackupPath},
+		Sudo:    true,
+	})
+	if err := ufm.writeError(result, err, fstabBackupPath); err != nil {
+		return err
+	}
+
+	result, err = ufm.CommandManager.Run(context.TODO(), cm.ReplaceFile(fstabPath, []byte(content)))
+	return ufm.writeError(result, err, fstabPath)
+}
+
+// parseFstab parses fstab content, skipping comments and blank lines.
+func parseFstab(content string) ([]FstabEntry, error) {
+	var entries []FstabEntry
+	for _, line := range strings.Split(content, "\n") {
+		entry, ok, err := parseFstabLine(line)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// parseFstabLine parses one fstab line. ok is false for comments and blank
+// lines. The dump and pass fields are optional and default to zero.
+func parseFstabLine(line string) (FstabEntry, bool, error) {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return FstabEntry{}, false, nil
+	}
+
+	fields := strings.Fields(trimmed)
+	if len(fields) < 4 || len(fields) > 6 {
+		return FstabEntry{}, false, fmt.Errorf("malformed fstab line: %q", trimmed)
+	}
+
+	entry := FstabEntry{
+		Device:     fields[0],
+		Mountpoint: fields[1],
+		FSType:     fields[2],
+		Options:    fields[3],
+	}
+
+	var err error
+	if len(fields) > 4 {
+		if entry.Dump, err = strconv.Atoi(fields[4]); err != nil {
+			return FstabEntry{}, false, fmt.Errorf("invalid dump field in fstab line %q: %v", trimmed, err)
+		}
+	}
+	if len(fields) > 5 {
+		if entry.Pass, err = strconv.Atoi(fields[5]); err != nil {
+			return FstabEntry{}, false, fmt.Errorf("invalid pass field in fstab line %q: %v", trimmed, err)
+		}
+	}
+
+	return entry, true, nil
+}
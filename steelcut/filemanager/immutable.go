@@ -0,0 +1,98 @@
+package filemanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// ErrFileImmutable is returned when a write fails because the target has the
+// immutable attribute set.
+var ErrFileImmutable = errors.New("file is immutable")
+
+// SetImmutable sets or clears the immutable attribute on path.
+func (ufm *UnixFileManager) SetImmutable(path string, immutable bool) error {
+	flag := "-i"
+	if immutable {
+		flag = "+i"
+	}
+
+	result, err := ufm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "chattr",
+		Args:    []string{flag, path},
+		Sudo:    true,
+	})
+	return chattrError(result, err)
+}
+
+// IsImmutable reports whether path has the immutable attribute set.
+func (ufm *UnixFileManager) IsImmutable(path string) (bool, error) {
+	result, err := ufm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "lsattr",
+		Args:    []string{"-d", path},
+	})
+	if err := chattrError(result, err); err != nil {
+		return false, err
+	}
+	return parseLsattr(result.STDOUT)
+}
+
+// chattrError maps chattr and lsattr failures on filesystems without
+// attribute support to ErrNotSupported.
+func chattrError(result cm.CommandResult, err error) error {
+	if strings.Contains(result.STDERR, "Inappropriate ioctl") ||
+		strings.Contains(result.STDERR, "Operation not supported") ||
+		strings.Contains(result.STDERR, "not found") {
+		return ErrNotSupported
+	}
+	if err != nil {
+		return err
+	}
+	if result.ExitCode != 0 {
+		return errors.New(result.STDERR)
+	}
+	return nil
+}
+
+// parseLsattr reports whether the flags in "lsattr -d" output, e.g.
+// "----i---------e------- /etc/resolv.conf", include the immutable flag.
+func parseLsattr(output string) (bool, error) {
+	fields := strings.Fields(output)
+	if len(fields) < 2 {
+		return false, fmt.Errorf("unexpected lsattr output format: %s", output)
+	}
+	return strings.Contains(fields[0], "i"), nil
+}
+
+// writeError converts the result of a command that writes to paths into an
+// error. A permission failure on an immutable path becomes ErrFileImmutable
+// rather than a generic "Operation not permitted".
+func (ufm *UnixFileManager) writeError(result cm.CommandResult, err error, paths ...string) error {
+	if err == nil && result.ExitCode == 0 {
+		return nil
+	}
+
+	if strings.Contains(result.STDERR, "Operation not permitted") {
+		if err := ufm.immutableError(paths...); err != nil {
+			return err
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return errors.New(result.STDERR)
+}
+
+// immutableError returns ErrFileImmutable for the first of paths that has the
+// immutable attribute set, and nil if none has.
+func (ufm *UnixFileManager) immutableError(paths ...string) error {
+	for _, path := range paths {
+		if immutable, _ := ufm.IsImmutable(path); immutable {
+			return fmt.Errorf("%w: %s", ErrFileImmutable, path)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package filemanager
+
+import (
+	"fmt"
+	"os"
+	"path"
+)
+
+// linkFS is the filesystem access needed for link operations. It is satisfied
+// by an SFTP session for remote hosts and by localFS for the local machine.
+type linkFS interface {
+	Symlink(target, linkPath string) error
+	Link(target, linkPath string) error
+	ReadLink(path string) (string, error)
+	Lstat(path string) (os.FileInfo, error)
+	Remove(path string) error
+	Close() error
+}
+
+type localFS struct{}
+
+func (localFS) Symlink(target, linkPath string) error  { return os.Symlink(target, linkPath) }
+func (localFS) Link(target, linkPath string) error     { return os.Link(target, linkPath) }
+func (localFS) ReadLink(path string) (string, error)   { return os.Readlink(path) }
+func (localFS) Lstat(path string) (os.FileInfo, error) { return os.Lstat(path) }
+func (localFS) Remove(path string) error               { return os.Remove(path) }
+func (localFS) Close() error                           { return nil }
+
+// CreateSymlink creates linkPath pointing at target. It does nothing if
+// linkPath is already a symlink to target, and fails with os.ErrExist if
+// something else is in the way.
+func (ufm *UnixFileManager) CreateSymlink(target, linkPath string) error {
+	fs, err := ufm.openFS(true)
+	if err != nil {
+		return err
+	}
+	defer fs.Close()
+
+	if info, err := fs.Lstat(linkPath); err == nil {
+		if info.Mode()&os.ModeSymlink != 0 {
+			current, err := fs.ReadLink(linkPath)
+			if err != nil {
+				return err
+			}
+			if current == target {
+				return nil
+			}
+			return fmt.Errorf("%w: %s is a symlink to %s, not %s", os.ErrExist, linkPath, current, target)
+		}
+		return fmt.Errorf("%w: %s is in the way of a symlink to %s", os.ErrExist, linkPath, target)
+	}
+
+	return fs.Symlink(target, linkPath)
+}
+
+// CreateHardLink creates linkPath as a hard link to target.
+func (ufm *UnixFileManager) CreateHardLink(target, linkPath string) error {
+	fs, err := ufm.openFS(true)
+	if err != nil {
+		return err
+	}
+	defer fs.Close()
+
+	return fs.Link(target, linkPath)
+}
+
+// ReadLink returns the target of the symlink at path.
+func (ufm *UnixFileManager) ReadLink(path string) (string, error) {
+	fs, err := ufm.openFS(false)
+	if err != nil {
+		return "", err
+	}
+	defer fs.Close()
+
+	return fs.ReadLink(path)
+}
+
+// RemoveLink removes a symlink or hard link. Directories are refused so a
+// mistaken path cannot remove one.
+func (ufm *UnixFileManager) RemoveLink(path string) error {
+	fs, err := ufm.openFS(true)
+	if err != nil {
+		return err
+	}
+	defer fs.Close()
+
+	info, err := fs.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s is a directory, not a link", path)
+	}
+	return fs.Remove(path)
+}
+
+// maxSymlinks bounds how many links resolveSymlinks follows, as the kernel
+// does, so that a link loop fails instead of running forever.
+const maxSymlinks = 40
+
+// resolveSymlinks follows filePath while it is a symlink and returns the path
+// of the file it ends at. A path that is not a symlink is returned unchanged.
+func resolveSymlinks(fs linkFS, filePath string) (string, error) {
+	for i := 0; i < maxSymlinks; i++ {
+		info, err := fs.Lstat(filePath)
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			return filePath, nil
+		}
+		target, err := fs.ReadLink(filePath)
+		if err != nil {
+			return "", err
+		}
+		if !path.IsAbs(target) {
+			target = path.Join(path.Dir(filePath), target)
+		}
+		filePath = target
+	}
+	return "", fmt.Errorf("too many levels of symbolic links: %s", filePath)
+}
@@ -0,0 +1,214 @@
+package filemanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// ErrFilesystemExists is returned by MakeFilesystem when the device already
+// holds a filesystem and Force is not set.
+var ErrFilesystemExists = errors.New("device already has a filesystem")
+
+var fsTypeName = regexp.MustCompile(`^[a-z0-9]+$`)
+
+// MkfsOptions controls MakeFilesystem.
+type MkfsOptions struct {
+	Label string
+	Force bool     // Overwrite an existing filesystem
+	Args  []string // Extra arguments passed to mkfs before the device
+}
+
+// MakeFilesystem creates an fstype filesystem on device with mkfs.<fstype>.
+// It refuses to overwrite an existing filesystem unless opts.Force is set.
+func (ufm *UnixFileManager) MakeFilesystem(device, fstype string, opts MkfsOptions) error {
+	if !path.IsAbs(device) {
+		return fmt.Errorf("device must be an absolute path: %q", device)
+	}
+	if !fsTypeName.MatchString(fstype) {
+		return fmt.Errorf("invalid filesystem type: %q", fstype)
+	}
+
+	existing, err := ufm.filesystemType(device)
+	if err != nil {
+		return err
+	}
+	if existing != "" && !opts.Force {
+		return fmt.Errorf("%w: %s has %s", ErrFilesystemExists, device, existing)
+	}
+
+	result, err := ufm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "mkfs." + fstype,
+		Args:    mkfsArgs(device, fstype, opts, existing != ""),
+		Sudo:    true,
+	})
+	if err != nil && !cm.IsExitError(err) {
+		return err
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("mkfs.%s failed on %s: %s", fstype, device, strings.TrimSpace(result.STDERR))
+	}
+	return nil
+}
+
+// mkfsArgs builds the mkfs arguments. The label and force flags differ
+// between filesystems; force is only passed when overwriting, as mkfs.ext4
+// otherwise also skips its other safety checks.
+func mkfsArgs(device, fstype string, opts MkfsOptions, overwrite bool) []string {
+	labelFlag, forceFlag := "-L", "-f"
+	switch {
+	case strings.HasPrefix(fstype, "ext"):
+		forceFlag = "-F"
+	case fstype == "vfat" || fstype == "fat" || fstype == "msdos":
+		labelFlag, forceFlag = "-n", ""
+	}
+
+	var args []string
+	if opts.Force && overwrite && forceFlag != "" {
+		args = append(args, forceFlag)
+	}
+	if opts.Label != "" {
+		args = append(args, labelFlag, opts.Label)
+	}
+	args = append(args, opts.Args...)
+	return append(args, device)
+}
+
+// filesystemType returns the filesystem blkid finds on device, or "" if
+// there is none. blkid exits 2 when it finds nothing.
+func (ufm *UnixFileManager) filesystemType(device string) (string, error) {
+	result, err := ufm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "blkid",
+		Args:    []string{"-o", "value", "-s", "TYPE", device},
+		Sudo:    true,
+	})
+	if err != nil && !cm.IsExitError(err) {
+		return "", err
+	}
+	switch result.ExitCode {
+	case 0:
+		return strings.TrimSpace(result.STDOUT), nil
+	case 2:
+		return "", nil
+	}
+	return "", fmt.Errorf("blkid failed on %s: %s", device, strings.TrimSpace(result.STDERR))
+}
+
+// Mount mounts device at mountpoint, creating the mountpoint if needed. An
+// empty fstype lets mount detect it. If device is already mounted there
+// nothing is done; a different device mounted there is an error.
+func (ufm *UnixFileManager) Mount(device, mountpoint, fstype string, options []string) error {
+	if !path.IsAbs(mountpoint) {
+		return fmt.Errorf("mountpoint must be an absolute path: %q", mountpoint)
+	}
+
+	source, err := ufm.mountedSource(mountpoint)
+	if err != nil {
+		return err
+	}
+	if source != "" {
+		resolved, err := ufm.resolveDevice(device)
+		if err != nil {
+			return err
+		}
+		if source == resolved {
+			return nil
+		}
+		return fmt.Errorf("%s is already mounted at %s", source, mountpoint)
+	}
+
+	if err := ufm.runMountCommand(cm.CommandConfig{
+		Command: "mkdir",
+		Args:    []string{"-p", mountpoint},
+		Sudo:    true,
+	}); err != nil {
+		return err
+	}
+
+	var args []string
+	if fstype != "" {
+		args = append(args, "-t", fstype)
+	}
+	if len(options) > 0 {
+		args = append(args, "-o", strings.Join(options, ","))
+	}
+	return ufm.runMountCommand(cm.CommandConfig{
+		Command: "mount",
+		Args:    append(args, device, mountpoint),
+		Sudo:    true,
+	})
+}
+
+// Unmount unmounts the filesystem at mountpoint. Unmounting a mountpoint
+// with nothing mounted is not an error.
+func (ufm *UnixFileManager) Unmount(mountpoint string) error {
+	source, err := ufm.mountedSource(mountpoint)
+	if err != nil {
+		return err
+	}
+	if source == "" {
+		return nil
+	}
+	return ufm.runMountCommand(cm.CommandConfig{
+		Command: "umount",
+		Args:    []string{mountpoint},
+		Sudo:    true,
+	})
+}
+
+// mountedSource returns the device mounted at mountpoint, or "" if nothing
+// is. findmnt exits 1 when the mountpoint is not mounted.
+func (ufm *UnixFileManager) mountedSource(mountpoint string) (string, error) {
+	result, err := ufm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "findmnt",
+		Args:    []string{"-n", "-o", "SOURCE", "--mountpoint", mountpoint},
+	})
+	if err != nil && !cm.IsExitError(err) {
+		return "", err
+	}
+	if result.ExitCode != 0 {
+		return "", nil
+	}
+	// Only the last line matters when filesystems are stacked
+	lines := cm.Lines(result.STDOUT)
+	if len(lines) == 0 {
+		return "", nil
+	}
+	return strings.TrimSpace(lines[len(lines)-1]), nil
+}
+
+// resolveDevice turns a UUID=, LABEL= or similar spec into a device path as
+// findmnt reports it. Paths are returned unchanged.
+func (ufm *UnixFileManager) resolveDevice(device string) (string, error) {
+	if !strings.Contains(device, "=") {
+		return device, nil
+	}
+	result, err := ufm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "findfs",
+		Args:    []string{device},
+		Sudo:    true,
+	})
+	if err != nil && !cm.IsExitError(err) {
+		return "", err
+	}
+	if result.ExitCode != 0 {
+		return "", fmt.Errorf("unable to resolve %s: %s", device, strings.TrimSpace(result.STDERR))
+	}
+	return strings.TrimSpace(result.STDOUT), nil
+}
+
+func (ufm *UnixFileManager) runMountCommand(config cm.CommandConfig) error {
+	result, err := ufm.CommandManager.Run(context.TODO(), config)
+	if err != nil && !cm.IsExitError(err) {
+		return err
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("%s failed: %s", config.Command, strings.TrimSpace(result.STDERR))
+	}
+	return nil
+}
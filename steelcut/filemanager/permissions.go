@@ -0,0 +1,74 @@
+package filemanager
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// SetPermissions sets the mode of path with chmod, run with sudo.
+func (ufm *UnixFileManager) SetPermissions(path string, mode os.FileMode) error {
+	return ufm.chmod(path, mode, false)
+}
+
+// SetPermissionsRecursive sets the mode of path and of everything under it,
+// files and directories alike. Symlinks inside path are not followed.
+func (ufm *UnixFileManager) SetPermissionsRecursive(path string, mode os.FileMode) error {
+	return ufm.chmod(path, mode, true)
+}
+
+func (ufm *UnixFileManager) chmod(path string, mode os.FileMode, recursive bool) error {
+	args := []string{"--", chmodMode(mode), path}
+	if recursive {
+		args = append([]string{"-R"}, args...)
+	}
+	result, err := ufm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "chmod",
+		Args:    args,
+		Sudo:    true,
+	})
+	return ufm.writeError(result, err, path)
+}
+
+// chmodMode formats mode as an octal chmod argument, including the setuid,
+// setgid and sticky bits that os.FileMode keeps outside its permission bits.
+func chmodMode(mode os.FileMode) string {
+	bits := uint32(mode.Perm())
+	if mode&os.ModeSetuid != 0 {
+		bits |= 04000
+	}
+	if mode&os.ModeSetgid != 0 {
+		bits |= 02000
+	}
+	if mode&os.ModeSticky != 0 {
+		bits |= 01000
+	}
+	return "0" + strconv.FormatUint(uint64(bits), 8)
+}
+
+// SetOwner changes the owner and group of path with chown, run with sudo.
+// Either user or group may be empty to leave it unchanged. If recursive is
+// set, everything under path is changed too, without following symlinks.
+func (ufm *UnixFileManager) SetOwner(path, user, group string, recursive bool) error {
+	if user == "" && group == "" {
+		return errors.New("user or group is required")
+	}
+	owner := user
+	if group != "" {
+		owner += ":" + group
+	}
+
+	args := []string{"--", owner, path}
+	if recursive {
+		args = append([]string{"-R"}, args...)
+	}
+	result, err := ufm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "chown",
+		Args:    args,
+		Sudo:    true,
+	})
+	return ufm.writeError(result, err, path)
+}
@@ -0,0 +1,245 @@
+package filemanager
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"syscall"
+
+	"github.com/pkg/sftp"
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// fileFS is the filesystem access needed to read and write whole files.
+type fileFS interface {
+	Open(path string) (io.ReadCloser, error)
+	CreateExclusive(path string) (io.WriteCloser, error)
+	OpenAppend(path string) (io.WriteCloser, error) // Create path if it doesn't exist
+	MkdirAll(path string) error
+	Chmod(path string, mode os.FileMode) error
+	Chown(path string, uid, gid int) error
+	Rename(oldpath, newpath string) error // Replace newpath if it exists
+	Remove(path string) error
+	Close() error
+}
+
+// hostFS is the filesystem of a host, as returned by openFS.
+type hostFS interface {
+	linkFS
+	fileFS
+}
+
+func (localFS) Open(path string) (io.ReadCloser, error)   { return os.Open(path) }
+func (localFS) MkdirAll(path string) error                { return os.MkdirAll(path, 0755) }
+func (localFS) Chmod(path string, mode os.FileMode) error { return os.Chmod(path, mode) }
+func (localFS) Chown(path string, uid, gid int) error     { return os.Chown(path, uid, gid) }
+func (localFS) Rename(oldpath, newpath string) error      { return os.Rename(oldpath, newpath) }
+func (localFS) CreateExclusive(path string) (io.WriteCloser, error) {
+	return os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+}
+func (localFS) OpenAppend(path string) (io.WriteCloser, error) {
+	return os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+}
+
+// sftpFS adapts an SFTP session to hostFS.
+type sftpFS struct {
+	*cm.SFTPClient
+}
+
+func (fs sftpFS) Open(path string) (io.ReadCloser, error) { return fs.SFTPClient.Open(path) }
+func (fs sftpFS) CreateExclusive(path string) (io.WriteCloser, error) {
+	return fs.SFTPClient.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL)
+}
+func (fs sftpFS) OpenAppend(path string) (io.WriteCloser, error) {
+	return fs.SFTPClient.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND)
+}
+
+// Rename uses the posix-rename extension, as a plain SFTP rename fails when
+// newpath exists.
+func (fs sftpFS) Rename(oldpath, newpath string) error {
+	return fs.SFTPClient.PosixRename(oldpath, newpath)
+}
+
+// ReadFile returns the contents of path. It is read over SFTP on remote
+// hosts, so binary content comes back unchanged, as the login user rather
+// than with sudo.
+func (ufm *UnixFileManager) ReadFile(path string) ([]byte, error) {
+	fs, err := ufm.openFS(false)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.Close()
+
+	file, err := fs.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return io.ReadAll(file)
+}
+
+// WriteFile replaces the contents of path with data and sets its mode,
+// creating path and its parent directories if needed. The data is written to
+// a temporary file next to path and renamed over it, so readers see either
+// the old or the new content, never a partial write. Like ReadFile it works
+// over SFTP as the login user.
+func (ufm *UnixFileManager) WriteFile(filePath string, data []byte, mode os.FileMode) error {
+	fs, err := ufm.openFS(true)
+	if err != nil {
+		return err
+	}
+	defer fs.Close()
+
+	if err := fs.MkdirAll(path.Dir(filePath)); err != nil {
+		return fmt.Errorf("failed to create parent of %s: %w", filePath, err)
+	}
+
+	if err := writeAtomic(fs, filePath, bytes.NewReader(data), mode, nil); err != nil {
+		return ufm.atomicWriteError(filePath, err)
+	}
+	return nil
+}
+
+// atomicWriteError describes a failed writeAtomic to filePath. A permission
+// failure because filePath or its directory is immutable becomes
+// ErrFileImmutable, as writeError does for commands.
+func (ufm *UnixFileManager) atomicWriteError(filePath string, err error) error {
+	if errors.Is(err, os.ErrPermission) {
+		if err := ufm.immutableError(filePath, path.Dir(filePath)); err != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("failed to write %s: %w", filePath, err)
+}
+
+// fileOwner is the numeric owner and group of a file.
+type fileOwner struct {
+	uid, gid int
+}
+
+// ownerOf returns the owner of the file described by info, or nil if info
+// doesn't carry one.
+func ownerOf(info os.FileInfo) *fileOwner {
+	switch sys := info.Sys().(type) {
+	case *syscall.Stat_t:
+		return &fileOwner{uid: int(sys.Uid), gid: int(sys.Gid)}
+	case *sftp.FileStat:
+		return &fileOwner{uid: int(sys.UID), gid: int(sys.GID)}
+	}
+	return nil
+}
+
+// writeAtomic writes the content of r to a temporary file next to filePath,
+// gives it mode, and owner unless that is nil, and renames it over filePath.
+func writeAtomic(fs fileFS, filePath string, r io.Reader, mode os.FileMode, owner *fileOwner) error {
+	tmp, err := tempPath(filePath)
+	if err != nil {
+		return err
+	}
+	file, err := fs.CreateExclusive(tmp)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(file, r)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && owner != nil {
+		err = fs.Chown(tmp, owner.uid, owner.gid)
+	}
+	if err == nil {
+		err = fs.Chmod(tmp, mode.Perm())
+	}
+	if err == nil {
+		err = fs.Rename(tmp, filePath)
+	}
+	if err != nil {
+		fs.Remove(tmp)
+	}
+	return err
+}
+
+// AppendToFile adds data to the end of path, creating it if it doesn't exist.
+// The file is opened for appending over SFTP, or locally, which keeps its
+// mode and ownership. When that is not possible or not permitted, as with
+// root-owned files such as /etc/hosts, data is appended with sudo tee -a.
+func (ufm *UnixFileManager) AppendToFile(path string, data []byte) error {
+	err := ufm.appendFS(path, data)
+	if errors.Is(err, ErrNotSupported) || errors.Is(err, os.ErrPermission) {
+		result, err := ufm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+			Command: "tee",
+			Args:    []string{"-a", path},
+			Sudo:    true,
+			Stdin:   bytes.NewReader(data),
+		})
+		return ufm.writeError(result, err, path)
+	}
+	return err
+}
+
+// appendFS appends data to path through openFS. Only errors opening the file
+// are returned for AppendToFile to match, so that a failed write is not
+// repeated with sudo on top of what was already appended.
+func (ufm *UnixFileManager) appendFS(path string, data []byte) error {
+	fs, err := ufm.openFS(true)
+	if err != nil {
+		return err
+	}
+	defer fs.Close()
+
+	file, err := fs.OpenAppend(path)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to append to %s: %w", path, err)
+	}
+	return nil
+}
+
+// tempPath returns a name for a temporary file next to filePath that no
+// other write will pick.
+func tempPath(filePath string) (string, error) {
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s.steelcut-%s.tmp", filePath, hex.EncodeToString(suffix)), nil
+}
+
+// openFS returns the local filesystem for local hosts and an SFTP session
+// otherwise. write reports whether the caller will modify the filesystem, which
+// read-only mode forbids.
+func (ufm *UnixFileManager) openFS(write bool) (hostFS, error) {
+	if local, ok := ufm.CommandManager.(*cm.UnixCommandManager); ok && local.IsLocal() {
+		if write && local.ReadOnly {
+			return nil, cm.ErrReadOnlyMode
+		}
+		return localFS{}, nil
+	}
+
+	provider, ok := ufm.CommandManager.(cm.SFTPProvider)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	client, err := provider.OpenSFTP(context.TODO())
+	if err != nil {
+		return nil, err
+	}
+	if write && client.ReadOnly {
+		client.Close()
+		return nil, cm.ErrReadOnlyMode
+	}
+	return sftpFS{client}, nil
+}
@@ -0,0 +1,150 @@
+package filemanager
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// CopyDirectory copies the local directory tree at localPath to remotePath on
+// the host, keeping its structure and modes. Files are written as by
+// WriteFile. Symlinks are recreated with the same target rather than
+// followed, so links that point back up the tree cannot loop.
+func (ufm *UnixFileManager) CopyDirectory(localPath, remotePath string) error {
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", localPath)
+	}
+
+	fs, err := ufm.openFS(true)
+	if err != nil {
+		return err
+	}
+	defer fs.Close()
+
+	// Directory modes are applied last, so that a read-only directory can
+	// still be filled
+	dirModes := make(map[string]os.FileMode)
+	var dirs []string
+
+	err = filepath.WalkDir(localPath, func(src string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(localPath, src)
+		if err != nil {
+			return err
+		}
+		dst := path.Join(remotePath, filepath.ToSlash(rel))
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		switch mode := info.Mode(); {
+		case mode.IsDir():
+			if err := fs.MkdirAll(dst); err != nil {
+				return fmt.Errorf("failed to create %s: %w", dst, err)
+			}
+			dirs = append(dirs, dst)
+			dirModes[dst] = mode.Perm()
+		case mode&os.ModeSymlink != 0:
+			return uploadSymlink(fs, src, dst)
+		case mode.IsRegular():
+			file, err := os.Open(src)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			if err := writeAtomic(fs, dst, file, mode, nil); err != nil {
+				return ufm.atomicWriteError(dst, err)
+			}
+		default:
+			return fmt.Errorf("cannot upload %s: unsupported file type %v", src, mode.Type())
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := fs.Chmod(dirs[i], dirModes[dirs[i]]); err != nil {
+			return fmt.Errorf("failed to set mode of %s: %w", dirs[i], err)
+		}
+	}
+	return nil
+}
+
+// uploadSymlink recreates the local symlink src at dst, replacing a symlink
+// already there.
+func uploadSymlink(fs hostFS, src, dst string) error {
+	target, err := os.Readlink(src)
+	if err != nil {
+		return err
+	}
+	if info, err := fs.Lstat(dst); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		if err := fs.Remove(dst); err != nil {
+			return err
+		}
+	}
+	if err := fs.Symlink(target, dst); err != nil {
+		return fmt.Errorf("failed to create symlink %s: %w", dst, err)
+	}
+	return nil
+}
+
+// ListTree returns the paths of everything under root, relative to root and
+// sorted. If pattern is not empty, only entries whose name matches it, as with
+// path.Match, are returned. Symlinks are listed but not followed.
+func (ufm *UnixFileManager) ListTree(root, pattern string) ([]string, error) {
+	if !path.IsAbs(root) {
+		return nil, fmt.Errorf("root must be an absolute path: %q", root)
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	root = path.Clean(root)
+
+	result, err := ufm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "find",
+		Args:    []string{root, "-mindepth", "1", "-print0"},
+	})
+	if err != nil && !cm.IsExitError(err) {
+		return nil, err
+	}
+	if result.ExitCode != 0 {
+		return nil, fmt.Errorf("failed to list %s: %s", root, strings.TrimSpace(result.STDERR))
+	}
+	return parseTree(result.STDOUT, root, pattern), nil
+}
+
+// parseTree turns NUL-separated find output into sorted paths relative to
+// root, keeping those whose name matches pattern.
+func parseTree(output, root, pattern string) []string {
+	prefix := strings.TrimSuffix(root, "/") + "/"
+	var paths []string
+	for _, name := range strings.Split(output, "\x00") {
+		rel := strings.TrimPrefix(name, prefix)
+		if rel == "" || rel == name {
+			continue
+		}
+		if pattern != "" {
+			if ok, _ := path.Match(pattern, path.Base(rel)); !ok {
+				continue
+			}
+		}
+		paths = append(paths, rel)
+	}
+	sort.Strings(paths)
+	return paths
+}
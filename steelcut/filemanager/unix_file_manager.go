@@ -2,7 +2,6 @@ package filemanager
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"os"
 	"strconv"
@@ -25,13 +24,26 @@ func (ufm *UnixFileManager) CreateDirectory(path string) error {
 	return err
 }
 
+// DeleteDirectory removes the directory at path and everything in it.
+// Symlinks inside path are removed rather than what they point to.
 func (ufm *UnixFileManager) DeleteDirectory(path string) error {
 	config := cm.CommandConfig{
 		Command: "rm",
 		Args:    []string{"-r", path},
 	}
-	_, err := ufm.CommandManager.Run(context.TODO(), config)
-	return err
+	result, err := ufm.CommandManager.Run(context.TODO(), config)
+	return ufm.writeError(result, err, path)
+}
+
+// DeleteEmptyDirectory removes the directory at path, failing if it is not
+// empty.
+func (ufm *UnixFileManager) DeleteEmptyDirectory(path string) error {
+	config := cm.CommandConfig{
+		Command: "rmdir",
+		Args:    []string{path},
+	}
+	result, err := ufm.CommandManager.Run(context.TODO(), config)
+	return ufm.writeError(result, err, path)
 }
 
 func (ufm *UnixFileManager) MoveDirectory(sourcePath, destPath string) error {
@@ -43,7 +55,9 @@ func (ufm *UnixFileManager) MoveDirectory(sourcePath, destPath string) error {
 	return err
 }
 
-func (ufm *UnixFileManager) CopyDirectory(sourcePath, destPath string) error {
+// CopyRemoteDirectory copies the tree at sourcePath to destPath, both on the
+// host, with cp -r.
+func (ufm *UnixFileManager) CopyRemoteDirectory(sourcePath, destPath string) error {
 	config := cm.CommandConfig{
 		Command: "cp",
 		Args:    []string{"-r", sourcePath, destPath},
@@ -112,13 +126,7 @@ func (ufm *UnixFileManager) CreateFile(path string) error {
 		Command: "touch",
 		Args:    []string{path},
 	})
-	if err != nil {
-		return err
-	}
-	if result.ExitCode != 0 {
-		return errors.New(result.STDERR)
-	}
-	return nil
+	return ufm.writeError(result, err, path)
 }
 
 func (ufm *UnixFileManager) DeleteFile(path string) error {
@@ -126,13 +134,7 @@ func (ufm *UnixFileManager) DeleteFile(path string) error {
 		Command: "rm",
 		Args:    []string{path},
 	})
-	if err != nil {
-		return err
-	}
-	if result.ExitCode != 0 {
-		return errors.New(result.STDERR)
-	}
-	return nil
+	return ufm.writeError(result, err, path)
 }
 
 func (ufm *UnixFileManager) MoveFile(sourcePath, destPath string) error {
@@ -140,13 +142,7 @@ func (ufm *UnixFileManager) MoveFile(sourcePath, destPath string) error {
 		Command: "mv",
 		Args:    []string{sourcePath, destPath},
 	})
-	if err != nil {
-		return err
-	}
-	if result.ExitCode != 0 {
-		return errors.New(result.STDERR)
-	}
-	return nil
+	return ufm.writeError(result, err, sourcePath, destPath)
 }
 
 func (ufm *UnixFileManager) CopyFile(sourcePath, destPath string) error {
@@ -154,13 +150,7 @@ func (ufm *UnixFileManager) CopyFile(sourcePath, destPath string) error {
 		Command: "cp",
 		Args:    []string{sourcePath, destPath},
 	})
-	if err != nil {
-		return err
-	}
-	if result.ExitCode != 0 {
-		return errors.New(result.STDERR)
-	}
-	return nil
+	return ufm.writeError(result, err, destPath)
 }
 
 func (ufm *UnixFileManager) GetFileAttributes(path string) (File, error) {
@@ -259,3 +249,59 @@ func (ufm *UnixFileManager) DiskUsage(path string) (DiskUsageInfo, error) {
 		UsePercent: usePercent,
 	}, nil
 }
+
+// DiskUsageByMount lists the usage of every mounted filesystem with POSIX df
+// output, which is the same on Linux and macOS. Pseudo filesystems reporting
+// no size, such as proc, are left out.
+func (ufm *UnixFileManager) DiskUsageByMount() ([]MountUsage, error) {
+	result, err := ufm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "df",
+		Args:    []string{"-kP"},
+	})
+	// df exits 1 if any filesystem could not be read but still lists the rest
+	if err != nil && !cm.IsExitError(err) {
+		return nil, err
+	}
+	return parseDfPortable(result.STDOUT)
+}
+
+// parseDfPortable parses "df -kP" output, whose sizes are in 1024-byte
+// blocks. Mount points may contain spaces.
+func parseDfPortable(output string) ([]MountUsage, error) {
+	lines := cm.Lines(output)
+	if len(lines) < 2 {
+		return nil, fmt.Errorf("unexpected df output format: %s", output)
+	}
+
+	var mounts []MountUsage
+	for _, line := range lines[1:] {
+		fields := strings.Fields(line)
+		if len(fields) < 6 {
+			return nil, fmt.Errorf("unexpected df output line: %q", line)
+		}
+		var sizes [3]int64
+		for i := range sizes {
+			n, err := strconv.ParseInt(fields[i+1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing df output line %q: %v", line, err)
+			}
+			sizes[i] = n * 1024
+		}
+		if sizes[0] == 0 {
+			continue
+		}
+		usePercent, err := strconv.ParseFloat(strings.TrimSuffix(fields[4], "%"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing df output line %q: %v", line, err)
+		}
+		mounts = append(mounts, MountUsage{
+			Filesystem:     fields[0],
+			MountPoint:     strings.Join(fields[5:], " "),
+			TotalBytes:     sizes[0],
+			UsedBytes:      sizes[1],
+			AvailableBytes: sizes[2],
+			UsePercent:     usePercent,
+		})
+	}
+	return mounts, nil
+}
@@ -3,6 +3,12 @@ package filemanager
 import (
 	"context"
 	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
 	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
@@ -11,6 +17,7 @@ import (
 type MockCommandManager struct {
 	Result cm.CommandResult
 	Err    error
+	Calls  []cm.CommandConfig
 }
 
 func (m *MockCommandManager) RunLocal(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
@@ -22,6 +29,7 @@ func (m *MockCommandManager) RunRemote(ctx context.Context, config cm.CommandCon
 }
 
 func (m *MockCommandManager) Run(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
+	m.Calls = append(m.Calls, config)
 	return m.Result, m.Err
 }
 
@@ -53,6 +61,16 @@ func TestDeleteDirectory(t *testing.T) {
 	if err != nil {
 		t.Errorf("Expected no error, got: %v", err)
 	}
+	if call := mockCmd.Calls[0]; call.Command != "rm" || !reflect.DeepEqual(call.Args, []string{"-r", "/path/to/directory"}) {
+		t.Errorf("Expected rm -r, got %+v", call)
+	}
+
+	if err := manager.DeleteEmptyDirectory("/path/to/directory"); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+	if call := mockCmd.Calls[1]; call.Command != "rmdir" {
+		t.Errorf("Expected rmdir for an empty directory, got %+v", call)
+	}
 }
 
 func TestListDirectoryError(t *testing.T) {
@@ -69,3 +87,977 @@ func TestListDirectoryError(t *testing.T) {
 		t.Errorf("Expected mock error, got: %v", err)
 	}
 }
+
+const getfattrDump = `# file: /var/www/index.html
+security.selinux="system_u:object_r:httpd_sys_content_t:s0"
+user.checksum="abc123"
+user.note="line one\012line two"
+user.empty
+`
+
+func TestParseGetfattrDump(t *testing.T) {
+	attrs, err := parseGetfattrDump(getfattrDump)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	expected := map[string]string{
+		"security.selinux": "system_u:object_r:httpd_sys_content_t:s0",
+		"user.checksum":    "abc123",
+		"user.note":        "line one\nline two",
+		"user.empty":       "",
+	}
+	if !reflect.DeepEqual(attrs, expected) {
+		t.Errorf("Expected %v, got %v", expected, attrs)
+	}
+}
+
+func TestGetXattr(t *testing.T) {
+	mockCmd := &MockCommandManager{
+		Result: cm.CommandResult{STDOUT: getfattrDump},
+	}
+	manager := UnixFileManager{CommandManager: mockCmd}
+
+	value, err := manager.GetXattr("/var/www/index.html", "user.checksum")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if value != "abc123" {
+		t.Errorf("Expected abc123, got %s", value)
+	}
+
+	names, err := manager.ListXattrs("/var/www/index.html")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	expectedNames := []string{"security.selinux", "user.checksum", "user.note", "user.empty"}
+	if !reflect.DeepEqual(names, expectedNames) {
+		t.Errorf("Expected %v, got %v", expectedNames, names)
+	}
+}
+
+func TestXattrNotSupported(t *testing.T) {
+	mockCmd := &MockCommandManager{
+		Result: cm.CommandResult{STDERR: "/mnt/vfat/file: Operation not supported", ExitCode: 1},
+	}
+	manager := UnixFileManager{CommandManager: mockCmd}
+
+	if _, err := manager.GetXattr("/mnt/vfat/file", "user.test"); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("Expected ErrNotSupported, got: %v", err)
+	}
+}
+
+func TestParseSELinuxContext(t *testing.T) {
+	tests := map[string]string{
+		"system_u:object_r:etc_t:s0 /etc/hosts\n":                       "system_u:object_r:etc_t:s0",
+		"-rw-r--r--. root root system_u:object_r:etc_t:s0 /etc/hosts\n": "system_u:object_r:etc_t:s0",
+	}
+	for input, expected := range tests {
+		got, err := parseSELinuxContext(input)
+		if err != nil || got != expected {
+			t.Errorf("parseSELinuxContext(%q): expected %s, got %s (%v)", input, expected, got, err)
+		}
+	}
+
+	if _, err := parseSELinuxContext("? /etc/hosts\n"); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("Expected ErrNotSupported when SELinux is disabled, got: %v", err)
+	}
+}
+
+// stdinOf returns the input config was run with.
+func stdinOf(config cm.CommandConfig) string {
+	if config.Stdin == nil {
+		return ""
+	}
+	data, _ := io.ReadAll(config.Stdin)
+	return string(data)
+}
+
+const testFstab = `# /etc/fstab: static file system information.
+UUID=0a1b2c3d-0000-4000-8000-000000000001 /               ext4    errors=remount-ro 0       1
+LABEL=boot	/boot	vfat	umask=0077	0	2
+
+/swapfile none swap sw 0 0
+tmpfs /tmp tmpfs defaults
+`
+
+func TestParseFstab(t *testing.T) {
+	entries, err := parseFstab(testFstab)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	expected := []FstabEntry{
+		{Device: "UUID=0a1b2c3d-0000-4000-8000-000000000001", Mountpoint: "/", FSType: "ext4", Options: "errors=remount-ro", Dump: 0, Pass: 1},
+		{Device: "LABEL=boot", Mountpoint: "/boot", FSType: "vfat", Options: "umask=0077", Dump: 0, Pass: 2},
+		{Device: "/swapfile", Mountpoint: "none", FSType: "swap", Options: "sw"},
+		{Device: "tmpfs", Mountpoint: "/tmp", FSType: "tmpfs", Options: "defaults"},
+	}
+	if !reflect.DeepEqual(entries, expected) {
+		t.Errorf("Expected %v, got: %v", expected, entries)
+	}
+
+	if _, err := parseFstab("/dev/sda1 /mnt\n"); err == nil {
+		t.Errorf("Expected error for malformed line")
+	}
+}
+
+func TestAddFstabEntry(t *testing.T) {
+	mockCmd := &MockCommandManager{Result: cm.CommandResult{STDOUT: testFstab}}
+	manager := UnixFileManager{CommandManager: mockCmd}
+
+	entry := FstabEntry{Device: "UUID=feed-beef", Mountpoint: "/data", FSType: "xfs", Options: "noatime", Pass: 2}
+	changed, err := manager.AddFstabEntry(entry)
+	if err != nil || !changed {
+		t.Fatalf("Expected entry to be added, got %v, %v", changed, err)
+	}
+
+	backup := mockCmd.Calls[len(mockCmd.Calls)-2]
+	if backup.Command != "cp" || !reflect.DeepEqual(backup.Args, []string{"-p", "/etc/fstab", "/etc/fstab.bak"}) {
+		t.Errorf("Expected fstab to be backed up first, got: %v", backup)
+	}
+	write := mockCmd.Calls[len(mockCmd.Calls)-1]
+	content := stdinOf(write)
+	if !write.Sudo || !strings.Contains(content, "UUID=feed-beef\t/data\txfs\tnoatime\t0\t2") {
+		t.Errorf("Expected new entry to be written with sudo, got: %v", write)
+	}
+	if !reflect.DeepEqual(write.Args, cm.ReplaceFile("/etc/fstab", nil).Args) {
+		t.Errorf("Expected fstab to be replaced atomically, got: %q", write.Args)
+	}
+	if !strings.Contains(content, "LABEL=boot\t/boot") {
+		t.Errorf("Expected existing lines to be preserved, got: %s", content)
+	}
+}
+
+func TestAddFstabEntryIdempotent(t *testing.T) {
+	mockCmd := &MockCommandManager{Result: cm.CommandResult{STDOUT: testFstab}}
+	manager := UnixFileManager{CommandManager: mockCmd}
+
+	changed, err := manager.AddFstabEntry(FstabEntry{Device: "LABEL=boot", Mountpoint: "/boot", FSType: "vfat", Options: "umask=0077", Pass: 2})
+	if err != nil || changed {
+		t.Errorf("Expected no change for an existing entry, got %v, %v", changed, err)
+	}
+	if len(mockCmd.Calls) != 1 {
+		t.Errorf("Expected only a read, got: %v", mockCmd.Calls)
+	}
+
+	if _, err := manager.AddFstabEntry(FstabEntry{Device: "/dev/sdb1", Mountpoint: "/boot", FSType: "ext4"}); err == nil {
+		t.Errorf("Expected error for a conflicting mountpoint")
+	}
+}
+
+func TestRemoveFstabEntry(t *testing.T) {
+	mockCmd := &MockCommandManager{Result: cm.CommandResult{STDOUT: testFstab}}
+	manager := UnixFileManager{CommandManager: mockCmd}
+
+	if err := manager.RemoveFstabEntry("/boot"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	content := stdinOf(mockCmd.Calls[len(mockCmd.Calls)-1])
+	if strings.Contains(content, "LABEL=boot") {
+		t.Errorf("Expected /boot entry to be removed, got: %s", content)
+	}
+	if !strings.Contains(content, "UUID=0a1b2c3d-0000-4000-8000-000000000001 /") || !strings.Contains(content, "# /etc/fstab") {
+		t.Errorf("Expected other lines to be preserved unchanged, got: %s", content)
+	}
+
+	mockCmd.Calls = nil
+	if err := manager.RemoveFstabEntry("/missing"); err != nil || len(mockCmd.Calls) != 1 {
+		t.Errorf("Expected no write when nothing matches, got %v, %v", err, mockCmd.Calls)
+	}
+}
+
+func TestSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "sites-available", "site.conf")
+	link := filepath.Join(dir, "site.conf")
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(target, []byte("server {}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	manager := UnixFileManager{CommandManager: &cm.UnixCommandManager{Hostname: "localhost"}}
+
+	if err := manager.CreateSymlink(target, link); err != nil {
+		t.Fatalf("Expected symlink to be created, got: %v", err)
+	}
+	if err := manager.CreateSymlink(target, link); err != nil {
+		t.Errorf("Expected re-creating the same symlink to succeed, got: %v", err)
+	}
+
+	got, err := manager.ReadLink(link)
+	if err != nil || got != target {
+		t.Errorf("Expected link to point at %s, got %q, %v", target, got, err)
+	}
+
+	if err := manager.CreateSymlink(filepath.Join(dir, "other.conf"), link); !errors.Is(err, os.ErrExist) {
+		t.Errorf("Expected os.ErrExist for a symlink to another target, got: %v", err)
+	}
+
+	if err := manager.RemoveLink(link); err != nil {
+		t.Fatalf("Expected link to be removed, got: %v", err)
+	}
+	if _, err := os.Lstat(link); !os.IsNotExist(err) {
+		t.Errorf("Expected link to be gone, got: %v", err)
+	}
+	if _, err := os.Stat(target); err != nil {
+		t.Errorf("Expected target to survive link removal, got: %v", err)
+	}
+}
+
+func TestSymlinkInTheWay(t *testing.T) {
+	dir := t.TempDir()
+	link := filepath.Join(dir, "site.conf")
+	if err := os.WriteFile(link, []byte("local edits"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	manager := UnixFileManager{CommandManager: &cm.UnixCommandManager{Hostname: "localhost"}}
+	if err := manager.CreateSymlink("/etc/nginx/site.conf", link); !errors.Is(err, os.ErrExist) {
+		t.Errorf("Expected os.ErrExist when a file is in the way, got: %v", err)
+	}
+	if data, _ := os.ReadFile(link); string(data) != "local edits" {
+		t.Errorf("Expected existing file to be left alone, got: %q", data)
+	}
+
+	if err := manager.RemoveLink(dir); err == nil {
+		t.Errorf("Expected RemoveLink to refuse a directory")
+	}
+}
+
+func TestHardLink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "data")
+	link := filepath.Join(dir, "data.link")
+	if err := os.WriteFile(target, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	manager := UnixFileManager{CommandManager: &cm.UnixCommandManager{Hostname: "localhost"}}
+	if err := manager.CreateHardLink(target, link); err != nil {
+		t.Fatalf("Expected hard link to be created, got: %v", err)
+	}
+
+	targetInfo, _ := os.Stat(target)
+	linkInfo, _ := os.Stat(link)
+	if !os.SameFile(targetInfo, linkInfo) {
+		t.Errorf("Expected %s and %s to be the same file", target, link)
+	}
+}
+
+func TestLinksReadOnly(t *testing.T) {
+	manager := UnixFileManager{CommandManager: &cm.UnixCommandManager{Hostname: "localhost", ReadOnly: true}}
+	if err := manager.CreateSymlink("/etc/hosts", filepath.Join(t.TempDir(), "hosts")); !errors.Is(err, cm.ErrReadOnlyMode) {
+		t.Errorf("Expected ErrReadOnlyMode, got: %v", err)
+	}
+}
+
+func TestReadWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "etc", "app", "key.bin")
+	data := []byte{0x00, 0xff, '\n', 0x1b, 'k', 0x00}
+
+	manager := UnixFileManager{CommandManager: &cm.UnixCommandManager{Hostname: "localhost"}}
+	if err := manager.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("Expected file to be written, got: %v", err)
+	}
+	got, err := manager.ReadFile(path)
+	if err != nil || !reflect.DeepEqual(got, data) {
+		t.Errorf("Expected %q back, got %q, %v", data, got, err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode 0600, got %v, %v", info.Mode(), err)
+	}
+
+	if err := manager.WriteFile(path, []byte("replaced"), 0644); err != nil {
+		t.Fatalf("Expected file to be replaced, got: %v", err)
+	}
+	if got, _ := os.ReadFile(path); string(got) != "replaced" {
+		t.Errorf("Expected replaced content, got %q", got)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("Expected no temporary files left behind, got %v", entries)
+	}
+}
+
+func TestWriteFileReadOnly(t *testing.T) {
+	manager := UnixFileManager{CommandManager: &cm.UnixCommandManager{Hostname: "localhost", ReadOnly: true}}
+	path := filepath.Join(t.TempDir(), "file")
+	if err := manager.WriteFile(path, []byte("x"), 0644); !errors.Is(err, cm.ErrReadOnlyMode) {
+		t.Errorf("Expected ErrReadOnlyMode, got: %v", err)
+	}
+
+	manager = UnixFileManager{CommandManager: &MockCommandManager{}}
+	if _, err := manager.ReadFile(path); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("Expected ErrNotSupported without SFTP, got: %v", err)
+	}
+}
+
+func TestAppendToFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts")
+	if err := os.WriteFile(path, []byte("127.0.0.1 localhost\n"), 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	manager := UnixFileManager{CommandManager: &cm.UnixCommandManager{Hostname: "localhost"}}
+	if err := manager.AppendToFile(path, []byte("10.0.0.5 db\n")); err != nil {
+		t.Fatalf("Expected append to succeed, got: %v", err)
+	}
+	if got, _ := os.ReadFile(path); string(got) != "127.0.0.1 localhost\n10.0.0.5 db\n" {
+		t.Errorf("Expected line to be appended, got %q", got)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0640 {
+		t.Errorf("Expected mode to be kept, got %v", info.Mode())
+	}
+
+	created := filepath.Join(t.TempDir(), "new.log")
+	if err := manager.AppendToFile(created, []byte("first\n")); err != nil {
+		t.Fatalf("Expected missing file to be created, got: %v", err)
+	}
+	if got, _ := os.ReadFile(created); string(got) != "first\n" {
+		t.Errorf("Expected new file content, got %q", got)
+	}
+}
+
+func TestAppendToFileSudoFallback(t *testing.T) {
+	mockCmd := &MockCommandManager{}
+	manager := UnixFileManager{CommandManager: mockCmd}
+
+	if err := manager.AppendToFile("/etc/hosts", []byte("10.0.0.5 db\n")); err != nil {
+		t.Fatalf("Expected append to succeed, got: %v", err)
+	}
+	if len(mockCmd.Calls) != 1 {
+		t.Fatalf("Expected one command, got %v", mockCmd.Calls)
+	}
+	call := mockCmd.Calls[0]
+	if call.Command != "tee" || !reflect.DeepEqual(call.Args, []string{"-a", "/etc/hosts"}) || !call.Sudo {
+		t.Errorf("Expected sudo tee -a /etc/hosts, got %+v", call)
+	}
+	if data, _ := io.ReadAll(call.Stdin); string(data) != "10.0.0.5 db\n" {
+		t.Errorf("Expected data on stdin, got %q", data)
+	}
+}
+
+func TestCopyDirectory(t *testing.T) {
+	src := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(src, "conf.d"), 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "app.conf"), []byte("port = 80"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "conf.d", "tls.conf"), []byte("tls = on"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	// A link back up the tree must not be followed
+	if err := os.Symlink("..", filepath.Join(src, "conf.d", "loop")); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := filepath.Join(t.TempDir(), "srv", "app")
+	manager := UnixFileManager{CommandManager: &cm.UnixCommandManager{Hostname: "localhost"}}
+	if err := manager.CopyDirectory(src, dst); err != nil {
+		t.Fatalf("Expected tree to be uploaded, got: %v", err)
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(dst, "conf.d", "tls.conf")); string(data) != "tls = on" {
+		t.Errorf("Expected nested file to be copied, got %q", data)
+	}
+	if info, err := os.Stat(filepath.Join(dst, "conf.d", "tls.conf")); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected file mode 0600, got %v, %v", info, err)
+	}
+	if info, err := os.Stat(filepath.Join(dst, "conf.d")); err != nil || info.Mode().Perm() != 0750 {
+		t.Errorf("Expected directory mode 0750, got %v, %v", info, err)
+	}
+	if target, err := os.Readlink(filepath.Join(dst, "conf.d", "loop")); err != nil || target != ".." {
+		t.Errorf("Expected symlink to be recreated, got %q, %v", target, err)
+	}
+
+	paths, err := manager.ListTree(dst, "")
+	want := []string{"app.conf", "conf.d", "conf.d/loop", "conf.d/tls.conf"}
+	if err != nil || !reflect.DeepEqual(paths, want) {
+		t.Errorf("Expected %v, got %v, %v", want, paths, err)
+	}
+	paths, err = manager.ListTree(dst, "*.conf")
+	want = []string{"app.conf", "conf.d/tls.conf"}
+	if err != nil || !reflect.DeepEqual(paths, want) {
+		t.Errorf("Expected %v, got %v, %v", want, paths, err)
+	}
+
+	if err := manager.DeleteEmptyDirectory(dst); err == nil {
+		t.Errorf("Expected deleting a non-empty directory as empty to fail")
+	}
+	if err := manager.DeleteDirectory(dst); err != nil {
+		t.Fatalf("Expected recursive delete to succeed, got: %v", err)
+	}
+	if _, err := os.Stat(dst); !os.IsNotExist(err) {
+		t.Errorf("Expected directory to be gone, got: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(src, "app.conf")); err != nil {
+		t.Errorf("Expected source tree to survive, got: %v", err)
+	}
+}
+
+func TestParseTree(t *testing.T) {
+	output := "/\x00/etc\x00/etc/hosts\x00"
+	if got := parseTree(output, "/", ""); !reflect.DeepEqual(got, []string{"etc", "etc/hosts"}) {
+		t.Errorf("Expected paths relative to /, got %v", got)
+	}
+	if _, err := (&UnixFileManager{CommandManager: &MockCommandManager{}}).ListTree("/etc", "["); err == nil {
+		t.Errorf("Expected an invalid pattern to be rejected")
+	}
+}
+
+func TestSetPermissions(t *testing.T) {
+	mockCmd := &MockCommandManager{}
+	manager := UnixFileManager{CommandManager: mockCmd}
+
+	if err := manager.SetPermissions("/srv/app/run.sh", 0755); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if err := manager.SetPermissionsRecursive("/srv/shared", 0770|os.ModeSetgid); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	want := [][]string{
+		{"--", "0755", "/srv/app/run.sh"},
+		{"-R", "--", "02770", "/srv/shared"},
+	}
+	for i, call := range mockCmd.Calls {
+		if call.Command != "chmod" || !call.Sudo || !reflect.DeepEqual(call.Args, want[i]) {
+			t.Errorf("Expected sudo chmod %v, got %+v", want[i], call)
+		}
+	}
+}
+
+func TestSetOwner(t *testing.T) {
+	mockCmd := &MockCommandManager{}
+	manager := UnixFileManager{CommandManager: mockCmd}
+
+	tests := []struct {
+		user, group string
+		recursive   bool
+		want        []string
+	}{
+		{"www-data", "", false, []string{"--", "www-data", "/var/www"}},
+		{"", "adm", false, []string{"--", ":adm", "/var/www"}},
+		{"www-data", "www-data", true, []string{"-R", "--", "www-data:www-data", "/var/www"}},
+	}
+	for _, tt := range tests {
+		mockCmd.Calls = nil
+		if err := manager.SetOwner("/var/www", tt.user, tt.group, tt.recursive); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if call := mockCmd.Calls[0]; call.Command != "chown" || !call.Sudo || !reflect.DeepEqual(call.Args, tt.want) {
+			t.Errorf("Expected sudo chown %v, got %+v", tt.want, call)
+		}
+	}
+
+	if err := manager.SetOwner("/var/www", "", "", false); err == nil {
+		t.Errorf("Expected an error without user or group")
+	}
+}
+
+func TestImmutable(t *testing.T) {
+	mockCmd := &MockCommandManager{
+		Result: cm.CommandResult{STDOUT: "----i---------e------- /etc/resolv.conf\n"},
+	}
+	manager := UnixFileManager{CommandManager: mockCmd}
+
+	immutable, err := manager.IsImmutable("/etc/resolv.conf")
+	if err != nil || !immutable {
+		t.Errorf("Expected immutable, got %v, %v", immutable, err)
+	}
+
+	if err := manager.SetImmutable("/etc/resolv.conf", false); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+	call := mockCmd.Calls[len(mockCmd.Calls)-1]
+	if call.Command != "chattr" || !reflect.DeepEqual(call.Args, []string{"-i", "/etc/resolv.conf"}) || !call.Sudo {
+		t.Errorf("Expected sudo chattr -i, got: %+v", call)
+	}
+
+	mockCmd.Result = cm.CommandResult{STDOUT: "--------------e------- /etc/hosts\n"}
+	if immutable, err := manager.IsImmutable("/etc/hosts"); err != nil || immutable {
+		t.Errorf("Expected mutable, got %v, %v", immutable, err)
+	}
+
+	mockCmd.Result = cm.CommandResult{
+		STDERR:   "lsattr: Inappropriate ioctl for device While reading flags on /mnt/nfs/file\n",
+		ExitCode: 1,
+	}
+	if _, err := manager.IsImmutable("/mnt/nfs/file"); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("Expected ErrNotSupported, got: %v", err)
+	}
+}
+
+// scriptedCommandManager answers each command from a function.
+type scriptedCommandManager struct {
+	MockCommandManager
+	Respond func(config cm.CommandConfig) cm.CommandResult
+}
+
+func (s *scriptedCommandManager) Run(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
+	s.Calls = append(s.Calls, config)
+	return s.Respond(config), nil
+}
+
+func TestWriteImmutableFile(t *testing.T) {
+	mockCmd := &scriptedCommandManager{
+		Respond: func(config cm.CommandConfig) cm.CommandResult {
+			switch config.Command {
+			case "lsattr":
+				return cm.CommandResult{STDOUT: "----i---------e------- /etc/resolv.conf\n"}
+			case "rm":
+				return cm.CommandResult{
+					STDERR:   "rm: cannot remove '/etc/resolv.conf': Operation not permitted\n",
+					ExitCode: 1,
+				}
+			}
+			return cm.CommandResult{}
+		},
+	}
+	manager := UnixFileManager{CommandManager: mockCmd}
+
+	err := manager.DeleteFile("/etc/resolv.conf")
+	if !errors.Is(err, ErrFileImmutable) {
+		t.Errorf("Expected ErrFileImmutable, got: %v", err)
+	}
+
+	if err := manager.CreateFile("/etc/resolv.conf"); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+}
+
+func TestWriteFileImmutable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "resolv.conf")
+	if err := os.WriteFile(path, []byte("nameserver 10.0.0.1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := exec.Command("chattr", "+i", path).Run(); err != nil {
+		t.Skipf("Cannot set the immutable attribute here: %v", err)
+	}
+	t.Cleanup(func() { exec.Command("chattr", "-i", path).Run() })
+
+	manager := UnixFileManager{CommandManager: &cm.UnixCommandManager{Hostname: "localhost"}}
+	if err := manager.WriteFile(path, []byte("nameserver 10.0.0.2\n"), 0644); !errors.Is(err, ErrFileImmutable) {
+		t.Errorf("Expected ErrFileImmutable, got: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "nameserver 10.0.0.1\n" {
+		t.Errorf("Expected the immutable file to be unchanged, got %q", data)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("Expected no temporary files left behind, got %v", entries)
+	}
+}
+
+func TestDarwinImmutableNotSupported(t *testing.T) {
+	manager := DarwinFileManager{UnixFileManager{CommandManager: &MockCommandManager{}}}
+
+	if _, err := manager.IsImmutable("/etc/hosts"); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("Expected ErrNotSupported, got: %v", err)
+	}
+}
+
+// unprivilegedCommandManager runs commands on this machine without sudo.
+type unprivilegedCommandManager struct {
+	MockCommandManager
+	local cm.UnixCommandManager
+}
+
+func (u *unprivilegedCommandManager) Run(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
+	u.Calls = append(u.Calls, config)
+	config.Sudo = false
+	return u.local.RunLocal(ctx, config)
+}
+
+func TestEditFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.conf")
+	if err := os.WriteFile(path, []byte("port=80\nworkers=2\n"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	mockCmd := &unprivilegedCommandManager{local: cm.UnixCommandManager{Hostname: "localhost"}}
+	manager := UnixFileManager{CommandManager: mockCmd}
+
+	setPort := func(current []byte) ([]byte, error) {
+		return []byte(strings.Replace(string(current), "port=80\n", "port=8080\n", 1)), nil
+	}
+
+	changed, err := manager.EditFile(path, setPort)
+	if err != nil || !changed {
+		t.Fatalf("Expected a change, got %v, %v", changed, err)
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != "port=8080\nworkers=2\n" {
+		t.Errorf("Unexpected content: %q", data)
+	}
+	info, _ := os.Stat(path)
+	if info.Mode().Perm() != 0640 {
+		t.Errorf("Expected mode 0640 to be preserved, got %v", info.Mode().Perm())
+	}
+
+	// Applying the same edit again is a no-op
+	mockCmd.Calls = nil
+	changed, err = manager.EditFile(path, setPort)
+	if err != nil || changed {
+		t.Errorf("Expected no change, got %v, %v", changed, err)
+	}
+	if len(mockCmd.Calls) != 1 {
+		t.Errorf("Expected only a read for a no-op edit, got: %+v", mockCmd.Calls)
+	}
+
+	// A failing transform leaves the file alone
+	_, err = manager.EditFile(path, func(current []byte) ([]byte, error) {
+		return []byte("garbage"), errors.New("no port setting")
+	})
+	if err == nil || err.Error() != "no port setting" {
+		t.Errorf("Expected the transform error, got: %v", err)
+	}
+	data, _ = os.ReadFile(path)
+	if string(data) != "port=8080\nworkers=2\n" {
+		t.Errorf("Expected file to be untouched, got: %q", data)
+	}
+	if matches, _ := filepath.Glob(path + ".*"); len(matches) != 0 {
+		t.Errorf("Expected no temporary files, got: %v", matches)
+	}
+}
+
+func TestEditFileSudoStreamsContent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secret.conf")
+	if err := os.WriteFile(path, []byte("token=old\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	mockCmd := &unprivilegedCommandManager{local: cm.UnixCommandManager{Hostname: "localhost"}}
+	manager := UnixFileManager{CommandManager: mockCmd}
+
+	changed, err := manager.EditFile(path, func(current []byte) ([]byte, error) {
+		return []byte("token=new\n"), nil
+	})
+	if err != nil || !changed {
+		t.Fatalf("Expected a change, got %v, %v", changed, err)
+	}
+	if len(mockCmd.Calls) != 2 {
+		t.Fatalf("Expected a read and a write, got: %+v", mockCmd.Calls)
+	}
+	write := mockCmd.Calls[1]
+	if !write.Sudo || write.Stdin == nil {
+		t.Errorf("Expected the write to run with sudo and input, got %+v", write)
+	}
+	for _, arg := range write.Args {
+		if strings.Contains(arg, "token=new") {
+			t.Errorf("Expected the content to stay out of the command line, got %q", write.Args)
+		}
+	}
+	if data, _ := os.ReadFile(path); string(data) != "token=new\n" {
+		t.Errorf("Unexpected content: %q", data)
+	}
+}
+
+func TestEditFileAtomicWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.conf")
+	if err := os.WriteFile(path, []byte("port=80\n"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	manager := UnixFileManager{CommandManager: &cm.UnixCommandManager{Hostname: "localhost"}}
+
+	changed, err := manager.EditFile(path, func(current []byte) ([]byte, error) {
+		return []byte("port=8080\n"), nil
+	})
+	if err != nil || !changed {
+		t.Fatalf("Expected a change, got %v, %v", changed, err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "port=8080\n" {
+		t.Errorf("Unexpected content: %q", data)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0640 {
+		t.Errorf("Expected mode 0640 to be preserved, got %v", info.Mode().Perm())
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("Expected no temporary files left behind, got %v", entries)
+	}
+}
+
+func TestEditFileSymlink(t *testing.T) {
+	managers := map[string]cm.CommandManager{
+		"login user": &cm.UnixCommandManager{Hostname: "localhost"},
+		"sudo":       &unprivilegedCommandManager{local: cm.UnixCommandManager{Hostname: "localhost"}},
+	}
+	for name, mockCmd := range managers {
+		dir := t.TempDir()
+		target := filepath.Join(dir, "resolv.conf.real")
+		link := filepath.Join(dir, "resolv.conf")
+		if err := os.WriteFile(target, []byte("nameserver 10.0.0.1\n"), 0640); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink("resolv.conf.real", link); err != nil {
+			t.Fatal(err)
+		}
+		manager := UnixFileManager{CommandManager: mockCmd}
+
+		changed, err := manager.EditFile(link, func(current []byte) ([]byte, error) {
+			return []byte("nameserver 10.0.0.2\n"), nil
+		})
+		if err != nil || !changed {
+			t.Fatalf("%s: expected a change, got %v, %v", name, changed, err)
+		}
+		if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+			t.Errorf("%s: expected %s to stay a symlink, got %v, %v", name, link, info, err)
+		}
+		if data, _ := os.ReadFile(target); string(data) != "nameserver 10.0.0.2\n" {
+			t.Errorf("%s: expected the target to be updated, got %q", name, data)
+		}
+		if info, _ := os.Stat(target); info.Mode().Perm() != 0640 {
+			t.Errorf("%s: expected the target's mode 0640, got %v", name, info.Mode().Perm())
+		}
+		if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+			t.Errorf("%s: expected no temporary files left behind, got %v", name, entries)
+		}
+	}
+}
+
+func TestEditFileKeepsOwner(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("changing a file's owner needs root")
+	}
+	path := filepath.Join(t.TempDir(), "app.conf")
+	if err := os.WriteFile(path, []byte("port=80\n"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chown(path, 1234, 5678); err != nil {
+		t.Fatal(err)
+	}
+	manager := UnixFileManager{CommandManager: &cm.UnixCommandManager{Hostname: "localhost"}}
+
+	if _, err := manager.EditFile(path, func(current []byte) ([]byte, error) {
+		return []byte("port=8080\n"), nil
+	}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if owner := ownerOf(info); owner == nil || *owner != (fileOwner{uid: 1234, gid: 5678}) {
+		t.Errorf("Expected owner 1234:5678 to be kept, got %+v", owner)
+	}
+}
+
+func TestMkfsArgs(t *testing.T) {
+	tests := []struct {
+		fstype    string
+		opts      MkfsOptions
+		overwrite bool
+		want      []string
+	}{
+		{"ext4", MkfsOptions{Label: "data"}, false, []string{"-L", "data", "/dev/sdb1"}},
+		{"ext4", MkfsOptions{Force: true}, true, []string{"-F", "/dev/sdb1"}},
+		{"xfs", MkfsOptions{Force: true, Label: "data"}, true, []string{"-f", "-L", "data", "/dev/sdb1"}},
+		{"xfs", MkfsOptions{Force: true}, false, []string{"/dev/sdb1"}},
+		{"vfat", MkfsOptions{Force: true, Label: "EFI", Args: []string{"-F", "32"}}, true, []string{"-n", "EFI", "-F", "32", "/dev/sdb1"}},
+	}
+	for _, tt := range tests {
+		if got := mkfsArgs("/dev/sdb1", tt.fstype, tt.opts, tt.overwrite); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("mkfsArgs(%s, %+v) = %v, want %v", tt.fstype, tt.opts, got, tt.want)
+		}
+	}
+}
+
+func TestMakeFilesystem(t *testing.T) {
+	existing := "ext4\n"
+	mockCmd := &scriptedCommandManager{
+		Respond: func(config cm.CommandConfig) cm.CommandResult {
+			if config.Command == "blkid" {
+				if existing == "" {
+					return cm.CommandResult{ExitCode: 2}
+				}
+				return cm.CommandResult{STDOUT: existing}
+			}
+			return cm.CommandResult{}
+		},
+	}
+	manager := UnixFileManager{CommandManager: mockCmd}
+
+	err := manager.MakeFilesystem("/dev/sdb1", "xfs", MkfsOptions{})
+	if !errors.Is(err, ErrFilesystemExists) {
+		t.Errorf("Expected ErrFilesystemExists, got: %v", err)
+	}
+	for _, call := range mockCmd.Calls {
+		if call.Command == "mkfs.xfs" {
+			t.Errorf("Expected no mkfs without Force, got: %+v", call)
+		}
+	}
+
+	mockCmd.Calls = nil
+	existing = ""
+	if err := manager.MakeFilesystem("/dev/sdb1", "xfs", MkfsOptions{Label: "data"}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	last := mockCmd.Calls[len(mockCmd.Calls)-1]
+	if last.Command != "mkfs.xfs" || !last.Sudo || strings.Join(last.Args, " ") != "-L data /dev/sdb1" {
+		t.Errorf("Unexpected mkfs call: %+v", last)
+	}
+
+	if err := manager.MakeFilesystem("/dev/sdb1", "ext4; reboot", MkfsOptions{}); err == nil {
+		t.Errorf("Expected error for invalid filesystem type")
+	}
+}
+
+func TestMount(t *testing.T) {
+	mounted := ""
+	mockCmd := &scriptedCommandManager{
+		Respond: func(config cm.CommandConfig) cm.CommandResult {
+			switch config.Command {
+			case "findmnt":
+				if mounted == "" {
+					return cm.CommandResult{ExitCode: 1}
+				}
+				return cm.CommandResult{STDOUT: mounted + "\n"}
+			case "findfs":
+				return cm.CommandResult{STDOUT: "/dev/sdb1\n"}
+			}
+			return cm.CommandResult{}
+		},
+	}
+	manager := UnixFileManager{CommandManager: mockCmd}
+
+	if err := manager.Mount("/dev/sdb1", "/srv/data", "xfs", []string{"noatime", "nofail"}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	var commands []string
+	for _, call := range mockCmd.Calls {
+		if call.Sudo {
+			commands = append(commands, call.Command+" "+strings.Join(call.Args, " "))
+		}
+	}
+	want := []string{"mkdir -p /srv/data", "mount -t xfs -o noatime,nofail /dev/sdb1 /srv/data"}
+	if !reflect.DeepEqual(commands, want) {
+		t.Errorf("Expected %v, got: %v", want, commands)
+	}
+
+	// Mounting the same device again does nothing, including by UUID
+	mounted = "/dev/sdb1"
+	for _, device := range []string{"/dev/sdb1", "UUID=1b2c"} {
+		mockCmd.Calls = nil
+		if err := manager.Mount(device, "/srv/data", "xfs", nil); err != nil {
+			t.Errorf("Expected no error remounting %s, got: %v", device, err)
+		}
+		for _, call := range mockCmd.Calls {
+			if call.Command == "mount" {
+				t.Errorf("Expected no mount for %s, got: %+v", device, call)
+			}
+		}
+	}
+
+	if err := manager.Mount("/dev/sdc1", "/srv/data", "xfs", nil); err == nil {
+		t.Errorf("Expected error for a different device at the mountpoint")
+	}
+
+	mockCmd.Calls = nil
+	if err := manager.Unmount("/srv/data"); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+	if last := mockCmd.Calls[len(mockCmd.Calls)-1]; last.Command != "umount" {
+		t.Errorf("Expected umount, got: %+v", last)
+	}
+
+	mounted = ""
+	mockCmd.Calls = nil
+	if err := manager.Unmount("/srv/data"); err != nil || len(mockCmd.Calls) != 1 {
+		t.Errorf("Expected unmounting an unmounted path to do nothing, got: %v, %+v", err, mockCmd.Calls)
+	}
+}
+
+func TestParseChecksums(t *testing.T) {
+	output := `e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855  /srv/app/empty
+2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae  /srv/app/bin/run server
+\fcde2b2edba56bf408601fb721fe9b5c338d10ee429ea04fae5511b68fbf8fb9  /srv/app/odd\nname\\x
+`
+	checksums, err := parseChecksums(output, "/srv/app")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	want := map[string]string{
+		"empty":          "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		"bin/run server": "2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae",
+		"odd\nname\\x":   "fcde2b2edba56bf408601fb721fe9b5c338d10ee429ea04fae5511b68fbf8fb9",
+	}
+	if !reflect.DeepEqual(checksums, want) {
+		t.Errorf("Expected %v, got: %v", want, checksums)
+	}
+
+	if _, err := parseChecksums("find: '/srv/app/private': Permission denied\n", "/srv/app"); err == nil {
+		t.Errorf("Expected error for unexpected output")
+	}
+}
+
+func TestDiffTree(t *testing.T) {
+	actual := map[string]string{"a": "1", "b": "2", "new": "3"}
+	expected := map[string]string{"a": "1", "b": "9", "gone": "4"}
+
+	diff := diffTree(actual, expected)
+	want := TreeDiff{Added: []string{"new"}, Removed: []string{"gone"}, Changed: []string{"b"}}
+	if !reflect.DeepEqual(diff, want) {
+		t.Errorf("Expected %+v, got: %+v", want, diff)
+	}
+	if diff.Empty() || !diffTree(expected, expected).Empty() {
+		t.Errorf("Unexpected Empty result")
+	}
+}
+
+func TestCompareTree(t *testing.T) {
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, "conf"), 0755)
+	os.WriteFile(filepath.Join(root, "conf", "app.conf"), []byte("port=80\n"), 0644)
+	os.WriteFile(filepath.Join(root, "VERSION"), []byte("1.2.3\n"), 0644)
+
+	mockCmd := &unprivilegedCommandManager{local: cm.UnixCommandManager{Hostname: "localhost"}}
+	manager := UnixFileManager{CommandManager: mockCmd}
+
+	known, err := manager.TreeChecksums(root)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(known) != 2 || known["conf/app.conf"] == "" || len(mockCmd.Calls) != 1 {
+		t.Fatalf("Expected two checksums from one command, got: %v, %+v", known, mockCmd.Calls)
+	}
+
+	os.WriteFile(filepath.Join(root, "conf", "app.conf"), []byte("port=8080\n"), 0644)
+	diff, err := manager.CompareTree(root, known)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !reflect.DeepEqual(diff.Changed, []string{"conf/app.conf"}) || len(diff.Added) != 0 || len(diff.Removed) != 0 {
+		t.Errorf("Expected conf/app.conf to have changed, got: %+v", diff)
+	}
+}
+
+func TestDiskUsageByMount(t *testing.T) {
+	mockCmd := &MockCommandManager{Result: cm.CommandResult{STDOUT: `Filesystem     1024-blocks     Used Available Capacity Mounted on
+/dev/sda1         52428800 20971520  31457280      40% /
+proc                     0        0         0       -  /proc
+/dev/sdb1        104857600 10485760  94371840      10% /srv/my data
+`}}
+	manager := UnixFileManager{CommandManager: mockCmd}
+
+	mounts, err := manager.DiskUsageByMount()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	expected := []MountUsage{
+		{Filesystem: "/dev/sda1", MountPoint: "/", TotalBytes: 52428800 * 1024, UsedBytes: 20971520 * 1024, AvailableBytes: 31457280 * 1024, UsePercent: 40},
+		{Filesystem: "/dev/sdb1", MountPoint: "/srv/my data", TotalBytes: 104857600 * 1024, UsedBytes: 10485760 * 1024, AvailableBytes: 94371840 * 1024, UsePercent: 10},
+	}
+	if !reflect.DeepEqual(mounts, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, mounts)
+	}
+	if args := mockCmd.Calls[0].Args; mockCmd.Calls[0].Command != "df" || !reflect.DeepEqual(args, []string{"-kP"}) {
+		t.Errorf("Unexpected command %+v", mockCmd.Calls[0])
+	}
+
+	if _, err := parseDfPortable("Filesystem 1024-blocks Used Available Capacity Mounted on\n/dev/sda1 garbage\n"); err == nil {
+		t.Errorf("Expected an error for a malformed line")
+	}
+}
@@ -0,0 +1,153 @@
+package filemanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// GetXattr returns the value of the extended attribute name on path.
+func (ufm *UnixFileManager) GetXattr(path, name string) (string, error) {
+	result, err := ufm.runAttrCommand("getfattr", "--absolute-names", "-e", "text", "-n", name, path)
+	if err != nil {
+		return "", err
+	}
+
+	attrs, err := parseGetfattrDump(result.STDOUT)
+	if err != nil {
+		return "", err
+	}
+	value, ok := attrs[name]
+	if !ok {
+		return "", fmt.Errorf("attribute %s not found on %s", name, path)
+	}
+	return value, nil
+}
+
+// SetXattr sets the extended attribute name on path to value.
+func (ufm *UnixFileManager) SetXattr(path, name, value string) error {
+	_, err := ufm.runAttrCommand("setfattr", "-n", name, "-v", value, path)
+	return err
+}
+
+// ListXattrs returns the names of all extended attributes on path.
+func (ufm *UnixFileManager) ListXattrs(path string) ([]string, error) {
+	result, err := ufm.runAttrCommand("getfattr", "--absolute-names", "-d", "-m", "-", "-e", "text", path)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, line := range strings.Split(result.STDOUT, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, _, _ := strings.Cut(line, "=")
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// GetSELinuxContext returns the SELinux security context of path.
+func (ufm *UnixFileManager) GetSELinuxContext(path string) (string, error) {
+	result, err := ufm.runAttrCommand("ls", "-Zd", path)
+	if err != nil {
+		return "", err
+	}
+
+	return parseSELinuxContext(result.STDOUT)
+}
+
+// SetSELinuxContext sets the SELinux security context of path.
+func (ufm *UnixFileManager) SetSELinuxContext(path, context string) error {
+	_, err := ufm.runAttrCommand("chcon", context, path)
+	return err
+}
+
+func (ufm *UnixFileManager) runAttrCommand(command string, args ...string) (cm.CommandResult, error) {
+	result, err := ufm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: command,
+		Args:    args,
+	})
+	if strings.Contains(result.STDERR, "Operation not supported") {
+		return result, ErrNotSupported
+	}
+	if err != nil {
+		return result, err
+	}
+	if result.ExitCode != 0 {
+		return result, errors.New(result.STDERR)
+	}
+	return result, nil
+}
+
+// parseGetfattrDump parses "getfattr --dump -e text" output into a map of
+// attribute names to values.
+func parseGetfattrDump(output string) (map[string]string, error) {
+	attrs := make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name, value, ok := strings.Cut(line, "=")
+		if !ok {
+			// Attributes without a value are listed by name only
+			attrs[line] = ""
+			continue
+		}
+
+		if strings.HasPrefix(value, `"`) {
+			unquoted, err := unquoteGetfattr(value)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing value of %s: %v", name, err)
+			}
+			value = unquoted
+		}
+		attrs[name] = value
+	}
+	return attrs, nil
+}
+
+// unquoteGetfattr removes the surrounding quotes from a getfattr text value
+// and decodes its backslash-octal escapes.
+func unquoteGetfattr(value string) (string, error) {
+	if len(value) < 2 || !strings.HasSuffix(value, `"`) {
+		return "", fmt.Errorf("unterminated quoted value: %s", value)
+	}
+	value = value[1 : len(value)-1]
+
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] == '\\' && i+3 < len(value) {
+			if n, err := strconv.ParseUint(value[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(value[i])
+	}
+	return b.String(), nil
+}
+
+// parseSELinuxContext extracts the context from "ls -Zd" output. Newer
+// coreutils print "<context> <path>"; older releases print it after the
+// mode, owner and group.
+func parseSELinuxContext(output string) (string, error) {
+	for _, field := range strings.Fields(output) {
+		if field == "?" {
+			return "", ErrNotSupported
+		}
+		if strings.Count(field, ":") >= 2 {
+			return field, nil
+		}
+	}
+	return "", fmt.Errorf("unexpected ls -Z output format: %s", output)
+}
@@ -0,0 +1,310 @@
+package host
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// CopyOption configures a file transfer.
+type CopyOption func(*copyOptions)
+
+type copyOptions struct {
+	progress  func(written, total int64)
+	rateLimit int64
+}
+
+// WithProgress returns a CopyOption that calls fn as bytes are transferred
+// with the running count and the total size of the file.
+func WithProgress(fn func(written, total int64)) CopyOption {
+	return func(o *copyOptions) {
+		o.progress = fn
+	}
+}
+
+// WithRateLimit returns a CopyOption that caps the transfer at bytesPerSecond.
+func WithRateLimit(bytesPerSecond int64) CopyOption {
+	return func(o *copyOptions) {
+		o.rateLimit = bytesPerSecond
+	}
+}
+
+// CopyBetween copies srcPath on src to dstPath on dst over SFTP. Data is
+// streamed from one host to the other without being staged on local disk, and
+// the file mode is preserved.
+func CopyBetween(ctx context.Context, src *Host, srcPath string, dst *Host, dstPath string, opts ...CopyOption) error {
+	var options copyOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	srcClient, err := openSFTP(ctx, src)
+	if err != nil {
+		return err
+	}
+	defer srcClient.Close()
+
+	dstClient, err := openSFTP(ctx, dst)
+	if err != nil {
+		return err
+	}
+	defer dstClient.Close()
+
+	if dstClient.ReadOnly {
+		return commandmanager.ErrReadOnlyMode
+	}
+
+	in, err := srcClient.Open(srcPath)
+	if err != nil {
+		return fmt.Errorf("failed to open %s on %s: %w", srcPath, src.Hostname, err)
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat %s on %s: %w", srcPath, src.Hostname, err)
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%s on %s is not a regular file", srcPath, src.Hostname)
+	}
+
+	out, err := dstClient.OpenFile(dstPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return fmt.Errorf("failed to create %s on %s: %w", dstPath, dst.Hostname, err)
+	}
+
+	reader := &transferReader{
+		ctx:     ctx,
+		reader:  in,
+		total:   info.Size(),
+		options: options,
+		start:   time.Now(),
+	}
+	if _, err := io.Copy(out, reader); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to copy %s to %s: %w", srcPath, dst.Hostname, err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to write %s on %s: %w", dstPath, dst.Hostname, err)
+	}
+
+	return dstClient.Chmod(dstPath, info.Mode().Perm())
+}
+
+// CopyFile uploads localPath to remotePath on the Host over SFTP, preserving
+// file modes and creating missing remote parent directories. If localPath is
+// a directory, its whole tree is uploaded with remotePath as its new root by
+// the FileManager's CopyDirectory.
+func (h *Host) CopyFile(localPath, remotePath string) error {
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return h.FileManager.CopyDirectory(localPath, remotePath)
+	}
+
+	client, err := openSFTP(context.TODO(), h)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if client.ReadOnly {
+		return commandmanager.ErrReadOnlyMode
+	}
+
+	if err := client.MkdirAll(path.Dir(remotePath)); err != nil {
+		return fmt.Errorf("failed to create %s on %s: %w", path.Dir(remotePath), h.Hostname, err)
+	}
+	return uploadFile(client, localPath, remotePath, info.Mode())
+}
+
+// ErrChecksumMismatch is returned by CopyFileVerified when the uploaded file
+// does not match the local one.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// CopyFileVerified uploads localPath like CopyFile, then checks that the
+// SHA-256 of remotePath on the Host matches the local file, so that a
+// transfer truncated on a flaky link is caught.
+func (h *Host) CopyFileVerified(localPath, remotePath string) error {
+	want, err := localChecksum(localPath)
+	if err != nil {
+		return err
+	}
+	if err := h.CopyFile(localPath, remotePath); err != nil {
+		return err
+	}
+
+	// macOS has no sha256sum
+	config := commandmanager.CommandConfig{Command: "sha256sum", Args: []string{remotePath}}
+	if h.OSType == Darwin {
+		config = commandmanager.CommandConfig{Command: "shasum", Args: []string{"-a", "256", remotePath}}
+	}
+	result, err := h.CommandManager.Run(context.TODO(), config)
+	if err != nil {
+		return fmt.Errorf("failed to checksum %s on %s: %w", remotePath, h.Hostname, err)
+	}
+	fields := strings.Fields(result.STDOUT)
+	if len(fields) == 0 {
+		return fmt.Errorf("unexpected %s output: %q", config.Command, result.STDOUT)
+	}
+	if got := strings.TrimPrefix(fields[0], `\`); !strings.EqualFold(got, want) {
+		return fmt.Errorf("%w: %s on %s has SHA-256 %s, expected %s", ErrChecksumMismatch, remotePath, h.Hostname, got, want)
+	}
+	return nil
+}
+
+// localChecksum returns the hex SHA-256 of the regular file at path.
+func localChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	if !info.Mode().IsRegular() {
+		return "", fmt.Errorf("%s is not a regular file", path)
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// DownloadFile fetches remotePath from the Host over SFTP into localPath,
+// preserving the remote file's mode. Missing local parent directories are
+// created, and localPath is only replaced once the whole file has arrived.
+func (h *Host) DownloadFile(remotePath, localPath string) error {
+	client, err := openSFTP(context.TODO(), h)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	info, err := client.Stat(remotePath)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s on %s: %w", remotePath, h.Hostname, err)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s on %s is a directory", remotePath, h.Hostname)
+	}
+
+	in, err := client.Open(remotePath)
+	if err != nil {
+		return fmt.Errorf("failed to open %s on %s: %w", remotePath, h.Hostname, err)
+	}
+	defer in.Close()
+
+	dir := filepath.Dir(localPath)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	out, err := os.CreateTemp(dir, "."+filepath.Base(localPath)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(out.Name())
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to download %s from %s: %w", remotePath, h.Hostname, err)
+	}
+	if err := out.Chmod(info.Mode().Perm()); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Rename(out.Name(), localPath)
+}
+
+// uploadFile copies the local file src to dst and gives it mode.
+func uploadFile(client *commandmanager.SFTPClient, src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := client.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", dst, err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to upload %s: %w", src, err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", dst, err)
+	}
+	return client.Chmod(dst, mode.Perm())
+}
+
+func openSFTP(ctx context.Context, h *Host) (*commandmanager.SFTPClient, error) {
+	provider, ok := h.CommandManager.(commandmanager.SFTPProvider)
+	if !ok {
+		return nil, fmt.Errorf("host %s does not support SFTP", h.Hostname)
+	}
+	return provider.OpenSFTP(ctx)
+}
+
+// rateLimitChunk bounds each read when rate limited so the limit is applied
+// smoothly rather than in large bursts.
+const rateLimitChunk = 32 * 1024
+
+// transferReader reports progress, applies the rate limit and stops the
+// transfer when its context is done.
+type transferReader struct {
+	ctx     context.Context
+	reader  io.Reader
+	total   int64
+	written int64
+	options copyOptions
+	start   time.Time
+}
+
+func (t *transferReader) Read(p []byte) (int, error) {
+	if err := t.ctx.Err(); err != nil {
+		return 0, err
+	}
+	if t.options.rateLimit > 0 && len(p) > rateLimitChunk {
+		p = p[:rateLimitChunk]
+	}
+
+	n, err := t.reader.Read(p)
+	t.written += int64(n)
+	if n > 0 && t.options.progress != nil {
+		t.options.progress(t.written, t.total)
+	}
+
+	if t.options.rateLimit > 0 && n > 0 {
+		expected := time.Duration(float64(t.written) / float64(t.options.rateLimit) * float64(time.Second))
+		if wait := expected - time.Since(t.start); wait > 0 {
+			select {
+			case <-t.ctx.Done():
+				return n, t.ctx.Err()
+			case <-time.After(wait):
+			}
+		}
+	}
+
+	return n, err
+}
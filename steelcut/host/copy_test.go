@@ -0,0 +1,348 @@
+package host
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pkg/sftp"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+	"github.com/steelcutops/steelcut/steelcut/filemanager"
+)
+
+// MockSFTPCommandManager serves SFTP from an in-memory filesystem.
+type MockSFTPCommandManager struct {
+	MockCommandManager
+	handlers sftp.Handlers
+	modes    *modeRecorder
+	readOnly bool
+}
+
+// modeRecorder keeps file modes set with chmod, which the in-memory
+// filesystem ignores, carries them across renames and reports them from stat.
+type modeRecorder struct {
+	sftp.FileCmder
+	sftp.FileLister
+	mu    sync.Mutex
+	modes map[string]os.FileMode
+}
+
+func (m *modeRecorder) Filecmd(r *sftp.Request) error {
+	if r.Method == "Setstat" && r.AttrFlags().Permissions {
+		m.mu.Lock()
+		m.modes[r.Filepath] = r.Attributes().FileMode().Perm()
+		m.mu.Unlock()
+		// The in-memory filesystem cannot set attributes on directories
+		return nil
+	}
+	if err := m.FileCmder.Filecmd(r); err != nil {
+		return err
+	}
+	if r.Method == "Rename" || r.Method == "PosixRename" {
+		m.mu.Lock()
+		if mode, ok := m.modes[r.Filepath]; ok {
+			m.modes[r.Target] = mode
+			delete(m.modes, r.Filepath)
+		}
+		m.mu.Unlock()
+	}
+	return nil
+}
+
+func (m *modeRecorder) Filelist(r *sftp.Request) (sftp.ListerAt, error) {
+	lister, err := m.FileLister.Filelist(r)
+	if err != nil || r.Method != "Stat" {
+		return lister, err
+	}
+	return &modeLister{ListerAt: lister, mode: m.mode(r.Filepath)}, nil
+}
+
+func (m *modeRecorder) mode(path string) os.FileMode {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.modes[path]
+}
+
+type modeLister struct {
+	sftp.ListerAt
+	mode os.FileMode
+}
+
+func (l *modeLister) ListAt(infos []os.FileInfo, offset int64) (int, error) {
+	n, err := l.ListerAt.ListAt(infos, offset)
+	if l.mode != 0 {
+		for i := 0; i < n; i++ {
+			infos[i] = modeInfo{FileInfo: infos[i], mode: l.mode}
+		}
+	}
+	return n, err
+}
+
+type modeInfo struct {
+	os.FileInfo
+	mode os.FileMode
+}
+
+func (i modeInfo) Mode() os.FileMode {
+	return i.FileInfo.Mode()&^os.ModePerm | i.mode
+}
+
+func newMockSFTPHost(hostname string) (*Host, *MockSFTPCommandManager) {
+	handlers := sftp.InMemHandler()
+	modes := &modeRecorder{
+		FileCmder:  handlers.FileCmd,
+		FileLister: handlers.FileList,
+		modes:      make(map[string]os.FileMode),
+	}
+	handlers.FileCmd = modes
+	handlers.FileList = modes
+
+	manager := &MockSFTPCommandManager{handlers: handlers, modes: modes}
+	return &Host{
+		Hostname:       hostname,
+		CommandManager: manager,
+		FileManager:    &filemanager.UnixFileManager{CommandManager: manager},
+	}, manager
+}
+
+func (m *MockSFTPCommandManager) OpenSFTP(ctx context.Context) (*cm.SFTPClient, error) {
+	serverConn, clientConn := net.Pipe()
+	server := sftp.NewRequestServer(serverConn, m.handlers)
+	go server.Serve()
+
+	client, err := sftp.NewClientPipe(clientConn, clientConn)
+	if err != nil {
+		return nil, err
+	}
+	session := cm.NewSFTPClient(client, server)
+	session.ReadOnly = m.readOnly
+	return session, nil
+}
+
+func writeMockFile(t *testing.T, h *Host, path string, data []byte, mode os.FileMode) {
+	t.Helper()
+	client, err := h.CommandManager.(cm.SFTPProvider).OpenSFTP(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to open SFTP: %v", err)
+	}
+	defer client.Close()
+
+	f, err := client.Create(path)
+	if err != nil {
+		t.Fatalf("Failed to create %s: %v", path, err)
+	}
+	if _, err := f.Write(data); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+	f.Close()
+	if err := client.Chmod(path, mode); err != nil {
+		t.Fatalf("Failed to chmod %s: %v", path, err)
+	}
+}
+
+func readMockFile(t *testing.T, h *Host, path string) []byte {
+	t.Helper()
+	client, err := h.CommandManager.(cm.SFTPProvider).OpenSFTP(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to open SFTP: %v", err)
+	}
+	defer client.Close()
+
+	f, err := client.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open %s: %v", path, err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", path, err)
+	}
+	return data
+}
+
+func TestCopyBetween(t *testing.T) {
+	src, _ := newMockSFTPHost("host-a")
+	dst, dstManager := newMockSFTPHost("host-b")
+
+	data := bytes.Repeat([]byte("steelcut\x00\xff"), 20000)
+	writeMockFile(t, src, "/payload.bin", data, 0750)
+
+	var lastWritten, lastTotal int64
+	err := CopyBetween(context.Background(), src, "/payload.bin", dst, "/copy.bin",
+		WithProgress(func(written, total int64) {
+			lastWritten, lastTotal = written, total
+		}))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	copied := readMockFile(t, dst, "/copy.bin")
+	if !bytes.Equal(copied, data) {
+		t.Errorf("Expected %d bytes to arrive intact, got %d bytes", len(data), len(copied))
+	}
+	if mode := dstManager.modes.mode("/copy.bin"); mode != 0750 {
+		t.Errorf("Expected mode 0750 to be preserved, got: %o", mode)
+	}
+	if lastWritten != int64(len(data)) || lastTotal != int64(len(data)) {
+		t.Errorf("Expected final progress %d/%d, got %d/%d", len(data), len(data), lastWritten, lastTotal)
+	}
+}
+
+func TestCopyBetweenReadOnlyDestination(t *testing.T) {
+	src, _ := newMockSFTPHost("host-a")
+	dst, dstManager := newMockSFTPHost("host-b")
+	dstManager.readOnly = true
+
+	writeMockFile(t, src, "/file", []byte("data"), 0644)
+
+	err := CopyBetween(context.Background(), src, "/file", dst, "/file")
+	if !errors.Is(err, cm.ErrReadOnlyMode) {
+		t.Errorf("Expected ErrReadOnlyMode, got: %v", err)
+	}
+}
+
+func TestCopyBetweenCancelled(t *testing.T) {
+	src, _ := newMockSFTPHost("host-a")
+	dst, _ := newMockSFTPHost("host-b")
+	writeMockFile(t, src, "/file", bytes.Repeat([]byte("x"), 256*1024), 0644)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err := CopyBetween(ctx, src, "/file", dst, "/file",
+		WithRateLimit(64*1024),
+		WithProgress(func(written, total int64) { cancel() }))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got: %v", err)
+	}
+}
+
+func TestCopyFile(t *testing.T) {
+	h, manager := newMockSFTPHost("host-a")
+	local := filepath.Join(t.TempDir(), "app.conf")
+	if err := os.WriteFile(local, []byte("port=8080\n"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(local, 0o640); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.CopyFile(local, "/etc/app/app.conf"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if data := readMockFile(t, h, "/etc/app/app.conf"); string(data) != "port=8080\n" {
+		t.Errorf("Unexpected remote content: %q", data)
+	}
+	if mode := manager.modes.mode("/etc/app/app.conf"); mode != 0o640 {
+		t.Errorf("Expected mode 0640 to be preserved, got: %o", mode)
+	}
+}
+
+func TestCopyFileDirectory(t *testing.T) {
+	h, manager := newMockSFTPHost("host-a")
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "bin"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "bin", "run.sh"), []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "README"), []byte("hello\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(filepath.Join(root, "bin"), 0o750); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.CopyFile(root, "/opt/app"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if data := readMockFile(t, h, "/opt/app/bin/run.sh"); string(data) != "#!/bin/sh\n" {
+		t.Errorf("Unexpected content of run.sh: %q", data)
+	}
+	if data := readMockFile(t, h, "/opt/app/README"); string(data) != "hello\n" {
+		t.Errorf("Unexpected content of README: %q", data)
+	}
+	if mode := manager.modes.mode("/opt/app/bin/run.sh"); mode != 0o755 {
+		t.Errorf("Expected run.sh to keep mode 0755, got: %o", mode)
+	}
+	if mode := manager.modes.mode("/opt/app/bin"); mode != 0o750 {
+		t.Errorf("Expected bin to keep mode 0750, got: %o", mode)
+	}
+}
+
+func TestCopyFileReadOnly(t *testing.T) {
+	h, manager := newMockSFTPHost("host-a")
+	manager.readOnly = true
+	local := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(local, []byte("data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.CopyFile(local, "/file"); !errors.Is(err, cm.ErrReadOnlyMode) {
+		t.Errorf("Expected ErrReadOnlyMode, got: %v", err)
+	}
+}
+
+func TestDownloadFile(t *testing.T) {
+	h, _ := newMockSFTPHost("host-a")
+	writeMockFile(t, h, "/app.log", []byte("started\n"), 0o600)
+
+	local := filepath.Join(t.TempDir(), "logs", "app.log")
+	if err := h.DownloadFile("/app.log", local); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	data, err := os.ReadFile(local)
+	if err != nil || string(data) != "started\n" {
+		t.Errorf("Unexpected local content %q, %v", data, err)
+	}
+	if info, err := os.Stat(local); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected mode 0600 to be preserved, got %v, %v", info.Mode().Perm(), err)
+	}
+}
+
+func TestDownloadFileErrors(t *testing.T) {
+	h, _ := newMockSFTPHost("host-a")
+	if err := h.CopyFile(t.TempDir(), "/etc/app"); err != nil {
+		t.Fatalf("Failed to create remote directory: %v", err)
+	}
+	local := filepath.Join(t.TempDir(), "out")
+
+	if err := h.DownloadFile("/missing", local); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected os.ErrNotExist for a missing file, got: %v", err)
+	}
+	if err := h.DownloadFile("/etc/app", local); err == nil || !strings.Contains(err.Error(), "is a directory") {
+		t.Errorf("Expected a directory error, got: %v", err)
+	}
+	if _, err := os.Stat(local); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected no local file after failed downloads, got %v", err)
+	}
+}
+
+func TestCopyFileVerified(t *testing.T) {
+	local := filepath.Join(t.TempDir(), "app.bin")
+	if err := os.WriteFile(local, []byte("payload"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// SHA-256 of "payload"
+	const sum = "239f59ed55e737c77147cf55ad0c1b030b6d7ee748a7426952f9b852d5a935e5"
+
+	h, manager := newMockSFTPHost("host-a")
+	manager.Outputs = map[string]string{"sha256sum": sum + "  /app.bin\n"}
+	if err := h.CopyFileVerified(local, "/app.bin"); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+
+	h, manager = newMockSFTPHost("host-b")
+	h.OSType = Darwin
+	manager.Outputs = map[string]string{"shasum": strings.Repeat("0", 64) + "  /app.bin\n"}
+	if err := h.CopyFileVerified(local, "/app.bin"); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Expected ErrChecksumMismatch, got: %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package host
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// diagnosticArtifact is a single named piece of output in a diagnostics bundle.
+type diagnosticArtifact struct {
+	Name   string
+	Config commandmanager.CommandConfig
+}
+
+var diagnosticArtifacts = []diagnosticArtifact{
+	{Name: "os-release.txt", Config: commandmanager.CommandConfig{Command: "cat", Args: []string{"/etc/os-release"}}},
+	{Name: "uname.txt", Config: commandmanager.CommandConfig{Command: "uname", Args: []string{"-a"}}},
+	{Name: "uptime.txt", Config: commandmanager.CommandConfig{Command: "uptime"}},
+	{Name: "dmesg.txt", Config: commandmanager.CommandConfig{Command: "dmesg", Sudo: true}},
+	{Name: "journalctl.txt", Config: commandmanager.CommandConfig{Command: "journalctl", Args: []string{"-n", "1000", "--no-pager"}, Sudo: true}},
+	{Name: "df.txt", Config: commandmanager.CommandConfig{Command: "df", Args: []string{"-h"}}},
+	{Name: "free.txt", Config: commandmanager.CommandConfig{Command: "free", Args: []string{"-m"}}},
+	{Name: "ps.txt", Config: commandmanager.CommandConfig{Command: "ps", Args: []string{"aux"}}},
+	{Name: "failed-services.txt", Config: commandmanager.CommandConfig{Command: "systemctl", Args: []string{"--failed", "--no-pager"}}},
+}
+
+// CollectDiagnostics gathers logs and system information from the host and
+// streams them to w as a gzip-compressed tar archive. A failing collection is
+// recorded as a "<name>.err" entry instead of aborting the bundle. If ctx is
+// done, collection stops and the artifacts gathered so far are written,
+// followed by ctx.Err().
+func (h *Host) CollectDiagnostics(ctx context.Context, w io.Writer) error {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+
+	for _, artifact := range diagnosticArtifacts {
+		name := artifact.Name
+		result, err := h.CommandManager.Run(ctx, artifact.Config)
+		if ctx.Err() != nil {
+			break
+		}
+
+		var content string
+		var cmdErr *commandmanager.CommandError
+		switch {
+		case errors.As(err, &cmdErr):
+			name += ".err"
+			content = fmt.Sprintf("exit code %d\n%s", cmdErr.ExitCode, cmdErr.Stderr)
+		case err != nil:
+			name += ".err"
+			content = fmt.Sprintf("%v\n", err)
+		default:
+			content = result.STDOUT
+		}
+
+		if err := writeTarEntry(tw, name, h.redact(content)); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gw.Close(); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// redact masks the host's credentials in s, and anything else that looks
+// like a secret, such as passwords and tokens in the arguments listed by ps.
+func (h *Host) redact(s string) string {
+	for _, secret := range []string{h.Password, h.SudoPassword, h.KeyPassphrase} {
+		if secret != "" {
+			s = strings.ReplaceAll(s, secret, "[REDACTED]")
+		}
+	}
+	return commandmanager.RedactSecrets(s)
+}
+
+func writeTarEntry(tw *tar.Writer, name, content string) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(content)),
+		ModTime: time.Now(),
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("failed to write header for %s: %w", name, err)
+	}
+	if _, err := tw.Write([]byte(content)); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package host
+
+import (
+	"context"
+	"errors"
+
+	"github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// CanSudo reports whether the host's user can run commands as root with the
+// configured escalation strategy, such as sudo with the sudo password, doas or
+// pkexec. It runs "true" as root, so the strategy authenticates as it would
+// for any other privileged command. It returns false with a nil error when the
+// tool refuses the user, and an error for a rejected password, a connection
+// failure or anything else unexpected.
+func (h *Host) CanSudo() (bool, error) {
+	result, err := h.CommandManager.Run(context.TODO(), commandmanager.CommandConfig{
+		Command: "true",
+		Sudo:    true,
+	})
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, commandmanager.ErrSudoAuth):
+		return false, err
+	case result.ExitCode != 0:
+		// true cannot fail, so the escalation tool refused to run it
+		return false, nil
+	}
+	return false, err
+}
+
+// EscalationTool returns the name of the tool used for privileged commands,
+// such as "sudo" or "doas". An explicitly configured strategy is reported as
+// is; otherwise the host is probed, preferring sudo over doas.
+func (h *Host) EscalationTool() (string, error) {
+	if h.Escalation != nil {
+		return h.Escalation.Name(), nil
+	}
+
+	strategy, err := commandmanager.DetectEscalation(context.TODO(), h.CommandManager)
+	if err != nil {
+		return "", err
+	}
+	return strategy.Name(), nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log/slog"
 	"net"
+	"strconv"
 	"strings"
 	"time"
 
@@ -22,9 +23,41 @@ import (
 type Host struct {
 	common.Credentials
 
-	OSType    OSType
-	SSHClient SSHClient
-	Hostname  string
+	OSType         OSType
+	OSVersion      string
+	SSHClient      SSHClient
+	Hostname       string
+	Port           int
+	AddressFamily  string
+	Escalation     commandmanager.EscalationStrategy
+	MaxOutputBytes int64
+	ReadOnly       bool
+	StrictOutput   bool
+	KeepAlive      time.Duration
+	DialAttempts   int
+	DialBackoff    time.Duration
+	ConnectTimeout time.Duration
+	CommandTimeout time.Duration
+	Logger         *slog.Logger
+	RedactCommands bool
+
+	HostKeyCallback ssh.HostKeyCallback
+	AgentForwarding bool
+	Breaker         *commandmanager.CircuitBreaker
+	History         *commandmanager.CommandHistory
+	Pool            *commandmanager.ConnectionPool
+
+	// jumpHost holds the settings of the bastion set with WithJumpHost.
+	jumpHost *Host
+
+	// sshConfig holds the settings loaded with WithSSHConfig.
+	sshConfig *commandmanager.SSHHostConfig
+
+	// identityFiles are the private keys set with WithIdentityFile.
+	identityFiles []string
+
+	// optionErr records an invalid HostOption so NewHost can report it.
+	optionErr error
 
 	PackageManager packagemanager.PackageManager
 	NetworkManager networkmanager.NetworkManager
@@ -50,14 +83,38 @@ func (c RealSSHClient) Dial(network, addr string, config *ssh.ClientConfig, time
 		return nil, err
 	}
 
-	// Create an SSH client connection using the underlying network connection
+	// Create an SSH client connection using the underlying network connection,
+	// giving up if the handshake stalls past the timeout
+	if timeout > 0 {
+		conn.SetDeadline(time.Now().Add(timeout))
+	}
 	sshConn, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
 	if err != nil {
+		conn.Close()
 		return nil, err
 	}
+	conn.SetDeadline(time.Time{})
 	return ssh.NewClient(sshConn, chans, reqs), nil
 }
 
+// Close releases the Host's pooled SSH connection, if connection reuse is
+// enabled. The Host stays usable and dials again when next needed.
+func (h *Host) Close() error {
+	if h.Pool == nil {
+		return nil
+	}
+	return h.Pool.Close()
+}
+
+// logger returns the Host's Logger, defaulting to one that discards
+// everything.
+func (h *Host) logger() *slog.Logger {
+	if h.Logger == nil {
+		return commandmanager.DiscardLogger()
+	}
+	return h.Logger
+}
+
 // DefaultOSDetector is a default implementation of the OSDetector interface.
 type DefaultOSDetector struct{}
 
@@ -85,25 +142,25 @@ func (h *Host) DetermineOS(ctx context.Context) (OSType, error) {
 	}
 
 	result, err := h.CommandManager.Run(ctx, cmdConfig)
-	slog.Debug("Determine OS result", "hostname", h.Hostname, "result", result, "error", err)
+	h.logger().Debug("Determine OS result", "hostname", h.Hostname, "result", result, "error", err)
 	if err != nil {
-		slog.Error("Determine OS error", "hostname", h.Hostname, "error", err)
+		h.logger().Debug("Determine OS error", "hostname", h.Hostname, "error", err)
 		return Unknown, fmt.Errorf("failed to run uname: %w", err)
 	}
 	osName := strings.TrimSpace(result.STDOUT)
 
-	slog.Debug("Determining OS", "hostname", h.Hostname, "osname", osName)
+	h.logger().Debug("Determining OS", "hostname", h.Hostname, "osname", osName)
 
 	switch osName {
 	case "Linux":
-		slog.Debug("Detected Linux", "hostname", h.Hostname)
+		h.logger().Debug("Detected Linux", "hostname", h.Hostname)
 		return h.detectLinuxType(ctx)
 	case "Darwin":
-		slog.Debug("Detected Darwin", "hostname", h.Hostname)
+		h.logger().Debug("Detected Darwin", "hostname", h.Hostname)
 		h.OSType = Darwin
 		return Darwin, nil
 	default:
-		slog.Debug("Detected Unknown", "hostname", h.Hostname)
+		h.logger().Debug("Detected Unknown", "hostname", h.Hostname)
 		return Unknown, fmt.Errorf("unknown OS: %s", osName)
 	}
 }
@@ -117,33 +174,110 @@ func (h *Host) detectLinuxType(ctx context.Context) (OSType, error) {
 	}
 
 	result, err := h.CommandManager.Run(ctx, cmdConfig)
-	slog.Debug("Detecting Linux type", "hostname", h.Hostname, "result", result, "error", err)
+	h.logger().Debug("Detecting Linux type", "hostname", h.Hostname, "result", result, "error", err)
 	if err != nil {
 		return Unknown, fmt.Errorf("failed to retrieve OS release info: %w", err)
 	}
 
 	osRelease := result.STDOUT
-	slog.Debug("Detecting Linux type", "hostname", h.Hostname, "osrelease", osRelease)
-
-	if strings.Contains(osRelease, "ID=ubuntu") {
-		return LinuxUbuntu, nil
-	} else if strings.Contains(osRelease, "ID=debian") {
-		return LinuxDebian, nil
-	} else if strings.Contains(osRelease, "ID=fedora") {
-		return LinuxFedora, nil
-	} else if strings.Contains(osRelease, "ID=rhel") {
-		return LinuxRedHat, nil
-	} else if strings.Contains(osRelease, "ID=centos") {
-		return LinuxCentOS, nil
-	} else if strings.Contains(osRelease, "ID=arch") {
-		return LinuxArch, nil
-	} else if strings.Contains(osRelease, "ID=opensuse") {
-		return LinuxOpenSUSE, nil
-	}
+	h.logger().Debug("Detecting Linux type", "hostname", h.Hostname, "osrelease", osRelease)
 
+	release := ParseOSRelease(osRelease)
+	h.OSVersion = release.VersionID
+
+	if osType := release.OSType(); osType != Unknown {
+		return osType, nil
+	}
 	return Unknown, fmt.Errorf("unsupported Linux distribution detected on host: %s osRelease: %s", h.Hostname, osRelease)
 }
 
+// OSRelease holds the fields of /etc/os-release that identify a Linux
+// distribution.
+type OSRelease struct {
+	ID        string
+	IDLike    []string // distributions this one derives from, closest first
+	VersionID string
+}
+
+// ParseOSRelease parses the content of /etc/os-release.
+func ParseOSRelease(content string) OSRelease {
+	fields := parseOSRelease(content)
+	return OSRelease{
+		ID:        strings.ToLower(fields["ID"]),
+		IDLike:    strings.Fields(strings.ToLower(fields["ID_LIKE"])),
+		VersionID: fields["VERSION_ID"],
+	}
+}
+
+// OSType returns the supported distribution r is, or derives from according
+// to ID_LIKE, so that e.g. Linux Mint and Pop!_OS are treated as Ubuntu and
+// Rocky Linux as Red Hat. It returns Unknown if there is none.
+func (r OSRelease) OSType() OSType {
+	for _, id := range append([]string{r.ID}, r.IDLike...) {
+		if osType := osTypeByID(id); osType != Unknown {
+			return osType
+		}
+	}
+	return Unknown
+}
+
+// osTypeByID returns the OSType for an os-release ID or ID_LIKE entry.
+func osTypeByID(id string) OSType {
+	switch id {
+	case "ubuntu":
+		return LinuxUbuntu
+	case "debian":
+		return LinuxDebian
+	case "fedora":
+		return LinuxFedora
+	case "rhel":
+		return LinuxRedHat
+	case "centos":
+		return LinuxCentOS
+	case "arch":
+		return LinuxArch
+	case "alpine":
+		return LinuxAlpine
+	case "suse", "sles":
+		return LinuxOpenSUSE
+	}
+	if strings.HasPrefix(id, "opensuse") {
+		return LinuxOpenSUSE
+	}
+	return Unknown
+}
+
+// parseOSRelease parses the KEY=value lines of /etc/os-release, removing
+// any quotes around the values.
+func parseOSRelease(content string) map[string]string {
+	release := make(map[string]string)
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		} else {
+			value = strings.Trim(value, `"'`)
+		}
+		release[key] = value
+	}
+	return release
+}
+
+// majorVersion returns the leading number of an os-release VERSION_ID, or 0
+// if it has none.
+func majorVersion(version string) int {
+	major, _, _ := strings.Cut(version, ".")
+	n, _ := strconv.Atoi(major)
+	return n
+}
+
 // String method provides the string representation of the OSType.
 func (o OSType) String() string {
 	return [...]string{
@@ -156,5 +290,35 @@ func (o OSType) String() string {
 		"Linux_CentOS",
 		"Linux_Arch",
 		"Linux_OpenSUSE",
+		"Linux_Alpine",
 	}[o]
 }
+
+// ParseOSType returns the OSType named by name, either as String reports it,
+// e.g. "Linux_Ubuntu", or by its os-release ID, e.g. "ubuntu", "rhel" or
+// "darwin". Case is ignored.
+func ParseOSType(name string) (OSType, error) {
+	id := strings.ToLower(name)
+	if osType := osTypeByID(id); osType != Unknown {
+		return osType, nil
+	}
+	switch id {
+	case "redhat":
+		return LinuxRedHat, nil
+	case "darwin", "macos":
+		return Darwin, nil
+	}
+	for o := LinuxUbuntu; o <= LinuxAlpine; o++ {
+		if strings.EqualFold(name, o.String()) {
+			return o, nil
+		}
+	}
+	return Unknown, fmt.Errorf("unknown OS: %q", name)
+}
+
+// LastCommand returns the most recent command run on the Host. It requires
+// WithCommandHistory and returns commandmanager.ErrNoCommandHistory until a
+// command has run.
+func (h *Host) LastCommand() (commandmanager.CommandTrace, error) {
+	return h.History.Last()
+}
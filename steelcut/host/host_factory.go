@@ -3,9 +3,10 @@ package host
 import (
 	"context"
 	"fmt"
-	"log/slog"
+	"net"
 	"os"
 	"os/user"
+	"strconv"
 
 	"github.com/steelcutops/steelcut/steelcut/commandmanager"
 	"github.com/steelcutops/steelcut/steelcut/filemanager"
@@ -17,19 +18,86 @@ import (
 
 func NewHost(hostname string, options ...HostOption) (*Host, error) {
 	ch := &Host{}
-	ch.Hostname = hostname
+	var err error
+	if ch.Hostname, ch.Port, err = splitHostPort(hostname); err != nil {
+		return nil, err
+	}
 
 	// Apply each HostOption
 	for _, option := range options {
 		option(ch)
 	}
+	if ch.optionErr != nil {
+		return nil, ch.optionErr
+	}
+
+	cmdManager, err := newCommandManager(ch)
+	if err != nil {
+		return nil, err
+	}
+	ch.CommandManager = cmdManager
+
+	osType := ch.OSType
+	if osType == Unknown {
+		if osType, err = ch.DetermineOS(context.TODO()); err != nil {
+			return nil, err
+		}
+	}
+
+	// Use doas on hosts without sudo unless a strategy was chosen explicitly.
+	// The host is only probed once a privileged command runs, and sudo keeps
+	// the default, which is left unset so that commands with input can still
+	// switch to sudo askpass.
+	if ch.Escalation == nil {
+		cmdManager.EscalationDetector = &commandmanager.EscalationDetector{}
+	}
+
+	switch osType {
+	case LinuxUbuntu, LinuxDebian, LinuxFedora, LinuxRedHat, LinuxCentOS, LinuxArch, LinuxOpenSUSE, LinuxAlpine:
+		configureLinuxHost(ch, ch.CommandManager, osType)
+
+	case Darwin:
+		configureMacHost(ch, ch.CommandManager)
+	default:
+		return nil, fmt.Errorf("unsupported operating system: %s", osType)
+	}
+
+	return ch, nil
+}
+
+// newCommandManager fills in defaults for the Host's connection settings and
+// returns a command manager for it, including any jump hosts.
+func newCommandManager(ch *Host) (*commandmanager.UnixCommandManager, error) {
+	if !commandmanager.ValidAddressFamily(ch.AddressFamily) {
+		return nil, fmt.Errorf("invalid address family: %s", ch.AddressFamily)
+	}
 
 	// If SSHClient hasn't been set, set it to the default SSHClient
 	if ch.SSHClient == nil {
-		slog.Debug("SSHClient is nil, setting to default SSHClient")
+		ch.logger().Debug("SSHClient is nil, setting to default SSHClient")
 		ch.SSHClient = &RealSSHClient{}
 	} else {
-		slog.Debug("SSHClient is not nil, using provided SSHClient", "sshclient", ch.SSHClient)
+		ch.logger().Debug("SSHClient is not nil, using provided SSHClient", "sshclient", ch.SSHClient)
+	}
+
+	// Resolve aliases and fill in settings not given explicitly from ssh_config
+	address := ch.Hostname
+	port := ch.Port
+	var identityFiles []string
+	if config := ch.sshConfig; config != nil {
+		if config.HostName != "" {
+			address = config.HostName
+		}
+		if ch.Credentials.User == "" {
+			ch.Credentials.User = config.User
+		}
+		if port == 0 {
+			port = config.Port
+		}
+		identityFiles = config.IdentityFiles
+	}
+	if len(ch.identityFiles) > 0 {
+		identityFiles = ch.identityFiles
 	}
 
 	// If User hasn't been set, set it to the username of the current user
@@ -54,29 +122,69 @@ func NewHost(hostname string, options ...HostOption) (*Host, error) {
 		}
 	}
 
-	// Initializing the CommandManager with the new interface
-	ch.CommandManager = &commandmanager.UnixCommandManager{
-		Hostname:    hostname,
-		Credentials: ch.Credentials,
-		SSHClient:   ch.SSHClient,
+	cmdManager := &commandmanager.UnixCommandManager{
+		Hostname:       address,
+		Credentials:    ch.Credentials,
+		SSHClient:      ch.SSHClient,
+		AddressFamily:  ch.AddressFamily,
+		Escalation:     ch.Escalation,
+		MaxOutputBytes: ch.MaxOutputBytes,
+		ReadOnly:       ch.ReadOnly,
+		StrictOutput:   ch.StrictOutput,
+
+		HostKeyCallback: ch.HostKeyCallback,
+		Breaker:         ch.Breaker,
+		History:         ch.History,
+		Pool:            ch.Pool,
+		Port:            port,
+		KeepAlive:       ch.KeepAlive,
+		DialAttempts:    ch.DialAttempts,
+		DialBackoff:     ch.DialBackoff,
+		IdentityFiles:   identityFiles,
+		ConnectTimeout:  ch.ConnectTimeout,
+		CommandTimeout:  ch.CommandTimeout,
+		Logger:          ch.Logger,
+		RedactCommands:  ch.RedactCommands,
+		AgentForwarding: ch.AgentForwarding,
 	}
 
-	osType, err := ch.DetermineOS(context.TODO())
-	if err != nil {
-		return nil, err
+	if ch.jumpHost != nil {
+		// The jump host is reached the same way unless it was given its own client
+		if ch.jumpHost.SSHClient == nil {
+			ch.jumpHost.SSHClient = ch.SSHClient
+		}
+		// Idle bastion connections are dropped just the same
+		if ch.jumpHost.KeepAlive == 0 {
+			ch.jumpHost.KeepAlive = ch.KeepAlive
+		}
+		if ch.jumpHost.ConnectTimeout == 0 {
+			ch.jumpHost.ConnectTimeout = ch.ConnectTimeout
+		}
+		if ch.jumpHost.Logger == nil {
+			ch.jumpHost.Logger = ch.Logger
+			ch.jumpHost.RedactCommands = ch.RedactCommands
+		}
+		jump, err := newCommandManager(ch.jumpHost)
+		if err != nil {
+			return nil, fmt.Errorf("jump host %s: %w", ch.jumpHost.Hostname, err)
+		}
+		cmdManager.JumpHost = jump
 	}
+	return cmdManager, nil
+}
 
-	switch osType {
-	case LinuxUbuntu, LinuxDebian, LinuxFedora, LinuxRedHat, LinuxCentOS, LinuxArch, LinuxOpenSUSE:
-		configureLinuxHost(ch, ch.CommandManager, osType)
-
-	case Darwin:
-		configureMacHost(ch, ch.CommandManager)
-	default:
-		return nil, fmt.Errorf("unsupported operating system: %s", osType)
+// splitHostPort splits an optional port from hostname, as in
+// "example.com:2222" or "[::1]:2222". A bare IPv6 address has no port.
+func splitHostPort(hostname string) (string, int, error) {
+	host, portStr, err := net.SplitHostPort(hostname)
+	if err != nil {
+		return hostname, 0, nil
 	}
-
-	return ch, nil
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 1 || port > 65535 {
+		return "", 0, fmt.Errorf("invalid port in %q", hostname)
+	}
+	return host, port, nil
 }
 
 func configureLinuxHost(ch *Host, cmdManager commandmanager.CommandManager, osType OSType) {
@@ -88,9 +196,18 @@ func configureLinuxHost(ch *Host, cmdManager commandmanager.CommandManager, osTy
 	case LinuxFedora:
 		pkgManager = &packagemanager.DnfPackageManager{CommandManager: cmdManager}
 	case LinuxRedHat, LinuxCentOS:
-		pkgManager = &packagemanager.YumPackageManager{CommandManager: cmdManager}
+		// dnf replaced yum in RHEL 8
+		if majorVersion(ch.OSVersion) >= 8 {
+			pkgManager = &packagemanager.DnfPackageManager{CommandManager: cmdManager}
+		} else {
+			pkgManager = &packagemanager.YumPackageManager{CommandManager: cmdManager}
+		}
 	case LinuxAlpine:
 		pkgManager = &packagemanager.ApkPackageManager{CommandManager: cmdManager}
+	case LinuxArch:
+		pkgManager = &packagemanager.PacmanPackageManager{CommandManager: cmdManager}
+	case LinuxOpenSUSE:
+		pkgManager = &packagemanager.ZypperPackageManager{CommandManager: cmdManager}
 
 	default:
 		pkgManager = nil
@@ -99,16 +216,16 @@ func configureLinuxHost(ch *Host, cmdManager commandmanager.CommandManager, osTy
 	ch.CommandManager = cmdManager
 	ch.FileManager = &filemanager.UnixFileManager{CommandManager: cmdManager}
 	ch.HostManager = &hostmanager.UnixHostManager{CommandManager: cmdManager}
-	ch.NetworkManager = &networkmanager.UnixNetworkManager{CommandManager: cmdManager}
+	ch.NetworkManager = &networkmanager.UnixNetworkManager{CommandManager: cmdManager, AddressFamily: ch.AddressFamily}
 	ch.ServiceManager = &servicemanager.LinuxServiceManager{CommandManager: cmdManager}
 	ch.PackageManager = pkgManager
 }
 
 func configureMacHost(ch *Host, cmdManager commandmanager.CommandManager) {
 	ch.CommandManager = cmdManager
-	ch.FileManager = &filemanager.UnixFileManager{CommandManager: cmdManager}
+	ch.FileManager = &filemanager.DarwinFileManager{UnixFileManager: filemanager.UnixFileManager{CommandManager: cmdManager}}
 	ch.HostManager = &hostmanager.UnixHostManager{CommandManager: cmdManager}
-	ch.NetworkManager = &networkmanager.UnixNetworkManager{CommandManager: cmdManager}
+	ch.NetworkManager = &networkmanager.UnixNetworkManager{CommandManager: cmdManager, AddressFamily: ch.AddressFamily}
 	ch.ServiceManager = &servicemanager.DarwinServiceManager{CommandManager: cmdManager}
 	ch.PackageManager = &packagemanager.BrewPackageManager{CommandManager: cmdManager}
 }
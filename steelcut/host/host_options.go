@@ -1,5 +1,19 @@
 package host
 
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
 type HostOption func(*Host)
 
 // WithUser returns a HostOption that sets the user for a Host.
@@ -23,7 +37,21 @@ func WithKeyPassphrase(keyPassphrase string) HostOption {
 	}
 }
 
-// WithOS returns a HostOption that sets the OS for a Host.
+// WithPort returns a HostOption that sets the SSH port for a Host. It takes
+// precedence over a port given in the hostname, as in "example.com:2222", and
+// over ssh_config; 22 is used when no port is set anywhere.
+func WithPort(port int) HostOption {
+	return func(host *Host) {
+		if port < 1 || port > 65535 {
+			host.optionErr = fmt.Errorf("invalid port: %d", port)
+			return
+		}
+		host.Port = port
+	}
+}
+
+// WithOS returns a HostOption that sets the OS for a Host, so that NewHost
+// skips detecting it.
 func WithOS(os OSType) HostOption {
 	return func(host *Host) {
 		host.OSType = os
@@ -43,3 +71,266 @@ func WithSSHClient(client SSHClient) HostOption {
 		host.SSHClient = client
 	}
 }
+
+// WithPrivilegeEscalation returns a HostOption that sets how privileged
+// commands are run on a Host, e.g. commandmanager.PkexecStrategy{} on
+// polkit-based desktops. Sudo is used when no strategy is set, switching to
+// commandmanager.SudoAskpassStrategy for commands with input; passing
+// commandmanager.SudoStrategy{} keeps sudo -S on hosts whose temporary
+// directory does not allow executing files.
+func WithPrivilegeEscalation(strategy commandmanager.EscalationStrategy) HostOption {
+	return func(host *Host) {
+		host.Escalation = strategy
+	}
+}
+
+// WithAddressFamily returns a HostOption that selects the IP address family
+// used to reach a Host: "ip4", "ip6", or "auto" to race both and use
+// whichever connects first.
+func WithAddressFamily(family string) HostOption {
+	return func(host *Host) {
+		host.AddressFamily = family
+	}
+}
+
+// WithMaxOutputBytes returns a HostOption that caps how much command output
+// is captured from a Host. A command exceeding the cap is killed and
+// commandmanager.ErrOutputTooLarge is returned with the truncated output.
+func WithMaxOutputBytes(n int64) HostOption {
+	return func(host *Host) {
+		host.MaxOutputBytes = n
+	}
+}
+
+// WithReadOnly returns a HostOption that puts a Host in read-only mode. Any
+// command that could change the host fails with commandmanager.ErrReadOnlyMode
+// without being executed, while reporting, list and status operations still
+// run. Use it for audits and dry inspection of production hosts.
+func WithReadOnly() HostOption {
+	return func(host *Host) {
+		host.ReadOnly = true
+	}
+}
+
+// WithExpectedFingerprint returns a HostOption that pins the Host's key to a
+// single SHA256 fingerprint, with or without the "SHA256:" prefix. Connections
+// presenting any other key fail with commandmanager.ErrHostKeyMismatch. A
+// malformed fingerprint makes NewHost return an error.
+func WithExpectedFingerprint(sha256 string) HostOption {
+	return func(host *Host) {
+		callback, err := commandmanager.FingerprintCallback(sha256)
+		if err != nil {
+			host.optionErr = err
+			return
+		}
+		host.HostKeyCallback = callback
+	}
+}
+
+// WithKnownHosts returns a HostOption that verifies the Host's key against
+// the known_hosts file at path instead of ~/.ssh/known_hosts. An unreadable
+// file makes NewHost return an error.
+func WithKnownHosts(path string) HostOption {
+	return func(host *Host) {
+		callback, err := commandmanager.KnownHostsCallback(path)
+		if err != nil {
+			host.optionErr = err
+			return
+		}
+		host.HostKeyCallback = callback
+	}
+}
+
+// WithHostKeyCallback returns a HostOption that verifies the Host's key with
+// callback, for checks the other options do not cover.
+func WithHostKeyCallback(callback ssh.HostKeyCallback) HostOption {
+	return func(host *Host) {
+		host.HostKeyCallback = callback
+	}
+}
+
+// WithJumpHost returns a HostOption that reaches the Host through the bastion
+// at hostname. options configure the connection to the bastion, e.g. its own
+// WithUser and WithPassword; key authentication is used when no password is
+// given. Include WithJumpHost in options to chain a further bastion in front
+// of it. The dial timeout applies to each hop separately.
+func WithJumpHost(hostname string, options ...HostOption) HostOption {
+	return func(host *Host) {
+		name, port, err := splitHostPort(hostname)
+		if err != nil {
+			host.optionErr = err
+			return
+		}
+		jump := &Host{Hostname: name, Port: port}
+		for _, option := range options {
+			option(jump)
+		}
+		if jump.optionErr != nil {
+			host.optionErr = fmt.Errorf("jump host %s: %w", hostname, jump.optionErr)
+			return
+		}
+		host.jumpHost = jump
+	}
+}
+
+// WithConnectionReuse returns a HostOption that controls whether commands and
+// file transfers on a Host share one SSH connection instead of dialing for
+// each. The connection is dialed on first use, re-dialed if the server drops
+// it, and held until Host.Close.
+func WithConnectionReuse(enabled bool) HostOption {
+	return func(host *Host) {
+		host.Pool = nil
+		if enabled {
+			host.Pool = commandmanager.NewConnectionPool()
+		}
+	}
+}
+
+// WithSSHConfig returns a HostOption that looks the Host up in the ssh_config
+// file at path, or ~/.ssh/config if path is empty, so that an alias defined
+// there can be passed to NewHost. Its HostName, Port, User and IdentityFile
+// settings are used unless other options set them. A missing ~/.ssh/config
+// is ignored; any other unreadable file makes NewHost return an error.
+func WithSSHConfig(path string) HostOption {
+	return func(host *Host) {
+		file := path
+		if file == "" {
+			defaultPath, err := commandmanager.DefaultSSHConfigPath()
+			if err != nil {
+				host.optionErr = err
+				return
+			}
+			if _, err := os.Stat(defaultPath); errors.Is(err, os.ErrNotExist) {
+				return
+			}
+			file = defaultPath
+		}
+
+		config, err := commandmanager.LoadSSHConfig(file, host.Hostname)
+		if err != nil {
+			host.optionErr = err
+			return
+		}
+		host.sshConfig = &config
+	}
+}
+
+// WithIdentityFile returns a HostOption that authenticates with the private
+// key at path, which may start with "~/". It can be given more than once;
+// keys set this way replace any IdentityFile from ssh_config.
+func WithIdentityFile(path string) HostOption {
+	return func(host *Host) {
+		if strings.HasPrefix(path, "~/") {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				host.optionErr = err
+				return
+			}
+			path = filepath.Join(home, path[2:])
+		}
+		host.identityFiles = append(host.identityFiles, path)
+	}
+}
+
+// WithKeepAlive returns a HostOption that sends an SSH keepalive every
+// interval on the Host's open connections, so that firewalls do not drop
+// them during long commands or while pooled connections sit idle. A
+// connection whose keepalives go unanswered three times in a row is closed.
+// Jump hosts use the same interval unless given their own.
+func WithKeepAlive(interval time.Duration) HostOption {
+	return func(host *Host) {
+		host.KeepAlive = interval
+	}
+}
+
+// WithConnectTimeout returns a HostOption that bounds each attempt to connect
+// to the Host, SSH handshake included, to d instead of the default
+// commandmanager.DefaultConnectTimeout. Jump hosts use the same timeout
+// unless given their own.
+func WithConnectTimeout(d time.Duration) HostOption {
+	return func(host *Host) {
+		host.ConnectTimeout = d
+	}
+}
+
+// WithCommandTimeout returns a HostOption that cancels any command on the
+// Host still running after d, connecting included. By default commands run
+// as long as their context allows.
+func WithCommandTimeout(d time.Duration) HostOption {
+	return func(host *Host) {
+		host.CommandTimeout = d
+	}
+}
+
+// WithLogger returns a HostOption that sends the Host's diagnostics to
+// logger. They are mostly at debug level; by default they are discarded.
+// Jump hosts use the same logger unless given their own.
+func WithLogger(logger *slog.Logger) HostOption {
+	return func(host *Host) {
+		host.Logger = logger
+	}
+}
+
+// WithRedaction returns a HostOption that, when full is true, leaves command
+// lines and output out of the Host's log entirely, for environments where
+// even masked commands must not be recorded. Otherwise commands are logged
+// with credentials and likely secrets masked.
+func WithRedaction(full bool) HostOption {
+	return func(host *Host) {
+		host.RedactCommands = full
+	}
+}
+
+// WithAgentForwarding returns a HostOption that forwards the local SSH agent
+// to commands run on the Host, so that they can in turn authenticate with it,
+// as "git clone" from a private repository does. It only takes effect when
+// the Host authenticates with the agent, with no password or key files set,
+// and does nothing if SSH_AUTH_SOCK is unset or the server refuses. Only
+// forward an agent to hosts you trust: their root user can use it while the
+// command runs.
+func WithAgentForwarding(enabled bool) HostOption {
+	return func(host *Host) {
+		host.AgentForwarding = enabled
+	}
+}
+
+// WithDialRetry returns a HostOption that retries connecting to a Host up to
+// attempts times in all when dialing fails with a transient error, such as a
+// refused connection or a timeout while the host boots. The wait starts at
+// backoff and doubles after each attempt. Authentication and host key
+// failures are not retried.
+func WithDialRetry(attempts int, backoff time.Duration) HostOption {
+	return func(host *Host) {
+		host.DialAttempts = attempts
+		host.DialBackoff = backoff
+	}
+}
+
+// WithRetryBudget returns a HostOption that abandons a persistently failing
+// Host. After maxFailures dial or command failures within window, remote
+// operations fail immediately with commandmanager.ErrCircuitOpen. Once window
+// has passed again a single probe is attempted, and success resumes normal
+// operation.
+func WithRetryBudget(maxFailures int, window time.Duration) HostOption {
+	return func(host *Host) {
+		host.Breaker = commandmanager.NewCircuitBreaker(maxFailures, window)
+	}
+}
+
+// WithStrictOutputParsing returns a HostOption that makes output parsers fail
+// with a *commandmanager.ParseError on lines they do not recognize, rather
+// than skipping them. Use it to notice when a tool's output format changes,
+// e.g. after a distribution upgrade.
+func WithStrictOutputParsing() HostOption {
+	return func(host *Host) {
+		host.StrictOutput = true
+	}
+}
+
+// WithCommandHistory returns a HostOption that keeps the last n commands run
+// on a Host, with credentials masked, for Host.LastCommand.
+func WithCommandHistory(n int) HostOption {
+	return func(host *Host) {
+		host.History = commandmanager.NewCommandHistory(n)
+	}
+}
@@ -0,0 +1,784 @@
+package host
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/steelcutops/steelcut/common"
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+	"github.com/steelcutops/steelcut/steelcut/filemanager"
+	"github.com/steelcutops/steelcut/steelcut/hostmanager"
+	"github.com/steelcutops/steelcut/steelcut/packagemanager"
+)
+
+type MockCommandManager struct {
+	Outputs map[string]string
+	Results map[string]cm.CommandResult
+	Errors  map[string]error
+}
+
+func (m *MockCommandManager) result(config cm.CommandConfig) (cm.CommandResult, error) {
+	// A key with the first argument, such as "sudo -S", takes precedence
+	if len(config.Args) > 0 {
+		key := config.Command + " " + config.Args[0]
+		if result, ok := m.Results[key]; ok {
+			return result, m.Errors[key]
+		}
+	}
+	if result, ok := m.Results[config.Command]; ok {
+		return result, m.Errors[config.Command]
+	}
+	return cm.CommandResult{STDOUT: m.Outputs[config.Command]}, m.Errors[config.Command]
+}
+
+func (m *MockCommandManager) RunLocal(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
+	return m.result(config)
+}
+
+func (m *MockCommandManager) RunRemote(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
+	return m.result(config)
+}
+
+func (m *MockCommandManager) Run(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
+	return m.result(config)
+}
+
+func TestCollectDiagnostics(t *testing.T) {
+	h := &Host{
+		Credentials: common.Credentials{SudoPassword: "hunter2"},
+		CommandManager: &MockCommandManager{
+			Outputs: map[string]string{
+				"uname":      "Linux test 6.1.0\n",
+				"journalctl": "sudo: password hunter2 rejected\n",
+				"ps":         "root 812 /usr/bin/backup --password=s3cret --token abc123\n",
+			},
+			Results: map[string]cm.CommandResult{
+				"dmesg": {STDERR: "dmesg: read kernel buffer failed: Operation not permitted\n", ExitCode: 1},
+			},
+			Errors: map[string]error{
+				"dmesg": &cm.CommandError{Command: "dmesg", ExitCode: 1, Stderr: "dmesg: read kernel buffer failed: Operation not permitted\n"},
+				"df":    errors.New("connection reset"),
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := h.CollectDiagnostics(context.Background(), &buf); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	gr, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatalf("Expected gzip output, got: %v", err)
+	}
+	tr := tar.NewReader(gr)
+
+	entries := make(map[string]string)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Failed to read tar entry: %v", err)
+		}
+		content, _ := io.ReadAll(tr)
+		entries[hdr.Name] = string(content)
+	}
+
+	expected := []string{
+		"os-release.txt",
+		"uname.txt",
+		"uptime.txt",
+		"dmesg.txt.err",
+		"journalctl.txt",
+		"df.txt.err",
+		"free.txt",
+		"ps.txt",
+		"failed-services.txt",
+	}
+	for _, name := range expected {
+		if _, ok := entries[name]; !ok {
+			t.Errorf("Expected entry %s in bundle, got: %v", name, entries)
+		}
+	}
+	if len(entries) != len(expected) {
+		t.Errorf("Expected %d entries, got %d", len(expected), len(entries))
+	}
+
+	if got, want := entries["dmesg.txt.err"], "exit code 1\ndmesg: read kernel buffer failed: Operation not permitted\n"; got != want {
+		t.Errorf("Expected %q in dmesg.txt.err, got %q", want, got)
+	}
+	if got := entries["df.txt.err"]; got != "connection reset\n" {
+		t.Errorf("Expected the error in df.txt.err, got %q", got)
+	}
+
+	if strings.Contains(entries["journalctl.txt"], "hunter2") {
+		t.Errorf("Expected sudo password to be redacted, got: %s", entries["journalctl.txt"])
+	}
+	if strings.Contains(entries["ps.txt"], "s3cret") || strings.Contains(entries["ps.txt"], "abc123") {
+		t.Errorf("Expected credentials in process arguments to be redacted, got: %s", entries["ps.txt"])
+	}
+}
+
+func TestCollectDiagnosticsCancelled(t *testing.T) {
+	h := &Host{CommandManager: &MockCommandManager{}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var buf bytes.Buffer
+	if err := h.CollectDiagnostics(ctx, &buf); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got: %v", err)
+	}
+	gr, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatalf("Expected a complete gzip stream, got: %v", err)
+	}
+	if _, err := tar.NewReader(gr).Next(); err != io.EOF {
+		t.Errorf("Expected no entries after cancellation, got: %v", err)
+	}
+}
+
+func TestCanSudoPasswordless(t *testing.T) {
+	h := &Host{CommandManager: &MockCommandManager{}}
+
+	ok, err := h.CanSudo()
+	if err != nil || !ok {
+		t.Errorf("Expected passwordless sudo to succeed, got %v, %v", ok, err)
+	}
+}
+
+func TestCanSudoWrongPassword(t *testing.T) {
+	h := &Host{CommandManager: &MockCommandManager{
+		Results: map[string]cm.CommandResult{
+			"true": {STDERR: "sudo: 3 incorrect password attempts\n", ExitCode: 1},
+		},
+		Errors: map[string]error{"true": cm.ErrSudoAuth},
+	}}
+
+	if ok, err := h.CanSudo(); !errors.Is(err, cm.ErrSudoAuth) || ok {
+		t.Errorf("Expected a wrong sudo password to fail validation, got %v, %v", ok, err)
+	}
+}
+
+func TestCanSudoNotInSudoers(t *testing.T) {
+	h := &Host{CommandManager: &MockCommandManager{
+		Results: map[string]cm.CommandResult{
+			"true": {STDERR: "deploy is not in the sudoers file.  This incident will be reported.\n", ExitCode: 1},
+		},
+		Errors: map[string]error{
+			"true": errors.New("sudo: user is not in the sudoers file"),
+		},
+	}}
+
+	ok, err := h.CanSudo()
+	if err != nil || ok {
+		t.Errorf("Expected false with no error for a user without sudo, got %v, %v", ok, err)
+	}
+}
+
+// fakePasswordSudo stands in for sudo -S -p, requiring the password "secret"
+// on stdin to run the command.
+const fakePasswordSudo = `#!/bin/sh
+[ "$1" = -S ] && [ "$2" = -p ] && [ "$4" = -- ] || exit 99
+prompt=$3
+shift 4
+for i in 1 2 3; do
+	printf '%s' "$prompt" >&2
+	IFS= read -r p || exit 1
+	[ "$p" = secret ] && exec "$@"
+done
+exit 1
+`
+
+// fakeDeniedDoas stands in for doas under a doas.conf without a rule for
+// the user.
+const fakeDeniedDoas = `#!/bin/sh
+echo "doas: Operation not permitted" >&2
+exit 1
+`
+
+// withFakeTool puts an executable called name with content first in PATH.
+func withFakeTool(t *testing.T, name, content string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestCanSudoWithPassword(t *testing.T) {
+	withFakeTool(t, "sudo", fakePasswordSudo)
+
+	for password, want := range map[string]bool{"secret": true, "wrong": false} {
+		manager := &cm.UnixCommandManager{Hostname: "localhost", Credentials: common.Credentials{SudoPassword: password}}
+		h := &Host{Credentials: manager.Credentials, CommandManager: manager}
+		ok, err := h.CanSudo()
+		if ok != want || (want && err != nil) || (!want && !errors.Is(err, cm.ErrSudoAuth)) {
+			t.Errorf("Expected %v with password %q, got %v, %v", want, password, ok, err)
+		}
+	}
+}
+
+func TestCanSudoUsesEscalation(t *testing.T) {
+	withFakeTool(t, "doas", fakeDeniedDoas)
+
+	h := &Host{
+		Escalation:     cm.DoasStrategy{},
+		CommandManager: &cm.UnixCommandManager{Hostname: "localhost", Escalation: cm.DoasStrategy{}},
+	}
+	ok, err := h.CanSudo()
+	if err != nil || ok {
+		t.Errorf("Expected doas to refuse the user, got %v, %v", ok, err)
+	}
+}
+
+func TestCanSudoReadOnly(t *testing.T) {
+	withFakeTool(t, "sudo", fakePasswordSudo)
+
+	h := &Host{
+		Credentials:    common.Credentials{SudoPassword: "secret"},
+		CommandManager: &cm.UnixCommandManager{Hostname: "localhost", Credentials: common.Credentials{SudoPassword: "secret"}, ReadOnly: true},
+	}
+	ok, err := h.CanSudo()
+	if err != nil || !ok {
+		t.Errorf("Expected sudo validation to work in read-only mode, got %v, %v", ok, err)
+	}
+}
+
+func TestCanSudoConnectionError(t *testing.T) {
+	h := &Host{CommandManager: &MockCommandManager{
+		Errors: map[string]error{"true": errors.New("dial tcp: connection refused")},
+	}}
+
+	if _, err := h.CanSudo(); err == nil {
+		t.Errorf("Expected connection failure to be returned as an error")
+	}
+}
+
+func TestEscalationTool(t *testing.T) {
+	h := &Host{CommandManager: &MockCommandManager{
+		Outputs: map[string]string{"which": "/usr/bin/doas\n"},
+	}}
+	if tool, err := h.EscalationTool(); err != nil || tool != "doas" {
+		t.Errorf("Expected doas to be detected, got %q, %v", tool, err)
+	}
+
+	h.Escalation = cm.PkexecStrategy{}
+	if tool, err := h.EscalationTool(); err != nil || tool != "pkexec" {
+		t.Errorf("Expected configured strategy to be reported, got %q, %v", tool, err)
+	}
+}
+
+// countingDialer fails every dial and counts them.
+type countingDialer struct {
+	dials int
+}
+
+func (d *countingDialer) Dial(network, addr string, config *ssh.ClientConfig, timeout time.Duration) (*ssh.Client, error) {
+	d.dials++
+	return nil, errNoDial
+}
+
+func TestNewHostDetectsEscalationLazily(t *testing.T) {
+	dialer := &countingDialer{}
+	h, err := NewHost("web.example.com", WithOS(LinuxUbuntu), WithSSHClient(dialer), WithUser("deploy"))
+	if err != nil {
+		t.Fatalf("NewHost failed: %v", err)
+	}
+	if dialer.dials != 0 {
+		t.Errorf("Expected NewHost not to connect, got %d dials", dialer.dials)
+	}
+	if manager := h.CommandManager.(*cm.UnixCommandManager); manager.EscalationDetector == nil {
+		t.Errorf("Expected escalation to be detected on the first privileged command")
+	}
+
+	h, err = NewHost("web.example.com", WithOS(LinuxUbuntu), WithSSHClient(dialer), WithUser("deploy"),
+		WithPrivilegeEscalation(cm.DoasStrategy{}))
+	if err != nil {
+		t.Fatalf("NewHost failed: %v", err)
+	}
+	if manager := h.CommandManager.(*cm.UnixCommandManager); manager.EscalationDetector != nil {
+		t.Errorf("Expected no detection with an explicit strategy")
+	}
+}
+
+func TestWithExpectedFingerprintMalformed(t *testing.T) {
+	if _, err := NewHost("localhost", WithExpectedFingerprint("not-a-fingerprint")); err == nil {
+		t.Errorf("Expected NewHost to reject a malformed fingerprint")
+	}
+}
+
+func TestWithJumpHostChain(t *testing.T) {
+	h := &Host{Hostname: "app.internal"}
+	WithUser("deploy")(h)
+	WithJumpHost("bastion2.example.com",
+		WithUser("jump"),
+		WithPassword("secret"),
+		WithJumpHost("bastion1.example.com", WithUser("edge")),
+	)(h)
+
+	manager, err := newCommandManager(h)
+	if err != nil {
+		t.Fatalf("newCommandManager failed: %v", err)
+	}
+	inner := manager.JumpHost
+	if inner == nil || inner.Hostname != "bastion2.example.com" || inner.User != "jump" || inner.Password != "secret" {
+		t.Fatalf("Unexpected jump host: %+v", inner)
+	}
+	outer := inner.JumpHost
+	if outer == nil || outer.Hostname != "bastion1.example.com" || outer.User != "edge" || outer.Password != "" {
+		t.Fatalf("Unexpected outer jump host: %+v", outer)
+	}
+	if outer.JumpHost != nil || outer.SSHClient == nil {
+		t.Errorf("Expected the outer jump host to be dialed directly")
+	}
+}
+
+func TestWithJumpHostInvalidOption(t *testing.T) {
+	_, err := NewHost("app.internal", WithJumpHost("bastion.example.com", WithExpectedFingerprint("not-a-fingerprint")))
+	if err == nil || !strings.Contains(err.Error(), "bastion.example.com") {
+		t.Errorf("Expected NewHost to reject the jump host option, got %v", err)
+	}
+}
+
+// commandLineManager answers by the full command line, e.g. "cat /proc/uptime".
+type commandLineManager struct {
+	MockCommandManager
+	Lines map[string]cm.CommandResult
+}
+
+func (m *commandLineManager) Run(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
+	line := strings.TrimSpace(config.Command + " " + strings.Join(config.Args, " "))
+	if result, ok := m.Lines[line]; ok {
+		return result, nil
+	}
+	return cm.CommandResult{}, errors.New("unexpected command: " + line)
+}
+
+func TestPrometheusMetrics(t *testing.T) {
+	mockCmd := &commandLineManager{Lines: map[string]cm.CommandResult{
+		"nproc":             {STDOUT: "4\n"},
+		"cat /proc/meminfo": {STDOUT: "MemTotal:       8048576 kB\nMemAvailable:   4024288 kB\n"},
+		"df -kP": {STDOUT: `Filesystem     1024-blocks     Used Available Capacity Mounted on
+/dev/sda1         52428800 20971520  31457280      40% /
+proc                     0        0         0       -  /proc
+/dev/sdb1        104857600 10485760  94371840      10% /srv/my "data"
+`},
+		"cat /proc/loadavg": {STDOUT: "0.52 0.58 0.59 1/467 12345\n"},
+		"cat /proc/uptime":  {STDOUT: "11040.00 40000.00\n"},
+	}}
+	h := &Host{
+		CommandManager: mockCmd,
+		HostManager:    &hostmanager.UnixHostManager{CommandManager: mockCmd},
+		FileManager:    &filemanager.UnixFileManager{CommandManager: mockCmd},
+	}
+
+	output, err := h.PrometheusMetrics()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	// Every sample line must follow a TYPE line for its family
+	sample := regexp.MustCompile(`^([a-zA-Z_:][a-zA-Z0-9_:]*)(\{([a-zA-Z_][a-zA-Z0-9_]*="(\\.|[^"\\])*",?)*\})? (\S+)$`)
+	typed := make(map[string]bool)
+	families := make(map[string]bool)
+	for _, line := range strings.Split(strings.TrimSuffix(output, "\n"), "\n") {
+		if strings.HasPrefix(line, "# TYPE ") {
+			fields := strings.Fields(line)
+			if len(fields) != 4 || (fields[3] != "gauge" && fields[3] != "counter") {
+				t.Errorf("Invalid TYPE line: %q", line)
+				continue
+			}
+			typed[fields[2]] = true
+			continue
+		}
+		if strings.HasPrefix(line, "# HELP ") {
+			continue
+		}
+		m := sample.FindStringSubmatch(line)
+		if m == nil {
+			t.Errorf("Invalid sample line: %q", line)
+			continue
+		}
+		if !typed[m[1]] {
+			t.Errorf("Sample before TYPE line: %q", line)
+		}
+		if _, err := strconv.ParseFloat(m[5], 64); err != nil {
+			t.Errorf("Invalid sample value: %q", line)
+		}
+		families[m[1]] = true
+	}
+
+	for _, name := range []string{
+		"steelcut_cpu_count", "steelcut_memory_total_bytes", "steelcut_memory_available_bytes",
+		"steelcut_disk_used_bytes", "steelcut_load1", "steelcut_load15", "steelcut_uptime_seconds",
+		"steelcut_collector_success",
+	} {
+		if !families[name] {
+			t.Errorf("Expected metric family %s, got:\n%s", name, output)
+		}
+	}
+	for _, want := range []string{
+		`steelcut_disk_used_bytes{device="/dev/sda1",mount="/"} 2.147483648e+10`,
+		`steelcut_disk_used_bytes{device="/dev/sdb1",mount="/srv/my \"data\""}`,
+		`steelcut_memory_total_bytes 8.241741824e+09`,
+		`steelcut_load1 0.52`,
+		`steelcut_collector_success{collector="cpu_usage"} 0`,
+		`steelcut_collector_success{collector="disk"} 1`,
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %s in output:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, `mount="/proc"`) {
+		t.Errorf("Expected pseudo filesystems to be skipped")
+	}
+}
+
+func TestWithConnectionReuse(t *testing.T) {
+	h := &Host{}
+	WithConnectionReuse(true)(h)
+	if h.Pool == nil {
+		t.Fatal("Expected a connection pool")
+	}
+	if err := h.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+
+	WithConnectionReuse(false)(h)
+	if h.Pool != nil {
+		t.Error("Expected connection reuse to be disabled")
+	}
+	if err := h.Close(); err != nil {
+		t.Errorf("Close without a pool failed: %v", err)
+	}
+}
+
+func TestWithSSHConfig(t *testing.T) {
+	config := filepath.Join(t.TempDir(), "config")
+	content := "Host web\n  HostName web.example.com\n  Port 2222\n  User deploy\n  IdentityFile /keys/web\n"
+	if err := os.WriteFile(config, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	h := &Host{Hostname: "web"}
+	WithSSHConfig(config)(h)
+	manager, err := newCommandManager(h)
+	if err != nil {
+		t.Fatalf("newCommandManager failed: %v", err)
+	}
+	if manager.Hostname != "web.example.com" || manager.Port != 2222 || manager.User != "deploy" {
+		t.Errorf("Expected settings from ssh_config, got %s:%d as %s", manager.Hostname, manager.Port, manager.User)
+	}
+	if !reflect.DeepEqual(manager.IdentityFiles, []string{"/keys/web"}) {
+		t.Errorf("Unexpected identity files: %v", manager.IdentityFiles)
+	}
+
+	// Explicit options win over the file
+	h = &Host{Hostname: "web"}
+	WithSSHConfig(config)(h)
+	WithUser("admin")(h)
+	if manager, err = newCommandManager(h); err != nil || manager.User != "admin" {
+		t.Errorf("Expected WithUser to take precedence, got %q, %v", manager.User, err)
+	}
+
+	if _, err := NewHost("web", WithSSHConfig(filepath.Join(t.TempDir(), "missing"))); err == nil {
+		t.Error("Expected NewHost to fail for a missing ssh_config file")
+	}
+}
+
+func TestSplitHostPort(t *testing.T) {
+	tests := []struct {
+		in   string
+		host string
+		port int
+	}{
+		{"example.com", "example.com", 0},
+		{"example.com:2222", "example.com", 2222},
+		{"[::1]:2222", "::1", 2222},
+		{"::1", "::1", 0},
+	}
+	for _, tt := range tests {
+		host, port, err := splitHostPort(tt.in)
+		if err != nil || host != tt.host || port != tt.port {
+			t.Errorf("splitHostPort(%q) = %q, %d, %v; want %q, %d", tt.in, host, port, err, tt.host, tt.port)
+		}
+	}
+	if _, _, err := splitHostPort("example.com:ssh"); err == nil {
+		t.Error("Expected a non-numeric port to be rejected")
+	}
+}
+
+func TestWithPort(t *testing.T) {
+	config := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(config, []byte("Host web\n  Port 2200\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	h := &Host{Hostname: "web", Port: 2222}
+	WithSSHConfig(config)(h)
+	if manager, err := newCommandManager(h); err != nil || manager.Port != 2222 {
+		t.Errorf("Expected the hostname's port to win over ssh_config, got %d, %v", manager.Port, err)
+	}
+
+	WithPort(2022)(h)
+	if manager, err := newCommandManager(h); err != nil || manager.Port != 2022 {
+		t.Errorf("Expected WithPort to set the port, got %d, %v", manager.Port, err)
+	}
+
+	if _, err := NewHost("web", WithPort(70000)); err == nil {
+		t.Error("Expected NewHost to reject an out of range port")
+	}
+}
+
+func TestWithKeepAlive(t *testing.T) {
+	h := &Host{Hostname: "app.internal"}
+	WithKeepAlive(30 * time.Second)(h)
+	WithJumpHost("bastion.example.com")(h)
+
+	manager, err := newCommandManager(h)
+	if err != nil {
+		t.Fatalf("newCommandManager failed: %v", err)
+	}
+	if manager.KeepAlive != 30*time.Second || manager.JumpHost.KeepAlive != 30*time.Second {
+		t.Errorf("Expected keepalives on both hops, got %s and %s", manager.KeepAlive, manager.JumpHost.KeepAlive)
+	}
+}
+
+func TestWithTimeouts(t *testing.T) {
+	h := &Host{Hostname: "app.internal"}
+	WithConnectTimeout(20 * time.Second)(h)
+	WithCommandTimeout(time.Hour)(h)
+	WithJumpHost("bastion.example.com")(h)
+
+	manager, err := newCommandManager(h)
+	if err != nil {
+		t.Fatalf("newCommandManager failed: %v", err)
+	}
+	if manager.ConnectTimeout != 20*time.Second || manager.JumpHost.ConnectTimeout != 20*time.Second {
+		t.Errorf("Expected connect timeouts on both hops, got %s and %s", manager.ConnectTimeout, manager.JumpHost.ConnectTimeout)
+	}
+	if manager.CommandTimeout != time.Hour {
+		t.Errorf("Expected a command timeout of 1h, got %s", manager.CommandTimeout)
+	}
+}
+
+func TestWithLogger(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	h := &Host{Hostname: "app.internal"}
+	WithLogger(logger)(h)
+	WithRedaction(true)(h)
+	WithJumpHost("bastion.example.com")(h)
+
+	manager, err := newCommandManager(h)
+	if err != nil {
+		t.Fatalf("newCommandManager failed: %v", err)
+	}
+	if manager.Logger != logger || manager.JumpHost.Logger != logger {
+		t.Errorf("Expected the logger on both hops")
+	}
+	if !manager.RedactCommands || !manager.JumpHost.RedactCommands {
+		t.Errorf("Expected full redaction on both hops")
+	}
+	if (&Host{}).logger() != cm.DiscardLogger() {
+		t.Errorf("Expected hosts to discard logs by default")
+	}
+}
+
+func TestDetectLinuxTypeSelectsDnf(t *testing.T) {
+	tests := []struct {
+		osRelease string
+		osType    OSType
+		dnf       bool
+	}{
+		{"NAME=\"Fedora Linux\"\nID=fedora\nVERSION_ID=40\n", LinuxFedora, true},
+		{"NAME=\"Red Hat Enterprise Linux\"\nID=\"rhel\"\nVERSION_ID=\"9.4\"\n", LinuxRedHat, true},
+		{"NAME=\"Red Hat Enterprise Linux Server\"\nID=\"rhel\"\nVERSION_ID=\"7.9\"\n", LinuxRedHat, false},
+		{"NAME=\"CentOS Linux\"\nID=\"centos\"\nVERSION_ID=\"8\"\n", LinuxCentOS, true},
+		{"NAME=\"CentOS Linux\"\nID=\"centos\"\nVERSION_ID=\"7\"\n", LinuxCentOS, false},
+	}
+
+	for _, tt := range tests {
+		h := &Host{CommandManager: &MockCommandManager{Outputs: map[string]string{"cat": tt.osRelease}}}
+		osType, err := h.detectLinuxType(context.Background())
+		if err != nil || osType != tt.osType {
+			t.Fatalf("Expected %v, got %v, %v", tt.osType, osType, err)
+		}
+
+		configureLinuxHost(h, h.CommandManager, osType)
+		_, isDnf := h.PackageManager.(*packagemanager.DnfPackageManager)
+		if isDnf != tt.dnf {
+			t.Errorf("%s %s: expected dnf %v, got %T", osType, h.OSVersion, tt.dnf, h.PackageManager)
+		}
+	}
+}
+
+func TestParseOSRelease(t *testing.T) {
+	release := parseOSRelease("# comment\nNAME=\"Rocky Linux\"\nID='rocky'\nID_LIKE=\"rhel centos fedora\"\nVERSION_ID=9.3\n")
+	expected := map[string]string{"NAME": "Rocky Linux", "ID": "rocky", "ID_LIKE": "rhel centos fedora", "VERSION_ID": "9.3"}
+	if !reflect.DeepEqual(release, expected) {
+		t.Errorf("Expected %v, got %v", expected, release)
+	}
+}
+
+func TestDetectLinuxTypeAlpine(t *testing.T) {
+	h := &Host{CommandManager: &MockCommandManager{Outputs: map[string]string{
+		"cat": "NAME=\"Alpine Linux\"\nID=alpine\nVERSION_ID=3.20.1\n",
+	}}}
+
+	osType, err := h.detectLinuxType(context.Background())
+	if err != nil || osType != LinuxAlpine {
+		t.Fatalf("Expected LinuxAlpine, got %v, %v", osType, err)
+	}
+	if osType.String() != "Linux_Alpine" {
+		t.Errorf("Expected Linux_Alpine, got %s", osType)
+	}
+
+	configureLinuxHost(h, h.CommandManager, osType)
+	if _, ok := h.PackageManager.(*packagemanager.ApkPackageManager); !ok {
+		t.Errorf("Expected apk, got %T", h.PackageManager)
+	}
+}
+
+func TestParseInventory(t *testing.T) {
+	yamlInventory := `hosts:
+  - hostname: web1.example.com
+    user: deploy
+    port: 2222
+    key: ~/.ssh/deploy_ed25519
+    os: ubuntu
+    groups: [web, production]
+  - hostname: db1.example.com
+    groups: [production]
+`
+	entries, err := parseInventory([]byte(yamlInventory), false)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	expected := []InventoryEntry{
+		{Hostname: "web1.example.com", User: "deploy", Port: 2222, Key: "~/.ssh/deploy_ed25519", OS: "ubuntu", Groups: []string{"web", "production"}},
+		{Hostname: "db1.example.com", Groups: []string{"production"}},
+	}
+	if !reflect.DeepEqual(entries, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, entries)
+	}
+
+	if _, err := parseInventory([]byte(`{"hosts": [{"hostname": "web1", "port": "22"}]}`), true); err == nil || !strings.Contains(err.Error(), `host "web1"`) {
+		t.Errorf("Expected the JSON error to name web1, got: %v", err)
+	}
+	jsonEntries, err := parseInventory([]byte(`{"hosts": [{"hostname": "web1.example.com", "user": "deploy", "port": 2222, "key": "~/.ssh/deploy_ed25519", "os": "ubuntu", "groups": ["web", "production"]}, {"hostname": "db1.example.com", "groups": ["production"]}]}`), true)
+	if err != nil || !reflect.DeepEqual(jsonEntries, expected) {
+		t.Errorf("Expected JSON to match YAML, got %+v, %v", jsonEntries, err)
+	}
+
+	// Entries are checked before anything connects, naming the bad host
+	malformed := map[string]string{
+		"hosts:\n  - hostname: web1\n    prot: 22\n":           `host "web1": line 3: unknown field "prot"`,
+		"hosts:\n  - user: deploy\n":                           "host #1",
+		"hosts:\n  - hostname: web1\n  - hostname: web1\n":     "duplicate",
+		"hosts:\n  - hostname: web1\n    port: not-a-number\n": "host \"web1\": yaml: unmarshal errors:\n  line 3",
+		"hosts:\n  - hostname: web1\n    os: plan9\n":          `host "web1": unknown OS`,
+		"hosts:\n  - hostname: web1\n    port: 70000\n":        `host "web1": invalid port`,
+		"hosts:\n  - hostname: web1\n    groups: [web, ' ']\n": `host "web1": empty group name`,
+	}
+	for data, want := range malformed {
+		path := filepath.Join(t.TempDir(), "inventory.yaml")
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadInventory(path); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected an error mentioning %q for %q, got: %v", want, data, err)
+		}
+	}
+}
+
+func TestLoadInventoryGroups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "inventory.json")
+	data := `{"hosts": [{"hostname": "localhost", "os": "ubuntu", "key": "/keys/deploy", "groups": ["web"]}]}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	inv, err := LoadInventory(path, WithUser("deploy"))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(inv.Hosts) != 1 || len(inv.Group("web")) != 1 || inv.Group("web")[0] != inv.Hosts[0] || inv.Group("db") != nil {
+		t.Fatalf("Unexpected inventory %+v", inv)
+	}
+	h := inv.Hosts[0]
+	if h.OSType != LinuxUbuntu || h.User != "deploy" || !reflect.DeepEqual(h.identityFiles, []string{"/keys/deploy"}) {
+		t.Errorf("Expected the entry's settings to apply, got %v %q %v", h.OSType, h.User, h.identityFiles)
+	}
+	if _, ok := h.PackageManager.(*packagemanager.AptPackageManager); !ok {
+		t.Errorf("Expected apt for the declared OS, got %T", h.PackageManager)
+	}
+}
+
+func TestParseOSType(t *testing.T) {
+	for name, expected := range map[string]OSType{"ubuntu": LinuxUbuntu, "RHEL": LinuxRedHat, "Linux_Alpine": LinuxAlpine, "macos": Darwin} {
+		if osType, err := ParseOSType(name); err != nil || osType != expected {
+			t.Errorf("Expected %v for %q, got %v, %v", expected, name, osType, err)
+		}
+	}
+	if _, err := ParseOSType("Unknown"); err == nil {
+		t.Error("Expected Unknown to be rejected")
+	}
+}
+
+func TestOSReleaseDerivatives(t *testing.T) {
+	tests := []struct {
+		name      string
+		osRelease string
+		osType    OSType
+		apt       bool
+	}{
+		{"mint", "NAME=\"Linux Mint\"\nID=linuxmint\nID_LIKE=\"ubuntu debian\"\nVERSION_ID=\"21.3\"\n", LinuxUbuntu, true},
+		{"pop", "NAME=\"Pop!_OS\"\nID=pop\nID_LIKE=\"ubuntu debian\"\nVERSION_ID=\"22.04\"\n", LinuxUbuntu, true},
+		{"raspbian", "ID=raspbian\nID_LIKE=debian\nVERSION_ID=\"11\"\n", LinuxDebian, true},
+		{"rocky", "ID=\"rocky\"\nID_LIKE=\"rhel centos fedora\"\nVERSION_ID=\"9.3\"\n", LinuxRedHat, false},
+		{"manjaro", "ID=manjaro\nID_LIKE=arch\n", LinuxArch, false},
+		{"sles", "ID=\"sles\"\nID_LIKE=\"suse\"\nVERSION_ID=\"15.5\"\n", LinuxOpenSUSE, false},
+		{"unknown", "ID=plan9\n", Unknown, false},
+	}
+
+	for _, tt := range tests {
+		release := ParseOSRelease(tt.osRelease)
+		if osType := release.OSType(); osType != tt.osType {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.osType, osType)
+			continue
+		}
+		if tt.osType == Unknown {
+			continue
+		}
+
+		h := &Host{OSVersion: release.VersionID}
+		configureLinuxHost(h, &MockCommandManager{}, tt.osType)
+		if _, isApt := h.PackageManager.(*packagemanager.AptPackageManager); isApt != tt.apt {
+			t.Errorf("%s: expected apt %v, got %T", tt.name, tt.apt, h.PackageManager)
+		}
+	}
+
+	release := ParseOSRelease("ID=\"rocky\"\nID_LIKE=\"rhel centos fedora\"\nVERSION_ID=\"9.3\"\n")
+	expected := OSRelease{ID: "rocky", IDLike: []string{"rhel", "centos", "fedora"}, VersionID: "9.3"}
+	if !reflect.DeepEqual(release, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, release)
+	}
+}
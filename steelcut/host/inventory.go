@@ -0,0 +1,201 @@
+package host
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// InventoryEntry describes one host in an inventory file. Only Hostname is
+// required.
+type InventoryEntry struct {
+	Hostname string   `json:"hostname" yaml:"hostname"`
+	User     string   `json:"user,omitempty" yaml:"user,omitempty"`
+	Port     int      `json:"port,omitempty" yaml:"port,omitempty"`
+	Key      string   `json:"key,omitempty" yaml:"key,omitempty"`       // private key path
+	OS       string   `json:"os,omitempty" yaml:"os,omitempty"`         // skips detection, see ParseOSType
+	Groups   []string `json:"groups,omitempty" yaml:"groups,omitempty"` // group names the host belongs to
+}
+
+// Inventory is the set of hosts loaded by LoadInventory.
+type Inventory struct {
+	Hosts  []*Host
+	Groups map[string][]*Host
+}
+
+// Group returns the hosts in the named group, in inventory order, e.g. to
+// pass to hostgroup.NewHostGroup.
+func (inv *Inventory) Group(name string) []*Host {
+	return inv.Groups[name]
+}
+
+// LoadInventory reads the hosts listed in the YAML or JSON file at path and
+// connects to each with NewHost. The file holds a "hosts" list of
+// InventoryEntry:
+//
+//	hosts:
+//	  - hostname: web1.example.com
+//	    user: deploy
+//	    port: 2222
+//	    key: ~/.ssh/deploy_ed25519
+//	    groups: [web, production]
+//
+// Files ending in .json are read as JSON and anything else as YAML. options
+// apply to every host, ahead of the settings from its entry. Every entry is
+// checked before any host is connected to, and an error names the offending
+// host.
+func LoadInventory(path string, options ...HostOption) (*Inventory, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := parseInventory(data, strings.EqualFold(filepath.Ext(path), ".json"))
+	if err != nil {
+		return nil, fmt.Errorf("inventory %s: %w", path, err)
+	}
+
+	hostOptions := make([][]HostOption, len(entries))
+	for i, entry := range entries {
+		entryOptions, err := entry.options()
+		if err != nil {
+			return nil, fmt.Errorf("inventory %s: %s: %w", path, entry.name(i), err)
+		}
+		hostOptions[i] = append(append([]HostOption(nil), options...), entryOptions...)
+	}
+
+	inv := &Inventory{Groups: make(map[string][]*Host)}
+	for i, entry := range entries {
+		h, err := NewHost(entry.Hostname, hostOptions[i]...)
+		if err != nil {
+			return nil, fmt.Errorf("inventory %s: %s: %w", path, entry.name(i), err)
+		}
+		inv.Hosts = append(inv.Hosts, h)
+		for _, group := range entry.Groups {
+			inv.Groups[group] = append(inv.Groups[group], h)
+		}
+	}
+	return inv, nil
+}
+
+// parseInventory decodes an inventory file, rejecting unknown keys so that
+// typos are not silently ignored, and checks that hostnames are present and
+// unique. Each entry is decoded on its own so that errors name its host.
+func parseInventory(data []byte, isJSON bool) ([]InventoryEntry, error) {
+	var decoders []func(*InventoryEntry) error
+	if isJSON {
+		var file struct {
+			Hosts []json.RawMessage `json:"hosts"`
+		}
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&file); err != nil {
+			return nil, err
+		}
+		for _, raw := range file.Hosts {
+			raw := raw
+			decoders = append(decoders, func(entry *InventoryEntry) error {
+				decoder := json.NewDecoder(bytes.NewReader(raw))
+				decoder.DisallowUnknownFields()
+				return decoder.Decode(entry)
+			})
+		}
+	} else {
+		var file struct {
+			Hosts []yaml.Node `yaml:"hosts"`
+		}
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		for i := range file.Hosts {
+			node := &file.Hosts[i]
+			decoders = append(decoders, func(entry *InventoryEntry) error {
+				if err := node.Decode(entry); err != nil {
+					return err
+				}
+				return checkInventoryKeys(node)
+			})
+		}
+	}
+
+	entries := make([]InventoryEntry, len(decoders))
+	seen := make(map[string]bool, len(decoders))
+	for i, decode := range decoders {
+		err := decode(&entries[i])
+		entry := entries[i]
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.name(i), err)
+		}
+		if strings.TrimSpace(entry.Hostname) == "" {
+			return nil, fmt.Errorf("%s: hostname is required", entry.name(i))
+		}
+		if seen[entry.Hostname] {
+			return nil, fmt.Errorf("%s: duplicate hostname", entry.name(i))
+		}
+		seen[entry.Hostname] = true
+	}
+	return entries, nil
+}
+
+// checkInventoryKeys rejects keys of a YAML host entry that InventoryEntry
+// does not have, which Node.Decode would ignore.
+func checkInventoryKeys(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i < len(node.Content); i += 2 {
+		switch key := node.Content[i]; key.Value {
+		case "hostname", "user", "port", "key", "os", "groups":
+		default:
+			return fmt.Errorf("line %d: unknown field %q", key.Line, key.Value)
+		}
+	}
+	return nil
+}
+
+// options returns the HostOptions for the entry's settings.
+func (e InventoryEntry) options() ([]HostOption, error) {
+	var options []HostOption
+	if e.User != "" {
+		options = append(options, WithUser(e.User))
+	}
+	if e.Port != 0 {
+		if e.Port < 1 || e.Port > 65535 {
+			return nil, fmt.Errorf("invalid port: %d", e.Port)
+		}
+		options = append(options, WithPort(e.Port))
+	}
+	if e.Key != "" {
+		options = append(options, WithIdentityFile(e.Key))
+	}
+	if e.OS != "" {
+		osType, err := ParseOSType(e.OS)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, WithOS(osType))
+	}
+	for _, group := range e.Groups {
+		if strings.TrimSpace(group) == "" {
+			return nil, errors.New("empty group name")
+		}
+	}
+	return options, nil
+}
+
+// name identifies the entry in errors by its hostname, or by its position
+// in the file when it has none.
+func (e InventoryEntry) name(index int) string {
+	if e.Hostname != "" {
+		return fmt.Sprintf("host %q", e.Hostname)
+	}
+	return fmt.Sprintf("host #%d", index+1)
+}
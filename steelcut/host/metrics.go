@@ -0,0 +1,131 @@
+package host
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// metricFamily is one metric with its HELP and TYPE lines.
+type metricFamily struct {
+	name    string
+	help    string
+	kind    string // "gauge" or "counter"
+	samples []metricSample
+}
+
+type metricSample struct {
+	labels [][2]string
+	value  float64
+}
+
+// PrometheusMetrics collects the Host's CPU, memory, disk, load and uptime
+// figures and renders them in the Prometheus text exposition format, for
+// serving from a /metrics endpoint. A figure that cannot be collected is
+// left out, and steelcut_collector_success reports which collectors failed;
+// an error is only returned when nothing could be collected.
+func (h *Host) PrometheusMetrics() (string, error) {
+	var families []metricFamily
+	success := metricFamily{
+		name: "steelcut_collector_success",
+		help: "Whether a collector succeeded (1) or failed (0).",
+		kind: "gauge",
+	}
+	var firstErr error
+	succeeded := false
+	collect := func(collector string, err error) bool {
+		value := 1.0
+		if err != nil {
+			value = 0
+			if firstErr == nil {
+				firstErr = fmt.Errorf("%s: %w", collector, err)
+			}
+		} else {
+			succeeded = true
+		}
+		success.samples = append(success.samples, metricSample{labels: [][2]string{{"collector", collector}}, value: value})
+		return err == nil
+	}
+	gauge := func(name, help string, value float64) {
+		families = append(families, metricFamily{name: name, help: help, kind: "gauge", samples: []metricSample{{value: value}}})
+	}
+
+	if count, err := h.HostManager.CPUCount(); collect("cpu_count", err) {
+		gauge("steelcut_cpu_count", "Number of CPUs.", float64(count))
+	}
+	if usage, err := h.HostManager.CPUUsage(); collect("cpu_usage", err) {
+		gauge("steelcut_cpu_usage_percent", "CPU usage as a percentage.", usage)
+	}
+
+	total, totalErr := h.HostManager.TotalMemory()
+	free, freeErr := h.HostManager.FreeMemory()
+	if totalErr == nil {
+		totalErr = freeErr
+	}
+	if collect("memory", totalErr) {
+		gauge("steelcut_memory_total_bytes", "Total memory in bytes.", float64(total))
+		gauge("steelcut_memory_available_bytes", "Memory available for new processes in bytes.", float64(free))
+	}
+
+	if mounts, err := h.FileManager.DiskUsageByMount(); collect("disk", err) {
+		sizes := metricFamily{name: "steelcut_disk_size_bytes", help: "Filesystem size in bytes.", kind: "gauge"}
+		used := metricFamily{name: "steelcut_disk_used_bytes", help: "Filesystem space used in bytes.", kind: "gauge"}
+		avail := metricFamily{name: "steelcut_disk_available_bytes", help: "Filesystem space available to unprivileged users in bytes.", kind: "gauge"}
+		for _, m := range mounts {
+			labels := [][2]string{{"device", m.Filesystem}, {"mount", m.MountPoint}}
+			sizes.samples = append(sizes.samples, metricSample{labels: labels, value: float64(m.TotalBytes)})
+			used.samples = append(used.samples, metricSample{labels: labels, value: float64(m.UsedBytes)})
+			avail.samples = append(avail.samples, metricSample{labels: labels, value: float64(m.AvailableBytes)})
+		}
+		families = append(families, sizes, used, avail)
+	}
+
+	if load, err := h.HostManager.LoadAverage(); collect("load", err) {
+		gauge("steelcut_load1", "1 minute load average.", load.One)
+		gauge("steelcut_load5", "5 minute load average.", load.Five)
+		gauge("steelcut_load15", "15 minute load average.", load.Fifteen)
+	}
+
+	if uptime, err := h.HostManager.Uptime(); collect("uptime", err) {
+		gauge("steelcut_uptime_seconds", "Time since the host booted in seconds.", uptime.Seconds())
+	}
+
+	if !succeeded {
+		return "", fmt.Errorf("no metrics collected: %w", firstErr)
+	}
+	families = append(families, success)
+	return renderMetrics(families), nil
+}
+
+// renderMetrics writes families in the Prometheus text format, sorted by
+// metric name.
+func renderMetrics(families []metricFamily) string {
+	sort.SliceStable(families, func(i, j int) bool {
+		return families[i].name < families[j].name
+	})
+
+	var b strings.Builder
+	for _, f := range families {
+		fmt.Fprintf(&b, "# HELP %s %s\n", f.name, f.help)
+		fmt.Fprintf(&b, "# TYPE %s %s\n", f.name, f.kind)
+		for _, s := range f.samples {
+			b.WriteString(f.name)
+			if len(s.labels) > 0 {
+				pairs := make([]string, len(s.labels))
+				for i, l := range s.labels {
+					pairs[i] = l[0] + `="` + escapeLabelValue(l[1]) + `"`
+				}
+				b.WriteString("{" + strings.Join(pairs, ",") + "}")
+			}
+			b.WriteString(" " + strconv.FormatFloat(s.value, 'g', -1, 64) + "\n")
+		}
+	}
+	return b.String()
+}
+
+// escapeLabelValue escapes backslashes, double quotes and newlines, as the
+// text format requires.
+func escapeLabelValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
@@ -0,0 +1,180 @@
+package host
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+	"github.com/steelcutops/steelcut/steelcut/filemanager"
+	"github.com/steelcutops/steelcut/steelcut/hostmanager"
+	"github.com/steelcutops/steelcut/steelcut/networkmanager"
+	"github.com/steelcutops/steelcut/steelcut/packagemanager"
+	"github.com/steelcutops/steelcut/steelcut/servicemanager"
+	"github.com/steelcutops/steelcut/steelcut/usermanager"
+)
+
+var errNoDial = errors.New("not dialing in tests")
+
+type noDial struct{}
+
+func (noDial) Dial(network, addr string, config *ssh.ClientConfig, timeout time.Duration) (*ssh.Client, error) {
+	return nil, errNoDial
+}
+
+// readOnlyGate passes every command through a read-only UnixCommandManager,
+// which fails to dial for the commands it lets through, and answers those
+// like MockCommandManager. Commands that read-only mode blocks are recorded.
+type readOnlyGate struct {
+	MockCommandManager
+	gate    cm.UnixCommandManager
+	blocked []cm.CommandConfig
+}
+
+func newReadOnlyGate() *readOnlyGate {
+	return &readOnlyGate{
+		// Enough output for reads that run a second command to get there
+		MockCommandManager: MockCommandManager{Outputs: map[string]string{
+			"apt": "Package: nginx\nVersion: 1.24.0-1\nDescription: web server\n",
+		}},
+		gate: cm.UnixCommandManager{
+			Hostname:        "readonly.example.com",
+			SSHClient:       noDial{},
+			HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+			ReadOnly:        true,
+		},
+	}
+}
+
+func (g *readOnlyGate) Run(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
+	if _, err := g.gate.Run(ctx, config); errors.Is(err, cm.ErrReadOnlyMode) {
+		g.blocked = append(g.blocked, config)
+		return cm.CommandResult{}, err
+	}
+	return g.MockCommandManager.Run(ctx, config)
+}
+
+func (g *readOnlyGate) RunLocal(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
+	return g.Run(ctx, config)
+}
+
+func (g *readOnlyGate) RunRemote(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
+	return g.Run(ctx, config)
+}
+
+// TestReadOnlyAllowsReads runs every method that only lists, reports or
+// describes through a read-only command manager. A read added later that runs
+// a command missing from the read-only allowlist fails here.
+func TestReadOnlyAllowsReads(t *testing.T) {
+	g := newReadOnlyGate()
+
+	fileManagers := []filemanager.FileManager{
+		&filemanager.UnixFileManager{CommandManager: g},
+		&filemanager.DarwinFileManager{UnixFileManager: filemanager.UnixFileManager{CommandManager: g}},
+	}
+	for _, fm := range fileManagers {
+		fm.ListDirectory("/etc")
+		fm.ListTree("/etc", "*.conf")
+		fm.GetDirAttributes("/etc")
+		fm.DiskUsage("/")
+		fm.DiskUsageByMount()
+		fm.TreeChecksums("/etc/nginx")
+		fm.CompareTree("/etc/nginx", map[string]string{})
+		fm.GetFileAttributes("/etc/hosts")
+		fm.ReadFile("/etc/hosts")
+		fm.GetXattr("/etc/hosts", "user.note")
+		fm.ListXattrs("/etc/hosts")
+		fm.GetSELinuxContext("/etc/hosts")
+		fm.IsImmutable("/etc/hosts")
+		fm.ReadLink("/etc/localtime")
+		fm.ReadFstab()
+	}
+
+	packageManagers := []packagemanager.PackageManager{
+		&packagemanager.AptPackageManager{CommandManager: g},
+		&packagemanager.YumPackageManager{CommandManager: g},
+		&packagemanager.DnfPackageManager{CommandManager: g},
+		&packagemanager.ZypperPackageManager{CommandManager: g},
+		&packagemanager.PacmanPackageManager{CommandManager: g},
+		&packagemanager.ApkPackageManager{CommandManager: g},
+		&packagemanager.BrewPackageManager{CommandManager: g},
+	}
+	for _, pm := range packageManagers {
+		pm.ListPackages()
+		pm.PackageInfo("nginx")
+		pm.SearchPackages("nginx")
+		pm.ListHeldPackages()
+	}
+
+	serviceManagers := []servicemanager.ServiceManager{
+		&servicemanager.LinuxServiceManager{CommandManager: g},
+		&servicemanager.DarwinServiceManager{CommandManager: g},
+	}
+	for _, sm := range serviceManagers {
+		sm.CheckServiceStatus("nginx")
+		sm.IsServiceEnabled("nginx")
+		sm.ListServices()
+		sm.ServiceLogs("nginx", 10)
+		sm.FollowServiceLogs(context.Background(), "nginx", func(string) {})
+		sm.FailedUnits()
+	}
+
+	hm := &hostmanager.UnixHostManager{CommandManager: g}
+	hm.Info()
+	hm.Hostname()
+	hm.SystemHostname()
+	hm.Uptime()
+	hm.BootTime()
+	hm.CPUCount()
+	hm.LoadAverage()
+	hm.TotalMemory()
+	hm.FreeMemory()
+	hm.CPUUsage()
+	hm.Processes()
+	hm.RemoteTime()
+	hm.ClockSkew()
+	hm.ListScheduledCommands()
+	hm.Virtualization()
+	hm.KernelMessages(hostmanager.DmesgOptions{})
+	hm.ProcessInfo(1)
+	hm.ProcessList()
+	hm.ProcessTree()
+	hm.ProcessTreeFrom(1)
+	hm.TopProcesses("cpu", 5)
+	hm.TopProcesses("memory", 5)
+	hm.BlockDevices()
+	hm.SecurityModuleStatus()
+	hm.InstalledKernels()
+	hm.RunningKernel()
+	hm.Timezone()
+	hm.TimeSyncStatus()
+
+	nm := &networkmanager.UnixNetworkManager{CommandManager: g}
+	nm.Ping("192.0.2.1")
+	nm.PingStats("192.0.2.1", 3, time.Second)
+
+	um := &usermanager.LinuxUserManager{CommandManager: g}
+	um.GetUser("deploy")
+	um.ListUsers()
+	um.UserQuota("deploy")
+	um.RepquotaAll()
+
+	h := &Host{
+		CommandManager: g,
+		FileManager:    fileManagers[0],
+		HostManager:    hm,
+		ServiceManager: serviceManagers[0],
+	}
+	h.CanSudo()
+	h.EscalationTool()
+	h.PrometheusMetrics()
+	h.CollectDiagnostics(context.Background(), io.Discard)
+
+	for _, config := range g.blocked {
+		t.Errorf("Expected %s %q to be allowed in read-only mode", config.Command, config.Args)
+	}
+}
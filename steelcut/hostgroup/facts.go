@@ -0,0 +1,166 @@
+package hostgroup
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/steelcutops/steelcut/steelcut/commandmanager"
+	"github.com/steelcutops/steelcut/steelcut/host"
+)
+
+// HostFacts is a point-in-time health snapshot of a host. Facts that could not
+// be collected are left zero and the failures are recorded in Errors.
+type HostFacts struct {
+	Hostname       string
+	CPUCount       int
+	Load1          float64 // 1 minute load average
+	DiskUsePercent float64 // usage of the root filesystem
+	TotalMemory    int64   // bytes
+	FreeMemory     int64   // bytes
+	RebootRequired bool
+	Errors         []error
+}
+
+// OK reports whether every fact was collected.
+func (f HostFacts) OK() bool {
+	return len(f.Errors) == 0
+}
+
+// Thresholds configures when Summary flags a host as an outlier. A zero
+// value disables that check.
+type Thresholds struct {
+	MaxLoadPerCPU        float64 // 1 minute load divided by CPU count
+	MaxDiskUsePercent    float64
+	MinFreeMemoryPercent float64
+}
+
+// DefaultThresholds are reasonable starting points for a general fleet.
+var DefaultThresholds = Thresholds{
+	MaxLoadPerCPU:        1.0,
+	MaxDiskUsePercent:    90,
+	MinFreeMemoryPercent: 10,
+}
+
+// FleetSummary lists the hosts that need attention. Each list is sorted by
+// hostname.
+type FleetSummary struct {
+	Total          int
+	Healthy        int
+	Failed         []string // hosts with at least one collection error
+	HighLoad       []string
+	LowDisk        []string
+	LowMemory      []string
+	RebootRequired []string
+}
+
+// GatherFacts collects HostFacts from every host in the group, respecting the
+// group's Concurrency limit. A host whose collection fails still has an entry,
+// with the failures in its Errors. The returned error is only set when ctx is
+// done before collection finished.
+func (hg *HostGroup) GatherFacts(ctx context.Context) (map[string]HostFacts, error) {
+	hosts := hg.snapshot()
+	facts := make([]HostFacts, len(hosts))
+
+	hg.forEach(hosts, func(index int, h *host.Host) {
+		if err := ctx.Err(); err != nil {
+			facts[index] = HostFacts{Hostname: h.Hostname, Errors: []error{err}}
+			return
+		}
+		facts[index] = gatherHostFacts(ctx, h)
+	})
+
+	result := make(map[string]HostFacts, len(facts))
+	for _, f := range facts {
+		result[f.Hostname] = f
+	}
+	return result, ctx.Err()
+}
+
+func gatherHostFacts(ctx context.Context, h *host.Host) HostFacts {
+	facts := HostFacts{Hostname: h.Hostname}
+	record := func(name string, err error) {
+		if err != nil {
+			facts.Errors = append(facts.Errors, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+
+	var err error
+	facts.CPUCount, err = h.HostManager.CPUCount()
+	record("cpu count", err)
+
+	load, err := h.HostManager.LoadAverage()
+	record("load average", err)
+	facts.Load1 = load.One
+
+	usage, err := h.FileManager.DiskUsage("/")
+	record("disk usage", err)
+	facts.DiskUsePercent = usage.UsePercent
+
+	facts.TotalMemory, err = h.HostManager.TotalMemory()
+	record("total memory", err)
+
+	facts.FreeMemory, err = h.HostManager.FreeMemory()
+	record("free memory", err)
+
+	facts.RebootRequired, err = rebootRequired(ctx, h.CommandManager)
+	record("reboot required", err)
+
+	return facts
+}
+
+// rebootRequired checks for the flag file Debian and Ubuntu create when an
+// update needs a reboot.
+func rebootRequired(ctx context.Context, manager commandmanager.CommandManager) (bool, error) {
+	result, err := manager.Run(ctx, commandmanager.CommandConfig{
+		Command: "ls",
+		Args:    []string{"/var/run/reboot-required"},
+	})
+	if strings.Contains(result.STDERR, "No such file") {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(result.STDOUT) != "", nil
+}
+
+// Summary flags the hosts in facts that exceed thresholds.
+func Summary(facts map[string]HostFacts, thresholds Thresholds) FleetSummary {
+	summary := FleetSummary{Total: len(facts)}
+
+	for hostname, f := range facts {
+		healthy := true
+		flag := func(list *[]string) {
+			*list = append(*list, hostname)
+			healthy = false
+		}
+
+		if !f.OK() {
+			flag(&summary.Failed)
+		}
+		if thresholds.MaxLoadPerCPU > 0 && f.CPUCount > 0 && f.Load1/float64(f.CPUCount) > thresholds.MaxLoadPerCPU {
+			flag(&summary.HighLoad)
+		}
+		if thresholds.MaxDiskUsePercent > 0 && f.DiskUsePercent > thresholds.MaxDiskUsePercent {
+			flag(&summary.LowDisk)
+		}
+		if thresholds.MinFreeMemoryPercent > 0 && f.TotalMemory > 0 &&
+			float64(f.FreeMemory)/float64(f.TotalMemory)*100 < thresholds.MinFreeMemoryPercent {
+			flag(&summary.LowMemory)
+		}
+		if f.RebootRequired {
+			flag(&summary.RebootRequired)
+		}
+
+		if healthy {
+			summary.Healthy++
+		}
+	}
+
+	for _, list := range [][]string{summary.Failed, summary.HighLoad, summary.LowDisk, summary.LowMemory, summary.RebootRequired} {
+		sort.Strings(list)
+	}
+	return summary
+}
@@ -11,6 +11,14 @@ import (
 type HostGroup struct {
 	sync.RWMutex
 	Hosts map[string]*host.Host
+
+	// Concurrency limits how many hosts are operated on at once. Zero means
+	// no limit.
+	Concurrency int
+
+	// MaxUnavailable is how many hosts in a RollingApply batch may fail
+	// without halting the rollout.
+	MaxUnavailable int
 }
 
 // NewHostGroup creates a new HostGroup with the given hosts.
@@ -45,8 +53,8 @@ func (hg *HostGroup) HasHost(hostname string) bool {
 }
 
 func (hg *HostGroup) Run(ctx context.Context, cmd string, args ...string) []commandmanager.CommandResult {
-	var wg sync.WaitGroup
-	results := make([]commandmanager.CommandResult, len(hg.Hosts))
+	hosts := hg.snapshot()
+	results := make([]commandmanager.CommandResult, len(hosts))
 
 	config := commandmanager.CommandConfig{
 		Command: cmd,
@@ -54,24 +62,48 @@ func (hg *HostGroup) Run(ctx context.Context, cmd string, args ...string) []comm
 		Sudo:    false,
 	}
 
+	hg.forEach(hosts, func(index int, hostInstance *host.Host) {
+		result, err := hostInstance.CommandManager.Run(ctx, config)
+		if err != nil {
+			result.STDERR = err.Error()
+		}
+		result.Command = cmd // Store the command in the result
+		results[index] = result
+	})
+
+	return results
+}
+
+// snapshot returns the group's hosts so they can be used without holding the lock.
+func (hg *HostGroup) snapshot() []*host.Host {
 	hg.RLock()
-	i := 0
+	defer hg.RUnlock()
+	hosts := make([]*host.Host, 0, len(hg.Hosts))
 	for _, h := range hg.Hosts {
+		hosts = append(hosts, h)
+	}
+	return hosts
+}
+
+// forEach calls fn for every host concurrently, running at most
+// Concurrency calls at a time, and waits for all of them to finish.
+func (hg *HostGroup) forEach(hosts []*host.Host, fn func(index int, h *host.Host)) {
+	var sem chan struct{}
+	if hg.Concurrency > 0 {
+		sem = make(chan struct{}, hg.Concurrency)
+	}
+
+	var wg sync.WaitGroup
+	for i, h := range hosts {
 		wg.Add(1)
-		go func(hostInstance *host.Host, index int) {
+		go func(index int, hostInstance *host.Host) {
 			defer wg.Done()
-			result, err := hostInstance.CommandManager.Run(ctx, config)
-			if err != nil {
-				result.STDERR = err.Error()
+			if sem != nil {
+				sem <- struct{}{}
+				defer func() { <-sem }()
 			}
-			result.Command = cmd // Store the command in the result
-			results[index] = result
-		}(h, i)
-		i++
+			fn(index, hostInstance)
+		}(i, h)
 	}
-	hg.RUnlock()
-
 	wg.Wait()
-
-	return results
 }
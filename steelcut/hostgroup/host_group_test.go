@@ -0,0 +1,274 @@
+package hostgroup
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+	"github.com/steelcutops/steelcut/steelcut/filemanager"
+	"github.com/steelcutops/steelcut/steelcut/host"
+	"github.com/steelcutops/steelcut/steelcut/hostmanager"
+	"github.com/steelcutops/steelcut/steelcut/packagemanager"
+)
+
+type MockCommandManager struct {
+	Results map[string]cm.CommandResult
+	Errors  map[string]error
+}
+
+func (m *MockCommandManager) RunLocal(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
+	return m.Run(ctx, config)
+}
+
+func (m *MockCommandManager) RunRemote(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
+	return m.Run(ctx, config)
+}
+
+// Run answers by "command arg" when Results has an entry for the first
+// argument, e.g. "cat /proc/loadavg", and by the command otherwise.
+func (m *MockCommandManager) Run(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
+	if len(config.Args) > 0 {
+		key := config.Command + " " + config.Args[0]
+		if result, ok := m.Results[key]; ok {
+			return result, m.Errors[key]
+		}
+	}
+	return m.Results[config.Command], m.Errors[config.Command]
+}
+
+type mockHealth struct {
+	cpus, load, disk, totalKB, freeKB string
+	rebootRequired                    bool
+	dfErr                             error
+}
+
+func newMockHost(hostname string, health mockHealth) *host.Host {
+	ls := cm.CommandResult{STDERR: "ls: cannot access '/var/run/reboot-required': No such file or directory"}
+	if health.rebootRequired {
+		ls = cm.CommandResult{STDOUT: "/var/run/reboot-required\n"}
+	}
+
+	mock := &MockCommandManager{
+		Results: map[string]cm.CommandResult{
+			"nproc":             {STDOUT: health.cpus + "\n"},
+			"cat /proc/loadavg": {STDOUT: health.load + " 0.50 0.40 1/467 12345\n"},
+			"df": {STDOUT: "Filesystem 1B-blocks Used Available Use% Mounted on\n" +
+				"/dev/sda1 1000 500 500 " + health.disk + "% /\n"},
+			"cat": {STDOUT: "MemTotal: " + health.totalKB + " kB\nMemAvailable: " + health.freeKB + " kB\n"},
+			"ls":  ls,
+		},
+		Errors: map[string]error{"df": health.dfErr},
+	}
+
+	return &host.Host{
+		Hostname:       hostname,
+		CommandManager: mock,
+		HostManager:    &hostmanager.UnixHostManager{CommandManager: mock},
+		FileManager:    &filemanager.UnixFileManager{CommandManager: mock},
+	}
+}
+
+func TestGatherFactsSummary(t *testing.T) {
+	hg := NewHostGroup(
+		newMockHost("healthy", mockHealth{cpus: "4", load: "1.20", disk: "40", totalKB: "8000000", freeKB: "4000000"}),
+		newMockHost("busy", mockHealth{cpus: "2", load: "5.00", disk: "40", totalKB: "8000000", freeKB: "4000000"}),
+		newMockHost("full", mockHealth{cpus: "4", load: "0.10", disk: "97", totalKB: "8000000", freeKB: "200000", rebootRequired: true}),
+		newMockHost("broken", mockHealth{cpus: "4", load: "0.10", disk: "10", totalKB: "8000000", freeKB: "4000000", dfErr: errors.New("df: timed out")}),
+	)
+	hg.Concurrency = 2
+
+	facts, err := hg.GatherFacts(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(facts) != 4 {
+		t.Fatalf("Expected facts for 4 hosts, got: %v", facts)
+	}
+
+	if f := facts["busy"]; f.Load1 != 5.0 || f.CPUCount != 2 {
+		t.Errorf("Expected busy host load 5.0 on 2 CPUs, got: %+v", f)
+	}
+	if f := facts["broken"]; f.OK() || f.CPUCount != 4 {
+		t.Errorf("Expected partial facts with an error for broken host, got: %+v", f)
+	}
+
+	summary := Summary(facts, DefaultThresholds)
+	expected := FleetSummary{
+		Total:          4,
+		Healthy:        1,
+		Failed:         []string{"broken"},
+		HighLoad:       []string{"busy"},
+		LowDisk:        []string{"full"},
+		LowMemory:      []string{"full"},
+		RebootRequired: []string{"full"},
+	}
+	if !reflect.DeepEqual(summary, expected) {
+		t.Errorf("Expected %+v, got: %+v", expected, summary)
+	}
+}
+
+type countingCommandManager struct {
+	MockCommandManager
+	mu      sync.Mutex
+	running int
+	peak    int
+	release chan struct{}
+}
+
+func (c *countingCommandManager) Run(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
+	c.mu.Lock()
+	c.running++
+	if c.running > c.peak {
+		c.peak = c.running
+	}
+	c.mu.Unlock()
+
+	<-c.release
+
+	c.mu.Lock()
+	c.running--
+	c.mu.Unlock()
+	return cm.CommandResult{}, nil
+}
+
+func TestRunConcurrencyLimit(t *testing.T) {
+	counter := &countingCommandManager{release: make(chan struct{})}
+	hg := NewHostGroup()
+	hg.Concurrency = 2
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		hg.AddHost(&host.Host{Hostname: name, CommandManager: counter})
+	}
+
+	done := make(chan struct{})
+	go func() {
+		hg.Run(context.Background(), "true")
+		close(done)
+	}()
+	for i := 0; i < 5; i++ {
+		counter.release <- struct{}{}
+	}
+	<-done
+
+	if counter.peak > 2 {
+		t.Errorf("Expected at most 2 concurrent commands, got %d", counter.peak)
+	}
+}
+
+func TestRollingApply(t *testing.T) {
+	var hosts []*host.Host
+	for _, name := range []string{"web5", "web2", "web1", "web4", "web3"} {
+		hosts = append(hosts, &host.Host{Hostname: name})
+	}
+	group := NewHostGroup(hosts...)
+
+	var mu sync.Mutex
+	var order []string
+	apply := func(fail string) func(*host.Host) error {
+		return func(h *host.Host) error {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, h.Hostname)
+			if h.Hostname == fail {
+				return errors.New("health check failed")
+			}
+			return nil
+		}
+	}
+	// batchesOf groups the call order into batches of two, sorted within each
+	// batch as hosts in a batch run concurrently.
+	batchesOf := func() [][]string {
+		var batches [][]string
+		for i := 0; i < len(order); i += 2 {
+			batch := append([]string(nil), order[i:min(i+2, len(order))]...)
+			sort.Strings(batch)
+			batches = append(batches, batch)
+		}
+		return batches
+	}
+
+	if err := group.RollingApply(context.Background(), 2, apply("")); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	expected := [][]string{{"web1", "web2"}, {"web3", "web4"}, {"web5"}}
+	if !reflect.DeepEqual(batchesOf(), expected) {
+		t.Errorf("Expected batches %v, got: %v", expected, batchesOf())
+	}
+
+	// A failure in the second batch halts before web5
+	order = nil
+	err := group.RollingApply(context.Background(), 2, apply("web3"))
+	var rollingErr *RollingError
+	if !errors.As(err, &rollingErr) {
+		t.Fatalf("Expected a RollingError, got: %v", err)
+	}
+	if !reflect.DeepEqual(rollingErr.Remaining, []string{"web5"}) || rollingErr.Failures["web3"] == nil {
+		t.Errorf("Expected web3 to fail and web5 to remain, got: %+v", rollingErr)
+	}
+	if !reflect.DeepEqual(batchesOf(), expected[:2]) {
+		t.Errorf("Expected the third batch to be untouched, got: %v", batchesOf())
+	}
+
+	// Tolerated failures let the rollout finish
+	order = nil
+	group.MaxUnavailable = 1
+	err = group.RollingApply(context.Background(), 2, apply("web3"))
+	if !errors.As(err, &rollingErr) || len(rollingErr.Remaining) != 0 || len(order) != 5 {
+		t.Errorf("Expected a completed rollout reporting web3, got: %v (order %v)", err, order)
+	}
+}
+
+// upgradingPackageManager lists one pending update and fails UpgradeAll when
+// fail is set. Other PackageManager methods are not implemented.
+type upgradingPackageManager struct {
+	packagemanager.PackageManager
+	fail     bool
+	upgraded bool
+}
+
+func (m *upgradingPackageManager) ListUpdates() ([]packagemanager.Update, error) {
+	return []packagemanager.Update{{Name: "openssl", AvailableVersion: "3.0.13"}}, nil
+}
+
+func (m *upgradingPackageManager) UpgradeAll() ([]string, error) {
+	if m.fail {
+		return nil, errors.New("dpkg was interrupted")
+	}
+	m.upgraded = true
+	return nil, nil
+}
+
+func TestRollingUpgradeAll(t *testing.T) {
+	managers := make(map[string]*upgradingPackageManager)
+	var hosts []*host.Host
+	for _, name := range []string{"web1", "web2", "web3", "web4", "web5", "web6"} {
+		managers[name] = &upgradingPackageManager{fail: name == "web1" || name == "web4"}
+		hosts = append(hosts, &host.Host{Hostname: name, PackageManager: managers[name]})
+	}
+	group := NewHostGroup(hosts...)
+
+	// Two failures are tolerated per batch but not in total
+	group.MaxUnavailable = 2
+	reports, err := group.RollingUpgradeAll(context.Background(), 2, 1)
+	var rollingErr *RollingError
+	if !errors.As(err, &rollingErr) || !reflect.DeepEqual(rollingErr.Remaining, []string{"web5", "web6"}) {
+		t.Fatalf("Expected the rollout to halt after web4, got: %v", err)
+	}
+	if len(reports) != 4 || reports["web1"].Err == nil || reports["web2"].Err != nil {
+		t.Errorf("Unexpected reports %+v", reports)
+	}
+	if updates := reports["web2"].Updates; len(updates) != 1 || updates[0].Name != "openssl" {
+		t.Errorf("Expected the planned update for web2, got %+v", updates)
+	}
+	if managers["web5"].upgraded || !managers["web3"].upgraded {
+		t.Errorf("Expected web3 upgraded and web5 untouched")
+	}
+
+	reports, err = group.RollingUpgradeAll(context.Background(), 2, 2)
+	if !errors.As(err, &rollingErr) || len(rollingErr.Remaining) != 0 || len(reports) != 6 {
+		t.Errorf("Expected a completed rollout reporting two failures, got: %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+package hostgroup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/steelcutops/steelcut/steelcut/host"
+	"github.com/steelcutops/steelcut/steelcut/packagemanager"
+)
+
+// RollingError reports the hosts that failed during RollingApply or
+// RollingUpgradeAll. Remaining lists the hosts that were never attempted
+// because the rollout halted; it is empty when every failure was tolerated.
+type RollingError struct {
+	Failures  map[string]error
+	Remaining []string
+}
+
+func (e *RollingError) Error() string {
+	names := make([]string, 0, len(e.Failures))
+	for name := range e.Failures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	msgs := make([]string, len(names))
+	for i, name := range names {
+		msgs[i] = fmt.Sprintf("%s: %v", name, e.Failures[name])
+	}
+	if len(e.Remaining) > 0 {
+		return fmt.Sprintf("rollout halted with %d hosts remaining: %s", len(e.Remaining), strings.Join(msgs, "; "))
+	}
+	return fmt.Sprintf("rollout completed with %d failed hosts: %s", len(names), strings.Join(msgs, "; "))
+}
+
+// RollingApply calls fn for the group's hosts in batches of batchSize, in
+// hostname order. Hosts within a batch are processed concurrently, limited by
+// Concurrency, and the next batch starts only once the current one is done.
+// When more than MaxUnavailable hosts in a batch fail, the rollout halts and
+// the remaining hosts are left untouched. Any failure is reported as a
+// *RollingError.
+func (hg *HostGroup) RollingApply(ctx context.Context, batchSize int, fn func(*host.Host) error) error {
+	return hg.rollout(ctx, batchSize, fn, func(batchFailures, _ int) bool {
+		return batchFailures > hg.MaxUnavailable
+	})
+}
+
+// UpgradeReport is the outcome of upgrading one host in RollingUpgradeAll.
+type UpgradeReport struct {
+	Updates []packagemanager.Update // the updates planned before upgrading
+	Err     error
+}
+
+// RollingUpgradeAll upgrades every package on the group's hosts in batches
+// of batchSize, as RollingApply does, but halts once more than maxFailures
+// hosts have failed in total. The report holds an entry for every host that
+// was attempted; any failure is also reported as a *RollingError.
+func (hg *HostGroup) RollingUpgradeAll(ctx context.Context, batchSize int, maxFailures int) (map[string]UpgradeReport, error) {
+	var mu sync.Mutex
+	reports := make(map[string]UpgradeReport)
+	err := hg.rollout(ctx, batchSize, func(h *host.Host) error {
+		updates, err := upgradeHost(h)
+		mu.Lock()
+		reports[h.Hostname] = UpgradeReport{Updates: updates, Err: err}
+		mu.Unlock()
+		return err
+	}, func(_, totalFailures int) bool {
+		return totalFailures > maxFailures
+	})
+	return reports, err
+}
+
+// upgradeHost lists the host's pending updates, then upgrades everything.
+// Package managers that can neither plan an upgrade nor list updates report
+// the names UpgradeAll returns.
+func upgradeHost(h *host.Host) ([]packagemanager.Update, error) {
+	pm := h.PackageManager
+	if pm == nil {
+		return nil, errors.New("host has no package manager")
+	}
+
+	var updates []packagemanager.Update
+	var err error
+	switch lister := pm.(type) {
+	case packagemanager.DryRunner:
+		updates, err = lister.PlanUpgradeAll()
+	case interface {
+		ListUpdates() ([]packagemanager.Update, error)
+	}:
+		updates, err = lister.ListUpdates()
+	default:
+		names, err := pm.UpgradeAll()
+		for _, name := range names {
+			updates = append(updates, packagemanager.Update{Name: name})
+		}
+		return updates, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error listing updates: %w", err)
+	}
+
+	if _, err := pm.UpgradeAll(); err != nil {
+		return updates, err
+	}
+	return updates, nil
+}
+
+// rollout calls fn for hosts in batches, halting when halt reports true for
+// the failures in the last batch and in total.
+func (hg *HostGroup) rollout(ctx context.Context, batchSize int, fn func(*host.Host) error, halt func(batchFailures, totalFailures int) bool) error {
+	if batchSize <= 0 {
+		return fmt.Errorf("batch size must be positive, got %d", batchSize)
+	}
+
+	hosts := hg.snapshot()
+	sort.Slice(hosts, func(i, j int) bool { return hosts[i].Hostname < hosts[j].Hostname })
+
+	failures := make(map[string]error)
+	for start := 0; start < len(hosts); start += batchSize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		batch := hosts[start:min(start+batchSize, len(hosts))]
+		var mu sync.Mutex
+		batchFailures := 0
+		hg.forEach(batch, func(_ int, h *host.Host) {
+			if err := fn(h); err != nil {
+				mu.Lock()
+				failures[h.Hostname] = err
+				batchFailures++
+				mu.Unlock()
+			}
+		})
+
+		if halt(batchFailures, len(failures)) {
+			remaining := make([]string, 0, len(hosts)-start-len(batch))
+			for _, h := range hosts[start+len(batch):] {
+				remaining = append(remaining, h.Hostname)
+			}
+			return &RollingError{Failures: failures, Remaining: remaining}
+		}
+	}
+
+	if len(failures) > 0 {
+		return &RollingError{Failures: failures}
+	}
+	return nil
+}
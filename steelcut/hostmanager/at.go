@@ -0,0 +1,150 @@
+package hostmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// ErrAtUnavailable is returned when at is not installed or atd is not running.
+var ErrAtUnavailable = errors.New("at is not installed or atd is not running")
+
+// AtJob is a command queued with at.
+type AtJob struct {
+	ID    string
+	When  time.Time
+	Queue string
+	User  string
+}
+
+// at and atq run with TZ=UTC so time specs and listings are independent of
+// the host's local time zone.
+var atEnv = []string{"TZ=UTC"}
+
+var atJobPattern = regexp.MustCompile(`job (\d+) at`)
+
+// ScheduleCommand queues command to run once at when and returns the at job ID.
+func (uhm *UnixHostManager) ScheduleCommand(command string, when time.Time) (string, error) {
+	if strings.TrimSpace(command) == "" {
+		return "", errors.New("scheduled command must not be empty")
+	}
+	if strings.ContainsRune(command, 0) {
+		return "", errors.New("scheduled command must not contain NUL bytes")
+	}
+	if !when.After(time.Now()) {
+		return "", fmt.Errorf("scheduled time %s is in the past", when.Format(time.RFC3339))
+	}
+
+	script := fmt.Sprintf("printf '%%s\\n' %s | at -t %s", cm.ShellQuote(command), atTimeSpec(when))
+	result, err := uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "sh",
+		Args:    []string{"-c", script},
+		Env:     atEnv,
+	})
+	if atErr := checkAtErrors(result); atErr != nil {
+		return "", atErr
+	}
+	if err != nil {
+		return "", err
+	}
+
+	// at reports the job on stderr
+	match := atJobPattern.FindStringSubmatch(result.STDERR + result.STDOUT)
+	if match == nil {
+		return "", fmt.Errorf("unexpected at output: %s", strings.TrimSpace(result.STDERR))
+	}
+	return match[1], nil
+}
+
+// ListScheduledCommands returns the jobs in the at queue.
+func (uhm *UnixHostManager) ListScheduledCommands() ([]AtJob, error) {
+	result, err := uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "atq",
+		Env:     atEnv,
+	})
+	if atErr := checkAtErrors(result); atErr != nil {
+		return nil, atErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return parseAtq(result.STDOUT)
+}
+
+// CancelScheduledCommand removes a job from the at queue.
+func (uhm *UnixHostManager) CancelScheduledCommand(jobID string) error {
+	if _, err := strconv.Atoi(jobID); err != nil {
+		return fmt.Errorf("invalid at job ID: %q", jobID)
+	}
+
+	result, err := uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "atrm",
+		Args:    []string{jobID},
+	})
+	if atErr := checkAtErrors(result); atErr != nil {
+		return atErr
+	}
+	if err != nil {
+		return err
+	}
+	if result.ExitCode != 0 || strings.Contains(result.STDERR, "Cannot find jobid") {
+		return fmt.Errorf("failed to cancel at job %s: %s", jobID, strings.TrimSpace(result.STDERR))
+	}
+	return nil
+}
+
+// atTimeSpec formats when for "at -t", which takes [[CC]YY]MMDDhhmm[.ss].
+func atTimeSpec(when time.Time) string {
+	return when.UTC().Format("200601021504.05")
+}
+
+func checkAtErrors(result cm.CommandResult) error {
+	// "not found" covers both bash's and dash's missing command messages
+	if strings.Contains(result.STDERR, "not found") || strings.Contains(result.STDERR, "No atd running") {
+		return fmt.Errorf("%w: %s", ErrAtUnavailable, strings.TrimSpace(result.STDERR))
+	}
+	return nil
+}
+
+// parseAtq parses atq output. GNU atq prints "ID<TAB>date queue user" while
+// BSD atq omits the queue and user.
+func parseAtq(output string) ([]AtJob, error) {
+	var jobs []AtJob
+	err := cm.EachLine(output, func(line string) error {
+		id, rest, ok := strings.Cut(line, "\t")
+		if !ok {
+			return fmt.Errorf("unexpected atq line: %q", line)
+		}
+
+		fields := strings.Fields(rest)
+		if len(fields) < 5 {
+			return fmt.Errorf("unexpected atq line: %q", line)
+		}
+
+		when, err := time.Parse("Mon Jan 2 15:04:05 2006", strings.Join(fields[:5], " "))
+		if err != nil {
+			return fmt.Errorf("error parsing atq time in %q: %v", line, err)
+		}
+
+		job := AtJob{ID: strings.TrimSpace(id), When: when}
+		if len(fields) > 5 {
+			job.Queue = fields[5]
+		}
+		if len(fields) > 6 {
+			job.User = fields[6]
+		}
+		jobs = append(jobs, job)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
@@ -0,0 +1,264 @@
+package hostmanager
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// BlockDevice is a disk, partition or other block device, with the devices
+// built on it as Children: partitions on a disk, LVM volumes on a partition,
+// or APFS volumes on macOS. Type uses lsblk's names ("disk", "part", "lvm",
+// "crypt", ...); on macOS it is "disk", "part" or "volume", and FSType of a
+// partition is its content type, e.g. "Apple_APFS".
+type BlockDevice struct {
+	Name       string
+	Size       int64 // in bytes
+	Type       string
+	FSType     string
+	MountPoint string
+	UUID       string
+	Children   []BlockDevice
+}
+
+// BlockDevices returns the host's block devices as a tree of disks and the
+// devices on them.
+func (uhm *UnixHostManager) BlockDevices() ([]BlockDevice, error) {
+	darwin, err := uhm.isDarwin()
+	if err != nil {
+		return nil, err
+	}
+
+	config := cm.CommandConfig{
+		Command: "lsblk",
+		Args:    []string{"-J", "-b", "-o", "NAME,SIZE,TYPE,FSTYPE,MOUNTPOINT,UUID"},
+	}
+	if darwin {
+		config = cm.CommandConfig{Command: "diskutil", Args: []string{"list", "-plist"}}
+	}
+	result, err := uhm.CommandManager.Run(context.TODO(), config)
+	if err != nil {
+		return nil, err
+	}
+	if result.ExitCode != 0 {
+		return nil, fmt.Errorf("%s failed: %s", config.Command, strings.TrimSpace(result.STDERR))
+	}
+
+	if darwin {
+		return parseDiskutilPlist(result.STDOUT)
+	}
+	return parseLsblkJSON(result.STDOUT)
+}
+
+// lsblkSize accepts SIZE both as a JSON number and as a string, which
+// util-linux before 2.33 emitted even with -b.
+type lsblkSize int64
+
+func (s *lsblkSize) UnmarshalJSON(data []byte) error {
+	text := strings.Trim(string(data), `"`)
+	if text == "null" || text == "" {
+		return nil
+	}
+	n, err := strconv.ParseInt(text, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid size %s: %v", data, err)
+	}
+	*s = lsblkSize(n)
+	return nil
+}
+
+type lsblkDevice struct {
+	Name       string        `json:"name"`
+	Size       lsblkSize     `json:"size"`
+	Type       string        `json:"type"`
+	FSType     string        `json:"fstype"`
+	MountPoint string        `json:"mountpoint"`
+	UUID       string        `json:"uuid"`
+	Children   []lsblkDevice `json:"children"`
+}
+
+func (d lsblkDevice) blockDevice() BlockDevice {
+	device := BlockDevice{
+		Name:       d.Name,
+		Size:       int64(d.Size),
+		Type:       d.Type,
+		FSType:     d.FSType,
+		MountPoint: d.MountPoint,
+		UUID:       d.UUID,
+	}
+	for _, child := range d.Children {
+		device.Children = append(device.Children, child.blockDevice())
+	}
+	return device
+}
+
+// parseLsblkJSON parses "lsblk -J -b" output. Null fields are left empty.
+func parseLsblkJSON(output string) ([]BlockDevice, error) {
+	var parsed struct {
+		BlockDevices []lsblkDevice `json:"blockdevices"`
+	}
+	if err := json.Unmarshal([]byte(output), &parsed); err != nil {
+		return nil, fmt.Errorf("error parsing lsblk output: %v", err)
+	}
+
+	devices := make([]BlockDevice, 0, len(parsed.BlockDevices))
+	for _, d := range parsed.BlockDevices {
+		devices = append(devices, d.blockDevice())
+	}
+	return devices, nil
+}
+
+// parseDiskutilPlist parses "diskutil list -plist" output. Each entry of
+// AllDisksAndPartitions is a disk with its Partitions, or an APFS container
+// with its APFSVolumes.
+func parseDiskutilPlist(output string) ([]BlockDevice, error) {
+	root, err := decodePlist(strings.NewReader(output))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing diskutil output: %v", err)
+	}
+	dict, ok := root.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("unexpected diskutil output format")
+	}
+	disks, _ := dict["AllDisksAndPartitions"].([]any)
+
+	devices := make([]BlockDevice, 0, len(disks))
+	for _, d := range disks {
+		disk, ok := d.(map[string]any)
+		if !ok {
+			continue
+		}
+		device := diskutilDevice(disk, "disk")
+		for _, key := range []string{"Partitions", "APFSVolumes"} {
+			kind := "part"
+			if key == "APFSVolumes" {
+				kind = "volume"
+			}
+			children, _ := disk[key].([]any)
+			for _, c := range children {
+				if child, ok := c.(map[string]any); ok {
+					device.Children = append(device.Children, diskutilDevice(child, kind))
+				}
+			}
+		}
+		devices = append(devices, device)
+	}
+	return devices, nil
+}
+
+func diskutilDevice(entry map[string]any, kind string) BlockDevice {
+	str := func(key string) string {
+		s, _ := entry[key].(string)
+		return s
+	}
+	size, _ := entry["Size"].(int64)
+
+	device := BlockDevice{
+		Name:       str("DeviceIdentifier"),
+		Size:       size,
+		Type:       kind,
+		FSType:     str("Content"),
+		MountPoint: str("MountPoint"),
+		UUID:       str("VolumeUUID"),
+	}
+	if kind == "volume" {
+		device.FSType = "apfs"
+	}
+	return device
+}
+
+// decodePlist decodes an XML property list into dicts (map[string]any),
+// arrays ([]any), strings, int64, float64, bool and, for data, []byte.
+// Dates are returned as their string form.
+func decodePlist(r io.Reader) (any, error) {
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			if start.Name.Local == "plist" {
+				continue
+			}
+			return decodePlistValue(decoder, start)
+		}
+	}
+}
+
+func decodePlistValue(decoder *xml.Decoder, start xml.StartElement) (any, error) {
+	switch start.Name.Local {
+	case "dict":
+		dict := make(map[string]any)
+		var key string
+		for {
+			token, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			switch t := token.(type) {
+			case xml.EndElement:
+				return dict, nil
+			case xml.StartElement:
+				if t.Name.Local == "key" {
+					if err := decoder.DecodeElement(&key, &t); err != nil {
+						return nil, err
+					}
+					continue
+				}
+				value, err := decodePlistValue(decoder, t)
+				if err != nil {
+					return nil, err
+				}
+				dict[key] = value
+			}
+		}
+	case "array":
+		array := []any{}
+		for {
+			token, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			switch t := token.(type) {
+			case xml.EndElement:
+				return array, nil
+			case xml.StartElement:
+				value, err := decodePlistValue(decoder, t)
+				if err != nil {
+					return nil, err
+				}
+				array = append(array, value)
+			}
+		}
+	case "true", "false":
+		if err := decoder.Skip(); err != nil {
+			return nil, err
+		}
+		return start.Name.Local == "true", nil
+	}
+
+	var text string
+	if err := decoder.DecodeElement(&text, &start); err != nil {
+		return nil, err
+	}
+	text = strings.TrimSpace(text)
+	switch start.Name.Local {
+	case "integer":
+		return strconv.ParseInt(text, 10, 64)
+	case "real":
+		return strconv.ParseFloat(text, 64)
+	case "data":
+		return base64.StdEncoding.DecodeString(strings.Join(strings.Fields(text), ""))
+	case "string", "date":
+		return text, nil
+	}
+	return nil, fmt.Errorf("unsupported plist element: %s", start.Name.Local)
+}
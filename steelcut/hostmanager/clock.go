@@ -0,0 +1,81 @@
+package hostmanager
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// RemoteTime retrieves the host's current time in UTC.
+func (uhm *UnixHostManager) RemoteTime() (time.Time, error) {
+	return remoteTime(uhm.CommandManager)
+}
+
+func remoteTime(manager cm.CommandManager) (time.Time, error) {
+	output, err := manager.Run(context.TODO(), cm.CommandConfig{
+		Command: "date",
+		Args:    []string{"-u", "+%s.%N"},
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return parseEpoch(output.STDOUT)
+}
+
+// ClockSkew measures how far the host's clock is ahead of the local clock.
+// A negative value means the host is behind. The local clock is sampled
+// before and after the remote read and the midpoint is used, so the command
+// round trip does not count as skew. A first, untimed read opens the
+// connection, which the timed read reuses, so the dial is not counted either.
+func (uhm *UnixHostManager) ClockSkew() (time.Duration, error) {
+	var before, remote, after time.Time
+	err := cm.WithConnection(uhm.CommandManager, func(manager cm.CommandManager) error {
+		if _, err := remoteTime(manager); err != nil {
+			return err
+		}
+
+		var err error
+		before = time.Now()
+		remote, err = remoteTime(manager)
+		after = time.Now()
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return clockSkew(before, remote, after), nil
+}
+
+func clockSkew(before, remote, after time.Time) time.Duration {
+	midpoint := before.Add(after.Sub(before) / 2)
+	return remote.Sub(midpoint)
+}
+
+// parseEpoch parses "seconds.nanoseconds" output from date. BSD date does not
+// support %N and prints it literally, in which case only seconds are used.
+func parseEpoch(output string) (time.Time, error) {
+	secStr, fracStr, _ := strings.Cut(strings.TrimSpace(output), ".")
+
+	sec, err := strconv.ParseInt(secStr, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error parsing epoch seconds: %v", err)
+	}
+
+	var nsec int64
+	if fracStr != "" && strings.Trim(fracStr, "0123456789") == "" {
+		// Normalise to nine digits of nanoseconds
+		fracStr = (fracStr + "000000000")[:9]
+		nsec, err = strconv.ParseInt(fracStr, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("error parsing epoch nanoseconds: %v", err)
+		}
+	}
+
+	return time.Unix(sec, nsec).UTC(), nil
+}
@@ -0,0 +1,218 @@
+package hostmanager
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// KernelMessage is a single entry from the kernel ring buffer.
+type KernelMessage struct {
+	Timestamp time.Time
+	Facility  string
+	Level     string
+	Message   string
+}
+
+// DmesgOptions filters the kernel messages returned by KernelMessages.
+type DmesgOptions struct {
+	Levels     []string  // e.g. "err", "warn"; empty returns all levels
+	Since      time.Time // zero means no lower bound
+	Until      time.Time // zero means no upper bound
+	KernelOnly bool      // only messages from the kernel facility
+}
+
+var dmesgLevels = map[string]bool{
+	"emerg": true, "alert": true, "crit": true, "err": true,
+	"warn": true, "notice": true, "info": true, "debug": true,
+}
+
+const dmesgISOLayout = "2006-01-02T15:04:05,999999-07:00"
+
+// KernelMessages reads the kernel ring buffer. sudo is used automatically when
+// the host restricts dmesg to root, and dmesg versions without
+// --time-format=iso fall back to boot-relative timestamps.
+func (uhm *UnixHostManager) KernelMessages(opts DmesgOptions) ([]KernelMessage, error) {
+	var filters []string
+	if len(opts.Levels) > 0 {
+		for _, level := range opts.Levels {
+			if !dmesgLevels[level] {
+				return nil, fmt.Errorf("unknown dmesg level: %s", level)
+			}
+		}
+		filters = append(filters, "-l", strings.Join(opts.Levels, ","))
+	}
+	if opts.KernelOnly {
+		filters = append(filters, "-k")
+	}
+
+	result, err := uhm.runDmesg(append([]string{"-x", "--time-format=iso"}, filters...))
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []KernelMessage
+	if dmesgUnsupportedOption(result.STDERR) {
+		result, err = uhm.runDmesg(append([]string{"-x"}, filters...))
+		if err != nil {
+			return nil, err
+		}
+		if dmesgUnsupportedOption(result.STDERR) {
+			return nil, fmt.Errorf("dmesg does not support decoding: %s", strings.TrimSpace(result.STDERR))
+		}
+		bootTime, err := uhm.bootTime()
+		if err != nil {
+			return nil, err
+		}
+		messages, err = parseDmesgBracketed(result.STDOUT, bootTime)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		messages, err = parseDmesgISO(result.STDOUT)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return filterKernelMessages(messages, opts.Since, opts.Until), nil
+}
+
+// runDmesg runs dmesg, retrying with sudo when kernel.dmesg_restrict denies
+// unprivileged access.
+func (uhm *UnixHostManager) runDmesg(args []string) (cm.CommandResult, error) {
+	config := cm.CommandConfig{Command: "dmesg", Args: args}
+	result, err := uhm.CommandManager.Run(context.TODO(), config)
+	if strings.Contains(result.STDERR, "Operation not permitted") {
+		config.Sudo = true
+		result, err = uhm.CommandManager.Run(context.TODO(), config)
+	}
+	if err != nil && !dmesgUnsupportedOption(result.STDERR) {
+		return result, err
+	}
+	return result, nil
+}
+
+func dmesgUnsupportedOption(stderr string) bool {
+	return strings.Contains(stderr, "unrecognized option") ||
+		strings.Contains(stderr, "unknown time format") ||
+		strings.Contains(stderr, "invalid option")
+}
+
+// bootTime reads the boot time from the btime line of /proc/stat.
+func (uhm *UnixHostManager) bootTime() (time.Time, error) {
+	result, err := uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "cat",
+		Args:    []string{"/proc/stat"},
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	for _, line := range cm.Lines(result.STDOUT) {
+		if value, ok := strings.CutPrefix(line, "btime "); ok {
+			sec, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			if err != nil {
+				return time.Time{}, fmt.Errorf("error parsing boot time: %v", err)
+			}
+			return time.Unix(sec, 0).UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("boot time not found in /proc/stat")
+}
+
+// parseDmesgPrefix splits the "facility:level : " prefix added by dmesg -x
+// from the rest of the line.
+func parseDmesgPrefix(line string) (facility, level, rest string, err error) {
+	parts := strings.SplitN(line, ":", 3)
+	if len(parts) != 3 {
+		return "", "", "", fmt.Errorf("unexpected dmesg line: %q", line)
+	}
+	return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]), strings.TrimLeft(parts[2], " "), nil
+}
+
+// parseDmesgISO parses "dmesg -x --time-format=iso" output, e.g.
+// "kern  :err   : 2026-10-14T09:12:01,123456+00:00 Out of memory: Killed process 1234".
+func parseDmesgISO(output string) ([]KernelMessage, error) {
+	var messages []KernelMessage
+	err := cm.EachLine(output, func(line string) error {
+		facility, level, rest, err := parseDmesgPrefix(line)
+		if err != nil {
+			return err
+		}
+
+		stamp, message, _ := strings.Cut(rest, " ")
+		timestamp, err := time.Parse(dmesgISOLayout, stamp)
+		if err != nil {
+			return fmt.Errorf("error parsing dmesg timestamp %q: %v", stamp, err)
+		}
+
+		messages = append(messages, KernelMessage{
+			Timestamp: timestamp,
+			Facility:  facility,
+			Level:     level,
+			Message:   message,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// parseDmesgBracketed parses "dmesg -x" output with the older seconds-since-boot
+// timestamps, e.g. "kern  :warn  : [   12.345678] message", converting them to
+// absolute times using bootTime.
+func parseDmesgBracketed(output string, bootTime time.Time) ([]KernelMessage, error) {
+	var messages []KernelMessage
+	err := cm.EachLine(output, func(line string) error {
+		facility, level, rest, err := parseDmesgPrefix(line)
+		if err != nil {
+			return err
+		}
+
+		stamp, message, ok := strings.Cut(strings.TrimPrefix(rest, "["), "]")
+		if !ok || !strings.HasPrefix(rest, "[") {
+			return fmt.Errorf("unexpected dmesg line: %q", line)
+		}
+		seconds, err := strconv.ParseFloat(strings.TrimSpace(stamp), 64)
+		if err != nil {
+			return fmt.Errorf("error parsing dmesg timestamp %q: %v", stamp, err)
+		}
+
+		messages = append(messages, KernelMessage{
+			Timestamp: bootTime.Add(time.Duration(seconds * float64(time.Second))),
+			Facility:  facility,
+			Level:     level,
+			Message:   strings.TrimPrefix(message, " "),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+func filterKernelMessages(messages []KernelMessage, since, until time.Time) []KernelMessage {
+	if since.IsZero() && until.IsZero() {
+		return messages
+	}
+
+	var filtered []KernelMessage
+	for _, m := range messages {
+		if !since.IsZero() && m.Timestamp.Before(since) {
+			continue
+		}
+		if !until.IsZero() && m.Timestamp.After(until) {
+			continue
+		}
+		filtered = append(filtered, m)
+	}
+	return filtered
+}
@@ -1,25 +1,84 @@
 package hostmanager
 
-import "time"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNotSupported is returned when an operation is not available on the host.
+var ErrNotSupported = errors.New("operation not supported on this host")
 
 type HostInfo struct {
-	Hostname      string
-	OSVersion     string
-	KernelVersion string
-	Uptime        string
-	NumberOfCores int
+	Hostname      string  `json:"hostname"`
+	OSVersion     string  `json:"os_version"`
+	KernelVersion string  `json:"kernel_version"`
+	Uptime        string  `json:"uptime"`
+	NumberOfCores int     `json:"number_of_cores"`
+	LoadAverage   LoadAvg `json:"load_average"`
+}
+
+// LoadAvg holds the 1, 5 and 15 minute load averages.
+type LoadAvg struct {
+	One     float64 `json:"one"`
+	Five    float64 `json:"five"`
+	Fifteen float64 `json:"fifteen"`
+}
+
+// String summarizes the HostInfo on one line, e.g.
+// "web1: Linux 6.1.0-21-amd64, 4 cores, up 49h12m0s, load 0.52 0.58 0.59".
+func (h HostInfo) String() string {
+	return fmt.Sprintf("%s: %s %s, %d cores, up %s, load %.2f %.2f %.2f", h.Hostname, h.OSVersion, h.KernelVersion,
+		h.NumberOfCores, h.Uptime, h.LoadAverage.One, h.LoadAverage.Five, h.LoadAverage.Fifteen)
 }
 
 // HostManager encompasses operations related to host management.
 type HostManager interface {
 	Info() (HostInfo, error)
 	Hostname() (string, error)
+	SystemHostname() (string, error) // Return the name the host reports, not the connection target
+	SetHostname(name string) error
 	Uptime() (time.Duration, error)
+	BootTime() (time.Time, error)
 	CPUCount() (int, error)
+	LoadAverage() (LoadAvg, error)
 	TotalMemory() (int64, error) // Return memory in bytes
 	FreeMemory() (int64, error)  // Return free memory in bytes
 	Reboot() error
+	RebootAndWait(ctx context.Context, timeout time.Duration) error
 	Shutdown() error
-	CPUUsage() (float64, error)   // Return CPU usage as a percentage
-	Processes() ([]string, error) // Return a list of running processes
+	ShutdownIn(d time.Duration, message string) error // Broadcast message to logged-in users
+	RebootIn(d time.Duration, message string) error
+	CancelShutdown() error
+	CPUUsage() (float64, error)        // Return CPU usage as a percentage
+	Processes() ([]string, error)      // Return a list of running processes
+	RemoteTime() (time.Time, error)    // Return the host's current UTC time
+	ClockSkew() (time.Duration, error) // Return how far the host's clock is ahead of the local clock
+
+	ScheduleCommand(command string, when time.Time) (string, error) // Return the at job ID
+	ListScheduledCommands() ([]AtJob, error)
+	CancelScheduledCommand(jobID string) error
+
+	Virtualization() (VirtInfo, error)
+	KernelMessages(opts DmesgOptions) ([]KernelMessage, error)
+	ProcessInfo(pid int) (ProcessDetail, error)
+	ProcessList() ([]Process, error)
+	ProcessTree() (*ProcessNode, error)
+	ProcessTreeFrom(pid int) (*ProcessNode, error)
+	TopProcesses(by string, n int) ([]ProcessUsage, error) // by is "cpu" or "memory"
+	BlockDevices() ([]BlockDevice, error)
+
+	SecurityModuleStatus() (SecurityStatus, error)
+	SetSELinuxMode(mode string, persist bool) error
+
+	InstalledKernels() ([]string, error) // Return kernel versions, newest first
+	RunningKernel() (string, error)
+	SetDefaultKernel(version string) error
+	RemoveOldKernels(keep int) ([]string, error) // Return the removed versions
+
+	Timezone() (string, error)
+	SetTimezone(tz string) error
+	TimeSyncStatus() (TimeSyncInfo, error)
+	EnableTimeSync() error
 }
@@ -0,0 +1,198 @@
+package hostmanager
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+const (
+	hostnameFile = "/etc/hostname"
+	hostsFile    = "/etc/hosts"
+	loopbackName = "127.0.1.1"
+)
+
+// SystemHostname returns the hostname the host reports for itself, which may
+// differ from the name used to connect to it. The static hostname is
+// preferred on systemd hosts.
+func (uhm *UnixHostManager) SystemHostname() (string, error) {
+	result, err := uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "hostnamectl",
+		Args:    []string{"--static"},
+	})
+	if err == nil && result.ExitCode == 0 {
+		if name := strings.TrimSpace(result.STDOUT); name != "" {
+			return name, nil
+		}
+	}
+
+	return uhm.Hostname()
+}
+
+// SetHostname changes the host's hostname. On Linux /etc/hostname and the
+// 127.0.1.1 line of /etc/hosts are updated as well; files that already match
+// are left untouched.
+func (uhm *UnixHostManager) SetHostname(name string) error {
+	if !validHostname(name) {
+		return fmt.Errorf("invalid hostname: %q", name)
+	}
+
+	darwin, err := uhm.isDarwin()
+	if err != nil {
+		return err
+	}
+	if darwin {
+		return uhm.setDarwinHostname(name)
+	}
+
+	config := cm.CommandConfig{Command: "hostname", Args: []string{name}, Sudo: true}
+	if uhm.hasCommand("hostnamectl") {
+		config = cm.CommandConfig{Command: "hostnamectl", Args: []string{"set-hostname", name}, Sudo: true}
+	}
+	if err := uhm.runChecked(config); err != nil {
+		return err
+	}
+
+	current, err := uhm.readFile(hostnameFile)
+	if err != nil {
+		current = ""
+	}
+	if strings.TrimSpace(current) != name {
+		if err := uhm.writeRootFile(hostnameFile, name+"\n"); err != nil {
+			return err
+		}
+	}
+
+	hosts, err := uhm.readFile(hostsFile)
+	if err != nil {
+		return err
+	}
+	if updated, changed := updateHostsFile(hosts, name); changed {
+		return uhm.writeRootFile(hostsFile, updated)
+	}
+	return nil
+}
+
+// setDarwinHostname sets all three macOS names. LocalHostName is the Bonjour
+// name and cannot contain dots, so only the first label is used for it.
+func (uhm *UnixHostManager) setDarwinHostname(name string) error {
+	short, _, _ := strings.Cut(name, ".")
+	for _, setting := range []struct{ key, value string }{
+		{"HostName", name},
+		{"LocalHostName", short},
+		{"ComputerName", name},
+	} {
+		err := uhm.runChecked(cm.CommandConfig{
+			Command: "scutil",
+			Args:    []string{"--set", setting.key, setting.value},
+			Sudo:    true,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (uhm *UnixHostManager) isDarwin() (bool, error) {
+	result, err := uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "uname",
+		Args:    []string{"-s"},
+	})
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(result.STDOUT) == "Darwin", nil
+}
+
+func (uhm *UnixHostManager) hasCommand(name string) bool {
+	result, err := uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "which",
+		Args:    []string{name},
+	})
+	return err == nil && result.ExitCode == 0 && strings.TrimSpace(result.STDOUT) != ""
+}
+
+func (uhm *UnixHostManager) runChecked(config cm.CommandConfig) error {
+	result, err := uhm.CommandManager.Run(context.TODO(), config)
+	if err != nil {
+		return err
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("%s failed: %s", config.Command, strings.TrimSpace(result.STDERR))
+	}
+	return nil
+}
+
+func (uhm *UnixHostManager) readFile(path string) (string, error) {
+	result, err := uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "cat",
+		Args:    []string{path},
+	})
+	if err != nil {
+		return "", err
+	}
+	if result.ExitCode != 0 {
+		return "", fmt.Errorf("failed to read %s: %s", path, strings.TrimSpace(result.STDERR))
+	}
+	return result.STDOUT, nil
+}
+
+// writeRootFile atomically replaces a root-owned file, keeping the mode and
+// ownership of the existing file, so readers never see it half-written.
+func (uhm *UnixHostManager) writeRootFile(path, content string) error {
+	return uhm.runChecked(cm.ReplaceFile(path, []byte(content)))
+}
+
+// updateHostsFile points the 127.0.1.1 line of an /etc/hosts file at name,
+// adding the line if there is none. Fully qualified names are listed with
+// their short name as an alias. It reports whether the content changed.
+func updateHostsFile(content, name string) (string, bool) {
+	entry := loopbackName + "\t" + name
+	if short, _, ok := strings.Cut(name, "."); ok {
+		entry += " " + short
+	}
+
+	lines := strings.SplitAfter(content, "\n")
+	for i, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || fields[0] != loopbackName {
+			continue
+		}
+		if strings.Join(fields, " ") == strings.Join(strings.Fields(entry), " ") {
+			return content, false
+		}
+		lines[i] = entry + "\n"
+		return strings.Join(lines, ""), true
+	}
+
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	return content + entry + "\n", true
+}
+
+// validHostname reports whether name is a valid RFC 1123 hostname: dot
+// separated labels of 1-63 letters, digits and hyphens that do not start or
+// end with a hyphen, at most 253 characters in total.
+func validHostname(name string) bool {
+	if name == "" || len(name) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(name, ".") {
+		if len(label) == 0 || len(label) > 63 {
+			return false
+		}
+		if label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
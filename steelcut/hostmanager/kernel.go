@@ -0,0 +1,337 @@
+package hostmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// ErrRunningKernel is returned when an operation would remove the kernel the
+// host is currently running.
+var ErrRunningKernel = errors.New("refusing to remove the running kernel")
+
+// Package name prefixes of Debian kernel packages, longest first so that
+// linux-image-unsigned- is not mistaken for linux-image-.
+var debianKernelPrefixes = []string{
+	"linux-image-unsigned-",
+	"linux-modules-extra-",
+	"linux-headers-",
+	"linux-modules-",
+	"linux-image-",
+}
+
+// rpmKernelPackages lists the install-only RPM kernel packages, which are
+// installed side by side per version.
+var rpmKernelPackages = map[string]bool{
+	"kernel":               true,
+	"kernel-core":          true,
+	"kernel-modules":       true,
+	"kernel-modules-core":  true,
+	"kernel-modules-extra": true,
+	"kernel-devel":         true,
+}
+
+var versionChunk = regexp.MustCompile(`\d+|\D+`)
+
+// kernelPackages maps each installed kernel version, as reported by
+// "uname -r", to the packages installed for it.
+type kernelPackages map[string][]string
+
+// InstalledKernels returns the installed kernel versions, newest first, in
+// the form "uname -r" reports them.
+func (uhm *UnixHostManager) InstalledKernels() ([]string, error) {
+	kernels, _, err := uhm.kernelPackages()
+	if err != nil {
+		return nil, err
+	}
+	return sortedKernels(kernels), nil
+}
+
+// RunningKernel returns the version of the running kernel.
+func (uhm *UnixHostManager) RunningKernel() (string, error) {
+	result, err := uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "uname",
+		Args:    []string{"-r"},
+	})
+	if err != nil {
+		return "", err
+	}
+	version := strings.TrimSpace(result.STDOUT)
+	if version == "" {
+		return "", fmt.Errorf("uname -r returned no kernel version")
+	}
+	return version, nil
+}
+
+// SetDefaultKernel makes version the kernel booted by default. grubby is
+// used where available; otherwise the GRUB menu entry for version is set as
+// the saved default, which requires GRUB_DEFAULT=saved.
+func (uhm *UnixHostManager) SetDefaultKernel(version string) error {
+	kernels, err := uhm.InstalledKernels()
+	if err != nil {
+		return err
+	}
+	if !containsString(kernels, version) {
+		return fmt.Errorf("kernel %s is not installed", version)
+	}
+
+	if uhm.hasCommand("grubby") {
+		return uhm.runChecked(cm.CommandConfig{
+			Command: "grubby",
+			Args:    []string{"--set-default", "/boot/vmlinuz-" + version},
+			Sudo:    true,
+		})
+	}
+
+	setDefault, grubConfig := "grub-set-default", "/boot/grub/grub.cfg"
+	if uhm.hasCommand("grub2-set-default") {
+		setDefault, grubConfig = "grub2-set-default", "/boot/grub2/grub.cfg"
+	}
+	result, err := uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "cat",
+		Args:    []string{grubConfig},
+		Sudo:    true,
+	})
+	if err != nil {
+		return err
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("failed to read %s: %s", grubConfig, strings.TrimSpace(result.STDERR))
+	}
+	entry, err := grubMenuEntry(result.STDOUT, version)
+	if err != nil {
+		return err
+	}
+	return uhm.runChecked(cm.CommandConfig{
+		Command: setDefault,
+		Args:    []string{entry},
+		Sudo:    true,
+	})
+}
+
+// RemoveOldKernels removes all but the newest keep kernels and returns the
+// removed versions. The running kernel is never removed, even if it is older.
+func (uhm *UnixHostManager) RemoveOldKernels(keep int) ([]string, error) {
+	if keep < 1 {
+		return nil, fmt.Errorf("must keep at least one kernel, got %d", keep)
+	}
+
+	kernels, debian, err := uhm.kernelPackages()
+	if err != nil {
+		return nil, err
+	}
+	running, err := uhm.RunningKernel()
+	if err != nil {
+		return nil, err
+	}
+
+	remove := oldKernels(sortedKernels(kernels), running, keep)
+	if len(remove) == 0 {
+		return nil, nil
+	}
+	var packages []string
+	for _, version := range remove {
+		if version == running {
+			return nil, ErrRunningKernel
+		}
+		packages = append(packages, kernels[version]...)
+	}
+
+	config := cm.CommandConfig{
+		Command: "dnf",
+		Args:    append([]string{"remove", "-y"}, packages...),
+		Sudo:    true,
+	}
+	switch {
+	case debian:
+		config = cm.CommandConfig{
+			Command: "apt-get",
+			Args:    append([]string{"remove", "--purge", "-y"}, packages...),
+			Sudo:    true,
+			Env:     []string{"DEBIAN_FRONTEND=noninteractive"},
+		}
+	case !uhm.hasCommand("dnf"):
+		config.Command = "yum"
+	}
+	if err := uhm.runChecked(config); err != nil {
+		return nil, err
+	}
+	return remove, nil
+}
+
+// oldKernels returns the kernels to remove from kernels, sorted newest first,
+// keeping the newest keep and the running kernel.
+func oldKernels(kernels []string, running string, keep int) []string {
+	var remove []string
+	for i, version := range kernels {
+		if i < keep || version == running {
+			continue
+		}
+		remove = append(remove, version)
+	}
+	return remove
+}
+
+// kernelPackages lists the installed kernel packages with dpkg or rpm and
+// reports whether the host uses dpkg.
+func (uhm *UnixHostManager) kernelPackages() (kernelPackages, bool, error) {
+	if uhm.hasCommand("dpkg") {
+		// dpkg -l exits 1 when a pattern matches nothing
+		result, err := uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+			Command: "dpkg",
+			Args:    []string{"-l", "linux-image-*", "linux-modules-*", "linux-headers-*"},
+		})
+		if err != nil && !cm.IsExitError(err) {
+			return nil, true, err
+		}
+		return parseDpkgKernels(result.STDOUT), true, nil
+	}
+
+	if uhm.hasCommand("rpm") {
+		result, err := uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+			Command: "rpm",
+			Args:    []string{"-qa", "--qf", `%{NAME} %{VERSION}-%{RELEASE}.%{ARCH}\n`, "kernel*"},
+		})
+		if err != nil {
+			return nil, false, err
+		}
+		return parseRPMKernels(result.STDOUT), false, nil
+	}
+
+	return nil, false, fmt.Errorf("%w: no dpkg or rpm to list kernels", ErrNotSupported)
+}
+
+// parseDpkgKernels parses "dpkg -l" output for kernel packages. Only
+// installed packages count, and a version is only listed if its image is
+// installed; headers packages such as linux-headers-6.1.0-18-common are
+// ignored.
+func parseDpkgKernels(output string) kernelPackages {
+	images := make(map[string]bool)
+	all := make(map[string][]string)
+	for _, line := range cm.Lines(output) {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || (fields[0] != "ii" && fields[0] != "hi") {
+			continue
+		}
+		name, _, _ := strings.Cut(fields[1], ":")
+		for _, prefix := range debianKernelPrefixes {
+			version, ok := strings.CutPrefix(name, prefix)
+			if !ok {
+				continue
+			}
+			if version == "" || version[0] < '0' || version[0] > '9' {
+				break // a metapackage such as linux-image-amd64
+			}
+			if strings.HasPrefix(prefix, "linux-image-") {
+				images[version] = true
+			}
+			all[version] = append(all[version], name)
+			break
+		}
+	}
+
+	kernels := make(kernelPackages)
+	for version := range images {
+		kernels[version] = all[version]
+	}
+	return kernels
+}
+
+// parseRPMKernels parses "rpm -qa --qf '%{NAME} %{VERSION}-%{RELEASE}.%{ARCH}\n'"
+// output, keeping the install-only kernel packages.
+func parseRPMKernels(output string) kernelPackages {
+	kernels := make(kernelPackages)
+	for _, line := range cm.Lines(output) {
+		fields := strings.Fields(line)
+		if len(fields) != 2 || !rpmKernelPackages[fields[0]] {
+			continue
+		}
+		name, version := fields[0], fields[1]
+		kernels[version] = append(kernels[version], name+"-"+version)
+	}
+	return kernels
+}
+
+// sortedKernels returns the versions in kernels, newest first.
+func sortedKernels(kernels kernelPackages) []string {
+	versions := make([]string, 0, len(kernels))
+	for version := range kernels {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return compareVersions(versions[i], versions[j]) > 0
+	})
+	return versions
+}
+
+// compareVersions compares versions chunk by chunk, numerically for runs of
+// digits, so that 6.1.0-18 sorts after 6.1.0-9.
+func compareVersions(a, b string) int {
+	ac, bc := versionChunk.FindAllString(a, -1), versionChunk.FindAllString(b, -1)
+	for i := 0; i < len(ac) && i < len(bc); i++ {
+		an, aerr := strconv.ParseUint(ac[i], 10, 64)
+		bn, berr := strconv.ParseUint(bc[i], 10, 64)
+		switch {
+		case aerr == nil && berr == nil:
+			if an != bn {
+				if an < bn {
+					return -1
+				}
+				return 1
+			}
+		case ac[i] != bc[i]:
+			return strings.Compare(ac[i], bc[i])
+		}
+	}
+	return len(ac) - len(bc)
+}
+
+// grubMenuEntry returns the grub-set-default argument for the non-recovery
+// menu entry booting version, including its submenu if it has one.
+func grubMenuEntry(grubConfig, version string) (string, error) {
+	var submenu string
+	for _, line := range cm.Lines(grubConfig) {
+		line = strings.TrimSpace(line)
+		id := grubEntryID(line)
+		switch {
+		case strings.HasPrefix(line, "submenu "):
+			submenu = id
+		case strings.HasPrefix(line, "menuentry ") && id != "":
+			if !strings.Contains(id, "-"+version+"-") || strings.Contains(id, "recovery") {
+				continue
+			}
+			if submenu != "" {
+				return submenu + ">" + id, nil
+			}
+			return id, nil
+		}
+	}
+	return "", fmt.Errorf("no GRUB menu entry found for kernel %s", version)
+}
+
+// grubEntryID extracts the $menuentry_id_option value from a menuentry or
+// submenu line.
+func grubEntryID(line string) string {
+	_, rest, ok := strings.Cut(line, "$menuentry_id_option ")
+	if !ok {
+		return ""
+	}
+	rest = strings.TrimLeft(rest, `'"`)
+	id, _, _ := strings.Cut(rest, "'")
+	return id
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,250 @@
+package hostmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// ErrProcessNotFound is returned when the requested process does not exist,
+// for example because it exited.
+var ErrProcessNotFound = errors.New("process not found")
+
+// ProcessDetail describes a single process. Fields the host does not expose
+// to the connecting user, such as another user's IO counters, are left zero.
+type ProcessDetail struct {
+	PID        int
+	PPID       int
+	UID        int
+	Name       string
+	State      string   // e.g. "S (sleeping)" on Linux, "Ss" on macOS
+	Cmdline    []string // empty for kernel threads
+	Threads    int
+	RSS        int64 // resident memory in bytes
+	VMSize     int64 // virtual memory in bytes
+	OpenFiles  int
+	ReadBytes  int64 // bytes read from storage; Linux only
+	WriteBytes int64 // bytes written to storage; Linux only
+}
+
+// ProcessInfo returns details of the process with the given PID, read from
+// /proc on Linux and from ps and lsof on macOS.
+func (uhm *UnixHostManager) ProcessInfo(pid int) (ProcessDetail, error) {
+	if pid <= 0 {
+		return ProcessDetail{}, fmt.Errorf("invalid pid: %d", pid)
+	}
+
+	darwin, err := uhm.isDarwin()
+	if err != nil {
+		return ProcessDetail{}, err
+	}
+	if darwin {
+		return uhm.darwinProcessInfo(pid)
+	}
+
+	dir := "/proc/" + strconv.Itoa(pid)
+	status, err := uhm.readProcFile(dir + "/status")
+	if err != nil {
+		return ProcessDetail{}, err
+	}
+	detail, err := parseProcStatus(status)
+	if err != nil {
+		return ProcessDetail{}, err
+	}
+
+	cmdline, err := uhm.readProcFile(dir + "/cmdline")
+	if err != nil {
+		return ProcessDetail{}, err
+	}
+	detail.Cmdline = parseCmdline(cmdline)
+
+	// io and fd are only readable by the process owner and root
+	if io, err := uhm.readProcFile(dir + "/io"); err == nil {
+		detail.ReadBytes, detail.WriteBytes = parseProcIO(io)
+	} else if errors.Is(err, ErrProcessNotFound) {
+		return ProcessDetail{}, err
+	}
+	fds, err := uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "ls",
+		Args:    []string{dir + "/fd"},
+	})
+	if err == nil && fds.ExitCode == 0 {
+		detail.OpenFiles = len(cm.Lines(fds.STDOUT))
+	}
+
+	return detail, nil
+}
+
+// readProcFile reads a file under /proc, mapping a vanished entry to
+// ErrProcessNotFound.
+func (uhm *UnixHostManager) readProcFile(path string) (string, error) {
+	result, err := uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "cat",
+		Args:    []string{path},
+	})
+	if strings.Contains(result.STDERR, "No such file or directory") || strings.Contains(result.STDERR, "No such process") {
+		return "", fmt.Errorf("%w: %s", ErrProcessNotFound, path)
+	}
+	if err != nil {
+		return "", err
+	}
+	if result.ExitCode != 0 {
+		return "", fmt.Errorf("failed to read %s: %s", path, strings.TrimSpace(result.STDERR))
+	}
+	return result.STDOUT, nil
+}
+
+// darwinProcessInfo gathers process details from ps and lsof, as macOS has no
+// /proc. IO counters are not available.
+func (uhm *UnixHostManager) darwinProcessInfo(pid int) (ProcessDetail, error) {
+	pidArg := strconv.Itoa(pid)
+	result, err := uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "ps",
+		Args:    []string{"-p", pidArg, "-o", "ppid=,uid=,state=,rss=,vsz=,comm="},
+	})
+	// ps exits 1 with no output when the process does not exist
+	if strings.TrimSpace(result.STDOUT) == "" && (err != nil || result.ExitCode != 0) {
+		return ProcessDetail{}, fmt.Errorf("%w: %d", ErrProcessNotFound, pid)
+	}
+	if err != nil {
+		return ProcessDetail{}, err
+	}
+	detail, err := parseDarwinPs(result.STDOUT)
+	if err != nil {
+		return ProcessDetail{}, err
+	}
+	detail.PID = pid
+
+	if args, err := uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "ps",
+		Args:    []string{"-p", pidArg, "-o", "args="},
+	}); err == nil {
+		detail.Cmdline = strings.Fields(args.STDOUT)
+	}
+	if threads, err := uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "ps",
+		Args:    []string{"-M", "-p", pidArg},
+	}); err == nil {
+		// One line per thread after the header
+		detail.Threads = max(len(cm.Lines(threads.STDOUT))-1, 0)
+	}
+	if files, err := uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "lsof",
+		Args:    []string{"-p", pidArg},
+	}); err == nil {
+		detail.OpenFiles = max(len(cm.Lines(files.STDOUT))-1, 0)
+	}
+
+	return detail, nil
+}
+
+// parseProcStatus parses /proc/<pid>/status. Memory values are reported in
+// kB and converted to bytes; kernel threads have none.
+func parseProcStatus(content string) (ProcessDetail, error) {
+	var detail ProcessDetail
+	for _, line := range cm.Lines(content) {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+
+		var err error
+		switch key {
+		case "Name":
+			detail.Name = value
+		case "State":
+			detail.State = value
+		case "Pid":
+			detail.PID, err = strconv.Atoi(value)
+		case "PPid":
+			detail.PPID, err = strconv.Atoi(value)
+		case "Uid":
+			// Real, effective, saved and filesystem UIDs; report the real one
+			detail.UID, err = strconv.Atoi(strings.Fields(value)[0])
+		case "Threads":
+			detail.Threads, err = strconv.Atoi(value)
+		case "VmRSS":
+			detail.RSS, err = parseKB(value)
+		case "VmSize":
+			detail.VMSize, err = parseKB(value)
+		}
+		if err != nil {
+			return ProcessDetail{}, fmt.Errorf("error parsing %s in process status: %v", key, err)
+		}
+	}
+	if detail.PID == 0 {
+		return ProcessDetail{}, fmt.Errorf("unexpected process status format: %s", content)
+	}
+	return detail, nil
+}
+
+// parseKB parses a "5232 kB" value into bytes.
+func parseKB(value string) (int64, error) {
+	kb, err := strconv.ParseInt(strings.TrimSuffix(value, " kB"), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return kb * 1024, nil
+}
+
+// parseCmdline splits /proc/<pid>/cmdline, where each argument is terminated
+// by a NUL byte. Arguments may themselves contain spaces.
+func parseCmdline(content string) []string {
+	content = strings.TrimSuffix(content, "\x00")
+	if content == "" {
+		return nil
+	}
+	return strings.Split(content, "\x00")
+}
+
+// parseProcIO returns the storage bytes read and written from /proc/<pid>/io.
+func parseProcIO(content string) (read, write int64) {
+	for _, line := range cm.Lines(content) {
+		key, value, _ := strings.Cut(line, ":")
+		n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			continue
+		}
+		switch key {
+		case "read_bytes":
+			read = n
+		case "write_bytes":
+			write = n
+		}
+	}
+	return read, write
+}
+
+// parseDarwinPs parses "ps -o ppid=,uid=,state=,rss=,vsz=,comm=" output. rss
+// and vsz are in KiB; comm may contain spaces.
+func parseDarwinPs(output string) (ProcessDetail, error) {
+	fields := strings.Fields(output)
+	if len(fields) < 6 {
+		return ProcessDetail{}, fmt.Errorf("unexpected ps output format: %s", output)
+	}
+
+	var detail ProcessDetail
+	var err error
+	if detail.PPID, err = strconv.Atoi(fields[0]); err != nil {
+		return ProcessDetail{}, fmt.Errorf("error parsing ppid: %v", err)
+	}
+	if detail.UID, err = strconv.Atoi(fields[1]); err != nil {
+		return ProcessDetail{}, fmt.Errorf("error parsing uid: %v", err)
+	}
+	detail.State = fields[2]
+	if detail.RSS, err = strconv.ParseInt(fields[3], 10, 64); err != nil {
+		return ProcessDetail{}, fmt.Errorf("error parsing rss: %v", err)
+	}
+	if detail.VMSize, err = strconv.ParseInt(fields[4], 10, 64); err != nil {
+		return ProcessDetail{}, fmt.Errorf("error parsing vsz: %v", err)
+	}
+	detail.RSS *= 1024
+	detail.VMSize *= 1024
+	detail.Name = strings.Join(fields[5:], " ")
+	return detail, nil
+}
@@ -0,0 +1,145 @@
+package hostmanager
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// Process is a single entry of the host's process list.
+type Process struct {
+	PID     int
+	PPID    int
+	User    string
+	Command string // the full command line
+}
+
+// ProcessNode is a process and the processes it spawned, sorted by PID.
+type ProcessNode struct {
+	Process
+	Children []*ProcessNode
+}
+
+// ProcessList returns every process on the host.
+func (uhm *UnixHostManager) ProcessList() ([]Process, error) {
+	result, err := uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "ps",
+		Args:    []string{"-e", "-o", "pid=,ppid=,user=,args="},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return parseProcessList(result.STDOUT)
+}
+
+// ProcessTree returns the host's processes as a tree rooted at PID 1.
+// Processes whose parent is not in the list, such as kernel threads with
+// parent 0, are attached to the root, as is a process closing a parent cycle.
+func (uhm *UnixHostManager) ProcessTree() (*ProcessNode, error) {
+	processes, err := uhm.ProcessList()
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := buildProcessTree(processes)
+	if err != nil {
+		return nil, err
+	}
+	return nodes[1], nil
+}
+
+// ProcessTreeFrom returns the subtree of processes descending from pid.
+func (uhm *UnixHostManager) ProcessTreeFrom(pid int) (*ProcessNode, error) {
+	processes, err := uhm.ProcessList()
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := buildProcessTree(processes)
+	if err != nil {
+		return nil, err
+	}
+	node, ok := nodes[pid]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrProcessNotFound, pid)
+	}
+	return node, nil
+}
+
+// parseProcessList parses "ps -o pid=,ppid=,user=,args=" output.
+func parseProcessList(output string) ([]Process, error) {
+	var processes []Process
+	for _, line := range cm.Lines(output) {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("unexpected ps output line: %q", line)
+		}
+		pid, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("error parsing pid: %v", err)
+		}
+		ppid, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("error parsing ppid: %v", err)
+		}
+		processes = append(processes, Process{
+			PID:     pid,
+			PPID:    ppid,
+			User:    fields[2],
+			Command: strings.Join(fields[3:], " "),
+		})
+	}
+	return processes, nil
+}
+
+// buildProcessTree links processes into a tree rooted at PID 1 and returns
+// every node by PID.
+func buildProcessTree(processes []Process) (map[int]*ProcessNode, error) {
+	nodes := make(map[int]*ProcessNode, len(processes))
+	for _, p := range processes {
+		nodes[p.PID] = &ProcessNode{Process: p}
+	}
+	if _, ok := nodes[1]; !ok {
+		return nil, fmt.Errorf("%w: no PID 1 in process list", ErrProcessNotFound)
+	}
+
+	// Visit processes in PID order so that cycles are broken the same way
+	// every time
+	pids := make([]int, 0, len(nodes))
+	for pid := range nodes {
+		if pid != 1 {
+			pids = append(pids, pid)
+		}
+	}
+	sort.Ints(pids)
+
+	parents := make(map[int]int, len(nodes))
+	for _, pid := range pids {
+		parent := nodes[pid].PPID
+		if _, ok := nodes[parent]; !ok || parent == pid {
+			parent = 1
+		}
+		parents[pid] = parent
+	}
+
+	// Walk up from each process; reaching an ancestor twice means a cycle,
+	// which is broken by reattaching the process that closes it to the root.
+	for _, pid := range pids {
+		seen := map[int]bool{pid: true}
+		for child, ancestor := pid, parents[pid]; ancestor != 1; child, ancestor = ancestor, parents[ancestor] {
+			if seen[ancestor] {
+				parents[child] = 1
+				break
+			}
+			seen[ancestor] = true
+		}
+	}
+
+	for _, pid := range pids {
+		parent := nodes[parents[pid]]
+		parent.Children = append(parent.Children, nodes[pid])
+	}
+	return nodes, nil
+}
@@ -0,0 +1,69 @@
+package hostmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// rebootPollInterval is how often RebootAndWait checks whether the host is
+// back.
+var rebootPollInterval = 5 * time.Second
+
+// RebootAndWait reboots the host and waits until it is reachable again with
+// a later boot time, so that a host still shutting down is not mistaken for
+// one that has come back. The connection dropping during the reboot is
+// expected and not an error. It returns cm.ErrWaitTimeout if the host is not
+// back within timeout, or the context's error if it is cancelled first. A
+// non-positive timeout waits as long as ctx allows.
+func (uhm *UnixHostManager) RebootAndWait(ctx context.Context, timeout time.Duration) error {
+	before, err := uhm.BootTime()
+	if err != nil {
+		return fmt.Errorf("error reading boot time before reboot: %w", err)
+	}
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	result, err := uhm.CommandManager.Run(ctx, cm.CommandConfig{
+		Command: "sudo",
+		Args:    []string{"reboot"},
+	})
+	// A reboot that starts before the command returns closes the connection
+	// without an exit status
+	if err != nil && (result.ExitCode != 0 || errors.Is(err, cm.ErrReadOnlyMode)) {
+		return err
+	}
+
+	ticker := time.NewTicker(rebootPollInterval)
+	defer ticker.Stop()
+
+	var lastErr error
+	for {
+		select {
+		case <-ctx.Done():
+			if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return ctx.Err()
+			}
+			if lastErr != nil {
+				return fmt.Errorf("%w: reboot: last error: %v", cm.ErrWaitTimeout, lastErr)
+			}
+			return fmt.Errorf("%w: reboot", cm.ErrWaitTimeout)
+		case <-ticker.C:
+		}
+
+		// Boot times derived from uptime drift by a fraction of a second
+		// between reads
+		after, err := uhm.BootTime()
+		lastErr = err
+		if err == nil && after.After(before.Add(time.Second)) {
+			return nil
+		}
+	}
+}
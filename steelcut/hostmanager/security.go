@@ -0,0 +1,218 @@
+package hostmanager
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+const selinuxConfigFile = "/etc/selinux/config"
+
+// SecurityStatus describes the host's mandatory access control module.
+// Module is "selinux", "apparmor" or "none". Mode is "enforcing",
+// "permissive" or "disabled" for SELinux, "enforcing", "complain" or
+// "disabled" for AppArmor, and "disabled" when there is no module.
+type SecurityStatus struct {
+	Module string
+	Mode   string
+}
+
+var (
+	aaProfileCount = regexp.MustCompile(`^(\d+) profiles are in (enforce|complain) mode`)
+	selinuxSetting = regexp.MustCompile(`^\s*SELINUX\s*=`)
+)
+
+// SecurityModuleStatus reports whether SELinux or AppArmor is active. An
+// installed but disabled SELinux is only reported when AppArmor is not
+// active either.
+func (uhm *UnixHostManager) SecurityModuleStatus() (SecurityStatus, error) {
+	selinux, found := uhm.selinuxStatus()
+	if found && selinux.Mode != "disabled" {
+		return selinux, nil
+	}
+
+	// aa-status needs root to read the loaded profiles
+	result, err := uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "aa-status",
+		Sudo:    true,
+	})
+	if err != nil && !cm.IsExitError(err) {
+		return SecurityStatus{}, err
+	}
+	if apparmor, ok := parseAAStatus(result.STDOUT); ok && apparmor.Mode != "disabled" {
+		return apparmor, nil
+	}
+
+	if found {
+		return selinux, nil
+	}
+	return SecurityStatus{Module: "none", Mode: "disabled"}, nil
+}
+
+// selinuxStatus asks sestatus, falling back to getenforce where only the
+// base utilities are installed. It reports false if neither is available.
+func (uhm *UnixHostManager) selinuxStatus() (SecurityStatus, bool) {
+	result, err := uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "sestatus",
+	})
+	if err == nil && result.ExitCode == 0 {
+		if status, ok := parseSestatus(result.STDOUT); ok {
+			return status, true
+		}
+	}
+
+	result, err = uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "getenforce",
+	})
+	if err == nil && result.ExitCode == 0 {
+		switch mode := strings.ToLower(strings.TrimSpace(result.STDOUT)); mode {
+		case "enforcing", "permissive", "disabled":
+			return SecurityStatus{Module: "selinux", Mode: mode}, true
+		}
+	}
+	return SecurityStatus{}, false
+}
+
+// SetSELinuxMode switches SELinux to "enforcing" or "permissive" with
+// setenforce. With persist, SELINUX= in /etc/selinux/config is updated too so
+// the mode survives a reboot. "disabled" can only be persisted and takes
+// effect after a reboot, as can any mode while SELinux is disabled.
+func (uhm *UnixHostManager) SetSELinuxMode(mode string, persist bool) error {
+	var value string
+	switch mode {
+	case "enforcing":
+		value = "1"
+	case "permissive":
+		value = "0"
+	case "disabled":
+		if !persist {
+			return fmt.Errorf("SELinux cannot be disabled at runtime; persist the mode and reboot")
+		}
+	default:
+		return fmt.Errorf("invalid SELinux mode: %q", mode)
+	}
+
+	if value != "" {
+		result, err := uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+			Command: "setenforce",
+			Args:    []string{value},
+			Sudo:    true,
+		})
+		if err != nil && !cm.IsExitError(err) {
+			return err
+		}
+		disabled := strings.Contains(result.STDERR, "SELinux is disabled")
+		if result.ExitCode != 0 && !(persist && disabled) {
+			return fmt.Errorf("setenforce failed: %s", strings.TrimSpace(result.STDERR))
+		}
+	}
+
+	if !persist {
+		return nil
+	}
+	config, err := uhm.readFile(selinuxConfigFile)
+	if err != nil {
+		return err
+	}
+	if updated, changed := updateSELinuxConfig(config, mode); changed {
+		return uhm.writeRootFile(selinuxConfigFile, updated)
+	}
+	return nil
+}
+
+// parseSestatus parses sestatus output, e.g. "SELinux status: enabled" and
+// "Current mode: enforcing". It reports false for unrecognised output.
+func parseSestatus(output string) (SecurityStatus, bool) {
+	status := SecurityStatus{Module: "selinux"}
+	var enabled bool
+	for _, line := range cm.Lines(output) {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "SELinux status":
+			if value == "disabled" {
+				status.Mode = "disabled"
+				return status, true
+			}
+			enabled = value == "enabled"
+		case "Current mode":
+			status.Mode = value
+		}
+	}
+	if !enabled || status.Mode == "" {
+		return SecurityStatus{}, false
+	}
+	return status, true
+}
+
+// parseAAStatus parses aa-status output. AppArmor is enforcing if any profile
+// is enforced, complain if profiles are loaded only in complain mode, and
+// disabled when the module or all profiles are unloaded. It reports false for
+// unrecognised output such as a missing command.
+func parseAAStatus(output string) (SecurityStatus, bool) {
+	status := SecurityStatus{Module: "apparmor"}
+	var loaded bool
+	var enforce, complain int
+	for _, line := range cm.Lines(output) {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "apparmor module is not loaded"):
+			status.Mode = "disabled"
+			return status, true
+		case strings.HasPrefix(line, "apparmor module is loaded"):
+			loaded = true
+		}
+		// Later sections count processes, which use a different wording
+		if m := aaProfileCount.FindStringSubmatch(line); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			if m[2] == "enforce" {
+				enforce = n
+			} else {
+				complain = n
+			}
+		}
+	}
+	if !loaded {
+		return SecurityStatus{}, false
+	}
+
+	switch {
+	case enforce > 0:
+		status.Mode = "enforcing"
+	case complain > 0:
+		status.Mode = "complain"
+	default:
+		status.Mode = "disabled"
+	}
+	return status, true
+}
+
+// updateSELinuxConfig sets the SELINUX= line of an /etc/selinux/config file,
+// adding it if missing and leaving SELINUXTYPE= alone. It reports whether the
+// content changed.
+func updateSELinuxConfig(content, mode string) (string, bool) {
+	entry := "SELINUX=" + mode
+	lines := strings.SplitAfter(content, "\n")
+	for i, line := range lines {
+		if !selinuxSetting.MatchString(line) {
+			continue
+		}
+		if strings.TrimSpace(line) == entry {
+			return content, false
+		}
+		lines[i] = entry + "\n"
+		return strings.Join(lines, ""), true
+	}
+
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	return content + entry + "\n", true
+}
@@ -0,0 +1,59 @@
+package hostmanager
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// ShutdownIn schedules the host to power off after d, broadcasting message
+// to logged-in users. shutdown counts in whole minutes, so d is rounded up
+// to the next minute; a non-positive d shuts down now.
+func (uhm *UnixHostManager) ShutdownIn(d time.Duration, message string) error {
+	return uhm.scheduleShutdown("-h", d, message)
+}
+
+// RebootIn schedules the host to reboot after d, as ShutdownIn does.
+func (uhm *UnixHostManager) RebootIn(d time.Duration, message string) error {
+	return uhm.scheduleShutdown("-r", d, message)
+}
+
+func (uhm *UnixHostManager) scheduleShutdown(mode string, d time.Duration, message string) error {
+	args := []string{mode, shutdownTime(d)}
+	if message != "" {
+		args = append(args, message)
+	}
+	_, err := uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "shutdown",
+		Args:    args,
+		Sudo:    true,
+	})
+	return err
+}
+
+// CancelShutdown cancels a shutdown or reboot scheduled with ShutdownIn or
+// RebootIn. macOS's shutdown has no cancel flag; the waiting shutdown
+// process is killed instead.
+func (uhm *UnixHostManager) CancelShutdown() error {
+	darwin, err := uhm.isDarwin()
+	if err != nil {
+		return err
+	}
+
+	config := cm.CommandConfig{Command: "shutdown", Args: []string{"-c"}, Sudo: true}
+	if darwin {
+		config = cm.CommandConfig{Command: "killall", Args: []string{"shutdown"}, Sudo: true}
+	}
+	_, err = uhm.CommandManager.Run(context.TODO(), config)
+	return err
+}
+
+// shutdownTime formats d as a shutdown time argument, "now" or "+minutes".
+func shutdownTime(d time.Duration) string {
+	if d <= 0 {
+		return "now"
+	}
+	return fmt.Sprintf("+%d", (d+time.Minute-1)/time.Minute)
+}
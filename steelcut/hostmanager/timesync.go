@@ -0,0 +1,196 @@
+package hostmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// ErrNoTimeSync is returned when the host has no time synchronization
+// service that can be queried or enabled.
+var ErrNoTimeSync = errors.New("no time synchronization service found")
+
+// TimeSyncInfo describes the host's network time synchronization. Server and
+// Offset are only known when chrony is running, and on macOS, which does not
+// report synchronization, Synchronized mirrors Enabled.
+type TimeSyncInfo struct {
+	Enabled      bool
+	Synchronized bool
+	Server       string
+	Offset       time.Duration // how far the host clock is ahead of NTP time
+}
+
+// TimeSyncStatus reports whether the host synchronizes its clock over NTP.
+func (uhm *UnixHostManager) TimeSyncStatus() (TimeSyncInfo, error) {
+	darwin, err := uhm.isDarwin()
+	if err != nil {
+		return TimeSyncInfo{}, err
+	}
+	if darwin {
+		return uhm.darwinTimeSyncStatus()
+	}
+
+	chrony, chronyErr := uhm.chronyTracking()
+
+	result, err := uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "timedatectl",
+		Args:    []string{"show"},
+	})
+	if err == nil && result.ExitCode == 0 {
+		info, ok := parseTimedatectlShow(result.STDOUT)
+		if ok {
+			if chronyErr == nil {
+				info.Server, info.Offset = chrony.Server, chrony.Offset
+			}
+			return info, nil
+		}
+	}
+
+	if chronyErr == nil {
+		return chrony, nil
+	}
+	return TimeSyncInfo{}, ErrNoTimeSync
+}
+
+// EnableTimeSync turns on NTP synchronization with timedatectl, or on macOS
+// with systemsetup. Hosts running chrony without systemd already synchronize
+// and are left alone.
+func (uhm *UnixHostManager) EnableTimeSync() error {
+	darwin, err := uhm.isDarwin()
+	if err != nil {
+		return err
+	}
+	if darwin {
+		return uhm.runChecked(cm.CommandConfig{
+			Command: "systemsetup",
+			Args:    []string{"-setusingnetworktime", "on"},
+			Sudo:    true,
+		})
+	}
+
+	result, err := uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "timedatectl",
+		Args:    []string{"show"},
+	})
+	if err == nil && result.ExitCode == 0 {
+		if _, ok := parseTimedatectlShow(result.STDOUT); ok {
+			return uhm.runChecked(cm.CommandConfig{
+				Command: "timedatectl",
+				Args:    []string{"set-ntp", "true"},
+				Sudo:    true,
+			})
+		}
+	}
+
+	if _, err := uhm.chronyTracking(); err == nil {
+		return nil
+	}
+	return ErrNoTimeSync
+}
+
+func (uhm *UnixHostManager) chronyTracking() (TimeSyncInfo, error) {
+	result, err := uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "chronyc",
+		Args:    []string{"tracking"},
+	})
+	if err != nil {
+		return TimeSyncInfo{}, err
+	}
+	if result.ExitCode != 0 {
+		return TimeSyncInfo{}, fmt.Errorf("chronyc failed: %s", strings.TrimSpace(result.STDERR))
+	}
+	return parseChronyTracking(result.STDOUT)
+}
+
+func (uhm *UnixHostManager) darwinTimeSyncStatus() (TimeSyncInfo, error) {
+	var info TimeSyncInfo
+	for _, query := range []string{"-getusingnetworktime", "-getnetworktimeserver"} {
+		result, err := uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+			Command: "systemsetup",
+			Args:    []string{query},
+			Sudo:    true,
+		})
+		if err != nil {
+			return TimeSyncInfo{}, err
+		}
+		// "Network Time: On" and "Network Time Server: time.apple.com"
+		_, value, _ := strings.Cut(result.STDOUT, ":")
+		value = strings.TrimSpace(value)
+		if query == "-getusingnetworktime" {
+			info.Enabled = strings.EqualFold(value, "On")
+		} else {
+			info.Server = value
+		}
+	}
+	info.Synchronized = info.Enabled
+	return info, nil
+}
+
+// parseTimedatectlShow parses "timedatectl show" properties. It reports false
+// when the host cannot synchronize, i.e. CanNTP=no because no supported
+// service is installed.
+func parseTimedatectlShow(output string) (TimeSyncInfo, bool) {
+	properties := make(map[string]string)
+	for _, line := range cm.Lines(output) {
+		if key, value, ok := strings.Cut(line, "="); ok {
+			properties[key] = strings.TrimSpace(value)
+		}
+	}
+	if properties["CanNTP"] == "no" {
+		return TimeSyncInfo{}, false
+	}
+	if _, ok := properties["NTP"]; !ok {
+		return TimeSyncInfo{}, false
+	}
+	return TimeSyncInfo{
+		Enabled:      properties["NTP"] == "yes",
+		Synchronized: properties["NTPSynchronized"] == "yes",
+	}, true
+}
+
+// parseChronyTracking parses "chronyc tracking" output. The server is taken
+// from the Reference ID's parenthesised name and the offset from the System
+// time line, which says whether the clock is fast or slow of NTP time.
+func parseChronyTracking(output string) (TimeSyncInfo, error) {
+	info := TimeSyncInfo{Enabled: true}
+	var sawLeap bool
+	for _, line := range cm.Lines(output) {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+
+		switch strings.TrimSpace(key) {
+		case "Reference ID":
+			if _, name, ok := strings.Cut(value, "("); ok {
+				info.Server = strings.TrimSuffix(name, ")")
+			}
+		case "System time":
+			fields := strings.Fields(value)
+			if len(fields) < 3 {
+				return TimeSyncInfo{}, fmt.Errorf("unexpected chronyc system time: %q", value)
+			}
+			seconds, err := strconv.ParseFloat(fields[0], 64)
+			if err != nil {
+				return TimeSyncInfo{}, fmt.Errorf("error parsing chronyc system time: %v", err)
+			}
+			if fields[2] == "slow" {
+				seconds = -seconds
+			}
+			info.Offset = time.Duration(seconds * float64(time.Second))
+		case "Leap status":
+			sawLeap = true
+			info.Synchronized = value != "Not synchronised"
+		}
+	}
+	if !sawLeap {
+		return TimeSyncInfo{}, fmt.Errorf("unexpected chronyc tracking output: %s", output)
+	}
+	return info, nil
+}
@@ -0,0 +1,146 @@
+package hostmanager
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+const (
+	localtimeFile = "/etc/localtime"
+	zoneinfoDir   = "/usr/share/zoneinfo/"
+)
+
+var timezoneName = regexp.MustCompile(`^[A-Za-z0-9_+-]+(/[A-Za-z0-9_+-]+)*$`)
+
+// Timezone returns the host's IANA timezone name, e.g. "Europe/Berlin".
+func (uhm *UnixHostManager) Timezone() (string, error) {
+	darwin, err := uhm.isDarwin()
+	if err != nil {
+		return "", err
+	}
+	if darwin {
+		result, err := uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+			Command: "systemsetup",
+			Args:    []string{"-gettimezone"},
+			Sudo:    true,
+		})
+		if err != nil {
+			return "", err
+		}
+		// "Time Zone: Europe/Berlin"
+		_, tz, ok := strings.Cut(result.STDOUT, ":")
+		if !ok || strings.TrimSpace(tz) == "" {
+			return "", fmt.Errorf("unexpected systemsetup output: %s", result.STDOUT)
+		}
+		return strings.TrimSpace(tz), nil
+	}
+
+	result, err := uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "timedatectl",
+		Args:    []string{"show", "--property=Timezone", "--value"},
+	})
+	if err == nil && result.ExitCode == 0 {
+		if tz := strings.TrimSpace(result.STDOUT); tz != "" {
+			return tz, nil
+		}
+	}
+
+	// Without systemd, /etc/localtime links into the zoneinfo database
+	result, err = uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "readlink",
+		Args:    []string{localtimeFile},
+	})
+	if err != nil {
+		return "", err
+	}
+	return timezoneFromLink(result.STDOUT)
+}
+
+// SetTimezone changes the host's timezone. tz must be one of the zones the
+// host knows, so a typo is rejected rather than leaving the host on UTC.
+func (uhm *UnixHostManager) SetTimezone(tz string) error {
+	if !timezoneName.MatchString(tz) {
+		return fmt.Errorf("invalid timezone: %q", tz)
+	}
+
+	darwin, err := uhm.isDarwin()
+	if err != nil {
+		return err
+	}
+	if darwin {
+		zones, err := uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+			Command: "systemsetup",
+			Args:    []string{"-listtimezones"},
+			Sudo:    true,
+		})
+		if err != nil {
+			return err
+		}
+		if !containsString(parseTimezones(zones.STDOUT), tz) {
+			return fmt.Errorf("unknown timezone: %q", tz)
+		}
+		return uhm.runChecked(cm.CommandConfig{
+			Command: "systemsetup",
+			Args:    []string{"-settimezone", tz},
+			Sudo:    true,
+		})
+	}
+
+	if uhm.hasCommand("timedatectl") {
+		zones, err := uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+			Command: "timedatectl",
+			Args:    []string{"list-timezones", "--no-pager"},
+		})
+		if err != nil {
+			return err
+		}
+		if !containsString(parseTimezones(zones.STDOUT), tz) {
+			return fmt.Errorf("unknown timezone: %q", tz)
+		}
+		return uhm.runChecked(cm.CommandConfig{
+			Command: "timedatectl",
+			Args:    []string{"set-timezone", tz},
+			Sudo:    true,
+		})
+	}
+
+	// Without systemd, check the zone file exists and link /etc/localtime to it
+	zoneFile := zoneinfoDir + tz
+	if _, err := uhm.readFile(zoneFile); err != nil {
+		return fmt.Errorf("unknown timezone: %q", tz)
+	}
+	return uhm.runChecked(cm.CommandConfig{
+		Command: "ln",
+		Args:    []string{"-sf", zoneFile, localtimeFile},
+		Sudo:    true,
+	})
+}
+
+// parseTimezones parses a list of zone names, one per line. systemsetup
+// indents them below a heading, which is skipped.
+func parseTimezones(output string) []string {
+	var zones []string
+	for _, line := range cm.Lines(output) {
+		zone := strings.TrimSpace(line)
+		if timezoneName.MatchString(zone) {
+			zones = append(zones, zone)
+		}
+	}
+	return zones
+}
+
+// timezoneFromLink extracts the zone name from an /etc/localtime link target
+// such as "/usr/share/zoneinfo/Europe/Berlin" or "../usr/share/zoneinfo/UTC".
+func timezoneFromLink(target string) (string, error) {
+	_, tz, ok := strings.Cut(strings.TrimSpace(target), "zoneinfo/")
+	// Some distributions link into zoneinfo/posix/ or zoneinfo/right/
+	tz = strings.TrimPrefix(strings.TrimPrefix(tz, "posix/"), "right/")
+	if !ok || !timezoneName.MatchString(tz) {
+		return "", fmt.Errorf("unable to determine timezone from %s link: %q", localtimeFile, strings.TrimSpace(target))
+	}
+	return tz, nil
+}
@@ -0,0 +1,93 @@
+package hostmanager
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// ProcessUsage is a process with its share of CPU and memory as ps reports
+// them.
+type ProcessUsage struct {
+	Process
+	CPUPercent float64
+	MemPercent float64
+}
+
+// TopProcesses returns the n processes using the most CPU or memory, by
+// "cpu" or "memory". A non-positive n returns every process. The list is
+// sorted here rather than by ps, whose sort flags differ between Linux and
+// macOS.
+func (uhm *UnixHostManager) TopProcesses(by string, n int) ([]ProcessUsage, error) {
+	var less func(a, b ProcessUsage) bool
+	switch by {
+	case "cpu":
+		less = func(a, b ProcessUsage) bool { return a.CPUPercent > b.CPUPercent }
+	case "memory":
+		less = func(a, b ProcessUsage) bool { return a.MemPercent > b.MemPercent }
+	default:
+		return nil, fmt.Errorf("unknown process sort key %q, want \"cpu\" or \"memory\"", by)
+	}
+
+	result, err := uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "ps",
+		Args:    []string{"-e", "-o", "pid=,ppid=,user=,%cpu=,%mem=,args="},
+	})
+	if err != nil {
+		return nil, err
+	}
+	processes, err := parseProcessUsage(result.STDOUT)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(processes, func(i, j int) bool {
+		return less(processes[i], processes[j])
+	})
+	if n > 0 && n < len(processes) {
+		processes = processes[:n]
+	}
+	return processes, nil
+}
+
+// parseProcessUsage parses "ps -o pid=,ppid=,user=,%cpu=,%mem=,args=" output.
+func parseProcessUsage(output string) ([]ProcessUsage, error) {
+	var processes []ProcessUsage
+	for _, line := range cm.Lines(output) {
+		fields := strings.Fields(line)
+		if len(fields) < 5 {
+			return nil, fmt.Errorf("unexpected ps output line: %q", line)
+		}
+		pid, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("error parsing pid: %v", err)
+		}
+		ppid, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("error parsing ppid: %v", err)
+		}
+		cpu, err := strconv.ParseFloat(fields[3], 64)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing CPU usage: %v", err)
+		}
+		mem, err := strconv.ParseFloat(fields[4], 64)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing memory usage: %v", err)
+		}
+		processes = append(processes, ProcessUsage{
+			Process: Process{
+				PID:     pid,
+				PPID:    ppid,
+				User:    fields[2],
+				Command: strings.Join(fields[5:], " "),
+			},
+			CPUPercent: cpu,
+			MemPercent: mem,
+		})
+	}
+	return processes, nil
+}
@@ -3,6 +3,7 @@ package hostmanager
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strconv"
 	"strings"
 	"time"
@@ -14,8 +15,31 @@ type UnixHostManager struct {
 	CommandManager cm.CommandManager
 }
 
-// Info gathers comprehensive information about the host system.
+// infoFiles are the procfs and sysfs files Info reads in one command on
+// Linux, in the order parseInfoFiles expects them.
+var infoFiles = []string{
+	"/proc/sys/kernel/hostname",
+	"/proc/sys/kernel/osrelease",
+	"/proc/sys/kernel/ostype",
+	"/proc/uptime",
+	"/sys/devices/system/cpu/online",
+	"/proc/loadavg",
+}
+
+// Info gathers comprehensive information about the host system. On Linux it
+// is read from procfs in a single command; elsewhere each value is queried
+// separately.
 func (uhm *UnixHostManager) Info() (HostInfo, error) {
+	result, err := uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "cat",
+		Args:    infoFiles,
+	})
+	if err == nil && result.ExitCode == 0 {
+		if info, ok := parseInfoFiles(result.STDOUT); ok {
+			return info, nil
+		}
+	}
+
 	hostname, err := uhm.Hostname()
 	if err != nil {
 		return HostInfo{}, err
@@ -47,15 +71,110 @@ func (uhm *UnixHostManager) Info() (HostInfo, error) {
 		return HostInfo{}, err
 	}
 
+	load, err := uhm.LoadAverage()
+	if err != nil {
+		return HostInfo{}, err
+	}
+
 	return HostInfo{
 		Hostname:      hostname,
 		OSVersion:     strings.TrimSpace(osVersionOutput.STDOUT),
 		KernelVersion: strings.TrimSpace(kernelVersionOutput.STDOUT),
-		Uptime:        uptime.String(),
+		Uptime:        uptime.Truncate(time.Minute).String(),
 		NumberOfCores: cpuCount,
+		LoadAverage:   load,
 	}, nil
 }
 
+// parseInfoFiles parses the contents of infoFiles, one value per line.
+func parseInfoFiles(output string) (HostInfo, bool) {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) != len(infoFiles) {
+		return HostInfo{}, false
+	}
+
+	uptime, err := parseProcUptime(lines[3])
+	if err != nil {
+		return HostInfo{}, false
+	}
+	cores, ok := countCPUList(strings.TrimSpace(lines[4]))
+	if !ok {
+		return HostInfo{}, false
+	}
+	load, err := parseLoadAvg(lines[5])
+	if err != nil {
+		return HostInfo{}, false
+	}
+
+	return HostInfo{
+		Hostname:      strings.TrimSpace(lines[0]),
+		OSVersion:     strings.TrimSpace(lines[2]),
+		KernelVersion: strings.TrimSpace(lines[1]),
+		Uptime:        uptime.Truncate(time.Minute).String(),
+		NumberOfCores: cores,
+		LoadAverage:   load,
+	}, true
+}
+
+// countCPUList counts the CPUs in a sysfs CPU list such as "0-3,6,8-9".
+func countCPUList(list string) (int, bool) {
+	count := 0
+	for _, part := range strings.Split(list, ",") {
+		first, last, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(first)
+		if err != nil {
+			return 0, false
+		}
+		end := start
+		if isRange {
+			if end, err = strconv.Atoi(last); err != nil || end < start {
+				return 0, false
+			}
+		}
+		count += end - start + 1
+	}
+	return count, count > 0
+}
+
+// LoadAverage returns the 1, 5 and 15 minute load averages from
+// /proc/loadavg, or from sysctl on macOS.
+func (uhm *UnixHostManager) LoadAverage() (LoadAvg, error) {
+	result, err := uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "cat",
+		Args:    []string{"/proc/loadavg"},
+	})
+	if err == nil && result.ExitCode == 0 && strings.TrimSpace(result.STDOUT) != "" {
+		return parseLoadAvg(result.STDOUT)
+	}
+
+	result, err = uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "sysctl",
+		Args:    []string{"-n", "vm.loadavg"},
+	})
+	if err != nil {
+		return LoadAvg{}, err
+	}
+	return parseLoadAvg(result.STDOUT)
+}
+
+// parseLoadAvg parses /proc/loadavg, "0.52 0.58 0.59 1/467 12345", or
+// macOS's vm.loadavg, "{ 1.23 1.45 1.67 }".
+func parseLoadAvg(output string) (LoadAvg, error) {
+	fields := strings.Fields(strings.Trim(strings.TrimSpace(output), "{}"))
+	if len(fields) < 3 {
+		return LoadAvg{}, fmt.Errorf("unexpected load average format: %q", strings.TrimSpace(output))
+	}
+	var values [3]float64
+	for i := range values {
+		value, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return LoadAvg{}, fmt.Errorf("error parsing load average %q: %v", fields[i], err)
+		}
+		values[i] = value
+	}
+	return LoadAvg{One: values[0], Five: values[1], Fifteen: values[2]}, nil
+}
+
 func (uhm *UnixHostManager) Hostname() (string, error) {
 	output, err := uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
 		Command: "hostname",
@@ -95,33 +214,82 @@ func (uhm *UnixHostManager) CPUCount() (int, error) {
 	return strconv.Atoi(strings.TrimSpace(output.STDOUT))
 }
 
-// Uptime retrieves the system's uptime duration.
+// Uptime reports how long the host has been up, read from /proc/uptime on
+// Linux and derived from kern.boottime on macOS.
 func (uhm *UnixHostManager) Uptime() (time.Duration, error) {
-	output, err := uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
-		Command: "uptime",
-		Args:    []string{"-p"},
+	result, err := uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "cat",
+		Args:    []string{"/proc/uptime"},
 	})
+	if err == nil && result.ExitCode == 0 && strings.TrimSpace(result.STDOUT) != "" {
+		return parseProcUptime(result.STDOUT)
+	}
+
+	boot, err := uhm.sysctlBootTime()
+	if err != nil {
+		return 0, err
+	}
+	now, err := uhm.RemoteTime()
 	if err != nil {
 		return 0, err
 	}
+	return now.Sub(boot), nil
+}
+
+// BootTime reports when the host booted, in UTC.
+func (uhm *UnixHostManager) BootTime() (time.Time, error) {
+	result, err := uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "cat",
+		Args:    []string{"/proc/uptime"},
+	})
+	if err != nil || result.ExitCode != 0 || strings.TrimSpace(result.STDOUT) == "" {
+		return uhm.sysctlBootTime()
+	}
 
-	// Parse the uptime -p output. This is a naive way; you may want to enhance this parsing.
-	uptimeStr := strings.TrimSpace(output.STDOUT)
-	uptimeStr = strings.TrimPrefix(uptimeStr, "up ")
-	uptimeArr := strings.Split(uptimeStr, ", ")
-	var totalMinutes int
+	uptime, err := parseProcUptime(result.STDOUT)
+	if err != nil {
+		return time.Time{}, err
+	}
+	now, err := uhm.RemoteTime()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return now.Add(-uptime), nil
+}
 
-	for _, s := range uptimeArr {
-		if strings.Contains(s, "hour") {
-			hours, _ := strconv.Atoi(strings.Split(s, " ")[0])
-			totalMinutes += hours * 60
-		} else if strings.Contains(s, "minute") {
-			minutes, _ := strconv.Atoi(strings.Split(s, " ")[0])
-			totalMinutes += minutes
-		}
+func (uhm *UnixHostManager) sysctlBootTime() (time.Time, error) {
+	result, err := uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "sysctl",
+		Args:    []string{"-n", "kern.boottime"},
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	return parseBootTime(result.STDOUT)
+}
+
+// parseProcUptime parses /proc/uptime, "177120.51 690458.30", whose first
+// field is the seconds since boot.
+func parseProcUptime(output string) (time.Duration, error) {
+	fields := strings.Fields(output)
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("unexpected uptime format: %q", strings.TrimSpace(output))
 	}
+	seconds, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, fmt.Errorf("error parsing uptime %q: %v", fields[0], err)
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
 
-	return time.Duration(totalMinutes) * time.Minute, nil
+// parseBootTime parses macOS's kern.boottime,
+// "{ sec = 1718000000, usec = 123456 } Mon Jun 10 06:13:20 2024".
+func parseBootTime(output string) (time.Time, error) {
+	var sec, usec int64
+	if _, err := fmt.Sscanf(strings.TrimSpace(output), "{ sec = %d, usec = %d }", &sec, &usec); err != nil {
+		return time.Time{}, fmt.Errorf("unexpected boot time format: %q", strings.TrimSpace(output))
+	}
+	return time.Unix(sec, usec*int64(time.Microsecond)).UTC(), nil
 }
 
 // FreeMemory retrieves the amount of free memory in bytes.
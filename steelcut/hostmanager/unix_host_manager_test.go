@@ -2,7 +2,13 @@ package hostmanager
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
 )
@@ -10,25 +16,32 @@ import (
 type MockCommandManager struct {
 	Outputs map[string]string
 	Err     error
+	Calls   []cm.CommandConfig
 }
 
-func (m *MockCommandManager) getMockOutput(command string) cm.CommandResult {
-	if output, exists := m.Outputs[command]; exists {
+// getMockOutput prefers an output keyed by the full command line, e.g.
+// "sysctl -n kern.boottime", over one keyed by the command alone.
+func (m *MockCommandManager) getMockOutput(config cm.CommandConfig) cm.CommandResult {
+	if output, exists := m.Outputs[strings.Join(append([]string{config.Command}, config.Args...), " ")]; exists {
+		return cm.CommandResult{STDOUT: output}
+	}
+	if output, exists := m.Outputs[config.Command]; exists {
 		return cm.CommandResult{STDOUT: output}
 	}
 	return cm.CommandResult{}
 }
 
 func (m *MockCommandManager) RunLocal(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
-	return m.getMockOutput(config.Command), m.Err
+	return m.getMockOutput(config), m.Err
 }
 
 func (m *MockCommandManager) RunRemote(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
-	return m.getMockOutput(config.Command), m.Err
+	return m.getMockOutput(config), m.Err
 }
 
 func (m *MockCommandManager) Run(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
-	return m.getMockOutput(config.Command), m.Err
+	m.Calls = append(m.Calls, config)
+	return m.getMockOutput(config), m.Err
 }
 
 func TestInfo(t *testing.T) {
@@ -37,6 +50,10 @@ func TestInfo(t *testing.T) {
 			"hostname": "test-hostname\n",
 			"nproc":    "4\n",
 			"uname":    "test-version",
+			"sysctl":   "{ 1.23 1.45 1.67 }\n",
+			"date":     "1718003600.000000000\n",
+
+			"sysctl -n kern.boottime": "{ sec = 1718000000, usec = 0 } Mon Jun 10 06:13:20 2024\n",
 		},
 		Err: nil,
 	}
@@ -50,6 +67,91 @@ func TestInfo(t *testing.T) {
 	}
 }
 
+func TestInfoSingleCommand(t *testing.T) {
+	mockCmd := &MockCommandManager{
+		Outputs: map[string]string{
+			"cat": "web1\n6.1.0-21-amd64\nLinux\n177120.51 690458.30\n0-3,6\n0.52 0.58 0.59 1/467 12345\n",
+		},
+	}
+	hostManager := UnixHostManager{CommandManager: mockCmd}
+
+	info, err := hostManager.Info()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	expected := HostInfo{
+		Hostname: "web1", OSVersion: "Linux", KernelVersion: "6.1.0-21-amd64", Uptime: "49h12m0s", NumberOfCores: 5,
+		LoadAverage: LoadAvg{One: 0.52, Five: 0.58, Fifteen: 0.59},
+	}
+	if info != expected {
+		t.Errorf("Expected %+v, got %+v", expected, info)
+	}
+	if len(mockCmd.Calls) != 1 {
+		t.Errorf("Expected a single command, got %d", len(mockCmd.Calls))
+	}
+
+	if s := info.String(); s != "web1: Linux 6.1.0-21-amd64, 5 cores, up 49h12m0s, load 0.52 0.58 0.59" {
+		t.Errorf("Unexpected summary: %s", s)
+	}
+	data, err := json.Marshal(info)
+	if err != nil || string(data) != `{"hostname":"web1","os_version":"Linux","kernel_version":"6.1.0-21-amd64","uptime":"49h12m0s","number_of_cores":5,"load_average":{"one":0.52,"five":0.58,"fifteen":0.59}}` {
+		t.Errorf("Unexpected JSON %s, %v", data, err)
+	}
+}
+
+func TestLoadAverage(t *testing.T) {
+	mockCmd := &MockCommandManager{Outputs: map[string]string{"cat": "0.52 0.58 0.59 1/467 12345\n"}}
+	load, err := (&UnixHostManager{CommandManager: mockCmd}).LoadAverage()
+	if err != nil || load != (LoadAvg{One: 0.52, Five: 0.58, Fifteen: 0.59}) {
+		t.Errorf("Unexpected Linux load average %+v, %v", load, err)
+	}
+
+	// Without /proc, macOS reports the averages in braces
+	mockCmd = &MockCommandManager{Outputs: map[string]string{"sysctl": "{ 1.23 1.45 1.67 }\n"}}
+	load, err = (&UnixHostManager{CommandManager: mockCmd}).LoadAverage()
+	if err != nil || load != (LoadAvg{One: 1.23, Five: 1.45, Fifteen: 1.67}) {
+		t.Errorf("Unexpected macOS load average %+v, %v", load, err)
+	}
+	if _, err := parseLoadAvg("garbage"); err == nil {
+		t.Errorf("Expected an error for malformed output")
+	}
+}
+
+func TestUptime(t *testing.T) {
+	mockCmd := &MockCommandManager{Outputs: map[string]string{"cat": "177120.51 690458.30\n", "date": "1718177120.510000000\n"}}
+	hostManager := &UnixHostManager{CommandManager: mockCmd}
+	uptime, err := hostManager.Uptime()
+	if err != nil || uptime != 177120510*time.Millisecond {
+		t.Errorf("Unexpected Linux uptime %v, %v", uptime, err)
+	}
+	boot, err := hostManager.BootTime()
+	if err != nil || !boot.Equal(time.Unix(1718000000, 0)) {
+		t.Errorf("Unexpected Linux boot time %v, %v", boot, err)
+	}
+
+	// Without /proc, macOS reports the boot time
+	mockCmd = &MockCommandManager{Outputs: map[string]string{
+		"sysctl": "{ sec = 1718000000, usec = 250000 } Mon Jun 10 06:13:20 2024\n",
+		"date":   "1718003600.250000000\n",
+	}}
+	hostManager = &UnixHostManager{CommandManager: mockCmd}
+	boot, err = hostManager.BootTime()
+	if err != nil || !boot.Equal(time.Unix(1718000000, 250000000)) {
+		t.Errorf("Unexpected macOS boot time %v, %v", boot, err)
+	}
+	uptime, err = hostManager.Uptime()
+	if err != nil || uptime != time.Hour {
+		t.Errorf("Unexpected macOS uptime %v, %v", uptime, err)
+	}
+
+	if _, err := parseBootTime("garbage"); err == nil {
+		t.Errorf("Expected an error for malformed boot time")
+	}
+	if _, err := parseProcUptime(""); err == nil {
+		t.Errorf("Expected an error for empty uptime")
+	}
+}
+
 func TestHostname(t *testing.T) {
 	mockCmd := &MockCommandManager{
 		Outputs: map[string]string{
@@ -89,3 +191,1133 @@ func TestCPUCount(t *testing.T) {
 		t.Errorf("Expected 4 CPU cores, got: %v", cpuCount)
 	}
 }
+
+func TestParseEpoch(t *testing.T) {
+	tests := map[string]time.Time{
+		"1700000000.123456789\n": time.Unix(1700000000, 123456789).UTC(),
+		"1700000000.5\n":         time.Unix(1700000000, 500000000).UTC(),
+		"1700000000.N\n":         time.Unix(1700000000, 0).UTC(),
+		"1700000000\n":           time.Unix(1700000000, 0).UTC(),
+	}
+
+	for input, expected := range tests {
+		got, err := parseEpoch(input)
+		if err != nil {
+			t.Errorf("parseEpoch(%q) returned error: %v", input, err)
+			continue
+		}
+		if !got.Equal(expected) {
+			t.Errorf("parseEpoch(%q): expected %v, got %v", input, expected, got)
+		}
+	}
+
+	if _, err := parseEpoch("not a time"); err == nil {
+		t.Errorf("Expected error for invalid epoch output")
+	}
+}
+
+func TestClockSkew(t *testing.T) {
+	before := time.Unix(1700000000, 0)
+	after := before.Add(200 * time.Millisecond)
+
+	// Remote read at the midpoint plus 5s means the host is 5s ahead
+	remote := before.Add(100*time.Millisecond + 5*time.Second)
+	if skew := clockSkew(before, remote, after); skew != 5*time.Second {
+		t.Errorf("Expected skew of 5s, got %v", skew)
+	}
+
+	remote = before.Add(100*time.Millisecond - 2*time.Second)
+	if skew := clockSkew(before, remote, after); skew != -2*time.Second {
+		t.Errorf("Expected skew of -2s, got %v", skew)
+	}
+}
+
+func TestClockSkewWarmsUp(t *testing.T) {
+	mockCmd := &MockCommandManager{
+		Outputs: map[string]string{"date": "1700000000.250000000\n"},
+	}
+	hostManager := UnixHostManager{CommandManager: mockCmd}
+
+	if _, err := hostManager.ClockSkew(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(mockCmd.Calls) != 2 {
+		t.Errorf("Expected a warm-up read before the timed one, got %d calls", len(mockCmd.Calls))
+	}
+}
+
+func TestRemoteTime(t *testing.T) {
+	mockCmd := &MockCommandManager{
+		Outputs: map[string]string{"date": "1700000000.250000000\n"},
+	}
+	hostManager := UnixHostManager{CommandManager: mockCmd}
+
+	remote, err := hostManager.RemoteTime()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !remote.Equal(time.Unix(1700000000, 250000000)) {
+		t.Errorf("Unexpected remote time: %v", remote)
+	}
+}
+
+func TestParseAtq(t *testing.T) {
+	output := "5\tThu Oct 15 10:00:00 2026 a root\n12\tFri Oct 16 23:30:15 2026 b deploy\n"
+	jobs, err := parseAtq(output)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(jobs) != 2 {
+		t.Fatalf("Expected 2 jobs, got: %v", jobs)
+	}
+	expected := AtJob{ID: "12", When: time.Date(2026, 10, 16, 23, 30, 15, 0, time.UTC), Queue: "b", User: "deploy"}
+	if jobs[1] != expected {
+		t.Errorf("Expected %v, got: %v", expected, jobs[1])
+	}
+
+	// BSD atq has no queue or user columns
+	jobs, err = parseAtq("3\tSat Oct 17 08:05:00 2026\n")
+	if err != nil || len(jobs) != 1 || jobs[0].ID != "3" || jobs[0].Queue != "" {
+		t.Errorf("Expected BSD job to parse, got %v, %v", jobs, err)
+	}
+
+	if _, err := parseAtq("garbage\n"); err == nil {
+		t.Errorf("Expected error for malformed atq output")
+	}
+}
+
+func TestAtTimeSpec(t *testing.T) {
+	when := time.Date(2026, 3, 7, 9, 5, 30, 0, time.FixedZone("CET", 3600))
+	if spec := atTimeSpec(when); spec != "202603070805.30" {
+		t.Errorf("Expected UTC spec 202603070805.30, got: %s", spec)
+	}
+}
+
+func TestScheduleCommand(t *testing.T) {
+	mockCmd := &MockCommandManager{
+		Outputs: map[string]string{
+			"sh": "warning: commands will be executed using /bin/sh\njob 42 at Thu Oct 15 10:00:00 2026\n",
+		},
+	}
+	hostManager := UnixHostManager{CommandManager: mockCmd}
+
+	jobID, err := hostManager.ScheduleCommand("systemctl restart nginx", time.Now().Add(time.Hour))
+	if err != nil || jobID != "42" {
+		t.Errorf("Expected job 42, got %q, %v", jobID, err)
+	}
+
+	if _, err := hostManager.ScheduleCommand("  ", time.Now().Add(time.Hour)); err == nil {
+		t.Errorf("Expected error for empty command")
+	}
+	if _, err := hostManager.ScheduleCommand("true", time.Now().Add(-time.Hour)); err == nil {
+		t.Errorf("Expected error for a time in the past")
+	}
+	if err := hostManager.CancelScheduledCommand("1; rm -rf /"); err == nil {
+		t.Errorf("Expected error for invalid job ID")
+	}
+}
+
+func TestCheckAtErrors(t *testing.T) {
+	for _, stderr := range []string{
+		"sh: 1: at: not found\n",
+		"bash: atq: command not found\n",
+		"Can't open /var/run/atd.pid to signal atd. No atd running?\n",
+	} {
+		if err := checkAtErrors(cm.CommandResult{STDERR: stderr}); !errors.Is(err, ErrAtUnavailable) {
+			t.Errorf("Expected ErrAtUnavailable for %q, got: %v", stderr, err)
+		}
+	}
+}
+
+func TestVirtFromDMI(t *testing.T) {
+	tests := []struct {
+		name     string
+		dmi      string
+		virtType string
+		cloud    string
+	}{
+		{
+			name: "kvm",
+			dmi: "/sys/class/dmi/id/product_name:Standard PC (Q35 + ICH9, 2009)\n" +
+				"/sys/class/dmi/id/sys_vendor:QEMU\n" +
+				"/sys/class/dmi/id/bios_vendor:SeaBIOS\n",
+			virtType: "kvm",
+		},
+		{
+			name: "vmware",
+			dmi: "/sys/class/dmi/id/product_name:VMware Virtual Platform\n" +
+				"/sys/class/dmi/id/sys_vendor:VMware, Inc.\n",
+			virtType: "vmware",
+		},
+		{
+			name: "bare metal",
+			dmi: "/sys/class/dmi/id/product_name:PowerEdge R740\n" +
+				"/sys/class/dmi/id/sys_vendor:Dell Inc.\n" +
+				"/sys/class/dmi/id/bios_vendor:Dell Inc.\n",
+			virtType: "none",
+		},
+		{
+			name: "aws",
+			dmi: "/sys/class/dmi/id/product_name:m5.large\n" +
+				"/sys/class/dmi/id/sys_vendor:Amazon EC2\n" +
+				"/sys/class/dmi/id/bios_vendor:Amazon EC2\n",
+			virtType: "amazon",
+			cloud:    "aws",
+		},
+		{
+			name: "aws xen",
+			dmi: "/sys/class/dmi/id/product_name:HVM domU\n" +
+				"/sys/class/dmi/id/bios_version:4.11.amazon\n" +
+				"/sys/hypervisor/uuid:ec2e1916-9099-7caf-fd21-012345abcdef\n",
+			virtType: "amazon",
+			cloud:    "aws",
+		},
+		{
+			name: "azure",
+			dmi: "/sys/class/dmi/id/product_name:Virtual Machine\n" +
+				"/sys/class/dmi/id/sys_vendor:Microsoft Corporation\n" +
+				"/sys/class/dmi/id/chassis_asset_tag:7783-7084-3265-9085-8269-3286-77\n",
+			virtType: "microsoft",
+			cloud:    "azure",
+		},
+	}
+
+	for _, tt := range tests {
+		dmi := parseDMI(tt.dmi)
+		if got := virtFromDMI(dmi); got != tt.virtType {
+			t.Errorf("%s: expected type %s, got %s", tt.name, tt.virtType, got)
+		}
+		if got := cloudFromDMI(dmi); got != tt.cloud {
+			t.Errorf("%s: expected cloud %q, got %q", tt.name, tt.cloud, got)
+		}
+	}
+}
+
+func TestVirtualization(t *testing.T) {
+	mockCmd := &MockCommandManager{
+		Outputs: map[string]string{
+			"systemd-detect-virt": "kvm\n",
+			"grep":                "/sys/class/dmi/id/product_name:Google Compute Engine\n/sys/class/dmi/id/sys_vendor:Google\n",
+		},
+	}
+	hostManager := UnixHostManager{CommandManager: mockCmd}
+
+	info, err := hostManager.Virtualization()
+	expected := VirtInfo{Type: "kvm", IsVirtual: true, CloudProvider: "gcp"}
+	if err != nil || info != expected {
+		t.Errorf("Expected %+v, got %+v, %v", expected, info, err)
+	}
+
+	// macOS has neither systemd-detect-virt nor DMI in sysfs
+	mockCmd.Outputs = map[string]string{"sysctl": "0\n"}
+	info, err = hostManager.Virtualization()
+	if err != nil || info != (VirtInfo{Type: "none"}) {
+		t.Errorf("Expected physical macOS host, got %+v, %v", info, err)
+	}
+}
+
+func TestParseDmesgISO(t *testing.T) {
+	output := "kern  :err   : 2026-10-14T09:12:01,123456+00:00 Out of memory: Killed process 1234 (java)\n" +
+		"daemon:warn  : 2026-10-14T10:00:00,000000+02:00 systemd[1]: unit failed\n"
+
+	messages, err := parseDmesgISO(output)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(messages) != 2 {
+		t.Fatalf("Expected 2 messages, got: %v", messages)
+	}
+
+	expected := KernelMessage{
+		Timestamp: time.Date(2026, 10, 14, 9, 12, 1, 123456000, time.UTC),
+		Facility:  "kern",
+		Level:     "err",
+		Message:   "Out of memory: Killed process 1234 (java)",
+	}
+	if !messages[0].Timestamp.Equal(expected.Timestamp) || messages[0].Facility != expected.Facility ||
+		messages[0].Level != expected.Level || messages[0].Message != expected.Message {
+		t.Errorf("Expected %+v, got: %+v", expected, messages[0])
+	}
+	if !messages[1].Timestamp.Equal(time.Date(2026, 10, 14, 8, 0, 0, 0, time.UTC)) || messages[1].Message != "systemd[1]: unit failed" {
+		t.Errorf("Expected daemon message at 08:00 UTC, got: %+v", messages[1])
+	}
+}
+
+func TestParseDmesgBracketed(t *testing.T) {
+	boot := time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC)
+	output := "kern  :info  : [    0.000000] Linux version 6.1.0\n" +
+		"kern  :warn  : [12345.500000] ata1: link is slow to respond\n"
+
+	messages, err := parseDmesgBracketed(output, boot)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(messages) != 2 {
+		t.Fatalf("Expected 2 messages, got: %v", messages)
+	}
+	if !messages[1].Timestamp.Equal(boot.Add(12345500 * time.Millisecond)) {
+		t.Errorf("Expected boot-relative timestamp, got: %v", messages[1].Timestamp)
+	}
+	if messages[1].Level != "warn" || messages[1].Message != "ata1: link is slow to respond" {
+		t.Errorf("Unexpected message: %+v", messages[1])
+	}
+
+	if _, err := parseDmesgBracketed("kern  :info  : no timestamp\n", boot); err == nil {
+		t.Errorf("Expected error for a line without a timestamp")
+	}
+}
+
+func TestKernelMessagesFilters(t *testing.T) {
+	mockCmd := &MockCommandManager{
+		Outputs: map[string]string{
+			"dmesg": "kern  :err   : 2026-10-14T09:00:00,000000+00:00 early\n" +
+				"kern  :err   : 2026-10-14T11:00:00,000000+00:00 late\n",
+		},
+	}
+	hostManager := UnixHostManager{CommandManager: mockCmd}
+
+	messages, err := hostManager.KernelMessages(DmesgOptions{
+		Levels: []string{"err"},
+		Since:  time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC),
+	})
+	if err != nil || len(messages) != 1 || messages[0].Message != "late" {
+		t.Errorf("Expected only the late message, got %v, %v", messages, err)
+	}
+
+	if _, err := hostManager.KernelMessages(DmesgOptions{Levels: []string{"loud"}}); err == nil {
+		t.Errorf("Expected error for unknown level")
+	}
+}
+
+func TestSystemHostname(t *testing.T) {
+	mockCmd := &MockCommandManager{
+		Outputs: map[string]string{
+			"hostnamectl": "web1.example.com\n",
+			"hostname":    "web1\n",
+		},
+	}
+	hostManager := UnixHostManager{CommandManager: mockCmd}
+
+	name, err := hostManager.SystemHostname()
+	if err != nil || name != "web1.example.com" {
+		t.Errorf("Expected static hostname web1.example.com, got %q, %v", name, err)
+	}
+
+	delete(mockCmd.Outputs, "hostnamectl")
+	name, err = hostManager.SystemHostname()
+	if err != nil || name != "web1" {
+		t.Errorf("Expected hostname fallback web1, got %q, %v", name, err)
+	}
+}
+
+// fileCommandManager serves cat from Files, failing for files not in it, and
+// records the other commands.
+type fileCommandManager struct {
+	MockCommandManager
+	Files map[string]string
+}
+
+func (f *fileCommandManager) Run(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
+	if config.Command == "cat" {
+		content, ok := f.Files[config.Args[0]]
+		if !ok {
+			return cm.CommandResult{STDERR: "cat: " + config.Args[0] + ": No such file or directory", ExitCode: 1}, nil
+		}
+		return cm.CommandResult{STDOUT: content}, nil
+	}
+	return f.MockCommandManager.Run(ctx, config)
+}
+
+// stdinOf returns the data a recorded command was given on stdin.
+func stdinOf(config cm.CommandConfig) string {
+	if config.Stdin == nil {
+		return ""
+	}
+	data, _ := io.ReadAll(config.Stdin)
+	return string(data)
+}
+
+func TestSetHostname(t *testing.T) {
+	mockCmd := &fileCommandManager{
+		MockCommandManager: MockCommandManager{
+			Outputs: map[string]string{
+				"uname": "Linux\n",
+				"which": "/usr/bin/hostnamectl\n",
+			},
+		},
+		Files: map[string]string{
+			"/etc/hostname": "old\n",
+			"/etc/hosts":    "127.0.0.1\tlocalhost\n127.0.1.1\told\n",
+		},
+	}
+	hostManager := UnixHostManager{CommandManager: mockCmd}
+
+	if err := hostManager.SetHostname("web1.example.com"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	var set bool
+	var writes []string
+	for _, call := range mockCmd.Calls {
+		switch call.Command {
+		case "hostnamectl":
+			set = call.Sudo && strings.Join(call.Args, " ") == "set-hostname web1.example.com"
+		case "sh":
+			writes = append(writes, call.Args[3]+": "+stdinOf(call))
+		}
+	}
+	if !set {
+		t.Errorf("Expected sudo hostnamectl set-hostname, got: %+v", mockCmd.Calls)
+	}
+	if len(writes) != 2 || writes[0] != "/etc/hostname: web1.example.com\n" || !strings.HasPrefix(writes[1], "/etc/hosts: ") || !strings.Contains(writes[1], "127.0.1.1\tweb1.example.com web1") {
+		t.Errorf("Expected /etc/hostname and /etc/hosts writes, got: %v", writes)
+	}
+
+	// Nothing is rewritten once the files match
+	mockCmd.Calls = nil
+	mockCmd.Files["/etc/hostname"] = "web1.example.com\n"
+	mockCmd.Files["/etc/hosts"] = "127.0.0.1\tlocalhost\n127.0.1.1  web1.example.com   web1\n"
+	if err := hostManager.SetHostname("web1.example.com"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	for _, call := range mockCmd.Calls {
+		if call.Command == "sh" {
+			t.Errorf("Expected no file writes, got: %v", call.Args)
+		}
+	}
+
+	if err := hostManager.SetHostname("-bad_name"); err == nil {
+		t.Errorf("Expected error for invalid hostname")
+	}
+}
+
+func TestUpdateHostsFile(t *testing.T) {
+	updated, changed := updateHostsFile("127.0.0.1\tlocalhost\n::1\tlocalhost", "db1")
+	if !changed || updated != "127.0.0.1\tlocalhost\n::1\tlocalhost\n127.0.1.1\tdb1\n" {
+		t.Errorf("Expected 127.0.1.1 line appended, got %q", updated)
+	}
+
+	if _, changed := updateHostsFile(updated, "db1"); changed {
+		t.Errorf("Expected no change when the entry already matches")
+	}
+}
+
+func TestValidHostname(t *testing.T) {
+	for name, valid := range map[string]bool{
+		"web1":                  true,
+		"web-1.example.com":     true,
+		"1host":                 true,
+		"":                      false,
+		"-web":                  false,
+		"web-":                  false,
+		"web_1":                 false,
+		"web..example":          false,
+		strings.Repeat("a", 64): false,
+	} {
+		if validHostname(name) != valid {
+			t.Errorf("validHostname(%q) = %v, expected %v", name, !valid, valid)
+		}
+	}
+}
+
+const procStatus = `Name:	nginx
+Umask:	0022
+State:	S (sleeping)
+Tgid:	1234
+Pid:	1234
+PPid:	1
+Uid:	33	33	33	33
+Gid:	33	33	33	33
+VmSize:	   55300 kB
+VmRSS:	    5232 kB
+Threads:	4
+`
+
+func TestParseProcStatus(t *testing.T) {
+	detail, err := parseProcStatus(procStatus)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	expected := ProcessDetail{
+		PID:     1234,
+		PPID:    1,
+		UID:     33,
+		Name:    "nginx",
+		State:   "S (sleeping)",
+		Threads: 4,
+		RSS:     5232 * 1024,
+		VMSize:  55300 * 1024,
+	}
+	if !reflect.DeepEqual(detail, expected) {
+		t.Errorf("Expected %+v, got: %+v", expected, detail)
+	}
+}
+
+func TestParseCmdline(t *testing.T) {
+	args := parseCmdline("nginx: worker process\x00-c\x00/etc/nginx/my site.conf\x00")
+	expected := []string{"nginx: worker process", "-c", "/etc/nginx/my site.conf"}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("Expected %q, got: %q", expected, args)
+	}
+
+	if args := parseCmdline(""); args != nil {
+		t.Errorf("Expected no arguments for a kernel thread, got: %q", args)
+	}
+}
+
+func TestProcessInfo(t *testing.T) {
+	mockCmd := &fileCommandManager{
+		MockCommandManager: MockCommandManager{
+			Outputs: map[string]string{
+				"uname": "Linux\n",
+				"ls":    "0\n1\n2\n3\n",
+			},
+		},
+		Files: map[string]string{
+			"/proc/1234/status":  procStatus,
+			"/proc/1234/cmdline": "nginx\x00-g\x00daemon off;\x00",
+			"/proc/1234/io":      "rchar: 100\nwchar: 200\nread_bytes: 4096\nwrite_bytes: 8192\n",
+		},
+	}
+	hostManager := UnixHostManager{CommandManager: mockCmd}
+
+	detail, err := hostManager.ProcessInfo(1234)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if detail.OpenFiles != 4 || detail.ReadBytes != 4096 || detail.WriteBytes != 8192 {
+		t.Errorf("Unexpected open files or IO: %+v", detail)
+	}
+	if !reflect.DeepEqual(detail.Cmdline, []string{"nginx", "-g", "daemon off;"}) {
+		t.Errorf("Unexpected cmdline: %q", detail.Cmdline)
+	}
+
+	if _, err := hostManager.ProcessInfo(999); !errors.Is(err, ErrProcessNotFound) {
+		t.Errorf("Expected ErrProcessNotFound, got: %v", err)
+	}
+}
+
+func TestParseSestatus(t *testing.T) {
+	output := `SELinux status:                 enabled
+SELinuxfs mount:                /sys/fs/selinux
+SELinux root directory:         /etc/selinux
+Loaded policy name:             targeted
+Current mode:                   permissive
+Mode from config file:          enforcing
+Policy MLS status:              enabled
+Max kernel policy version:      33
+`
+	status, ok := parseSestatus(output)
+	if !ok || status != (SecurityStatus{Module: "selinux", Mode: "permissive"}) {
+		t.Errorf("Unexpected status: %+v, %v", status, ok)
+	}
+
+	status, ok = parseSestatus("SELinux status:                 disabled\n")
+	if !ok || status.Mode != "disabled" {
+		t.Errorf("Expected disabled, got: %+v, %v", status, ok)
+	}
+
+	if _, ok := parseSestatus(""); ok {
+		t.Errorf("Expected empty output not to parse")
+	}
+}
+
+func TestParseAAStatus(t *testing.T) {
+	output := `apparmor module is loaded.
+34 profiles are loaded.
+32 profiles are in enforce mode.
+   /usr/bin/man
+   /usr/sbin/chronyd
+2 profiles are in complain mode.
+   nvidia_modprobe
+0 profiles are in kill mode.
+0 profiles are in unconfined mode.
+2 processes have profiles defined.
+2 processes are in enforce mode.
+   /usr/sbin/chronyd (812)
+0 processes are in complain mode.
+`
+	status, ok := parseAAStatus(output)
+	if !ok || status != (SecurityStatus{Module: "apparmor", Mode: "enforcing"}) {
+		t.Errorf("Unexpected status: %+v, %v", status, ok)
+	}
+
+	status, _ = parseAAStatus("apparmor module is loaded.\n3 profiles are loaded.\n0 profiles are in enforce mode.\n3 profiles are in complain mode.\n")
+	if status.Mode != "complain" {
+		t.Errorf("Expected complain, got: %+v", status)
+	}
+
+	status, ok = parseAAStatus("apparmor module is not loaded.\n")
+	if !ok || status.Mode != "disabled" {
+		t.Errorf("Expected disabled, got: %+v, %v", status, ok)
+	}
+}
+
+func TestSecurityModuleStatus(t *testing.T) {
+	mockCmd := &MockCommandManager{Outputs: map[string]string{
+		"sestatus":  "SELinux status:                 disabled\n",
+		"aa-status": "apparmor module is loaded.\n1 profiles are loaded.\n1 profiles are in enforce mode.\n",
+	}}
+	hostManager := UnixHostManager{CommandManager: mockCmd}
+
+	status, err := hostManager.SecurityModuleStatus()
+	if err != nil || status != (SecurityStatus{Module: "apparmor", Mode: "enforcing"}) {
+		t.Errorf("Expected enforcing AppArmor, got: %+v, %v", status, err)
+	}
+
+	hostManager = UnixHostManager{CommandManager: &MockCommandManager{}}
+	status, err = hostManager.SecurityModuleStatus()
+	if err != nil || status.Module != "none" {
+		t.Errorf("Expected no security module, got: %+v, %v", status, err)
+	}
+}
+
+func TestSetSELinuxMode(t *testing.T) {
+	mockCmd := &fileCommandManager{
+		Files: map[string]string{
+			"/etc/selinux/config": "# comment\nSELINUX=enforcing\nSELINUXTYPE=targeted\n",
+		},
+	}
+	hostManager := UnixHostManager{CommandManager: mockCmd}
+
+	if err := hostManager.SetSELinuxMode("permissive", true); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	var setenforce, write string
+	for _, call := range mockCmd.Calls {
+		switch call.Command {
+		case "setenforce":
+			setenforce = strings.Join(call.Args, " ")
+		case "sh":
+			write = stdinOf(call)
+		}
+	}
+	if setenforce != "0" {
+		t.Errorf("Expected setenforce 0, got: %+v", mockCmd.Calls)
+	}
+	if !strings.Contains(write, "SELINUX=permissive\nSELINUXTYPE=targeted") {
+		t.Errorf("Expected config to be updated, got: %q", write)
+	}
+
+	if err := hostManager.SetSELinuxMode("disabled", false); err == nil {
+		t.Errorf("Expected error disabling SELinux at runtime")
+	}
+	if err := hostManager.SetSELinuxMode("off", false); err == nil {
+		t.Errorf("Expected error for invalid mode")
+	}
+}
+
+func TestParseLsblkJSON(t *testing.T) {
+	output := `{
+   "blockdevices": [
+      {"name":"sda", "size":53687091200, "type":"disk", "fstype":null, "mountpoint":null, "uuid":null,
+         "children": [
+            {"name":"sda1", "size":1073741824, "type":"part", "fstype":"ext4", "mountpoint":"/boot", "uuid":"9a1c-boot"},
+            {"name":"sda2", "size":52612300800, "type":"part", "fstype":"LVM2_member", "mountpoint":null, "uuid":"pv-uuid",
+               "children": [
+                  {"name":"vg0-root", "size":"42949672960", "type":"lvm", "fstype":"xfs", "mountpoint":"/", "uuid":"root-uuid"},
+                  {"name":"vg0-swap", "size":4294967296, "type":"lvm", "fstype":"swap", "mountpoint":"[SWAP]", "uuid":"swap-uuid"}
+               ]
+            }
+         ]
+      },
+      {"name":"sr0", "size":1073741312, "type":"rom", "fstype":null, "mountpoint":null, "uuid":null}
+   ]
+}`
+	devices, err := parseLsblkJSON(output)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(devices) != 2 || devices[0].Name != "sda" || devices[0].Size != 53687091200 || len(devices[0].Children) != 2 {
+		t.Fatalf("Unexpected devices: %+v", devices)
+	}
+	boot := devices[0].Children[0]
+	if boot.FSType != "ext4" || boot.MountPoint != "/boot" || boot.Type != "part" {
+		t.Errorf("Unexpected partition: %+v", boot)
+	}
+	lvs := devices[0].Children[1].Children
+	if len(lvs) != 2 || lvs[0].Type != "lvm" || lvs[0].MountPoint != "/" || lvs[0].Size != 42949672960 {
+		t.Errorf("Unexpected LVM volumes: %+v", lvs)
+	}
+	if devices[1].FSType != "" || devices[1].Children != nil {
+		t.Errorf("Expected empty fields for nulls, got: %+v", devices[1])
+	}
+
+	if _, err := parseLsblkJSON("NAME SIZE\nsda 50G\n"); err == nil {
+		t.Errorf("Expected error for non-JSON output")
+	}
+}
+
+func TestParseDiskutilPlist(t *testing.T) {
+	output := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>AllDisks</key>
+	<array><string>disk0</string><string>disk0s1</string></array>
+	<key>AllDisksAndPartitions</key>
+	<array>
+		<dict>
+			<key>Content</key><string>GUID_partition_scheme</string>
+			<key>DeviceIdentifier</key><string>disk0</string>
+			<key>OSInternal</key><false/>
+			<key>Partitions</key>
+			<array>
+				<dict>
+					<key>Content</key><string>Apple_APFS</string>
+					<key>DeviceIdentifier</key><string>disk0s2</string>
+					<key>Size</key><integer>494384795648</integer>
+				</dict>
+			</array>
+			<key>Size</key><integer>500107862016</integer>
+		</dict>
+		<dict>
+			<key>APFSVolumes</key>
+			<array>
+				<dict>
+					<key>DeviceIdentifier</key><string>disk3s1</string>
+					<key>MountPoint</key><string>/System/Volumes/Data</string>
+					<key>Size</key><integer>494384795648</integer>
+					<key>VolumeName</key><string>Data</string>
+					<key>VolumeUUID</key><string>6F0C-DATA</string>
+				</dict>
+			</array>
+			<key>DeviceIdentifier</key><string>disk3</string>
+			<key>Size</key><integer>494384795648</integer>
+		</dict>
+	</array>
+</dict>
+</plist>`
+	devices, err := parseDiskutilPlist(output)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(devices) != 2 || devices[0].Name != "disk0" || devices[0].Size != 500107862016 {
+		t.Fatalf("Unexpected devices: %+v", devices)
+	}
+	if part := devices[0].Children; len(part) != 1 || part[0].Type != "part" || part[0].FSType != "Apple_APFS" {
+		t.Errorf("Unexpected partitions: %+v", part)
+	}
+	volume := devices[1].Children
+	if len(volume) != 1 || volume[0].Type != "volume" || volume[0].MountPoint != "/System/Volumes/Data" || volume[0].UUID != "6F0C-DATA" {
+		t.Errorf("Unexpected APFS volumes: %+v", volume)
+	}
+}
+
+const dpkgKernels = `Desired=Unknown/Install/Remove/Purge/Hold
+| Status=Not/Inst/Conf-files/Unpacked/halF-conf/Half-inst/trig-aWait/Trig-pend
+|/ Err?=(none)/Reinst-required (Status,Err: uppercase=bad)
+||/ Name                         Version       Architecture Description
++++-============================-=============-============-=================================
+ii  linux-headers-6.1.0-17-amd64 6.1.69-1      amd64        Header files for Linux 6.1.0-17-amd64
+ii  linux-headers-6.1.0-18-common 6.1.76-1     all          Common header files for Linux 6.1.0-18
+ii  linux-image-6.1.0-9-amd64    6.1.27-1      amd64        Linux 6.1 for 64-bit PCs (signed)
+ii  linux-image-6.1.0-15-amd64   6.1.66-1      amd64        Linux 6.1 for 64-bit PCs (signed)
+ii  linux-image-6.1.0-17-amd64   6.1.69-1      amd64        Linux 6.1 for 64-bit PCs (signed)
+ii  linux-image-6.1.0-18-amd64   6.1.76-1      amd64        Linux 6.1 for 64-bit PCs (signed)
+rc  linux-image-6.1.0-7-amd64    6.1.20-2      amd64        Linux 6.1 for 64-bit PCs (signed)
+ii  linux-image-amd64            6.1.76-1      amd64        Linux for 64-bit PCs (meta-package)
+`
+
+func TestParseDpkgKernels(t *testing.T) {
+	kernels := parseDpkgKernels(dpkgKernels)
+	want := []string{"6.1.0-18-amd64", "6.1.0-17-amd64", "6.1.0-15-amd64", "6.1.0-9-amd64"}
+	if got := sortedKernels(kernels); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got: %v", want, got)
+	}
+	if got := kernels["6.1.0-17-amd64"]; !reflect.DeepEqual(got, []string{"linux-headers-6.1.0-17-amd64", "linux-image-6.1.0-17-amd64"}) {
+		t.Errorf("Unexpected packages for 6.1.0-17: %v", got)
+	}
+}
+
+func TestParseRPMKernels(t *testing.T) {
+	output := `kernel 5.14.0-362.8.1.el9_3.x86_64
+kernel-core 5.14.0-362.8.1.el9_3.x86_64
+kernel-modules 5.14.0-362.8.1.el9_3.x86_64
+kernel 5.14.0-70.13.1.el9_0.x86_64
+kernel-core 5.14.0-70.13.1.el9_0.x86_64
+kernel-headers 5.14.0-362.8.1.el9_3.x86_64
+kernel-tools 5.14.0-362.8.1.el9_3.x86_64
+`
+	kernels := parseRPMKernels(output)
+	want := []string{"5.14.0-362.8.1.el9_3.x86_64", "5.14.0-70.13.1.el9_0.x86_64"}
+	if got := sortedKernels(kernels); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got: %v", want, got)
+	}
+	if got := kernels["5.14.0-362.8.1.el9_3.x86_64"]; len(got) != 3 {
+		t.Errorf("Expected kernel, kernel-core and kernel-modules, got: %v", got)
+	}
+}
+
+func TestRemoveOldKernels(t *testing.T) {
+	mockCmd := &MockCommandManager{Outputs: map[string]string{
+		"which": "/usr/bin/dpkg\n",
+		"dpkg":  dpkgKernels,
+		"uname": "6.1.0-15-amd64\n",
+	}}
+	hostManager := UnixHostManager{CommandManager: mockCmd}
+
+	removed, err := hostManager.RemoveOldKernels(1)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if want := []string{"6.1.0-17-amd64", "6.1.0-9-amd64"}; !reflect.DeepEqual(removed, want) {
+		t.Errorf("Expected %v removed, got: %v", want, removed)
+	}
+
+	var args string
+	for _, call := range mockCmd.Calls {
+		if call.Command == "apt-get" {
+			args = strings.Join(call.Args, " ")
+		}
+	}
+	if strings.Contains(args, "6.1.0-15") || strings.Contains(args, "6.1.0-18") {
+		t.Errorf("Expected running and newest kernels to be kept, got: %s", args)
+	}
+	if !strings.Contains(args, "linux-image-6.1.0-9-amd64") || !strings.Contains(args, "linux-headers-6.1.0-17-amd64") {
+		t.Errorf("Expected old kernel packages to be removed, got: %s", args)
+	}
+
+	if _, err := hostManager.RemoveOldKernels(0); err == nil {
+		t.Errorf("Expected error when keeping no kernels")
+	}
+}
+
+func TestOldKernels(t *testing.T) {
+	kernels := []string{"6.8.0-45", "6.8.0-40", "6.8.0-31"}
+	if got := oldKernels(kernels, "6.8.0-31", 2); len(got) != 0 {
+		t.Errorf("Expected the running kernel to be kept, got: %v", got)
+	}
+	if got := oldKernels(kernels, "6.8.0-45", 1); !reflect.DeepEqual(got, []string{"6.8.0-40", "6.8.0-31"}) {
+		t.Errorf("Unexpected kernels to remove: %v", got)
+	}
+}
+
+func TestGrubMenuEntry(t *testing.T) {
+	grubConfig := `menuentry 'Debian GNU/Linux' --class debian $menuentry_id_option 'gnulinux-simple-1f2e' {
+}
+submenu 'Advanced options for Debian GNU/Linux' $menuentry_id_option 'gnulinux-advanced-1f2e' {
+	menuentry 'Debian GNU/Linux, with Linux 6.1.0-18-amd64' --class debian $menuentry_id_option 'gnulinux-6.1.0-18-amd64-advanced-1f2e' {
+	}
+	menuentry 'Debian GNU/Linux, with Linux 6.1.0-17-amd64 (recovery mode)' $menuentry_id_option 'gnulinux-6.1.0-17-amd64-recovery-1f2e' {
+	}
+	menuentry 'Debian GNU/Linux, with Linux 6.1.0-17-amd64' --class debian $menuentry_id_option 'gnulinux-6.1.0-17-amd64-advanced-1f2e' {
+	}
+}
+`
+	entry, err := grubMenuEntry(grubConfig, "6.1.0-17-amd64")
+	if err != nil || entry != "gnulinux-advanced-1f2e>gnulinux-6.1.0-17-amd64-advanced-1f2e" {
+		t.Errorf("Unexpected entry: %q, %v", entry, err)
+	}
+	if _, err := grubMenuEntry(grubConfig, "5.10.0-28-amd64"); err == nil {
+		t.Errorf("Expected error for a kernel without a menu entry")
+	}
+}
+
+func TestTimezone(t *testing.T) {
+	mockCmd := &MockCommandManager{Outputs: map[string]string{
+		"uname":       "Linux\n",
+		"timedatectl": "Europe/Berlin\n",
+	}}
+	hostManager := UnixHostManager{CommandManager: mockCmd}
+
+	tz, err := hostManager.Timezone()
+	if err != nil || tz != "Europe/Berlin" {
+		t.Errorf("Expected Europe/Berlin, got: %q, %v", tz, err)
+	}
+
+	// Without timedatectl the /etc/localtime link is used
+	hostManager = UnixHostManager{CommandManager: &MockCommandManager{Outputs: map[string]string{
+		"uname":    "Linux\n",
+		"readlink": "/usr/share/zoneinfo/America/Argentina/Buenos_Aires\n",
+	}}}
+	tz, err = hostManager.Timezone()
+	if err != nil || tz != "America/Argentina/Buenos_Aires" {
+		t.Errorf("Expected America/Argentina/Buenos_Aires, got: %q, %v", tz, err)
+	}
+
+	hostManager = UnixHostManager{CommandManager: &MockCommandManager{Outputs: map[string]string{
+		"uname":       "Darwin\n",
+		"systemsetup": "Time Zone: Asia/Tokyo\n",
+	}}}
+	tz, err = hostManager.Timezone()
+	if err != nil || tz != "Asia/Tokyo" {
+		t.Errorf("Expected Asia/Tokyo, got: %q, %v", tz, err)
+	}
+}
+
+func TestSetTimezone(t *testing.T) {
+	mockCmd := &MockCommandManager{Outputs: map[string]string{
+		"uname":       "Linux\n",
+		"which":       "/usr/bin/timedatectl\n",
+		"timedatectl": "Africa/Abidjan\nEurope/Berlin\nUTC\n",
+	}}
+	hostManager := UnixHostManager{CommandManager: mockCmd}
+
+	if err := hostManager.SetTimezone("Europe/Berlin"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	last := mockCmd.Calls[len(mockCmd.Calls)-1]
+	if last.Command != "timedatectl" || !last.Sudo || strings.Join(last.Args, " ") != "set-timezone Europe/Berlin" {
+		t.Errorf("Expected sudo timedatectl set-timezone, got: %+v", last)
+	}
+
+	for _, tz := range []string{"Europe/Berln", "../../etc/passwd", ""} {
+		mockCmd.Calls = nil
+		if err := hostManager.SetTimezone(tz); err == nil {
+			t.Errorf("Expected error for timezone %q", tz)
+		}
+		for _, call := range mockCmd.Calls {
+			if call.Sudo {
+				t.Errorf("Expected nothing to be changed for %q, got: %+v", tz, call)
+			}
+		}
+	}
+}
+
+func TestParseTimedatectlShow(t *testing.T) {
+	output := `Timezone=Europe/Berlin
+LocalRTC=no
+CanNTP=yes
+NTP=yes
+NTPSynchronized=no
+TimeUSec=Wed 2026-10-14 12:00:00 CEST
+RTCTimeUSec=Wed 2026-10-14 10:00:00 CEST
+`
+	info, ok := parseTimedatectlShow(output)
+	if !ok || !info.Enabled || info.Synchronized {
+		t.Errorf("Expected enabled but unsynchronized, got: %+v, %v", info, ok)
+	}
+
+	if _, ok := parseTimedatectlShow("Timezone=UTC\nCanNTP=no\nNTP=no\n"); ok {
+		t.Errorf("Expected CanNTP=no to report no time sync")
+	}
+}
+
+func TestParseChronyTracking(t *testing.T) {
+	output := `Reference ID    : A9FEA97B (169.254.169.123)
+Stratum         : 4
+Ref time (UTC)  : Wed Oct 14 10:00:00 2026
+System time     : 0.000250000 seconds slow of NTP time
+Last offset     : -0.000001234 seconds
+RMS offset      : 0.000012345 seconds
+Frequency       : 3.456 ppm fast
+Residual freq   : +0.001 ppm
+Skew            : 0.042 ppm
+Root delay      : 0.000534 seconds
+Root dispersion : 0.000123 seconds
+Update interval : 16.1 seconds
+Leap status     : Normal
+`
+	info, err := parseChronyTracking(output)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	want := TimeSyncInfo{Enabled: true, Synchronized: true, Server: "169.254.169.123", Offset: -250 * time.Microsecond}
+	if info != want {
+		t.Errorf("Expected %+v, got: %+v", want, info)
+	}
+
+	info, err = parseChronyTracking("Reference ID    : 00000000 ()\nSystem time     : 0.000000000 seconds fast of NTP time\nLeap status     : Not synchronised\n")
+	if err != nil || info.Synchronized {
+		t.Errorf("Expected unsynchronized, got: %+v, %v", info, err)
+	}
+
+	if _, err := parseChronyTracking("506 Cannot talk to daemon\n"); err == nil {
+		t.Errorf("Expected error when chronyd is not running")
+	}
+}
+
+func TestTimeSyncWithoutService(t *testing.T) {
+	hostManager := UnixHostManager{CommandManager: &MockCommandManager{Outputs: map[string]string{
+		"uname":       "Linux\n",
+		"timedatectl": "Timezone=UTC\nCanNTP=no\nNTP=no\n",
+	}}}
+	if _, err := hostManager.TimeSyncStatus(); !errors.Is(err, ErrNoTimeSync) {
+		t.Errorf("Expected ErrNoTimeSync, got: %v", err)
+	}
+	if err := hostManager.EnableTimeSync(); !errors.Is(err, ErrNoTimeSync) {
+		t.Errorf("Expected ErrNoTimeSync, got: %v", err)
+	}
+}
+
+func TestProcessTree(t *testing.T) {
+	output := `    1     0 root     /sbin/init splash
+    2     0 root     [kthreadd]
+  812     1 root     /usr/sbin/sshd -D
+ 4410   812 root     sshd: deploy [priv]
+ 4420  4410 deploy   -bash
+ 4431  4420 deploy   make -j8 build
+ 5001  9999 www-data php-fpm: pool www
+ 6001  6002 nobody   loop-a
+ 6002  6001 nobody   loop-b
+`
+	hostManager := UnixHostManager{CommandManager: &MockCommandManager{Outputs: map[string]string{"ps": output}}}
+
+	root, err := hostManager.ProcessTree()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if root.PID != 1 || root.Command != "/sbin/init splash" {
+		t.Errorf("Unexpected root: %+v", root.Process)
+	}
+
+	// kthreadd, sshd, the orphaned php-fpm and one process of the cycle
+	var rootChildren []int
+	for _, child := range root.Children {
+		rootChildren = append(rootChildren, child.PID)
+	}
+	if len(rootChildren) != 4 || rootChildren[0] != 2 || rootChildren[1] != 812 || rootChildren[2] != 5001 {
+		t.Errorf("Unexpected children of PID 1: %v", rootChildren)
+	}
+
+	sshd, err := hostManager.ProcessTreeFrom(812)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	node := sshd
+	for _, pid := range []int{4410, 4420, 4431} {
+		if len(node.Children) != 1 || node.Children[0].PID != pid {
+			t.Fatalf("Expected %d below %d, got: %+v", pid, node.PID, node.Children)
+		}
+		node = node.Children[0]
+	}
+	if node.User != "deploy" || node.Command != "make -j8 build" {
+		t.Errorf("Unexpected leaf process: %+v", node.Process)
+	}
+
+	loop, _ := hostManager.ProcessTreeFrom(6001)
+	loopChild, _ := hostManager.ProcessTreeFrom(6002)
+	if len(loop.Children)+len(loopChild.Children) != 1 {
+		t.Errorf("Expected the cycle to be broken into a single chain, got: %+v, %+v", loop.Children, loopChild.Children)
+	}
+
+	if _, err := hostManager.ProcessTreeFrom(7777); !errors.Is(err, ErrProcessNotFound) {
+		t.Errorf("Expected ErrProcessNotFound, got: %v", err)
+	}
+}
+
+func TestTopProcesses(t *testing.T) {
+	mockCmd := &MockCommandManager{Outputs: map[string]string{"ps": `    1     0 root      0.0  0.1 /sbin/init
+  812     1 postgres 12.5 20.3 postgres: checkpointer
+  901     1 www-data 40.2  5.0 nginx: worker process
+  950   901 www-data  3.1  9.9 php-fpm: pool www
+`}}
+	hostManager := &UnixHostManager{CommandManager: mockCmd}
+
+	top, err := hostManager.TopProcesses("cpu", 2)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	expected := []ProcessUsage{
+		{Process: Process{PID: 901, PPID: 1, User: "www-data", Command: "nginx: worker process"}, CPUPercent: 40.2, MemPercent: 5.0},
+		{Process: Process{PID: 812, PPID: 1, User: "postgres", Command: "postgres: checkpointer"}, CPUPercent: 12.5, MemPercent: 20.3},
+	}
+	if !reflect.DeepEqual(top, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, top)
+	}
+
+	top, err = hostManager.TopProcesses("memory", 0)
+	if err != nil || len(top) != 4 || top[0].PID != 812 || top[1].PID != 950 || top[3].PID != 1 {
+		t.Errorf("Unexpected processes by memory %+v, %v", top, err)
+	}
+
+	if _, err := hostManager.TopProcesses("disk", 5); err == nil {
+		t.Errorf("Expected an error for an unknown sort key")
+	}
+}
+
+// rebootingCommandManager drops the connection on "sudo reboot", fails the
+// next Drops commands, then answers with the After outputs.
+type rebootingCommandManager struct {
+	MockCommandManager
+	After    map[string]string
+	Drops    int
+	rebooted bool
+}
+
+func (m *rebootingCommandManager) Run(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
+	m.Calls = append(m.Calls, config)
+	if config.Command == "sudo" {
+		m.Outputs, m.rebooted = m.After, true
+		return cm.CommandResult{}, errors.New("wait: remote command exited without exit status or exit signal")
+	}
+	if m.rebooted && m.Drops > 0 {
+		m.Drops--
+		return cm.CommandResult{}, errors.New("dial tcp: connection refused")
+	}
+	return m.getMockOutput(config), nil
+}
+
+func TestRebootAndWait(t *testing.T) {
+	interval := rebootPollInterval
+	rebootPollInterval = time.Millisecond
+	defer func() { rebootPollInterval = interval }()
+
+	mockCmd := &rebootingCommandManager{
+		MockCommandManager: MockCommandManager{Outputs: map[string]string{"cat": "3600.00 1000.00\n", "date": "1718003600.000000000\n"}},
+		After:              map[string]string{"cat": "30.00 10.00\n", "date": "1718003700.000000000\n"},
+		Drops:              3,
+	}
+	hostManager := &UnixHostManager{CommandManager: mockCmd}
+	if err := hostManager.RebootAndWait(context.Background(), time.Second); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if mockCmd.Drops != 0 {
+		t.Errorf("Expected to poll through the dropped connections, %d left", mockCmd.Drops)
+	}
+
+	// A host that never comes back times out
+	mockCmd = &rebootingCommandManager{
+		MockCommandManager: MockCommandManager{Outputs: map[string]string{"cat": "3600.00 1000.00\n", "date": "1718003600.000000000\n"}},
+		After:              map[string]string{},
+		Drops:              1 << 30,
+	}
+	hostManager = &UnixHostManager{CommandManager: mockCmd}
+	err := hostManager.RebootAndWait(context.Background(), 20*time.Millisecond)
+	if !errors.Is(err, cm.ErrWaitTimeout) || !strings.Contains(err.Error(), "connection refused") {
+		t.Errorf("Expected a timeout with the last error, got: %v", err)
+	}
+}
+
+func TestShutdownIn(t *testing.T) {
+	mockCmd := &MockCommandManager{Outputs: map[string]string{"uname": "Linux\n"}}
+	hostManager := &UnixHostManager{CommandManager: mockCmd}
+
+	if err := hostManager.ShutdownIn(90*time.Second, "Maintenance at 02:00"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if err := hostManager.RebootIn(30*time.Second, ""); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if err := hostManager.CancelShutdown(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	expected := [][]string{
+		{"-h", "+2", "Maintenance at 02:00"},
+		{"-r", "+1"},
+	}
+	for i, args := range expected {
+		if call := mockCmd.Calls[i]; call.Command != "shutdown" || !call.Sudo || !reflect.DeepEqual(call.Args, args) {
+			t.Errorf("Expected sudo shutdown %v, got %+v", args, call)
+		}
+	}
+	if call := mockCmd.Calls[len(mockCmd.Calls)-1]; call.Command != "shutdown" || !reflect.DeepEqual(call.Args, []string{"-c"}) {
+		t.Errorf("Expected shutdown -c, got %+v", call)
+	}
+
+	mockCmd = &MockCommandManager{Outputs: map[string]string{"uname": "Darwin\n"}}
+	if err := (&UnixHostManager{CommandManager: mockCmd}).CancelShutdown(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if call := mockCmd.Calls[len(mockCmd.Calls)-1]; call.Command != "killall" || !reflect.DeepEqual(call.Args, []string{"shutdown"}) {
+		t.Errorf("Expected killall shutdown on macOS, got %+v", call)
+	}
+
+	if s := shutdownTime(0); s != "now" {
+		t.Errorf("Expected now, got %s", s)
+	}
+	if s := shutdownTime(10 * time.Minute); s != "+10" {
+		t.Errorf("Expected +10, got %s", s)
+	}
+}
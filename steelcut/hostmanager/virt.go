@@ -0,0 +1,169 @@
+package hostmanager
+
+import (
+	"context"
+	"path"
+	"strings"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// VirtInfo describes the virtualization environment of a host. Type uses
+// systemd-detect-virt's names ("kvm", "vmware", "amazon", ...) and is "none"
+// on bare metal. CloudProvider is "aws", "gcp", "azure" or empty.
+type VirtInfo struct {
+	Type          string
+	IsVirtual     bool
+	CloudProvider string
+}
+
+// DMI and hypervisor files read for the fallback and cloud detection
+var virtDMIFiles = []string{
+	"/sys/class/dmi/id/product_name",
+	"/sys/class/dmi/id/sys_vendor",
+	"/sys/class/dmi/id/bios_vendor",
+	"/sys/class/dmi/id/bios_version",
+	"/sys/class/dmi/id/chassis_asset_tag",
+	"/sys/hypervisor/uuid",
+}
+
+// azureAssetTag is the chassis asset tag set on every Azure VM.
+const azureAssetTag = "7783-7084-3265-9085-8269-3286-77"
+
+// Virtualization detects whether the host is a VM and which cloud it runs in.
+// systemd-detect-virt is used when present, with DMI strings as a fallback;
+// the cloud provider is always inferred from DMI.
+func (uhm *UnixHostManager) Virtualization() (VirtInfo, error) {
+	dmi, err := uhm.readDMI()
+	if err != nil {
+		return VirtInfo{}, err
+	}
+
+	virtType := uhm.detectVirt()
+	if virtType == "" {
+		virtType = virtFromDMI(dmi)
+	}
+	if virtType == "" && len(dmi) == 0 {
+		// No DMI in sysfs, so this is likely macOS
+		virtType = uhm.detectDarwinVirt()
+	}
+	if virtType == "" {
+		virtType = "none"
+	}
+
+	return VirtInfo{
+		Type:          virtType,
+		IsVirtual:     virtType != "none",
+		CloudProvider: cloudFromDMI(dmi),
+	}, nil
+}
+
+// detectVirt returns systemd-detect-virt's answer, or "" if it is unavailable.
+// It exits non-zero when printing "none", so the output is used regardless of
+// the exit status.
+func (uhm *UnixHostManager) detectVirt() string {
+	result, _ := uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "systemd-detect-virt",
+	})
+	return parseDetectVirt(result.STDOUT)
+}
+
+func parseDetectVirt(output string) string {
+	fields := strings.Fields(output)
+	if len(fields) != 1 {
+		return ""
+	}
+	return fields[0]
+}
+
+// detectDarwinVirt reports "apple" when macOS says it runs under a hypervisor.
+func (uhm *UnixHostManager) detectDarwinVirt() string {
+	result, err := uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "sysctl",
+		Args:    []string{"-n", "kern.hv_vmm_present"},
+	})
+	if err != nil {
+		return ""
+	}
+	switch strings.TrimSpace(result.STDOUT) {
+	case "1":
+		return "apple"
+	case "0":
+		return "none"
+	}
+	return ""
+}
+
+// readDMI reads the DMI and hypervisor files that exist into a map keyed by
+// file name. grep prefixes each line with its file and silently skips missing
+// files.
+func (uhm *UnixHostManager) readDMI() (map[string]string, error) {
+	result, err := uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "grep",
+		Args:    append([]string{"-s", "."}, virtDMIFiles...),
+	})
+	// grep exits 1 when no file matched, which is expected without sysfs
+	if err != nil && !cm.IsExitError(err) {
+		return nil, err
+	}
+	return parseDMI(result.STDOUT), nil
+}
+
+func parseDMI(output string) map[string]string {
+	dmi := make(map[string]string)
+	for _, line := range cm.Lines(output) {
+		file, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		dmi[path.Base(file)] = strings.TrimSpace(value)
+	}
+	return dmi
+}
+
+// virtFromDMI infers the hypervisor from DMI strings, returning "none" for
+// hardware with DMI data that matches no hypervisor.
+func virtFromDMI(dmi map[string]string) string {
+	if len(dmi) == 0 {
+		return ""
+	}
+
+	product := strings.ToLower(dmi["product_name"])
+	vendor := strings.ToLower(dmi["sys_vendor"])
+	bios := strings.ToLower(dmi["bios_vendor"] + " " + dmi["bios_version"])
+
+	switch {
+	case strings.Contains(vendor, "amazon") || strings.Contains(bios, "amazon"):
+		return "amazon"
+	case strings.Contains(vendor, "google"):
+		return "google"
+	case strings.Contains(vendor, "vmware") || strings.Contains(product, "vmware"):
+		return "vmware"
+	case strings.Contains(product, "virtualbox") || strings.Contains(vendor, "innotek"):
+		return "oracle"
+	case strings.Contains(vendor, "microsoft") && strings.Contains(product, "virtual machine"):
+		return "microsoft"
+	case strings.Contains(vendor, "qemu") || strings.Contains(product, "kvm") || strings.Contains(bios, "seabios"):
+		return "kvm"
+	case strings.Contains(vendor, "xen") || strings.Contains(product, "hvm domu"):
+		return "xen"
+	}
+	return "none"
+}
+
+// cloudFromDMI infers the cloud provider from DMI strings and the Xen
+// hypervisor UUID, which starts with "ec2" on older AWS instances.
+func cloudFromDMI(dmi map[string]string) string {
+	vendor := strings.ToLower(dmi["sys_vendor"] + " " + dmi["bios_vendor"] + " " + dmi["bios_version"])
+	product := strings.ToLower(dmi["product_name"])
+
+	switch {
+	case strings.Contains(vendor, "amazon") || strings.HasPrefix(strings.ToLower(dmi["uuid"]), "ec2"):
+		return "aws"
+	case strings.Contains(vendor, "google") || strings.Contains(product, "google compute engine"):
+		return "gcp"
+	case dmi["chassis_asset_tag"] == azureAssetTag:
+		return "azure"
+	}
+	return ""
+}
@@ -1,12 +1,23 @@
 package networkmanager
 
-// PingResult represents the result of a ping operation.
+import "time"
+
+// PingResult represents the result of a ping operation. The statistics
+// fields are filled in by PingStats.
 type PingResult struct {
 	Address string
 	RTT     float64 // Round Trip Time in milliseconds
 	Success bool
+
+	Sent        int
+	Received    int
+	LossPercent float64
+	MinRTT      float64 // milliseconds
+	AvgRTT      float64 // milliseconds
+	MaxRTT      float64 // milliseconds
 }
 
 type NetworkManager interface {
 	Ping(address string) (PingResult, error)
+	PingStats(address string, count int, interval time.Duration) (PingResult, error)
 }
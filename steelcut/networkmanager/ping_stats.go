@@ -0,0 +1,98 @@
+package networkmanager
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// minPingInterval is the shortest interval unprivileged ping allows on both
+// Linux and macOS.
+const minPingInterval = 200 * time.Millisecond
+
+const maxPingCount = 1000
+
+var (
+	// Linux: "5 packets transmitted, 4 received, +1 errors, 20% packet loss, time 4005ms"
+	// macOS: "5 packets transmitted, 4 packets received, 20.0% packet loss"
+	pingLossRegex = regexp.MustCompile(`(\d+) packets transmitted, (\d+) (?:packets )?received,.*?([\d.]+)% packet loss`)
+
+	// Linux: "rtt min/avg/max/mdev = 0.029/0.041/0.060/0.011 ms"
+	// macOS: "round-trip min/avg/max/stddev = 0.029/0.041/0.060/0.011 ms"
+	pingRTTRegex = regexp.MustCompile(`(?:rtt|round-trip) min/avg/max(?:/\w+)? = ([\d.]+)/([\d.]+)/([\d.]+)`)
+)
+
+// PingStats sends count pings to address, interval apart, and returns the
+// loss and round trip statistics from ping's summary. A host that answers
+// none of them is not an error; Received and the RTTs are then zero.
+func (unm *UnixNetworkManager) PingStats(address string, count int, interval time.Duration) (PingResult, error) {
+	if count < 1 || count > maxPingCount {
+		return PingResult{}, fmt.Errorf("ping count must be between 1 and %d, got %d", maxPingCount, count)
+	}
+	if interval < minPingInterval {
+		return PingResult{}, fmt.Errorf("ping interval must be at least %v, got %v", minPingInterval, interval)
+	}
+
+	args := []string{
+		"-c", strconv.Itoa(count),
+		"-i", strconv.FormatFloat(interval.Seconds(), 'f', -1, 64),
+		address,
+	}
+
+	var output cm.CommandResult
+	var err error
+	for _, command := range unm.pingCommands() {
+		output, err = unm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+			Command: command[0],
+			Args:    append(command[1:], args...),
+		})
+		if strings.Contains(output.STDOUT, "packets transmitted") {
+			break
+		}
+	}
+	// ping exits non-zero when replies are missing but still prints a summary
+	if err != nil && !cm.IsExitError(err) {
+		return PingResult{}, err
+	}
+	if !strings.Contains(output.STDOUT, "packets transmitted") {
+		return PingResult{}, fmt.Errorf("ping %s failed: %s", address, strings.TrimSpace(output.STDERR))
+	}
+
+	result, err := parsePingStats(output.STDOUT)
+	if err != nil {
+		return PingResult{}, err
+	}
+	result.Address = address
+	return result, nil
+}
+
+// parsePingStats parses the summary printed by Linux and macOS ping.
+func parsePingStats(output string) (PingResult, error) {
+	loss := pingLossRegex.FindStringSubmatch(output)
+	if loss == nil {
+		return PingResult{}, fmt.Errorf("unable to parse ping statistics: %s", output)
+	}
+
+	var result PingResult
+	result.Sent, _ = strconv.Atoi(loss[1])
+	result.Received, _ = strconv.Atoi(loss[2])
+	result.LossPercent, _ = strconv.ParseFloat(loss[3], 64)
+	result.Success = result.Received > 0
+
+	// No round trip line is printed when nothing was received
+	if rtt := pingRTTRegex.FindStringSubmatch(output); rtt != nil {
+		var err error
+		for i, field := range []*float64{&result.MinRTT, &result.AvgRTT, &result.MaxRTT} {
+			if *field, err = strconv.ParseFloat(rtt[i+1], 64); err != nil {
+				return PingResult{}, fmt.Errorf("unable to convert RTT to float: %v", err)
+			}
+		}
+		result.RTT = result.AvgRTT
+	}
+	return result, nil
+}
@@ -4,21 +4,46 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
 	"regexp"
 	"strconv"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
 )
 
 type UnixNetworkManager struct {
 	CommandManager cm.CommandManager
+	AddressFamily  string
+}
+
+// pingCommands returns the ping command lines to try, in order, for the
+// address family. ping resolves names to either family, so a fixed family is
+// forced with -4 or -6; ping -6 stands in for ping6 where it is missing.
+func (unm *UnixNetworkManager) pingCommands() [][]string {
+	switch unm.AddressFamily {
+	case cm.AddressFamilyIPv4:
+		return [][]string{{"ping", "-4"}}
+	case cm.AddressFamilyIPv6:
+		return [][]string{{"ping6"}, {"ping", "-6"}}
+	case cm.AddressFamilyAuto:
+		return [][]string{{"ping"}, {"ping6"}}
+	default:
+		return [][]string{{"ping"}}
+	}
 }
 
 func (unm *UnixNetworkManager) Ping(address string) (PingResult, error) {
 	// For simplicity, we'll use the 'ping' command and send a single packet
-	output, err := unm.CommandManager.Run(context.TODO(), cm.CommandConfig{
-		Command: "ping",
-		Args:    []string{"-c", "1", address},
-	})
+	var output cm.CommandResult
+	var err error
+	for _, command := range unm.pingCommands() {
+		output, err = unm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+			Command: command[0],
+			Args:    append(command[1:], "-c", "1", address),
+		})
+		if err == nil && output.ExitCode == 0 {
+			break
+		}
+	}
 	if err != nil {
 		return PingResult{}, err
 	}
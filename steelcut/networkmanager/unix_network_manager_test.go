@@ -0,0 +1,159 @@
+package networkmanager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+type MockCommandManager struct {
+	Outputs  map[string]cm.CommandResult
+	Commands []string
+	Calls    []cm.CommandConfig
+}
+
+func (m *MockCommandManager) RunLocal(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
+	return m.Run(ctx, config)
+}
+
+func (m *MockCommandManager) RunRemote(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
+	return m.Run(ctx, config)
+}
+
+func (m *MockCommandManager) Run(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
+	m.Commands = append(m.Commands, config.Command)
+	m.Calls = append(m.Calls, config)
+	return m.Outputs[config.Command], nil
+}
+
+const linuxPingOutput = `PING ::1(::1) 56 data bytes
+64 bytes from ::1: icmp_seq=1 ttl=64 time=0.045 ms
+
+--- ::1 ping statistics ---
+1 packets transmitted, 1 received, 0% packet loss, time 0ms
+rtt min/avg/max/mdev = 0.045/0.045/0.045/0.000 ms
+`
+
+func TestPingIPv6UsesPing6(t *testing.T) {
+	mockCmd := &MockCommandManager{
+		Outputs: map[string]cm.CommandResult{"ping6": {STDOUT: linuxPingOutput}},
+	}
+	manager := UnixNetworkManager{CommandManager: mockCmd, AddressFamily: cm.AddressFamilyIPv6}
+
+	result, err := manager.Ping("::1")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(mockCmd.Commands) != 1 || mockCmd.Commands[0] != "ping6" {
+		t.Errorf("Expected ping6 to be used, got %v", mockCmd.Commands)
+	}
+	if result.RTT != 0.045 {
+		t.Errorf("Expected RTT 0.045, got %v", result.RTT)
+	}
+}
+
+func TestPingForcesAddressFamily(t *testing.T) {
+	mockCmd := &MockCommandManager{
+		Outputs: map[string]cm.CommandResult{"ping": {STDOUT: linuxPingOutput}},
+	}
+	manager := UnixNetworkManager{CommandManager: mockCmd, AddressFamily: cm.AddressFamilyIPv4}
+	if _, err := manager.Ping("example.com"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if args := mockCmd.Calls[0].Args; len(args) == 0 || args[0] != "-4" {
+		t.Errorf("Expected ping -4 for IPv4, got %v", args)
+	}
+
+	// Without ping6, IPv6 falls back to ping -6
+	mockCmd = &MockCommandManager{
+		Outputs: map[string]cm.CommandResult{
+			"ping6": {STDERR: "sh: ping6: not found", ExitCode: 127},
+			"ping":  {STDOUT: linuxPingOutput},
+		},
+	}
+	manager = UnixNetworkManager{CommandManager: mockCmd, AddressFamily: cm.AddressFamilyIPv6}
+	if _, err := manager.PingStats("example.com", 3, time.Second); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(mockCmd.Calls) != 2 || mockCmd.Calls[1].Command != "ping" || mockCmd.Calls[1].Args[0] != "-6" {
+		t.Errorf("Expected ping6 then ping -6, got %+v", mockCmd.Calls)
+	}
+}
+
+func TestPingAutoFallsBackToPing6(t *testing.T) {
+	mockCmd := &MockCommandManager{
+		Outputs: map[string]cm.CommandResult{
+			"ping":  {STDERR: "ping: connect: Network is unreachable", ExitCode: 2},
+			"ping6": {STDOUT: linuxPingOutput},
+		},
+	}
+	manager := UnixNetworkManager{CommandManager: mockCmd, AddressFamily: cm.AddressFamilyAuto}
+
+	if _, err := manager.Ping("::1"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(mockCmd.Commands) != 2 || mockCmd.Commands[1] != "ping6" {
+		t.Errorf("Expected ping then ping6, got %v", mockCmd.Commands)
+	}
+}
+
+func TestParsePingStats(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		expected PingResult
+	}{
+		{
+			"linux",
+			`PING 10.0.0.1 (10.0.0.1) 56(84) bytes of data.
+
+--- 10.0.0.1 ping statistics ---
+5 packets transmitted, 4 received, +1 errors, 20% packet loss, time 4005ms
+rtt min/avg/max/mdev = 0.312/0.415/0.601/0.110 ms
+`,
+			PingResult{RTT: 0.415, Success: true, Sent: 5, Received: 4, LossPercent: 20, MinRTT: 0.312, AvgRTT: 0.415, MaxRTT: 0.601},
+		},
+		{
+			"macos",
+			`PING 10.0.0.1 (10.0.0.1): 56 data bytes
+
+--- 10.0.0.1 ping statistics ---
+3 packets transmitted, 3 packets received, 0.0% packet loss
+round-trip min/avg/max/stddev = 1.120/2.250/3.900/1.150 ms
+`,
+			PingResult{RTT: 2.25, Success: true, Sent: 3, Received: 3, LossPercent: 0, MinRTT: 1.12, AvgRTT: 2.25, MaxRTT: 3.9},
+		},
+		{
+			"all lost",
+			`--- 10.0.0.9 ping statistics ---
+4 packets transmitted, 0 received, 100% packet loss, time 3062ms
+`,
+			PingResult{Sent: 4, LossPercent: 100},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parsePingStats(tt.output)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %+v, got: %+v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestPingStatsValidation(t *testing.T) {
+	manager := UnixNetworkManager{CommandManager: &MockCommandManager{}}
+
+	if _, err := manager.PingStats("10.0.0.1", 0, time.Second); err == nil {
+		t.Errorf("Expected error for zero count")
+	}
+	if _, err := manager.PingStats("10.0.0.1", 5, 10*time.Millisecond); err == nil {
+		t.Errorf("Expected error for an interval below the unprivileged minimum")
+	}
+}
@@ -2,6 +2,7 @@ package packagemanager
 
 import (
 	"context"
+	"fmt"
 	"strings"
 
 	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
@@ -12,74 +13,207 @@ type ApkPackageManager struct {
 }
 
 func (apkm *ApkPackageManager) ListPackages() ([]string, error) {
-	output, err := apkm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+	lines, err := cm.RunCommandLines(context.TODO(), apkm.CommandManager, cm.CommandConfig{
 		Command: "apk",
-		Args:    []string{"info"},
+		Args:    []string{"info", "-v"},
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	return strings.Split(strings.TrimSpace(output.STDOUT), "\n"), nil
+	var names []string
+	for _, line := range lines {
+		if name, _, ok := splitPackageVersion(line); ok {
+			names = append(names, name)
+		}
+	}
+	return names, nil
 }
 
 func (apkm *ApkPackageManager) AddPackage(pkg string) error {
 	_, err := apkm.CommandManager.Run(context.TODO(), cm.CommandConfig{
 		Command: "apk",
+		Sudo:    true,
 		Args:    []string{"add", pkg},
 	})
 	return err
 }
 
+// AddPackageVersion installs pkg=version.
+func (apkm *ApkPackageManager) AddPackageVersion(pkg, version string) error {
+	result, err := apkm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "apk",
+		Sudo:    true,
+		Args:    []string{"add", pkg + "=" + version},
+	})
+	return checkVersionInstall(pkg, version, result, err, []string{"unable to select packages"})
+}
+
 func (apkm *ApkPackageManager) RemovePackage(pkg string) error {
 	_, err := apkm.CommandManager.Run(context.TODO(), cm.CommandConfig{
 		Command: "apk",
+		Sudo:    true,
 		Args:    []string{"del", pkg},
 	})
 	return err
 }
 
 func (apkm *ApkPackageManager) UpgradePackage(pkg string) error {
-	return apkm.AddPackage(pkg)
+	_, err := apkm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "apk",
+		Sudo:    true,
+		Args:    []string{"add", "--upgrade", pkg},
+	})
+	return err
 }
 
-func (apkm *ApkPackageManager) CheckOSUpdates() ([]string, error) {
+// ListUpdates refreshes the package index and returns the installed
+// packages with newer versions available.
+func (apkm *ApkPackageManager) ListUpdates() ([]Update, error) {
 	_, err := apkm.CommandManager.Run(context.TODO(), cm.CommandConfig{
 		Command: "apk",
+		Sudo:    true,
 		Args:    []string{"update"},
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	output, err := apkm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+	result, err := apkm.CommandManager.Run(context.TODO(), cm.CommandConfig{
 		Command: "apk",
-		Args:    []string{"version", "-v", "-l", "<"},
+		Args:    []string{"version", "-l", "<"},
 	})
 	if err != nil {
 		return nil, err
 	}
+	return parseApkVersion(result.STDOUT, cm.StrictParsing(apkm.CommandManager))
+}
 
-	lines := strings.Split(output.STDOUT, "\n")
-	var updates []string
-	for _, line := range lines {
-		parts := strings.Fields(line)
-		if len(parts) > 0 {
-			updates = append(updates, parts[0])
-		}
-	}
-	return updates, nil
+func (apkm *ApkPackageManager) CheckOSUpdates() ([]string, error) {
+	updates, err := apkm.ListUpdates()
+	return updateNames(updates), err
 }
 
+// UpgradeAll upgrades every package and returns the packages ListUpdates,
+// which refreshes the index, reported before the upgrade.
 func (apkm *ApkPackageManager) UpgradeAll() ([]string, error) {
-	_, err := apkm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+	updates, err := apkm.ListUpdates()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = apkm.CommandManager.Run(context.TODO(), cm.CommandConfig{
 		Command: "apk",
+		Sudo:    true,
 		Args:    []string{"upgrade"},
 	})
 	if err != nil {
 		return nil, err
 	}
-	return apkm.CheckOSUpdates()
+	return updateNames(updates), nil
+}
+
+// CleanCache clears the package cache and reports the bytes freed.
+func (apkm *ApkPackageManager) CleanCache() (int64, error) {
+	return cleanCache(apkm.CommandManager, "/var/cache/apk", cm.CommandConfig{
+		Command: "apk",
+		Sudo:    true,
+		Args:    []string{"cache", "clean"},
+	})
+}
+
+// PackageInfo describes pkg using apk info, which reports the installed
+// version if there is one and the repository version otherwise.
+func (apkm *ApkPackageManager) PackageInfo(pkg string) (PackageDetails, error) {
+	lines, err := cm.RunCommandLines(context.TODO(), apkm.CommandManager, cm.CommandConfig{
+		Command: "apk",
+		Args:    []string{"info", "-v", "-d", pkg},
+	})
+	if err != nil {
+		return PackageDetails{}, err
+	}
+	// The first line is "name-version description:", followed by the text
+	var details PackageDetails
+	if len(lines) > 0 {
+		heading := strings.TrimSuffix(lines[0], " description:")
+		name, version, ok := splitPackageVersion(heading)
+		if ok && name == pkg {
+			details = PackageDetails{Name: name, Version: version}
+			if len(lines) > 1 {
+				details.Description = lines[1]
+			}
+		}
+	}
+	if details.Name == "" {
+		return PackageDetails{}, packageNotFound(pkg)
+	}
+
+	installed, err := apkm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "apk",
+		Args:    []string{"info", "-e", pkg},
+	})
+	// apk info -e exits 1 when the package is not installed
+	if err != nil && !cm.IsExitError(err) {
+		return PackageDetails{}, err
+	}
+	details.Installed = installed.ExitCode == 0 && strings.TrimSpace(installed.STDOUT) != ""
+	return details, nil
+}
+
+// SearchPackages searches the package index with apk search.
+func (apkm *ApkPackageManager) SearchPackages(query string) ([]string, error) {
+	lines, err := cm.RunCommandLines(context.TODO(), apkm.CommandManager, cm.CommandConfig{
+		Command: "apk",
+		Args:    []string{"search", query},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, line := range lines {
+		if name, _, ok := splitPackageVersion(line); ok {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// HoldPackage constrains pkg to its installed version in /etc/apk/world.
+func (apkm *ApkPackageManager) HoldPackage(pkg string) error {
+	details, err := apkm.PackageInfo(pkg)
+	if err != nil {
+		return err
+	}
+	if !details.Installed {
+		return fmt.Errorf("cannot hold %s: not installed", pkg)
+	}
+	return apkm.AddPackageVersion(pkg, details.Version)
+}
+
+// UnholdPackage replaces the version constraint on pkg with its bare name.
+func (apkm *ApkPackageManager) UnholdPackage(pkg string) error {
+	return apkm.AddPackage(pkg)
+}
+
+// ListHeldPackages returns the packages /etc/apk/world constrains to a
+// version.
+func (apkm *ApkPackageManager) ListHeldPackages() ([]string, error) {
+	lines, err := cm.RunCommandLines(context.TODO(), apkm.CommandManager, cm.CommandConfig{
+		Command: "cat",
+		Args:    []string{"/etc/apk/world"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, line := range lines {
+		if i := strings.IndexAny(line, "=<>~"); i > 0 {
+			names = append(names, line[:i])
+		}
+	}
+	return names, nil
 }
 
 func (apkm *ApkPackageManager) EnsurePackagePresent(pkg string) error {
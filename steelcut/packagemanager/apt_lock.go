@@ -0,0 +1,101 @@
+package packagemanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// ErrPackageManagerLocked is returned when the package database stays locked
+// by another process after all retries.
+var ErrPackageManagerLocked = errors.New("package manager is locked by another process")
+
+const (
+	defaultLockRetries = 5
+	defaultLockBackoff = 5 * time.Second
+	maxLockBackoff     = time.Minute
+)
+
+// Poll interval used while waiting for a lock holder to exit.
+var lockPollInterval = time.Second
+
+var aptLockFiles = []string{
+	"/var/lib/dpkg/lock-frontend",
+	"/var/lib/dpkg/lock",
+	"/var/lib/apt/lists/lock",
+	"/var/cache/apt/archives/lock",
+}
+
+var aptLockMessages = []string{
+	"Could not get lock",
+	"Unable to acquire the dpkg frontend lock",
+	"Unable to lock the administration directory",
+	"Unable to lock directory",
+}
+
+func isAptLockError(result cm.CommandResult) bool {
+	for _, msg := range aptLockMessages {
+		if strings.Contains(result.STDERR, msg) {
+			return true
+		}
+	}
+	return false
+}
+
+// runApt runs an apt-get command, retrying with exponential backoff while the
+// dpkg or apt lock is held by another process such as unattended-upgrades.
+func (apm *AptPackageManager) runApt(config cm.CommandConfig) (cm.CommandResult, error) {
+	retries := apm.LockRetries
+	if retries <= 0 {
+		retries = defaultLockRetries
+	}
+	backoff := apm.LockBackoff
+	if backoff <= 0 {
+		backoff = defaultLockBackoff
+	}
+
+	for attempt := 0; ; attempt++ {
+		result, err := apm.CommandManager.Run(context.TODO(), config)
+		if !isAptLockError(result) {
+			return result, err
+		}
+		if attempt == retries {
+			return result, fmt.Errorf("%w: %s", ErrPackageManagerLocked, strings.TrimSpace(result.STDERR))
+		}
+
+		if apm.WaitForLock {
+			apm.waitForLockRelease(backoff)
+		} else {
+			time.Sleep(backoff)
+		}
+		backoff = min(backoff*2, maxLockBackoff)
+	}
+}
+
+// waitForLockRelease polls lsof until no process holds an apt lock file or
+// timeout elapses.
+func (apm *AptPackageManager) waitForLockRelease(timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for {
+		result, _ := apm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+			Command: "lsof",
+			Args:    aptLockFiles,
+			Sudo:    true,
+		})
+		// lsof prints nothing (and exits 1) when no process has the files
+		// open. Without lsof, fall back to waiting out the timeout.
+		if strings.TrimSpace(result.STDOUT) == "" && !strings.Contains(result.STDERR, "not found") {
+			return
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return
+		}
+		time.Sleep(min(lockPollInterval, remaining))
+	}
+}
@@ -2,17 +2,31 @@ package packagemanager
 
 import (
 	"context"
+	"fmt"
 	"strings"
+	"time"
 
 	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
 )
 
 type AptPackageManager struct {
 	CommandManager cm.CommandManager
+
+	// LockRetries is how many times a command is retried while the dpkg
+	// lock is held. Zero uses a default of 5.
+	LockRetries int
+
+	// LockBackoff is the initial delay between lock retries, doubled after
+	// each retry. Zero uses a default of 5 seconds.
+	LockBackoff time.Duration
+
+	// WaitForLock polls lsof between retries so a command is retried as soon
+	// as the lock holder exits rather than after the full backoff.
+	WaitForLock bool
 }
 
 func (apm *AptPackageManager) ListPackages() ([]string, error) {
-	output, err := apm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+	lines, err := cm.RunCommandLines(context.TODO(), apm.CommandManager, cm.CommandConfig{
 		Command: "dpkg",
 		Args:    []string{"--get-selections"},
 	})
@@ -20,19 +34,11 @@ func (apm *AptPackageManager) ListPackages() ([]string, error) {
 		return nil, err
 	}
 
-	lines := strings.Split(output.STDOUT, "\n")
-	var packages []string
-	for _, line := range lines {
-		parts := strings.Fields(line)
-		if len(parts) > 0 {
-			packages = append(packages, parts[0])
-		}
-	}
-	return packages, nil
+	return firstFields(lines), nil
 }
 
 func (apm *AptPackageManager) AddPackage(pkg string) error {
-	_, err := apm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+	_, err := apm.runApt(cm.CommandConfig{
 		Command: "apt-get",
 		Sudo:    true,
 		Env:     []string{"DEBIAN_FRONTEND=noninteractive"},
@@ -41,8 +47,19 @@ func (apm *AptPackageManager) AddPackage(pkg string) error {
 	return err
 }
 
+// AddPackageVersion installs pkg=version.
+func (apm *AptPackageManager) AddPackageVersion(pkg, version string) error {
+	result, err := apm.runApt(cm.CommandConfig{
+		Command: "apt-get",
+		Sudo:    true,
+		Env:     []string{"DEBIAN_FRONTEND=noninteractive"},
+		Args:    []string{"install", "-y", "-o", "Dpkg::Options::=--force-confdef", "-o", "Dpkg::Options::=--force-confold", pkg + "=" + version},
+	})
+	return checkVersionInstall(pkg, version, result, err, []string{"was not found", "Unable to locate package"})
+}
+
 func (apm *AptPackageManager) RemovePackage(pkg string) error {
-	_, err := apm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+	_, err := apm.runApt(cm.CommandConfig{
 		Command: "apt-get",
 		Sudo:    true,
 		Args:    []string{"remove", "-y", pkg},
@@ -51,7 +68,7 @@ func (apm *AptPackageManager) RemovePackage(pkg string) error {
 }
 
 func (apm *AptPackageManager) UpgradePackage(pkg string) error {
-	_, err := apm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+	_, err := apm.runApt(cm.CommandConfig{
 		Command: "apt-get",
 		Sudo:    true,
 		Env:     []string{"DEBIAN_FRONTEND=noninteractive"},
@@ -61,38 +78,30 @@ func (apm *AptPackageManager) UpgradePackage(pkg string) error {
 }
 
 func (apm *AptPackageManager) CheckOSUpdates() ([]string, error) {
-	_, err := apm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+	_, err := apm.runApt(cm.CommandConfig{
 		Command: "apt-get",
 		Sudo:    true,
 		Args:    []string{"update"},
 	})
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to update apt: %w", err)
 	}
 
-	output, err := apm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+	result, err := apm.CommandManager.Run(context.TODO(), cm.CommandConfig{
 		Command: "apt",
 		Args:    []string{"list", "--upgradable"},
 	})
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to list apt upgrades: %w", err)
 	}
 
-	lines := strings.Split(output.STDOUT, "\n")
-	var updates []string
-	for _, line := range lines {
-		if strings.Contains(line, "upgradable from") {
-			parts := strings.Fields(line)
-			if len(parts) > 0 {
-				updates = append(updates, parts[0])
-			}
-		}
-	}
-	return updates, nil
+	return parseAptUpgradable(result.STDOUT, cm.StrictParsing(apm.CommandManager))
 }
 
+// UpgradeAll upgrades every package and returns the packages apt-get
+// reported upgrading or newly installing.
 func (apm *AptPackageManager) UpgradeAll() ([]string, error) {
-	_, err := apm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+	result, err := apm.runApt(cm.CommandConfig{
 		Command: "apt-get",
 		Sudo:    true,
 		Env:     []string{"DEBIAN_FRONTEND=noninteractive"},
@@ -101,7 +110,127 @@ func (apm *AptPackageManager) UpgradeAll() ([]string, error) {
 	if err != nil {
 		return nil, err
 	}
-	return apm.CheckOSUpdates()
+	return parseAptUpgraded(result.STDOUT), nil
+}
+
+// CleanCache clears the package cache and reports the bytes freed.
+func (apm *AptPackageManager) CleanCache() (int64, error) {
+	return cleanCache(apm.CommandManager, "/var/cache/apt", cm.CommandConfig{
+		Command: "apt-get",
+		Sudo:    true,
+		Args:    []string{"clean"},
+	})
+}
+
+// PackageInfo describes pkg using apt show, with the installed version
+// taken from dpkg.
+func (apm *AptPackageManager) PackageInfo(pkg string) (PackageDetails, error) {
+	result, err := apm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "apt",
+		Args:    []string{"show", pkg},
+	})
+	if err != nil && !cm.IsExitError(err) {
+		return PackageDetails{}, err
+	}
+	fields := infoFields(strings.Split(result.STDOUT, "\n"))
+	if fields["Package"] == "" {
+		if result.ExitCode != 0 && !strings.Contains(result.STDERR, "No packages found") && !strings.Contains(result.STDERR, "Unable to locate package") {
+			return PackageDetails{}, err
+		}
+		return PackageDetails{}, packageNotFound(pkg)
+	}
+
+	details := PackageDetails{
+		Name:        fields["Package"],
+		Version:     fields["Version"],
+		Description: fields["Description"],
+	}
+
+	status, err := apm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "dpkg-query",
+		Args:    []string{"-W", "-f", "${Status}\t${Version}", pkg},
+	})
+	// dpkg-query fails for packages that were never installed
+	if err != nil && !cm.IsExitError(err) {
+		return PackageDetails{}, err
+	}
+	if state, version, ok := strings.Cut(strings.TrimSpace(status.STDOUT), "\t"); ok && strings.HasSuffix(state, " installed") {
+		details.Installed = true
+		details.Version = version
+	}
+	return details, nil
+}
+
+// SearchPackages searches package names and descriptions with apt-cache.
+func (apm *AptPackageManager) SearchPackages(query string) ([]string, error) {
+	lines, err := cm.RunCommandLines(context.TODO(), apm.CommandManager, cm.CommandConfig{
+		Command: "apt-cache",
+		Args:    []string{"search", query},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Each line is "name - summary"
+	names := []string{}
+	for _, line := range lines {
+		name, _, _ := strings.Cut(line, " - ")
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// PlanAddPackage reports what installing pkg would change, using apt-get's
+// simulation mode.
+func (apm *AptPackageManager) PlanAddPackage(pkg string) ([]Update, error) {
+	return apm.simulate("install", pkg)
+}
+
+// PlanUpgradePackage reports what upgrading pkg would change.
+func (apm *AptPackageManager) PlanUpgradePackage(pkg string) ([]Update, error) {
+	return apm.simulate("install", "--only-upgrade", pkg)
+}
+
+// PlanUpgradeAll reports what UpgradeAll would change.
+func (apm *AptPackageManager) PlanUpgradeAll() ([]Update, error) {
+	return apm.simulate("dist-upgrade")
+}
+
+func (apm *AptPackageManager) simulate(command string, args ...string) ([]Update, error) {
+	result, err := apm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "apt-get",
+		Args:    append([]string{command, "--dry-run"}, args...),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return parseAptSimulation(result.STDOUT), nil
+}
+
+// HoldPackage marks pkg held with apt-mark, which upgrades keep back.
+func (apm *AptPackageManager) HoldPackage(pkg string) error {
+	_, err := apm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "apt-mark",
+		Sudo:    true,
+		Args:    []string{"hold", pkg},
+	})
+	return err
+}
+
+func (apm *AptPackageManager) UnholdPackage(pkg string) error {
+	_, err := apm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "apt-mark",
+		Sudo:    true,
+		Args:    []string{"unhold", pkg},
+	})
+	return err
+}
+
+func (apm *AptPackageManager) ListHeldPackages() ([]string, error) {
+	return cm.RunCommandLines(context.TODO(), apm.CommandManager, cm.CommandConfig{
+		Command: "apt-mark",
+		Args:    []string{"showhold"},
+	})
 }
 
 func (apm *AptPackageManager) EnsurePackagePresent(pkg string) error {
@@ -0,0 +1,99 @@
+package packagemanager
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+const aptPreferencesDir = "/etc/apt/preferences.d"
+
+var (
+	debianPackageName = regexp.MustCompile(`^[a-z0-9][a-z0-9+.-]+$`)
+
+	// Pin specs as accepted by apt_preferences(5), e.g. "version 1.24.*",
+	// "release a=bookworm-backports" or "origin packages.example.com".
+	aptPinSpec = regexp.MustCompile(`^(version|release|origin) \S.*$`)
+)
+
+// aptPinPath returns the steelcut-managed preferences file for pkg. apt
+// ignores files in preferences.d with characters other than letters, digits,
+// '-', '_' and '.', so '+' is replaced.
+func aptPinPath(pkg string) string {
+	return path.Join(aptPreferencesDir, "steelcut-"+strings.ReplaceAll(pkg, "+", "_")+".pref")
+}
+
+// aptPinContent returns the preferences stanza pinning pkg to spec.
+func aptPinContent(pkg, spec string, priority int) string {
+	return fmt.Sprintf("Package: %s\nPin: %s\nPin-Priority: %d\n", pkg, spec, priority)
+}
+
+// SetPackagePin pins pkg with an apt preferences file, e.g. spec
+// "release a=bookworm-backports" with priority 990 to prefer backports, or
+// "version 1.24.*" with priority 1001 to hold a version even if it means a
+// downgrade. The file is only rewritten when its content changes.
+func (apm *AptPackageManager) SetPackagePin(pkg, spec string, priority int) error {
+	if !debianPackageName.MatchString(pkg) {
+		return fmt.Errorf("invalid package name: %q", pkg)
+	}
+	if !aptPinSpec.MatchString(spec) || strings.ContainsAny(spec, "\n\r") {
+		return fmt.Errorf("invalid pin %q: expected \"version ...\", \"release ...\" or \"origin ...\"", spec)
+	}
+	if priority == 0 {
+		return fmt.Errorf("pin priority must not be zero")
+	}
+
+	pinPath := aptPinPath(pkg)
+	content := aptPinContent(pkg, spec, priority)
+
+	current, err := apm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "cat",
+		Args:    []string{pinPath},
+	})
+	if err == nil && current.ExitCode == 0 && current.STDOUT == content {
+		return nil
+	}
+
+	// Write to a temporary file and rename it so apt never reads a partial file
+	tmpPath := path.Join(aptPreferencesDir, ".steelcut-pin.tmp")
+	script := fmt.Sprintf("printf '%%s' %s > %s && chmod 644 %s && mv %s %s",
+		cm.ShellQuote(content), tmpPath, tmpPath, tmpPath, cm.ShellQuote(pinPath))
+	result, err := apm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "sh",
+		Args:    []string{"-c", script},
+		Sudo:    true,
+	})
+	if err != nil {
+		return err
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("failed to write %s: %s", pinPath, result.STDERR)
+	}
+	return nil
+}
+
+// RemovePackagePin removes the pin set by SetPackagePin. Removing a pin that
+// does not exist is not an error.
+func (apm *AptPackageManager) RemovePackagePin(pkg string) error {
+	if !debianPackageName.MatchString(pkg) {
+		return fmt.Errorf("invalid package name: %q", pkg)
+	}
+
+	pinPath := aptPinPath(pkg)
+	result, err := apm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "rm",
+		Args:    []string{"-f", pinPath},
+		Sudo:    true,
+	})
+	if err != nil {
+		return err
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("failed to remove %s: %s", pinPath, result.STDERR)
+	}
+	return nil
+}
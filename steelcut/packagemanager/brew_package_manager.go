@@ -2,6 +2,7 @@ package packagemanager
 
 import (
 	"context"
+	"encoding/json"
 	"strings"
 
 	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
@@ -12,15 +13,10 @@ type BrewPackageManager struct {
 }
 
 func (bpm *BrewPackageManager) ListPackages() ([]string, error) {
-	output, err := bpm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+	return cm.RunCommandLines(context.TODO(), bpm.CommandManager, cm.CommandConfig{
 		Command: "brew",
 		Args:    []string{"list"},
 	})
-	if err != nil {
-		return nil, err
-	}
-
-	return strings.Split(strings.TrimSpace(output.STDOUT), "\n"), nil
 }
 
 func (bpm *BrewPackageManager) AddPackage(pkg string) error {
@@ -31,6 +27,17 @@ func (bpm *BrewPackageManager) AddPackage(pkg string) error {
 	return err
 }
 
+// AddPackageVersion installs the versioned formula pkg@version, such as
+// postgresql@16. Homebrew only offers the versions it packages as separate
+// formulae.
+func (bpm *BrewPackageManager) AddPackageVersion(pkg, version string) error {
+	result, err := bpm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "brew",
+		Args:    []string{"install", pkg + "@" + version},
+	})
+	return checkVersionInstall(pkg, version, result, err, []string{"No available formula"})
+}
+
 func (bpm *BrewPackageManager) RemovePackage(pkg string) error {
 	_, err := bpm.CommandManager.Run(context.TODO(), cm.CommandConfig{
 		Command: "brew",
@@ -48,15 +55,10 @@ func (bpm *BrewPackageManager) UpgradePackage(pkg string) error {
 }
 
 func (bpm *BrewPackageManager) CheckOSUpdates() ([]string, error) {
-	output, err := bpm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+	return cm.RunCommandLines(context.TODO(), bpm.CommandManager, cm.CommandConfig{
 		Command: "brew",
 		Args:    []string{"outdated"},
 	})
-	if err != nil {
-		return nil, err
-	}
-
-	return strings.Split(strings.TrimSpace(output.STDOUT), "\n"), nil
 }
 
 func (bpm *BrewPackageManager) UpgradeAll() ([]string, error) {
@@ -70,6 +72,152 @@ func (bpm *BrewPackageManager) UpgradeAll() ([]string, error) {
 	return bpm.CheckOSUpdates()
 }
 
+// CleanCache removes old downloads and versions and reports the bytes freed.
+func (bpm *BrewPackageManager) CleanCache() (int64, error) {
+	output, err := bpm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "brew",
+		Args:    []string{"--cache"},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return cleanCache(bpm.CommandManager, strings.TrimSpace(output.STDOUT), cm.CommandConfig{
+		Command: "brew",
+		Args:    []string{"cleanup"},
+	})
+}
+
+// PackageInfo describes the formula or cask pkg using brew info.
+func (bpm *BrewPackageManager) PackageInfo(pkg string) (PackageDetails, error) {
+	result, err := bpm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "brew",
+		Args:    []string{"info", "--json=v2", pkg},
+	})
+	if result.ExitCode != 0 && strings.Contains(result.STDERR, "No available formula") {
+		return PackageDetails{}, packageNotFound(pkg)
+	}
+	if err != nil {
+		return PackageDetails{}, err
+	}
+
+	var info struct {
+		Formulae []struct {
+			Name     string
+			Desc     string
+			Versions struct {
+				Stable string
+			}
+			Installed []struct {
+				Version string
+			}
+		}
+		Casks []struct {
+			Token     string
+			Desc      string
+			Version   string
+			Installed *string
+		}
+	}
+	if err := json.Unmarshal([]byte(result.STDOUT), &info); err != nil {
+		return PackageDetails{}, &cm.ParseError{Command: "brew info --json=v2", Text: err.Error()}
+	}
+
+	if len(info.Formulae) > 0 {
+		formula := info.Formulae[0]
+		details := PackageDetails{Name: formula.Name, Version: formula.Versions.Stable, Description: formula.Desc}
+		if len(formula.Installed) > 0 {
+			details.Installed = true
+			details.Version = formula.Installed[len(formula.Installed)-1].Version
+		}
+		return details, nil
+	}
+	if len(info.Casks) > 0 {
+		cask := info.Casks[0]
+		details := PackageDetails{Name: cask.Token, Version: cask.Version, Description: cask.Desc}
+		if cask.Installed != nil {
+			details.Installed = true
+			details.Version = *cask.Installed
+		}
+		return details, nil
+	}
+	return PackageDetails{}, packageNotFound(pkg)
+}
+
+// SearchPackages searches formulae and casks with brew search.
+func (bpm *BrewPackageManager) SearchPackages(query string) ([]string, error) {
+	result, err := bpm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "brew",
+		Args:    []string{"search", query},
+	})
+	if result.ExitCode != 0 && strings.Contains(result.STDERR, "No formulae or casks found") {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, line := range cm.Lines(result.STDOUT) {
+		// Formulae and casks are listed under "==> Formulae" and "==> Casks"
+		if strings.HasPrefix(line, "==>") {
+			continue
+		}
+		names = append(names, strings.Fields(line)...)
+	}
+	return names, nil
+}
+
+// PlanAddPackage reports what installing pkg would change.
+func (bpm *BrewPackageManager) PlanAddPackage(pkg string) ([]Update, error) {
+	return bpm.dryRun("install", pkg)
+}
+
+// PlanUpgradePackage reports what upgrading pkg would change.
+func (bpm *BrewPackageManager) PlanUpgradePackage(pkg string) ([]Update, error) {
+	return bpm.dryRun("upgrade", pkg)
+}
+
+// PlanUpgradeAll reports what UpgradeAll would change.
+func (bpm *BrewPackageManager) PlanUpgradeAll() ([]Update, error) {
+	return bpm.dryRun("upgrade")
+}
+
+func (bpm *BrewPackageManager) dryRun(command string, args ...string) ([]Update, error) {
+	result, err := bpm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "brew",
+		Args:    append([]string{command, "--dry-run"}, args...),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return parseBrewDryRun(result.STDOUT), nil
+}
+
+// HoldPackage pins pkg so brew upgrade leaves it alone.
+func (bpm *BrewPackageManager) HoldPackage(pkg string) error {
+	_, err := bpm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "brew",
+		Args:    []string{"pin", pkg},
+	})
+	return err
+}
+
+func (bpm *BrewPackageManager) UnholdPackage(pkg string) error {
+	_, err := bpm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "brew",
+		Args:    []string{"unpin", pkg},
+	})
+	return err
+}
+
+func (bpm *BrewPackageManager) ListHeldPackages() ([]string, error) {
+	return cm.RunCommandLines(context.TODO(), bpm.CommandManager, cm.CommandConfig{
+		Command: "brew",
+		Args:    []string{"list", "--pinned"},
+	})
+}
+
 func (bpm *BrewPackageManager) EnsurePackagePresent(pkg string) error {
 	packages, err := bpm.ListPackages()
 	if err != nil {
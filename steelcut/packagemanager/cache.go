@@ -0,0 +1,61 @@
+package packagemanager
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// cleanCache runs clean and returns how many bytes it freed from dir.
+func cleanCache(commandManager cm.CommandManager, dir string, clean cm.CommandConfig) (int64, error) {
+	before := cacheSize(commandManager, dir, clean.Sudo)
+
+	result, err := commandManager.Run(context.TODO(), clean)
+	if err != nil {
+		return 0, err
+	}
+	if result.ExitCode != 0 {
+		return 0, fmt.Errorf("%s failed: %s", clean.Command, result.STDERR)
+	}
+
+	after := cacheSize(commandManager, dir, clean.Sudo)
+	if after > before {
+		return 0, nil
+	}
+	return before - after, nil
+}
+
+// cacheSize returns the size of dir in bytes, or 0 if it cannot be measured.
+func cacheSize(commandManager cm.CommandManager, dir string, sudo bool) int64 {
+	result, err := commandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "du",
+		Args:    []string{"-sk", dir},
+		Sudo:    sudo,
+	})
+	if err != nil || result.ExitCode != 0 {
+		return 0
+	}
+
+	size, err := parseDuOutput(result.STDOUT)
+	if err != nil {
+		return 0
+	}
+	return size
+}
+
+// parseDuOutput parses "du -sk" output into bytes.
+func parseDuOutput(output string) (int64, error) {
+	fields := strings.Fields(output)
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("unexpected du output format: %s", output)
+	}
+
+	kb, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("error parsing du size: %v", err)
+	}
+	return kb * 1024, nil
+}
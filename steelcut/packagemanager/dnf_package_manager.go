@@ -2,6 +2,7 @@ package packagemanager
 
 import (
 	"context"
+	"fmt"
 	"strings"
 
 	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
@@ -12,23 +13,14 @@ type DnfPackageManager struct {
 }
 
 func (dpm *DnfPackageManager) ListPackages() ([]string, error) {
-	output, err := dpm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+	result, err := dpm.CommandManager.Run(context.TODO(), cm.CommandConfig{
 		Command: "dnf",
-		Args:    []string{"list", "installed"},
+		Args:    []string{"list", "--installed"},
 	})
 	if err != nil {
 		return nil, err
 	}
-
-	lines := strings.Split(output.STDOUT, "\n")
-	var packages []string
-	for _, line := range lines[1:] { // Skipping the header line
-		parts := strings.Fields(line)
-		if len(parts) > 0 {
-			packages = append(packages, parts[0])
-		}
-	}
-	return packages, nil
+	return parseRPMList("dnf list --installed", result.STDOUT, cm.StrictParsing(dpm.CommandManager))
 }
 
 func (dpm *DnfPackageManager) AddPackage(pkg string) error {
@@ -40,6 +32,16 @@ func (dpm *DnfPackageManager) AddPackage(pkg string) error {
 	return err
 }
 
+// AddPackageVersion installs pkg-version.
+func (dpm *DnfPackageManager) AddPackageVersion(pkg, version string) error {
+	result, err := dpm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "dnf",
+		Sudo:    true,
+		Args:    []string{"install", "-y", pkg + "-" + version},
+	})
+	return checkVersionInstall(pkg, version, result, err, rpmNotFoundMessages)
+}
+
 func (dpm *DnfPackageManager) RemovePackage(pkg string) error {
 	_, err := dpm.CommandManager.Run(context.TODO(), cm.CommandConfig{
 		Command: "dnf",
@@ -58,24 +60,24 @@ func (dpm *DnfPackageManager) UpgradePackage(pkg string) error {
 	return err
 }
 
+// CheckOSUpdates lists the packages with upgrades available.
 func (dpm *DnfPackageManager) CheckOSUpdates() ([]string, error) {
-	output, err := dpm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+	result, err := dpm.CommandManager.Run(context.TODO(), cm.CommandConfig{
 		Command: "dnf",
-		Args:    []string{"list", "upgrades"},
+		Args:    []string{"list", "--upgrades"},
 	})
-	if err != nil {
+	if err != nil && !cm.IsExitError(err) {
 		return nil, err
 	}
-
-	lines := strings.Split(output.STDOUT, "\n")
-	var updates []string
-	for _, line := range lines[1:] { // Skipping the header line
-		parts := strings.Fields(line)
-		if len(parts) > 0 {
-			updates = append(updates, parts[0])
-		}
+	// dnf 4 fails when there is nothing to upgrade
+	if result.ExitCode == 1 && strings.Contains(result.STDERR, "No matching Packages") {
+		return nil, nil
+	}
+	if result.ExitCode != 0 {
+		return nil, fmt.Errorf("dnf list --upgrades failed: %s", strings.TrimSpace(result.STDERR))
 	}
-	return updates, nil
+
+	return parseRPMList("dnf list --upgrades", result.STDOUT, cm.StrictParsing(dpm.CommandManager))
 }
 
 func (dpm *DnfPackageManager) UpgradeAll() ([]string, error) {
@@ -90,6 +92,61 @@ func (dpm *DnfPackageManager) UpgradeAll() ([]string, error) {
 	return dpm.CheckOSUpdates()
 }
 
+// CleanCache clears the package cache and reports the bytes freed.
+func (dpm *DnfPackageManager) CleanCache() (int64, error) {
+	return cleanCache(dpm.CommandManager, "/var/cache/dnf", cm.CommandConfig{
+		Command: "dnf",
+		Sudo:    true,
+		Args:    []string{"clean", "all"},
+	})
+}
+
+// PackageInfo describes pkg using dnf info.
+func (dpm *DnfPackageManager) PackageInfo(pkg string) (PackageDetails, error) {
+	return rpmPackageInfo(dpm.CommandManager, "dnf", pkg)
+}
+
+// SearchPackages searches package names and summaries with dnf search.
+func (dpm *DnfPackageManager) SearchPackages(query string) ([]string, error) {
+	return rpmSearch(dpm.CommandManager, "dnf", query)
+}
+
+// PlanAddPackage reports what installing pkg would change. dnf needs
+// root to resolve the transaction, which is then declined with --assumeno.
+func (dpm *DnfPackageManager) PlanAddPackage(pkg string) ([]Update, error) {
+	return rpmPlan(dpm.CommandManager, "dnf", "install", pkg)
+}
+
+// PlanUpgradePackage reports what upgrading pkg would change.
+func (dpm *DnfPackageManager) PlanUpgradePackage(pkg string) ([]Update, error) {
+	return rpmPlan(dpm.CommandManager, "dnf", "upgrade", pkg)
+}
+
+// PlanUpgradeAll reports what UpgradeAll would change.
+func (dpm *DnfPackageManager) PlanUpgradeAll() ([]Update, error) {
+	return rpmPlan(dpm.CommandManager, "dnf", "upgrade")
+}
+
+// HoldPackage locks pkg at its installed version with the versionlock
+// plugin, which must be installed.
+func (dpm *DnfPackageManager) HoldPackage(pkg string) error {
+	_, err := dpm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "dnf",
+		Sudo:    true,
+		Args:    []string{"versionlock", "add", pkg},
+	})
+	return err
+}
+
+func (dpm *DnfPackageManager) UnholdPackage(pkg string) error {
+	return rpmUnlock(dpm.CommandManager, "dnf", pkg)
+}
+
+func (dpm *DnfPackageManager) ListHeldPackages() ([]string, error) {
+	_, names, err := rpmVersionlocks(dpm.CommandManager, "dnf")
+	return names, err
+}
+
 func (dpm *DnfPackageManager) EnsurePackagePresent(pkg string) error {
 	packages, err := dpm.ListPackages()
 	if err != nil {
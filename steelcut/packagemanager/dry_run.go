@@ -0,0 +1,102 @@
+package packagemanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// ErrDryRunUnsupported is returned for a dry run on a package manager that
+// cannot report planned changes.
+var ErrDryRunUnsupported = errors.New("package manager does not support dry runs")
+
+// PackageOptions adjusts AddPackage, UpgradePackage and UpgradeAll.
+type PackageOptions struct {
+	// DryRun reports the packages that would be installed or upgraded
+	// without changing the host.
+	DryRun bool
+}
+
+// DryRunner is implemented by package managers that can plan an install or
+// upgrade without making it.
+type DryRunner interface {
+	PlanAddPackage(pkg string) ([]Update, error)
+	PlanUpgradePackage(pkg string) ([]Update, error)
+	PlanUpgradeAll() ([]Update, error)
+}
+
+// AddPackage installs pkg with pm. With DryRun set nothing is installed and
+// the planned changes, including dependencies, are returned instead.
+func AddPackage(pm PackageManager, pkg string, opts PackageOptions) ([]Update, error) {
+	if !opts.DryRun {
+		return nil, pm.AddPackage(pkg)
+	}
+	planner, err := dryRunner(pm)
+	if err != nil {
+		return nil, err
+	}
+	return planner.PlanAddPackage(pkg)
+}
+
+// UpgradePackage upgrades pkg with pm. With DryRun set nothing is upgraded
+// and the planned changes are returned instead.
+func UpgradePackage(pm PackageManager, pkg string, opts PackageOptions) ([]Update, error) {
+	if !opts.DryRun {
+		return nil, pm.UpgradePackage(pkg)
+	}
+	planner, err := dryRunner(pm)
+	if err != nil {
+		return nil, err
+	}
+	return planner.PlanUpgradePackage(pkg)
+}
+
+// UpgradeAll upgrades every package with pm and returns the upgraded
+// packages. PackageManager.UpgradeAll reports only names, so only Name is set.
+// With DryRun set nothing is upgraded and the planned changes are returned
+// instead.
+func UpgradeAll(pm PackageManager, opts PackageOptions) ([]Update, error) {
+	if !opts.DryRun {
+		names, err := pm.UpgradeAll()
+		if err != nil {
+			return nil, err
+		}
+		updates := make([]Update, 0, len(names))
+		for _, name := range names {
+			updates = append(updates, Update{Name: name})
+		}
+		return updates, nil
+	}
+	planner, err := dryRunner(pm)
+	if err != nil {
+		return nil, err
+	}
+	return planner.PlanUpgradeAll()
+}
+
+func dryRunner(pm PackageManager) (DryRunner, error) {
+	planner, ok := pm.(DryRunner)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", ErrDryRunUnsupported, pm)
+	}
+	return planner, nil
+}
+
+// rpmPlan runs a yum or dnf transaction with --assumeno, which resolves and
+// prints it, then aborts.
+func rpmPlan(manager cm.CommandManager, command string, args ...string) ([]Update, error) {
+	result, err := manager.Run(context.TODO(), cm.CommandConfig{
+		Command: command,
+		Sudo:    true,
+		Args:    append(args, "--assumeno"),
+	})
+	// Declining the transaction exits 1
+	aborted := result.ExitCode == 1 && strings.Contains(result.STDOUT+result.STDERR, "Operation aborted")
+	if err != nil && !aborted {
+		return nil, err
+	}
+	return parseRPMTransaction(result.STDOUT), nil
+}
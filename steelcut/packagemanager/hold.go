@@ -0,0 +1,51 @@
+package packagemanager
+
+import (
+	"context"
+	"errors"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// ErrHoldUnsupported is returned by HoldPackage and UnholdPackage on package
+// managers without a command for holding packages.
+var ErrHoldUnsupported = errors.New("package manager does not support holding packages")
+
+// rpmVersionlocks returns the entries and package names locked with the yum
+// or dnf versionlock plugin.
+func rpmVersionlocks(manager cm.CommandManager, command string) (entries, names []string, err error) {
+	result, err := manager.Run(context.TODO(), cm.CommandConfig{
+		Command: command,
+		Args:    []string{"versionlock", "list"},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	entries, names = parseVersionlockList(result.STDOUT)
+	return entries, names, nil
+}
+
+// rpmUnlock deletes the versionlock entries for pkg. yum only deletes
+// entries given exactly as listed, so they are looked up first.
+func rpmUnlock(manager cm.CommandManager, command, pkg string) error {
+	entries, names, err := rpmVersionlocks(manager, command)
+	if err != nil {
+		return err
+	}
+
+	var locked []string
+	for i, name := range names {
+		if name == pkg {
+			locked = append(locked, entries[i])
+		}
+	}
+	if len(locked) == 0 {
+		return nil
+	}
+	_, err = manager.Run(context.TODO(), cm.CommandConfig{
+		Command: command,
+		Sudo:    true,
+		Args:    append([]string{"versionlock", "delete"}, locked...),
+	})
+	return err
+}
@@ -0,0 +1,161 @@
+package packagemanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// ErrPackageNotFound is returned by PackageInfo when no installed or
+// available package has the given name.
+var ErrPackageNotFound = errors.New("package not found")
+
+// PackageDetails describes a single package. Version is the installed
+// version, or the version that would be installed if the package is not.
+type PackageDetails struct {
+	Name        string
+	Version     string
+	Description string
+	Installed   bool
+}
+
+func packageNotFound(pkg string) error {
+	return fmt.Errorf("%w: %s", ErrPackageNotFound, pkg)
+}
+
+// infoFields parses the "Key: value" lines printed by apt show, yum info,
+// pacman -Qi and zypper info, keeping the first value of each key. Indented
+// continuation lines are skipped.
+func infoFields(lines []string) map[string]string {
+	fields := make(map[string]string)
+	for _, line := range lines {
+		if line == "" || line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		if _, seen := fields[key]; !seen {
+			fields[key] = strings.TrimSpace(value)
+		}
+	}
+	return fields
+}
+
+// parseRPMInfo parses "yum info" and "dnf info" output, which lists the
+// installed package, if any, under "Installed Packages" ahead of those
+// available from repositories. The installed package is preferred.
+func parseRPMInfo(output string) (PackageDetails, bool) {
+	type stanza struct {
+		fields    map[string]string
+		installed bool
+	}
+	var stanzas []stanza
+	var lines []string
+	installed := false
+	flush := func() {
+		if fields := infoFields(lines); fields["Name"] != "" {
+			stanzas = append(stanzas, stanza{fields, installed})
+		}
+		lines = nil
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.EqualFold(trimmed, "Installed Packages"):
+			flush()
+			installed = true
+		case strings.EqualFold(trimmed, "Available Packages"), strings.EqualFold(trimmed, "Available Upgrades"):
+			flush()
+			installed = false
+		case trimmed == "":
+			flush()
+		default:
+			lines = append(lines, line)
+		}
+	}
+	flush()
+	if len(stanzas) == 0 {
+		return PackageDetails{}, false
+	}
+
+	chosen := stanzas[0]
+	for _, s := range stanzas {
+		if s.installed {
+			chosen = s
+			break
+		}
+	}
+	version := chosen.fields["Version"]
+	if release := chosen.fields["Release"]; release != "" {
+		version += "-" + release
+	}
+	if epoch := chosen.fields["Epoch"]; epoch != "" && epoch != "0" {
+		version = epoch + ":" + version
+	}
+	return PackageDetails{
+		Name:        chosen.fields["Name"],
+		Version:     version,
+		Description: chosen.fields["Summary"],
+		Installed:   chosen.installed,
+	}, true
+}
+
+// rpmPackageInfo runs "yum info" or "dnf info" for pkg.
+func rpmPackageInfo(manager cm.CommandManager, command, pkg string) (PackageDetails, error) {
+	result, err := manager.Run(context.TODO(), cm.CommandConfig{
+		Command: command,
+		Args:    []string{"info", pkg},
+	})
+	if result.ExitCode == 1 && strings.Contains(strings.ToLower(result.STDERR), "no matching packages") {
+		return PackageDetails{}, packageNotFound(pkg)
+	}
+	if err != nil {
+		return PackageDetails{}, err
+	}
+	details, ok := parseRPMInfo(result.STDOUT)
+	if !ok {
+		return PackageDetails{}, packageNotFound(pkg)
+	}
+	return details, nil
+}
+
+// rpmSearch runs "yum search" or "dnf search" for query. Matches are listed
+// as "name.arch : summary", under "N/S matched" headings in dnf 4 and yum;
+// the arch is dropped and a package built for several arches listed once.
+func rpmSearch(manager cm.CommandManager, command, query string) ([]string, error) {
+	result, err := manager.Run(context.TODO(), cm.CommandConfig{
+		Command: command,
+		Args:    []string{"search", query},
+	})
+	// dnf 4 exits 1 when nothing matches
+	if result.ExitCode == 1 && strings.Contains(result.STDERR, "No matches found") {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	seen := make(map[string]bool)
+	for _, line := range cm.Lines(result.STDOUT) {
+		name, _, ok := strings.Cut(line, ":")
+		name = strings.TrimSpace(name)
+		dot := strings.LastIndex(name, ".")
+		if !ok || strings.ContainsAny(name, " \t") || dot <= 0 {
+			continue
+		}
+		if name = name[:dot]; !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
@@ -3,10 +3,28 @@ package packagemanager
 type PackageManager interface {
 	ListPackages() ([]string, error)
 	AddPackage(pkg string) error
+	// AddPackageVersion installs a specific version of pkg, returning
+	// ErrVersionNotAvailable if no repository offers it.
+	AddPackageVersion(pkg, version string) error
 	RemovePackage(pkg string) error
 	UpgradePackage(pkg string) error
 	CheckOSUpdates() ([]string, error)
 	UpgradeAll() ([]string, error)
+	CleanCache() (int64, error) // Return the number of bytes freed
+
+	// PackageInfo describes pkg, returning ErrPackageNotFound if no
+	// installed or available package has that name.
+	PackageInfo(pkg string) (PackageDetails, error)
+
+	// SearchPackages returns the names of available packages matching
+	// query, or an empty slice if there are none.
+	SearchPackages(query string) ([]string, error)
+
+	// HoldPackage keeps pkg at its current version when upgrading, until
+	// UnholdPackage is called.
+	HoldPackage(pkg string) error
+	UnholdPackage(pkg string) error
+	ListHeldPackages() ([]string, error)
 
 	// Idempotent package management
 	EnsurePackagePresent(pkg string) error
@@ -0,0 +1,973 @@
+package packagemanager
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// MockCommandManager returns queued results per command, in order, and
+// fails every run of a command listed in Errors.
+type MockCommandManager struct {
+	Results map[string][]cm.CommandResult
+	Errors  map[string]error
+	Calls   []cm.CommandConfig
+	Strict  bool
+}
+
+func (m *MockCommandManager) StrictParsing() bool {
+	return m.Strict
+}
+
+func (m *MockCommandManager) RunLocal(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
+	return m.Run(ctx, config)
+}
+
+func (m *MockCommandManager) RunRemote(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
+	return m.Run(ctx, config)
+}
+
+func (m *MockCommandManager) Run(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
+	m.Calls = append(m.Calls, config)
+	queue := m.Results[config.Command]
+	if len(queue) == 0 {
+		return cm.CommandResult{}, m.Errors[config.Command]
+	}
+	m.Results[config.Command] = queue[1:]
+	return queue[0], m.Errors[config.Command]
+}
+
+func (m *MockCommandManager) argsFor(command string) [][]string {
+	var args [][]string
+	for _, call := range m.Calls {
+		if call.Command == command {
+			args = append(args, call.Args)
+		}
+	}
+	return args
+}
+
+func TestCleanCacheCommands(t *testing.T) {
+	tests := []struct {
+		name    string
+		manager func(cm.CommandManager) PackageManager
+		command string
+		args    []string
+	}{
+		{"apt", func(c cm.CommandManager) PackageManager { return &AptPackageManager{CommandManager: c} }, "apt-get", []string{"clean"}},
+		{"dnf", func(c cm.CommandManager) PackageManager { return &DnfPackageManager{CommandManager: c} }, "dnf", []string{"clean", "all"}},
+		{"yum", func(c cm.CommandManager) PackageManager { return &YumPackageManager{CommandManager: c} }, "yum", []string{"clean", "all"}},
+		{"apk", func(c cm.CommandManager) PackageManager { return &ApkPackageManager{CommandManager: c} }, "apk", []string{"cache", "clean"}},
+		{"brew", func(c cm.CommandManager) PackageManager { return &BrewPackageManager{CommandManager: c} }, "brew", []string{"cleanup"}},
+		{"pacman", func(c cm.CommandManager) PackageManager { return &PacmanPackageManager{CommandManager: c} }, "pacman", []string{"-Sc", "--noconfirm"}},
+		{"zypper", func(c cm.CommandManager) PackageManager { return &ZypperPackageManager{CommandManager: c} }, "zypper", []string{"--non-interactive", "clean", "--all"}},
+	}
+
+	for _, tt := range tests {
+		mockCmd := &MockCommandManager{Results: map[string][]cm.CommandResult{
+			"brew": {{STDOUT: "/Users/dev/Library/Caches/Homebrew\n"}},
+		}}
+		if _, err := tt.manager(mockCmd).CleanCache(); err != nil {
+			t.Errorf("%s: expected no error, got: %v", tt.name, err)
+			continue
+		}
+
+		calls := mockCmd.argsFor(tt.command)
+		if len(calls) == 0 || !reflect.DeepEqual(calls[len(calls)-1], tt.args) {
+			t.Errorf("%s: expected %s %v, got %v", tt.name, tt.command, tt.args, calls)
+		}
+	}
+}
+
+func TestCleanCacheFreedBytes(t *testing.T) {
+	mockCmd := &MockCommandManager{Results: map[string][]cm.CommandResult{
+		"du": {
+			{STDOUT: "204800\t/var/cache/apt\n"},
+			{STDOUT: "1024\t/var/cache/apt\n"},
+		},
+	}}
+	manager := AptPackageManager{CommandManager: mockCmd}
+
+	freed, err := manager.CleanCache()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if expected := int64(204800-1024) * 1024; freed != expected {
+		t.Errorf("Expected %d bytes freed, got %d", expected, freed)
+	}
+}
+
+const aptLockStderr = "E: Could not get lock /var/lib/dpkg/lock-frontend. It is held by process 1234 (unattended-upgr)\n"
+
+func TestAptLockThenSuccess(t *testing.T) {
+	mockCmd := &MockCommandManager{Results: map[string][]cm.CommandResult{
+		"apt-get": {{STDERR: aptLockStderr}, {STDERR: aptLockStderr}, {}},
+	}}
+	apm := &AptPackageManager{CommandManager: mockCmd, LockBackoff: time.Millisecond}
+
+	if err := apm.AddPackage("nginx"); err != nil {
+		t.Fatalf("Expected install to succeed once the lock frees, got: %v", err)
+	}
+	if calls := len(mockCmd.argsFor("apt-get")); calls != 3 {
+		t.Errorf("Expected 3 apt-get attempts, got %d", calls)
+	}
+}
+
+func TestAptLockNeverFrees(t *testing.T) {
+	locked := make([]cm.CommandResult, 10)
+	for i := range locked {
+		locked[i] = cm.CommandResult{STDERR: aptLockStderr}
+	}
+	mockCmd := &MockCommandManager{Results: map[string][]cm.CommandResult{"apt-get": locked}}
+	apm := &AptPackageManager{CommandManager: mockCmd, LockRetries: 2, LockBackoff: time.Millisecond}
+
+	if _, err := apm.UpgradeAll(); !errors.Is(err, ErrPackageManagerLocked) {
+		t.Errorf("Expected ErrPackageManagerLocked, got: %v", err)
+	}
+	if calls := len(mockCmd.argsFor("apt-get")); calls != 3 {
+		t.Errorf("Expected 1 attempt and 2 retries, got %d", calls)
+	}
+}
+
+func TestAptWaitForLock(t *testing.T) {
+	defer func(interval time.Duration) { lockPollInterval = interval }(lockPollInterval)
+	lockPollInterval = time.Millisecond
+
+	mockCmd := &MockCommandManager{Results: map[string][]cm.CommandResult{
+		"apt-get": {{STDERR: aptLockStderr}, {}},
+		"lsof":    {{STDOUT: "unattende 1234 root 4uW REG 8,1 0 /var/lib/dpkg/lock-frontend\n"}, {}},
+	}}
+	apm := &AptPackageManager{CommandManager: mockCmd, LockBackoff: time.Minute, WaitForLock: true}
+
+	start := time.Now()
+	if err := apm.RemovePackage("nginx"); err != nil {
+		t.Fatalf("Expected removal to succeed, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected retry as soon as the lock was released, took %v", elapsed)
+	}
+	if polls := len(mockCmd.argsFor("lsof")); polls != 2 {
+		t.Errorf("Expected 2 lsof polls, got %d", polls)
+	}
+}
+
+const dnfUpgradesOutput = `Last metadata expiration check: 0:12:04 ago on Wed 14 Oct 2026 09:00:00 AM UTC.
+Available Upgrades
+bash.x86_64                     5.1.8-9.el9                   baseos
+a-very-long-package-name-that-wraps.noarch
+                                1.2.3-4.el9                   appstream
+Extra Packages for Enterprise Linux 9 - x86_64  1.2 MB/s | 20 MB  00:16
+Obsoleting Packages
+grub2-tools.x86_64              1:2.06-70.el9                 baseos
+    grub2-tools.x86_64          1:2.06-61.el9                 @baseos
+`
+
+func TestCheckOSUpdatesStrictParsing(t *testing.T) {
+	mockCmd := &MockCommandManager{Results: map[string][]cm.CommandResult{
+		"dnf": {{STDOUT: dnfUpgradesOutput}, {STDOUT: dnfUpgradesOutput}},
+	}}
+	dpm := &DnfPackageManager{CommandManager: mockCmd}
+
+	updates, err := dpm.CheckOSUpdates()
+	if err != nil {
+		t.Fatalf("Expected no error in lenient mode, got: %v", err)
+	}
+	expected := []string{"bash.x86_64", "a-very-long-package-name-that-wraps.noarch"}
+	if !reflect.DeepEqual(updates, expected) {
+		t.Errorf("Expected %v, got: %v", expected, updates)
+	}
+
+	mockCmd.Strict = true
+	mockCmd.Calls = nil
+	_, err = dpm.CheckOSUpdates()
+	var parseErr *cm.ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("Expected a ParseError in strict mode, got: %v", err)
+	}
+	if parseErr.Line != 6 || !strings.HasPrefix(parseErr.Text, "Extra Packages") {
+		t.Errorf("Expected line 6 to be reported, got: %+v", parseErr)
+	}
+}
+
+const dnf5UpgradesOutput = `Updating and loading repositories:
+Repositories loaded.
+Available upgrades
+bash.x86_64                      5.2.26-3.fc40        updates
+kernel-core.x86_64               6.9.7-200.fc40       updates
+`
+
+func TestDnfCheckOSUpdates(t *testing.T) {
+	mockCmd := &MockCommandManager{Strict: true, Results: map[string][]cm.CommandResult{
+		"dnf": {
+			{STDOUT: dnf5UpgradesOutput},
+			{STDERR: "Error: No matching Packages to list\n", ExitCode: 1},
+			{STDERR: "Error: Failed to download metadata for repo 'baseos'\n", ExitCode: 1},
+		},
+	}}
+	dpm := &DnfPackageManager{CommandManager: mockCmd}
+
+	updates, err := dpm.CheckOSUpdates()
+	if err != nil {
+		t.Fatalf("Expected dnf 5 output to parse, got: %v", err)
+	}
+	expected := []string{"bash.x86_64", "kernel-core.x86_64"}
+	if !reflect.DeepEqual(updates, expected) {
+		t.Errorf("Expected %v, got: %v", expected, updates)
+	}
+	if args := mockCmd.argsFor("dnf"); !reflect.DeepEqual(args[0], []string{"list", "--upgrades"}) {
+		t.Errorf("Unexpected dnf arguments: %v", args[0])
+	}
+
+	updates, err = dpm.CheckOSUpdates()
+	if err != nil || len(updates) != 0 {
+		t.Errorf("Expected no updates, got %v, %v", updates, err)
+	}
+
+	if _, err = dpm.CheckOSUpdates(); err == nil || !strings.Contains(err.Error(), "baseos") {
+		t.Errorf("Expected the dnf failure to be reported, got: %v", err)
+	}
+}
+
+func TestParseAptUpgradable(t *testing.T) {
+	output := "Listing... Done\n" +
+		"bash/jammy-updates 5.1-6ubuntu1.1 amd64 [upgradable from: 5.1-6ubuntu1]\n" +
+		"N: There is 1 additional version. Please use the '-a' switch to see it\n"
+
+	updates, err := parseAptUpgradable(output, false)
+	if err != nil || len(updates) != 1 {
+		t.Errorf("Expected one update in lenient mode, got %v, %v", updates, err)
+	}
+
+	_, err = parseAptUpgradable(output, true)
+	var parseErr *cm.ParseError
+	if !errors.As(err, &parseErr) || parseErr.Line != 3 {
+		t.Errorf("Expected a ParseError for line 3, got: %v", err)
+	}
+}
+
+const aptDistUpgradeOutput = `Reading package lists...
+Building dependency tree...
+Reading state information...
+Calculating upgrade...
+The following packages were automatically installed and are no longer required:
+  libflashrom1 libftdi1-2
+Use 'sudo apt autoremove' to remove them.
+The following NEW packages will be installed:
+  linux-image-5.15.0-113-generic linux-modules-5.15.0-113-generic
+The following packages have been kept back:
+  python3-update-manager
+The following packages will be upgraded:
+  bash libssl3 linux-generic
+  openssl
+4 upgraded, 2 newly installed, 0 to remove and 1 not upgraded.
+Need to get 71.2 MB of archives.
+After this operation, 585 MB of additional disk space will be used.
+Get:1 http://archive.ubuntu.com/ubuntu jammy-updates/main amd64 bash amd64 5.1-6ubuntu1.1 [769 kB]
+Setting up bash (5.1-6ubuntu1.1) ...
+`
+
+func TestAptUpgradeAllReportsUpgraded(t *testing.T) {
+	mockCmd := &MockCommandManager{Results: map[string][]cm.CommandResult{
+		"apt-get": {{STDOUT: aptDistUpgradeOutput}},
+	}}
+	apm := &AptPackageManager{CommandManager: mockCmd}
+
+	upgraded, err := apm.UpgradeAll()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	expected := []string{
+		"linux-image-5.15.0-113-generic", "linux-modules-5.15.0-113-generic",
+		"bash", "libssl3", "linux-generic", "openssl",
+	}
+	if !reflect.DeepEqual(upgraded, expected) {
+		t.Errorf("Expected %v, got: %v", expected, upgraded)
+	}
+}
+
+func TestAptCheckOSUpdatesStripsSuite(t *testing.T) {
+	mockCmd := &MockCommandManager{Results: map[string][]cm.CommandResult{
+		"apt": {{STDOUT: "Listing... Done\nbash/jammy-updates 5.1-6ubuntu1.1 amd64 [upgradable from: 5.1-6ubuntu1]\nopenssl/jammy-updates,jammy-security 3.0.2-0ubuntu1.16 amd64 [upgradable from: 3.0.2-0ubuntu1.15]\n"}},
+	}}
+	apm := &AptPackageManager{CommandManager: mockCmd}
+
+	updates, err := apm.CheckOSUpdates()
+	if err != nil || !reflect.DeepEqual(updates, []string{"bash", "openssl"}) {
+		t.Errorf("Expected [bash openssl], got %v, %v", updates, err)
+	}
+}
+
+func TestAptCheckOSUpdatesUpdateFails(t *testing.T) {
+	updateErr := &cm.CommandError{Command: "apt-get update", ExitCode: 100, Stderr: "E: Release file for http://archive.ubuntu.com/ubuntu/dists/jammy-updates/InRelease is not valid yet\n"}
+	mockCmd := &MockCommandManager{
+		Results: map[string][]cm.CommandResult{"apt-get": {{STDERR: updateErr.Stderr, ExitCode: 100}}},
+		Errors:  map[string]error{"apt-get": updateErr},
+	}
+	apm := &AptPackageManager{CommandManager: mockCmd}
+
+	_, err := apm.CheckOSUpdates()
+	if !errors.Is(err, updateErr) || !strings.Contains(err.Error(), "failed to update apt") {
+		t.Errorf("Expected the apt-get update failure to be returned, got: %v", err)
+	}
+	if len(mockCmd.argsFor("apt")) != 0 {
+		t.Errorf("Expected apt list not to run after a failed update")
+	}
+}
+
+func TestSetPackagePin(t *testing.T) {
+	mockCmd := &MockCommandManager{Results: map[string][]cm.CommandResult{
+		"cat": {{STDERR: "cat: /etc/apt/preferences.d/steelcut-nginx.pref: No such file or directory", ExitCode: 1}},
+	}}
+	apm := &AptPackageManager{CommandManager: mockCmd}
+
+	if err := apm.SetPackagePin("nginx", "release a=bookworm-backports", 990); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	writes := mockCmd.argsFor("sh")
+	if len(writes) != 1 {
+		t.Fatalf("Expected one write, got: %v", writes)
+	}
+	expected := "Package: nginx\nPin: release a=bookworm-backports\nPin-Priority: 990\n"
+	if !strings.Contains(writes[0][1], cm.ShellQuote(expected)) || !strings.HasSuffix(writes[0][1], "/etc/apt/preferences.d/steelcut-nginx.pref") {
+		t.Errorf("Unexpected preferences write: %s", writes[0][1])
+	}
+
+	// Unchanged content is not rewritten
+	mockCmd.Results["cat"] = []cm.CommandResult{{STDOUT: expected}}
+	mockCmd.Calls = nil
+	if err := apm.SetPackagePin("nginx", "release a=bookworm-backports", 990); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if writes := mockCmd.argsFor("sh"); len(writes) != 0 {
+		t.Errorf("Expected no write for an unchanged pin, got: %v", writes)
+	}
+
+	for _, tt := range []struct {
+		pkg, spec string
+		priority  int
+	}{
+		{"Nginx", "version 1.24.*", 1001},
+		{"nginx", "1.24.*", 1001},
+		{"nginx", "version 1.24\nPackage: *", 1001},
+		{"nginx", "version 1.24.*", 0},
+	} {
+		if err := apm.SetPackagePin(tt.pkg, tt.spec, tt.priority); err == nil {
+			t.Errorf("Expected error for %+v", tt)
+		}
+	}
+}
+
+func TestRemovePackagePin(t *testing.T) {
+	mockCmd := &MockCommandManager{Results: map[string][]cm.CommandResult{}}
+	apm := &AptPackageManager{CommandManager: mockCmd}
+
+	for i := 0; i < 2; i++ {
+		if err := apm.RemovePackagePin("libstdc++6"); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+	}
+	removes := mockCmd.argsFor("rm")
+	if len(removes) != 2 || !reflect.DeepEqual(removes[0], []string{"-f", "/etc/apt/preferences.d/steelcut-libstdc__6.pref"}) {
+		t.Errorf("Unexpected removals: %v", removes)
+	}
+}
+
+const zypperListUpdatesOutput = `Loading repository data...
+Reading installed packages...
+S | Repository             | Name        | Current Version | Available Version | Arch
+--+------------------------+-------------+-----------------+-------------------+-------
+v | Main Update Repository | bash        | 4.4-19.6.1      | 4.4-19.9.1        | x86_64
+v | Main Update Repository | ca-certificates-mozilla | 2.60-150200.27.1 | 2.62-150200.30.1 | noarch
+`
+
+func TestZypperListUpdates(t *testing.T) {
+	mockCmd := &MockCommandManager{Strict: true, Results: map[string][]cm.CommandResult{
+		"zypper": {{STDOUT: zypperListUpdatesOutput}, {STDOUT: "Loading repository data...\nReading installed packages...\nNo updates found.\n"}},
+	}}
+	zpm := &ZypperPackageManager{CommandManager: mockCmd}
+
+	updates, err := zpm.ListUpdates()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	expected := []Update{
+		{Name: "bash", CurrentVersion: "4.4-19.6.1", AvailableVersion: "4.4-19.9.1", Arch: "x86_64", Repository: "Main Update Repository"},
+		{Name: "ca-certificates-mozilla", CurrentVersion: "2.60-150200.27.1", AvailableVersion: "2.62-150200.30.1", Arch: "noarch", Repository: "Main Update Repository"},
+	}
+	if !reflect.DeepEqual(updates, expected) {
+		t.Errorf("Expected %+v, got: %+v", expected, updates)
+	}
+
+	names, err := zpm.CheckOSUpdates()
+	if err != nil || len(names) != 0 {
+		t.Errorf("Expected no updates, got %v, %v", names, err)
+	}
+
+	_, err = parseZypperUpdates(zypperListUpdatesOutput+"v | truncated\n", true)
+	var parseErr *cm.ParseError
+	if !errors.As(err, &parseErr) || parseErr.Line != 7 {
+		t.Errorf("Expected a ParseError for line 7, got: %v", err)
+	}
+}
+
+func TestZypperListPackages(t *testing.T) {
+	output := `Loading repository data...
+Reading installed packages...
+
+S  | Name         | Summary                           | Type
+---+--------------+-----------------------------------+--------
+i+ | bash         | The GNU Bourne-Again Shell        | package
+i  | base         | Minimal Base System               | pattern
+i+ | vim          | Vi IMproved                       | package
+`
+	mockCmd := &MockCommandManager{Results: map[string][]cm.CommandResult{
+		"zypper": {{STDOUT: output}, {STDERR: "No matching items found.\n", ExitCode: zypperNoMatches}},
+	}}
+	zpm := &ZypperPackageManager{CommandManager: mockCmd}
+
+	packages, err := zpm.ListPackages()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !reflect.DeepEqual(packages, []string{"bash", "vim"}) {
+		t.Errorf("Expected [bash vim], got: %v", packages)
+	}
+
+	packages, err = zpm.ListPackages()
+	if err != nil || len(packages) != 0 {
+		t.Errorf("Expected no packages, got %v, %v", packages, err)
+	}
+}
+
+func TestPacmanListUpdates(t *testing.T) {
+	mockCmd := &MockCommandManager{Strict: true, Results: map[string][]cm.CommandResult{
+		"pacman": {
+			{STDOUT: "linux 6.9.7.arch1-1 -> 6.9.8.arch1-1\nopenssl 3.3.0-1 -> 3.3.1-1 [ignored]\n"},
+		},
+	}}
+	ppm := &PacmanPackageManager{CommandManager: mockCmd}
+
+	updates, err := ppm.ListUpdates()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	expected := []Update{
+		{Name: "linux", CurrentVersion: "6.9.7.arch1-1", AvailableVersion: "6.9.8.arch1-1"},
+		{Name: "openssl", CurrentVersion: "3.3.0-1", AvailableVersion: "3.3.1-1"},
+	}
+	if !reflect.DeepEqual(updates, expected) {
+		t.Errorf("Expected %+v, got: %+v", expected, updates)
+	}
+
+	_, err = parsePacmanUpdates("warning: database file for 'core' does not exist\n", true)
+	var parseErr *cm.ParseError
+	if !errors.As(err, &parseErr) || parseErr.Line != 1 {
+		t.Errorf("Expected a ParseError for line 1, got: %v", err)
+	}
+}
+
+func TestUpgradeAllReportsPendingUpdates(t *testing.T) {
+	tests := []struct {
+		name    string
+		manager func(cm.CommandManager) PackageManager
+		command string
+		results []cm.CommandResult
+		args    [][]string
+		names   []string
+	}{
+		{
+			"pacman", func(c cm.CommandManager) PackageManager { return &PacmanPackageManager{CommandManager: c} }, "pacman",
+			[]cm.CommandResult{{}, {STDOUT: "linux 6.9.7.arch1-1 -> 6.9.8.arch1-1\nopenssl 3.3.0-1 -> 3.3.1-1\n"}},
+			[][]string{{"-Sy", "--noconfirm"}, {"-Qu"}, {"-Su", "--noconfirm"}},
+			[]string{"linux", "openssl"},
+		},
+		{
+			"zypper", func(c cm.CommandManager) PackageManager { return &ZypperPackageManager{CommandManager: c} }, "zypper",
+			[]cm.CommandResult{{}, {STDOUT: zypperListUpdatesOutput}},
+			[][]string{{"--non-interactive", "refresh"}, {"--non-interactive", "list-updates"}, {"--non-interactive", "update", "-y"}},
+			[]string{"bash", "ca-certificates-mozilla"},
+		},
+		{
+			"apk", func(c cm.CommandManager) PackageManager { return &ApkPackageManager{CommandManager: c} }, "apk",
+			[]cm.CommandResult{{}, {STDOUT: "Installed:                                Available:\nbusybox-1.36.1-r28                      < 1.36.1-r29\n"}},
+			[][]string{{"update"}, {"version", "-l", "<"}, {"upgrade"}},
+			[]string{"busybox"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockCmd := &MockCommandManager{Results: map[string][]cm.CommandResult{tt.command: tt.results}}
+
+			names, err := tt.manager(mockCmd).UpgradeAll()
+			if err != nil || !reflect.DeepEqual(names, tt.names) {
+				t.Errorf("Expected %v, got %v, %v", tt.names, names, err)
+			}
+			if args := mockCmd.argsFor(tt.command); !reflect.DeepEqual(args, tt.args) {
+				t.Errorf("Expected commands %v, got %v", tt.args, args)
+			}
+		})
+	}
+}
+
+func TestApkListUpdates(t *testing.T) {
+	mockCmd := &MockCommandManager{Strict: true, Results: map[string][]cm.CommandResult{
+		"apk": {
+			{STDOUT: "fetch https://dl-cdn.alpinelinux.org/alpine/v3.20/main/x86_64/APKINDEX.tar.gz\nOK: 24163 distinct packages available\n"},
+			{STDOUT: "Installed:                                Available:\nbusybox-1.36.1-r28                      < 1.36.1-r29\nca-certificates-bundle-20240226-r0      < 20240705-r0\n"},
+		},
+	}}
+	apkm := &ApkPackageManager{CommandManager: mockCmd}
+
+	updates, err := apkm.ListUpdates()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	expected := []Update{
+		{Name: "busybox", CurrentVersion: "1.36.1-r28", AvailableVersion: "1.36.1-r29"},
+		{Name: "ca-certificates-bundle", CurrentVersion: "20240226-r0", AvailableVersion: "20240705-r0"},
+	}
+	if !reflect.DeepEqual(updates, expected) {
+		t.Errorf("Expected %+v, got: %+v", expected, updates)
+	}
+	if calls := mockCmd.Calls; !calls[0].Sudo || calls[1].Sudo {
+		t.Errorf("Expected only apk update to use sudo")
+	}
+
+	_, err = parseApkVersion("WARNING: opening /var/cache/apk: No such file or directory\n", true)
+	var parseErr *cm.ParseError
+	if !errors.As(err, &parseErr) || parseErr.Line != 1 {
+		t.Errorf("Expected a ParseError for line 1, got: %v", err)
+	}
+}
+
+func TestApkListPackages(t *testing.T) {
+	mockCmd := &MockCommandManager{Results: map[string][]cm.CommandResult{
+		"apk": {{STDOUT: "alpine-baselayout-3.6.5-r0\nbusybox-1.36.1-r29\nlibcrypto3-3.3.1-r3\n"}},
+	}}
+	apkm := &ApkPackageManager{CommandManager: mockCmd}
+
+	packages, err := apkm.ListPackages()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !reflect.DeepEqual(packages, []string{"alpine-baselayout", "busybox", "libcrypto3"}) {
+		t.Errorf("Unexpected packages: %v", packages)
+	}
+}
+
+func TestAptPackageInfo(t *testing.T) {
+	show := "Package: bash\nVersion: 5.2.21-2ubuntu4\nPriority: required\nDescription: GNU Bourne Again SHell\n Bash is an sh-compatible command language interpreter.\n"
+	mockCmd := &MockCommandManager{Results: map[string][]cm.CommandResult{
+		"apt": {
+			{STDOUT: show},
+			{STDOUT: show},
+			{STDERR: "N: Unable to locate package nosuch\nE: No packages found\n", ExitCode: 100},
+		},
+		"dpkg-query": {
+			{STDOUT: "install ok installed\t5.2.21-2ubuntu3"},
+			{STDERR: "dpkg-query: no packages found matching bash\n", ExitCode: 1},
+		},
+	}}
+	apm := &AptPackageManager{CommandManager: mockCmd}
+
+	details, err := apm.PackageInfo("bash")
+	expected := PackageDetails{Name: "bash", Version: "5.2.21-2ubuntu3", Description: "GNU Bourne Again SHell", Installed: true}
+	if err != nil || details != expected {
+		t.Errorf("Expected %+v, got %+v, %v", expected, details, err)
+	}
+
+	details, err = apm.PackageInfo("bash")
+	expected = PackageDetails{Name: "bash", Version: "5.2.21-2ubuntu4", Description: "GNU Bourne Again SHell"}
+	if err != nil || details != expected {
+		t.Errorf("Expected %+v, got %+v, %v", expected, details, err)
+	}
+
+	if _, err = apm.PackageInfo("nosuch"); !errors.Is(err, ErrPackageNotFound) {
+		t.Errorf("Expected ErrPackageNotFound, got: %v", err)
+	}
+}
+
+const yumInfoOutput = `Loaded plugins: fastestmirror
+Installed Packages
+Name        : bash
+Arch        : x86_64
+Version     : 4.2.46
+Release     : 34.el7
+Size        : 3.5 M
+Repo        : installed
+Summary     : The GNU Bourne Again shell
+Description : The GNU Bourne Again shell (Bash) is a shell or command language
+            : interpreter that is compatible with the Bourne shell (sh).
+
+Available Packages
+Name        : bash
+Arch        : x86_64
+Version     : 4.2.46
+Release     : 35.el7_9
+Summary     : The GNU Bourne Again shell
+`
+
+func TestYumPackageInfo(t *testing.T) {
+	mockCmd := &MockCommandManager{Results: map[string][]cm.CommandResult{
+		"yum": {
+			{STDOUT: yumInfoOutput},
+			{STDOUT: "Available Packages\nName        : tmux\nEpoch       : 1\nVersion     : 3.2a\nRelease     : 5.el9\nSummary     : A terminal multiplexer\n"},
+			{STDERR: "Error: No matching Packages to list\n", ExitCode: 1},
+		},
+	}}
+	ypm := &YumPackageManager{CommandManager: mockCmd}
+
+	details, err := ypm.PackageInfo("bash")
+	expected := PackageDetails{Name: "bash", Version: "4.2.46-34.el7", Description: "The GNU Bourne Again shell", Installed: true}
+	if err != nil || details != expected {
+		t.Errorf("Expected %+v, got %+v, %v", expected, details, err)
+	}
+
+	details, err = ypm.PackageInfo("tmux")
+	expected = PackageDetails{Name: "tmux", Version: "1:3.2a-5.el9", Description: "A terminal multiplexer"}
+	if err != nil || details != expected {
+		t.Errorf("Expected %+v, got %+v, %v", expected, details, err)
+	}
+
+	if _, err = ypm.PackageInfo("nosuch"); !errors.Is(err, ErrPackageNotFound) {
+		t.Errorf("Expected ErrPackageNotFound, got: %v", err)
+	}
+}
+
+func TestBrewPackageInfo(t *testing.T) {
+	mockCmd := &MockCommandManager{Results: map[string][]cm.CommandResult{
+		"brew": {
+			{STDOUT: `{"formulae":[{"name":"jq","desc":"Lightweight and flexible command-line JSON processor","versions":{"stable":"1.7.1"},"installed":[{"version":"1.7"}]}],"casks":[]}`},
+			{STDOUT: `{"formulae":[],"casks":[{"token":"firefox","desc":"Web browser","version":"128.0","installed":null}]}`},
+			{STDERR: "Error: No available formula with the name \"nosuch\".\n", ExitCode: 1},
+		},
+	}}
+	bpm := &BrewPackageManager{CommandManager: mockCmd}
+
+	details, err := bpm.PackageInfo("jq")
+	expected := PackageDetails{Name: "jq", Version: "1.7", Description: "Lightweight and flexible command-line JSON processor", Installed: true}
+	if err != nil || details != expected {
+		t.Errorf("Expected %+v, got %+v, %v", expected, details, err)
+	}
+
+	details, err = bpm.PackageInfo("firefox")
+	expected = PackageDetails{Name: "firefox", Version: "128.0", Description: "Web browser"}
+	if err != nil || details != expected {
+		t.Errorf("Expected %+v, got %+v, %v", expected, details, err)
+	}
+
+	if _, err = bpm.PackageInfo("nosuch"); !errors.Is(err, ErrPackageNotFound) {
+		t.Errorf("Expected ErrPackageNotFound, got: %v", err)
+	}
+}
+
+func TestSearchPackages(t *testing.T) {
+	tests := []struct {
+		name    string
+		manager func(cm.CommandManager) PackageManager
+		command string
+		results []cm.CommandResult
+		want    []string
+	}{
+		{
+			"apt", func(c cm.CommandManager) PackageManager { return &AptPackageManager{CommandManager: c} }, "apt-cache",
+			[]cm.CommandResult{{STDOUT: "tmux - terminal multiplexer\ntmux-plugin-manager - tmux plugin manager based on git\n"}, {}},
+			[]string{"tmux", "tmux-plugin-manager"},
+		},
+		{
+			"yum", func(c cm.CommandManager) PackageManager { return &YumPackageManager{CommandManager: c} }, "yum",
+			[]cm.CommandResult{
+				{STDOUT: "Loaded plugins: fastestmirror\n============================== N/S matched: tmux ==============================\ntmux.x86_64 : A terminal multiplexer\ntmux.i686 : A terminal multiplexer\npython3.11.x86_64 : Version 3.11 of the Python interpreter\n"},
+				{STDOUT: "Loaded plugins: fastestmirror\nWarning: No matches found for: nosuch\n", STDERR: "No matches found\n"},
+			},
+			[]string{"tmux", "python3.11"},
+		},
+		{
+			"dnf", func(c cm.CommandManager) PackageManager { return &DnfPackageManager{CommandManager: c} }, "dnf",
+			[]cm.CommandResult{
+				{STDOUT: "Updating and loading repositories:\nRepositories loaded.\nMatched fields: name (exact)\n tmux.x86_64: A terminal multiplexer\n"},
+				{STDERR: "No matches found.\n", ExitCode: 1},
+			},
+			[]string{"tmux"},
+		},
+		{
+			"brew", func(c cm.CommandManager) PackageManager { return &BrewPackageManager{CommandManager: c} }, "brew",
+			[]cm.CommandResult{
+				{STDOUT: "==> Formulae\ntmux\ntmuxinator\n\n==> Casks\ntmux-manager\n"},
+				{STDERR: "Error: No formulae or casks found for \"nosuch\".\n", ExitCode: 1},
+			},
+			[]string{"tmux", "tmuxinator", "tmux-manager"},
+		},
+	}
+
+	for _, tt := range tests {
+		mockCmd := &MockCommandManager{Results: map[string][]cm.CommandResult{tt.command: tt.results}}
+		manager := tt.manager(mockCmd)
+
+		names, err := manager.SearchPackages("tmux")
+		if err != nil || !reflect.DeepEqual(names, tt.want) {
+			t.Errorf("%s: expected %v, got %v, %v", tt.name, tt.want, names, err)
+		}
+
+		names, err = manager.SearchPackages("nosuch")
+		if err != nil || names == nil || len(names) != 0 {
+			t.Errorf("%s: expected an empty slice, got %#v, %v", tt.name, names, err)
+		}
+	}
+}
+
+const aptSimulationOutput = `NOTE: This is only a simulation!
+      apt-get needs root privileges for real execution.
+Reading package lists...
+The following NEW packages will be installed:
+  libevent-core-2.1-7 tmux
+Inst libevent-core-2.1-7 (2.1.12-stable-1build3 Ubuntu:22.04/jammy [amd64])
+Inst bash [5.1-6ubuntu1] (5.1-6ubuntu1.1 Ubuntu:22.04/jammy-updates [amd64])
+Inst tmux (3.2a-4ubuntu0.2 Ubuntu:22.04/jammy-updates, Ubuntu:22.04/jammy-security [amd64])
+Conf libevent-core-2.1-7 (2.1.12-stable-1build3 Ubuntu:22.04/jammy [amd64])
+`
+
+func TestDryRunApt(t *testing.T) {
+	mockCmd := &MockCommandManager{Results: map[string][]cm.CommandResult{
+		"apt-get": {{STDOUT: aptSimulationOutput}},
+	}}
+	apm := &AptPackageManager{CommandManager: mockCmd}
+
+	updates, err := AddPackage(apm, "tmux", PackageOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	expected := []Update{
+		{Name: "libevent-core-2.1-7", AvailableVersion: "2.1.12-stable-1build3", Repository: "Ubuntu:22.04/jammy", Arch: "amd64"},
+		{Name: "bash", CurrentVersion: "5.1-6ubuntu1", AvailableVersion: "5.1-6ubuntu1.1", Repository: "Ubuntu:22.04/jammy-updates", Arch: "amd64"},
+		{Name: "tmux", AvailableVersion: "3.2a-4ubuntu0.2", Repository: "Ubuntu:22.04/jammy-updates, Ubuntu:22.04/jammy-security", Arch: "amd64"},
+	}
+	if !reflect.DeepEqual(updates, expected) {
+		t.Errorf("Expected %+v, got: %+v", expected, updates)
+	}
+	if len(mockCmd.Calls) != 1 || mockCmd.Calls[0].Sudo || !reflect.DeepEqual(mockCmd.Calls[0].Args, []string{"install", "--dry-run", "tmux"}) {
+		t.Errorf("Expected a single unprivileged simulation, got: %+v", mockCmd.Calls)
+	}
+}
+
+const dnfTransactionOutput = `Last metadata expiration check: 0:12:01 ago on Mon 01 Jul 2024 10:00:00 AM UTC.
+Dependencies resolved.
+================================================================================
+ Package            Arch        Version              Repository           Size
+================================================================================
+Upgrading:
+ bash               x86_64      5.1.8-9.el9          baseos              1.7 M
+ a-very-long-package-name-that-wraps
+                    noarch      1.0-1.el9            appstream            10 k
+Installing dependencies:
+ libevent           x86_64      2.1.12-8.el9         baseos              257 k
+Removing:
+ oldpkg             x86_64      1.0-1.el9            @System              20 k
+
+Transaction Summary
+================================================================================
+Install  1 Package
+Upgrade  2 Packages
+
+Operation aborted.
+`
+
+const dnf5TransactionOutput = `Updating and loading repositories:
+Repositories loaded.
+Package                Arch    Version         Repository      Size
+Upgrading:
+ bash                  x86_64  5.2.26-3.fc40   updates      8.1 MiB
+   replacing bash      x86_64  5.2.26-1.fc40   updates      8.1 MiB
+
+Transaction Summary:
+ Upgrading:          1 package
+Operation aborted by the user.
+`
+
+func TestDryRunDnf(t *testing.T) {
+	mockCmd := &MockCommandManager{Results: map[string][]cm.CommandResult{
+		"dnf": {{STDOUT: dnfTransactionOutput, ExitCode: 1}, {STDOUT: dnf5TransactionOutput, ExitCode: 1}},
+	}}
+	dpm := &DnfPackageManager{CommandManager: mockCmd}
+
+	updates, err := UpgradeAll(dpm, PackageOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	expected := []Update{
+		{Name: "bash", AvailableVersion: "5.1.8-9.el9", Arch: "x86_64", Repository: "baseos"},
+		{Name: "a-very-long-package-name-that-wraps", AvailableVersion: "1.0-1.el9", Arch: "noarch", Repository: "appstream"},
+		{Name: "libevent", AvailableVersion: "2.1.12-8.el9", Arch: "x86_64", Repository: "baseos"},
+	}
+	if !reflect.DeepEqual(updates, expected) {
+		t.Errorf("Expected %+v, got: %+v", expected, updates)
+	}
+	if call := mockCmd.Calls[0]; !call.Sudo || !reflect.DeepEqual(call.Args, []string{"upgrade", "--assumeno"}) {
+		t.Errorf("Unexpected dnf call: %+v", call)
+	}
+
+	updates, err = UpgradePackage(dpm, "bash", PackageOptions{DryRun: true})
+	expected = []Update{{Name: "bash", CurrentVersion: "5.2.26-1.fc40", AvailableVersion: "5.2.26-3.fc40", Arch: "x86_64", Repository: "updates"}}
+	if err != nil || !reflect.DeepEqual(updates, expected) {
+		t.Errorf("Expected %+v, got %+v, %v", expected, updates, err)
+	}
+}
+
+func TestDryRunBrew(t *testing.T) {
+	mockCmd := &MockCommandManager{Results: map[string][]cm.CommandResult{
+		"brew": {
+			{STDOUT: "==> Would upgrade 2 outdated packages:\njq 1.7 -> 1.7.1\nnode 21.1.0 -> 21.2.0\n"},
+			{STDOUT: "==> Would install 1 formula:\ntmux\n==> Would install 2 dependencies for tmux:\nlibevent utf8proc\n"},
+		},
+	}}
+	bpm := &BrewPackageManager{CommandManager: mockCmd}
+
+	updates, err := UpgradeAll(bpm, PackageOptions{DryRun: true})
+	expected := []Update{
+		{Name: "jq", CurrentVersion: "1.7", AvailableVersion: "1.7.1"},
+		{Name: "node", CurrentVersion: "21.1.0", AvailableVersion: "21.2.0"},
+	}
+	if err != nil || !reflect.DeepEqual(updates, expected) {
+		t.Errorf("Expected %+v, got %+v, %v", expected, updates, err)
+	}
+
+	updates, err = AddPackage(bpm, "tmux", PackageOptions{DryRun: true})
+	expected = []Update{{Name: "tmux"}, {Name: "libevent"}, {Name: "utf8proc"}}
+	if err != nil || !reflect.DeepEqual(updates, expected) {
+		t.Errorf("Expected %+v, got %+v, %v", expected, updates, err)
+	}
+}
+
+func TestDryRunUnsupported(t *testing.T) {
+	mockCmd := &MockCommandManager{Results: map[string][]cm.CommandResult{}}
+	apkm := &ApkPackageManager{CommandManager: mockCmd}
+
+	if _, err := UpgradeAll(apkm, PackageOptions{DryRun: true}); !errors.Is(err, ErrDryRunUnsupported) {
+		t.Errorf("Expected ErrDryRunUnsupported, got: %v", err)
+	}
+	if len(mockCmd.Calls) != 0 {
+		t.Errorf("Expected no commands to run, got: %+v", mockCmd.Calls)
+	}
+}
+
+func TestUpgradeAllWithoutDryRun(t *testing.T) {
+	mockCmd := &MockCommandManager{Results: map[string][]cm.CommandResult{
+		"apk": {{}, {STDOUT: "Installed:                                Available:\nbusybox-1.36.1-r28                      < 1.36.1-r29\n"}},
+	}}
+	apkm := &ApkPackageManager{CommandManager: mockCmd}
+
+	updates, err := UpgradeAll(apkm, PackageOptions{})
+	expected := []Update{{Name: "busybox"}}
+	if err != nil || !reflect.DeepEqual(updates, expected) {
+		t.Errorf("Expected %+v, got %+v, %v", expected, updates, err)
+	}
+}
+
+func TestAddPackageVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		manager  func(cm.CommandManager) PackageManager
+		command  string
+		target   string
+		notFound cm.CommandResult
+	}{
+		{
+			"apt", func(c cm.CommandManager) PackageManager { return &AptPackageManager{CommandManager: c} }, "apt-get", "nginx=1.18.0-6ubuntu14",
+			cm.CommandResult{STDERR: "E: Version '1.18.0-6ubuntu14' for 'nginx' was not found\n", ExitCode: 100},
+		},
+		{
+			"yum", func(c cm.CommandManager) PackageManager { return &YumPackageManager{CommandManager: c} }, "yum", "nginx-1.18.0-6ubuntu14",
+			cm.CommandResult{STDOUT: "No package nginx-1.18.0-6ubuntu14 available.\n", STDERR: "Error: Nothing to do\n", ExitCode: 1},
+		},
+		{
+			"dnf", func(c cm.CommandManager) PackageManager { return &DnfPackageManager{CommandManager: c} }, "dnf", "nginx-1.18.0-6ubuntu14",
+			cm.CommandResult{STDOUT: "No match for argument: nginx-1.18.0-6ubuntu14\n", STDERR: "Error: Unable to find a match: nginx-1.18.0-6ubuntu14\n", ExitCode: 1},
+		},
+		{
+			"brew", func(c cm.CommandManager) PackageManager { return &BrewPackageManager{CommandManager: c} }, "brew", "nginx@1.18.0-6ubuntu14",
+			cm.CommandResult{STDERR: "Error: No available formula with the name \"nginx@1.18.0-6ubuntu14\".\n", ExitCode: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		mockCmd := &MockCommandManager{Results: map[string][]cm.CommandResult{tt.command: {{}, tt.notFound}}}
+		manager := tt.manager(mockCmd)
+
+		if err := manager.AddPackageVersion("nginx", "1.18.0-6ubuntu14"); err != nil {
+			t.Errorf("%s: expected no error, got: %v", tt.name, err)
+		}
+		args := mockCmd.argsFor(tt.command)
+		if len(args) == 0 || args[0][len(args[0])-1] != tt.target {
+			t.Errorf("%s: expected %s to be installed, got %v", tt.name, tt.target, args)
+		}
+
+		err := manager.AddPackageVersion("nginx", "1.18.0-6ubuntu14")
+		if !errors.Is(err, ErrVersionNotAvailable) || !strings.Contains(err.Error(), "nginx 1.18.0-6ubuntu14") {
+			t.Errorf("%s: expected ErrVersionNotAvailable, got: %v", tt.name, err)
+		}
+	}
+}
+
+func TestHoldPackageCommands(t *testing.T) {
+	tests := []struct {
+		name    string
+		manager func(cm.CommandManager) PackageManager
+		command string
+		hold    []string
+		unhold  []string
+	}{
+		{"apt", func(c cm.CommandManager) PackageManager { return &AptPackageManager{CommandManager: c} }, "apt-mark", []string{"hold", "nginx"}, []string{"unhold", "nginx"}},
+		{"brew", func(c cm.CommandManager) PackageManager { return &BrewPackageManager{CommandManager: c} }, "brew", []string{"pin", "nginx"}, []string{"unpin", "nginx"}},
+		{"zypper", func(c cm.CommandManager) PackageManager { return &ZypperPackageManager{CommandManager: c} }, "zypper", []string{"--non-interactive", "addlock", "nginx"}, []string{"--non-interactive", "removelock", "nginx"}},
+	}
+
+	for _, tt := range tests {
+		mockCmd := &MockCommandManager{Results: map[string][]cm.CommandResult{}}
+		manager := tt.manager(mockCmd)
+		if err := manager.HoldPackage("nginx"); err != nil {
+			t.Errorf("%s: expected no error, got: %v", tt.name, err)
+		}
+		if err := manager.UnholdPackage("nginx"); err != nil {
+			t.Errorf("%s: expected no error, got: %v", tt.name, err)
+		}
+		if calls := mockCmd.argsFor(tt.command); !reflect.DeepEqual(calls, [][]string{tt.hold, tt.unhold}) {
+			t.Errorf("%s: expected %v then %v, got %v", tt.name, tt.hold, tt.unhold, calls)
+		}
+	}
+
+	if err := (&PacmanPackageManager{}).HoldPackage("linux"); !errors.Is(err, ErrHoldUnsupported) {
+		t.Errorf("Expected ErrHoldUnsupported from pacman, got: %v", err)
+	}
+}
+
+func TestYumVersionlock(t *testing.T) {
+	list := "Loaded plugins: fastestmirror, versionlock\n0:bash-4.2.46-34.el7.*\n0:kernel-3.10.0-1160.el7.*\nversionlock list done\n"
+	mockCmd := &MockCommandManager{Results: map[string][]cm.CommandResult{
+		"yum": {{STDOUT: list}, {STDOUT: list}, {}},
+	}}
+	ypm := &YumPackageManager{CommandManager: mockCmd}
+
+	held, err := ypm.ListHeldPackages()
+	if err != nil || !reflect.DeepEqual(held, []string{"bash", "kernel"}) {
+		t.Errorf("Expected [bash kernel], got %v, %v", held, err)
+	}
+
+	if err := ypm.UnholdPackage("kernel"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	calls := mockCmd.argsFor("yum")
+	if expected := []string{"versionlock", "delete", "0:kernel-3.10.0-1160.el7.*"}; !reflect.DeepEqual(calls[2], expected) {
+		t.Errorf("Expected %v, got %v", expected, calls[2])
+	}
+
+	_, names := parseVersionlockList("Last metadata expiration check: 0:01:02 ago.\nbash-0:5.1.8-6.el9.*\n# Added by 'versionlock add' command on 2024-07-01 10:00:00\nPackage name: curl\nevr = 7.76.1-29.el9\n")
+	if !reflect.DeepEqual(names, []string{"bash", "curl"}) {
+		t.Errorf("Expected dnf 4 and dnf 5 entries to parse, got %v", names)
+	}
+}
@@ -0,0 +1,222 @@
+package packagemanager
+
+import (
+	"context"
+	"strings"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+type PacmanPackageManager struct {
+	CommandManager cm.CommandManager
+}
+
+func (ppm *PacmanPackageManager) ListPackages() ([]string, error) {
+	lines, err := cm.RunCommandLines(context.TODO(), ppm.CommandManager, cm.CommandConfig{
+		Command: "pacman",
+		Args:    []string{"-Q"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return firstFields(lines), nil
+}
+
+func (ppm *PacmanPackageManager) AddPackage(pkg string) error {
+	_, err := ppm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "pacman",
+		Sudo:    true,
+		Args:    []string{"-S", "--noconfirm", pkg},
+	})
+	return err
+}
+
+// AddPackageVersion installs pkg=version. The sync databases only hold the
+// current version of each package, so older versions are not available.
+func (ppm *PacmanPackageManager) AddPackageVersion(pkg, version string) error {
+	result, err := ppm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "pacman",
+		Sudo:    true,
+		Args:    []string{"-S", "--noconfirm", pkg + "=" + version},
+	})
+	return checkVersionInstall(pkg, version, result, err, []string{"target not found"})
+}
+
+func (ppm *PacmanPackageManager) RemovePackage(pkg string) error {
+	_, err := ppm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "pacman",
+		Sudo:    true,
+		Args:    []string{"-R", "--noconfirm", pkg},
+	})
+	return err
+}
+
+func (ppm *PacmanPackageManager) UpgradePackage(pkg string) error {
+	return ppm.AddPackage(pkg)
+}
+
+// ListUpdates returns the packages "pacman -Qu" reports as upgradable
+// against the last synced package databases.
+func (ppm *PacmanPackageManager) ListUpdates() ([]Update, error) {
+	result, err := ppm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "pacman",
+		Args:    []string{"-Qu"},
+	})
+	// pacman exits 1 without output when nothing is upgradable
+	if result.ExitCode == 1 && result.STDOUT == "" && result.STDERR == "" {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parsePacmanUpdates(result.STDOUT, cm.StrictParsing(ppm.CommandManager))
+}
+
+func (ppm *PacmanPackageManager) CheckOSUpdates() ([]string, error) {
+	updates, err := ppm.ListUpdates()
+	return updateNames(updates), err
+}
+
+// UpgradeAll syncs the package databases, upgrades every package and returns
+// the packages ListUpdates reported before the upgrade. Together the -Sy and
+// -Su steps are a pacman -Syu.
+func (ppm *PacmanPackageManager) UpgradeAll() ([]string, error) {
+	_, err := ppm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "pacman",
+		Sudo:    true,
+		Args:    []string{"-Sy", "--noconfirm"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	updates, err := ppm.ListUpdates()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = ppm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "pacman",
+		Sudo:    true,
+		Args:    []string{"-Su", "--noconfirm"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updateNames(updates), nil
+}
+
+// CleanCache clears the package cache and reports the bytes freed.
+func (ppm *PacmanPackageManager) CleanCache() (int64, error) {
+	return cleanCache(ppm.CommandManager, "/var/cache/pacman/pkg", cm.CommandConfig{
+		Command: "pacman",
+		Sudo:    true,
+		Args:    []string{"-Sc", "--noconfirm"},
+	})
+}
+
+// PackageInfo describes pkg using pacman -Qi, falling back to the sync
+// databases with pacman -Si when it is not installed.
+func (ppm *PacmanPackageManager) PackageInfo(pkg string) (PackageDetails, error) {
+	for _, query := range []string{"-Qi", "-Si"} {
+		result, err := ppm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+			Command: "pacman",
+			Args:    []string{query, pkg},
+		})
+		if result.ExitCode != 0 && strings.Contains(result.STDERR, "not found") {
+			continue
+		}
+		if err != nil {
+			return PackageDetails{}, err
+		}
+		fields := infoFields(strings.Split(result.STDOUT, "\n"))
+		return PackageDetails{
+			Name:        fields["Name"],
+			Version:     fields["Version"],
+			Description: fields["Description"],
+			Installed:   query == "-Qi",
+		}, nil
+	}
+	return PackageDetails{}, packageNotFound(pkg)
+}
+
+// SearchPackages searches the sync databases with pacman -Ss.
+func (ppm *PacmanPackageManager) SearchPackages(query string) ([]string, error) {
+	result, err := ppm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "pacman",
+		Args:    []string{"-Ss", query},
+	})
+	// pacman exits 1 without output when nothing matches
+	if result.ExitCode == 1 && result.STDOUT == "" && result.STDERR == "" {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Matches are "repo/name version [installed]", each followed by an
+	// indented description
+	names := []string{}
+	for _, line := range strings.Split(result.STDOUT, "\n") {
+		if line == "" || line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+		name := strings.Fields(line)[0]
+		if _, after, ok := strings.Cut(name, "/"); ok {
+			name = after
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// HoldPackage is not supported: pacman holds packages with IgnorePkg in
+// pacman.conf, which steelcut does not edit.
+func (ppm *PacmanPackageManager) HoldPackage(pkg string) error {
+	return ErrHoldUnsupported
+}
+
+func (ppm *PacmanPackageManager) UnholdPackage(pkg string) error {
+	return ErrHoldUnsupported
+}
+
+// ListHeldPackages returns the IgnorePkg entries from pacman.conf.
+func (ppm *PacmanPackageManager) ListHeldPackages() ([]string, error) {
+	return cm.RunCommandLines(context.TODO(), ppm.CommandManager, cm.CommandConfig{
+		Command: "pacman-conf",
+		Args:    []string{"IgnorePkg"},
+	})
+}
+
+func (ppm *PacmanPackageManager) EnsurePackagePresent(pkg string) error {
+	packages, err := ppm.ListPackages()
+	if err != nil {
+		return err
+	}
+
+	for _, installedPkg := range packages {
+		if installedPkg == pkg {
+			// Package is already installed; return without taking action
+			return nil
+		}
+	}
+
+	// Package is not installed; proceed with installation
+	return ppm.AddPackage(pkg)
+}
+
+func (ppm *PacmanPackageManager) EnsurePackageAbsent(pkg string) error {
+	packages, err := ppm.ListPackages()
+	if err != nil {
+		return err
+	}
+
+	for _, installedPkg := range packages {
+		if installedPkg == pkg {
+			// Package is installed; proceed with removal
+			return ppm.RemovePackage(pkg)
+		}
+	}
+
+	// Package is not installed; return without taking action
+	return nil
+}
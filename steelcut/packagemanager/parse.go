@@ -0,0 +1,384 @@
+package packagemanager
+
+import (
+	"regexp"
+	"strings"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// firstFields returns the first whitespace-separated field of each line,
+// which is where every supported package manager prints the package name.
+func firstFields(lines []string) []string {
+	var names []string
+	for _, line := range lines {
+		names = append(names, strings.Fields(line)[0])
+	}
+	return names
+}
+
+// parseAptUpgradable parses "apt list --upgradable" output, e.g.
+// "bash/jammy-updates 5.1-6ubuntu1.1 amd64 [upgradable from: 5.1-6ubuntu1]",
+// into package names without the suite.
+func parseAptUpgradable(output string, strict bool) ([]string, error) {
+	var updates []string
+	for i, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "" || strings.HasPrefix(line, "Listing...") || strings.HasPrefix(line, "WARNING:"):
+		case strings.Contains(line, "upgradable from"):
+			name, _, _ := strings.Cut(strings.Fields(line)[0], "/")
+			updates = append(updates, name)
+		case strict:
+			return nil, &cm.ParseError{Command: "apt list --upgradable", Line: i + 1, Text: line}
+		}
+	}
+	return updates, nil
+}
+
+// aptChangeHeadings introduce the package lists apt-get prints for packages
+// it is about to upgrade or newly install.
+var aptChangeHeadings = []string{
+	"The following packages will be upgraded:",
+	"The following NEW packages will be installed:",
+}
+
+// parseAptUpgraded parses the output of "apt-get upgrade" or "dist-upgrade"
+// into the packages it upgraded or installed. Each list follows one of
+// aptChangeHeadings as indented, space-separated names; packages kept back
+// or removed are listed under other headings and not reported.
+func parseAptUpgraded(output string) []string {
+	var names []string
+	listing := false
+	for _, line := range strings.Split(output, "\n") {
+		if !strings.HasPrefix(line, " ") {
+			listing = hasAnyPrefix(strings.TrimSpace(line), aptChangeHeadings)
+			continue
+		}
+		if listing {
+			names = append(names, strings.Fields(line)...)
+		}
+	}
+	return names
+}
+
+// rpmListHeaders are the non-package lines printed by "dnf list" and
+// "yum list" before the package table. dnf 5 capitalizes its headings
+// differently and reports loading repositories first.
+var rpmListHeaders = []string{
+	"Last metadata expiration check",
+	"Loaded plugins:",
+	"Loading mirror speeds",
+	"Available Upgrades",
+	"Available upgrades",
+	"Updated Packages",
+	"Upgraded Packages",
+	"Installed Packages",
+	"Installed packages",
+	"Available Packages",
+	"Updating and loading repositories:",
+	"Repositories loaded.",
+	"Security:",
+	"* ",
+}
+
+// parseRPMList parses "dnf list" and "yum list" output into package names.
+// Package lines are "name.arch version repo"; yum wraps long names onto a
+// line of their own with the version and repo on the next. The "Obsoleting
+// Packages" section that may follow is not part of the listing.
+func parseRPMList(command, output string, strict bool) ([]string, error) {
+	var names []string
+	lines := strings.Split(output, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" || hasAnyPrefix(line, rpmListHeaders) {
+			continue
+		}
+		if strings.HasPrefix(line, "Obsoleting Packages") {
+			break
+		}
+
+		lineNo := i + 1
+		fields := strings.Fields(line)
+		if len(fields) == 1 && i+1 < len(lines) && len(strings.Fields(lines[i+1])) == 2 {
+			fields = append(fields, strings.Fields(lines[i+1])...)
+			i++
+		}
+		if len(fields) == 3 && strings.Contains(fields[0], ".") {
+			names = append(names, fields[0])
+			continue
+		}
+		if strict {
+			return nil, &cm.ParseError{Command: command, Line: lineNo, Text: line}
+		}
+	}
+	return names, nil
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseZypperTable parses the "|"-separated table zypper prints into rows
+// keyed by column heading. Progress messages before the table, such as
+// "Loading repository data...", and notes like "No updates found." are
+// skipped.
+func parseZypperTable(command, output string, strict bool) ([]map[string]string, error) {
+	var columns []string
+	var rows []map[string]string
+	for i, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if columns == nil {
+			if strings.Contains(line, "|") {
+				columns = splitZypperRow(line)
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "--") {
+			continue
+		}
+
+		fields := splitZypperRow(line)
+		if len(fields) != len(columns) {
+			if strict {
+				return nil, &cm.ParseError{Command: command, Line: i + 1, Text: line}
+			}
+			continue
+		}
+		row := make(map[string]string, len(columns))
+		for j, column := range columns {
+			row[column] = fields[j]
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func splitZypperRow(line string) []string {
+	fields := strings.Split(line, "|")
+	for i := range fields {
+		fields[i] = strings.TrimSpace(fields[i])
+	}
+	return fields
+}
+
+// parseZypperUpdates parses "zypper list-updates" output, e.g.
+// "v | Main Update Repository | bash | 4.4-19.6.1 | 4.4-19.9.1 | x86_64".
+func parseZypperUpdates(output string, strict bool) ([]Update, error) {
+	rows, err := parseZypperTable("zypper list-updates", output, strict)
+	if err != nil {
+		return nil, err
+	}
+	var updates []Update
+	for _, row := range rows {
+		updates = append(updates, Update{
+			Name:             row["Name"],
+			CurrentVersion:   row["Current Version"],
+			AvailableVersion: row["Available Version"],
+			Arch:             row["Arch"],
+			Repository:       row["Repository"],
+		})
+	}
+	return updates, nil
+}
+
+// parsePacmanUpdates parses "pacman -Qu" output, e.g.
+// "linux 6.9.7.arch1-1 -> 6.9.8.arch1-1". Packages held back by IgnorePkg
+// carry a trailing "[ignored]" and are still reported.
+func parsePacmanUpdates(output string, strict bool) ([]Update, error) {
+	var updates []Update
+	for i, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) >= 4 && fields[2] == "->" {
+			updates = append(updates, Update{Name: fields[0], CurrentVersion: fields[1], AvailableVersion: fields[3]})
+			continue
+		}
+		if strict {
+			return nil, &cm.ParseError{Command: "pacman -Qu", Line: i + 1, Text: strings.TrimSpace(line)}
+		}
+	}
+	return updates, nil
+}
+
+// splitPackageVersion splits a package string such as apk's
+// "busybox-1.36.1-r29" or rpm's "bash-5.1.8-6.el9" into its name and
+// version. The version is the last two "-"-separated parts, since package
+// names may contain "-" too.
+func splitPackageVersion(pkg string) (string, string, bool) {
+	release := strings.LastIndex(pkg, "-")
+	if release <= 0 {
+		return "", "", false
+	}
+	version := strings.LastIndex(pkg[:release], "-")
+	if version <= 0 {
+		return "", "", false
+	}
+	return pkg[:version], pkg[version+1:], true
+}
+
+// parseApkVersion parses "apk version -l '<'" output, e.g.
+// "busybox-1.36.1-r28   < 1.36.1-r29", skipping the
+// "Installed: Available:" heading.
+func parseApkVersion(output string, strict bool) ([]Update, error) {
+	var updates []Update
+	for i, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "Installed:") {
+			continue
+		}
+		if len(fields) >= 3 && fields[1] == "<" {
+			if name, current, ok := splitPackageVersion(fields[0]); ok {
+				updates = append(updates, Update{Name: name, CurrentVersion: current, AvailableVersion: fields[2]})
+				continue
+			}
+		}
+		if strict {
+			return nil, &cm.ParseError{Command: "apk version -l <", Line: i + 1, Text: strings.TrimSpace(line)}
+		}
+	}
+	return updates, nil
+}
+
+// aptSimulatedInstall matches the "Inst" lines of apt-get --dry-run, e.g.
+// "Inst bash [5.1-6ubuntu1] (5.1-6ubuntu1.1 Ubuntu:22.04/jammy-updates [amd64])".
+// The bracketed current version is absent for new installs.
+var aptSimulatedInstall = regexp.MustCompile(`^Inst (\S+)(?: \[([^\]]*)\])? \((\S+)(?: (.*?))?(?: \[([^\]]*)\])?\)`)
+
+// parseAptSimulation returns the packages apt-get --dry-run would install or
+// upgrade. Removals and configuration steps are not reported.
+func parseAptSimulation(output string) []Update {
+	var updates []Update
+	for _, line := range strings.Split(output, "\n") {
+		m := aptSimulatedInstall.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		updates = append(updates, Update{
+			Name:             m[1],
+			CurrentVersion:   m[2],
+			AvailableVersion: m[3],
+			Repository:       m[4],
+			Arch:             m[5],
+		})
+	}
+	return updates
+}
+
+// rpmTransactionSections are the headings of the yum and dnf transaction
+// table that list packages being installed or upgraded, including as
+// dependencies.
+var rpmTransactionSections = []string{"Installing", "Upgrading", "Updating", "Downgrading"}
+
+// parseRPMTransaction parses the transaction table yum and dnf print before
+// asking for confirmation, e.g. " bash  x86_64  5.1.8-9.el9  baseos  1.7 M"
+// under "Upgrading:". yum wraps long names onto a line of their own, and
+// dnf 5 follows each upgrade with a "replacing" line giving the current
+// version.
+func parseRPMTransaction(output string) []Update {
+	var updates []Update
+	listing := false
+	lines := strings.Split(output, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], "\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "Transaction Summary") {
+			break
+		}
+		if !strings.HasPrefix(line, " ") {
+			listing = strings.HasSuffix(trimmed, ":") && hasAnyPrefix(trimmed, rpmTransactionSections)
+			continue
+		}
+		if !listing {
+			continue
+		}
+
+		fields := strings.Fields(trimmed)
+		if fields[0] == "replacing" {
+			if len(updates) > 0 && len(fields) >= 4 {
+				updates[len(updates)-1].CurrentVersion = fields[3]
+			}
+			continue
+		}
+		if len(fields) == 1 && i+1 < len(lines) {
+			fields = append(fields, strings.Fields(lines[i+1])...)
+			i++
+		}
+		if len(fields) < 4 {
+			continue
+		}
+		updates = append(updates, Update{
+			Name:             fields[0],
+			Arch:             fields[1],
+			AvailableVersion: fields[2],
+			Repository:       fields[3],
+		})
+	}
+	return updates
+}
+
+// parseBrewDryRun parses the output of brew install or upgrade with
+// --dry-run. Upgrades are listed as "jq 1.7 -> 1.7.1" under "==> Would
+// upgrade"; installs list bare names, possibly several to a line.
+func parseBrewDryRun(output string) []Update {
+	var updates []Update
+	listing := false
+	for _, line := range cm.Lines(output) {
+		if strings.HasPrefix(line, "==>") {
+			listing = strings.HasPrefix(line, "==> Would")
+			continue
+		}
+		if !listing {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 4 && fields[2] == "->" {
+			updates = append(updates, Update{Name: fields[0], CurrentVersion: fields[1], AvailableVersion: fields[3]})
+			continue
+		}
+		for _, name := range fields {
+			updates = append(updates, Update{Name: strings.TrimSuffix(name, ",")})
+		}
+	}
+	return updates
+}
+
+// parseVersionlockList parses "yum versionlock list" and "dnf versionlock
+// list" into the locked entries and their package names. Entries are
+// "0:bash-4.2.46-34.el7.*" with yum, "bash-0:5.1.8-6.el9.*" with dnf 4, and
+// a "Package name: bash" line with dnf 5.
+func parseVersionlockList(output string) (entries, names []string) {
+	for _, line := range cm.Lines(output) {
+		if name, ok := strings.CutPrefix(line, "Package name:"); ok {
+			name = strings.TrimSpace(name)
+			entries, names = append(entries, name), append(names, name)
+			continue
+		}
+		if strings.Contains(line, " ") || !strings.HasSuffix(line, "*") {
+			// Plugin banners, metadata notices and comments
+			continue
+		}
+
+		spec := strings.TrimSuffix(strings.TrimSuffix(line, "*"), ".")
+		if epoch, rest, ok := strings.Cut(spec, ":"); ok && !strings.Contains(epoch, "-") {
+			spec = rest
+		}
+		if name, _, ok := splitPackageVersion(spec); ok {
+			entries, names = append(entries, line), append(names, name)
+		}
+	}
+	return entries, names
+}
@@ -0,0 +1,32 @@
+package packagemanager
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// ErrVersionNotAvailable is returned by AddPackageVersion when no
+// repository offers the requested version of the package.
+var ErrVersionNotAvailable = errors.New("package version not available")
+
+// checkVersionInstall converts the outcome of installing a specific version
+// into ErrVersionNotAvailable when the output contains one of the package
+// manager's not-found messages, so callers can tell it from other failures.
+func checkVersionInstall(pkg, version string, result cm.CommandResult, err error, notFound []string) error {
+	if result.ExitCode != 0 {
+		output := result.STDOUT + result.STDERR
+		for _, msg := range notFound {
+			if strings.Contains(output, msg) {
+				return fmt.Errorf("%w: %s %s", ErrVersionNotAvailable, pkg, version)
+			}
+		}
+	}
+	return err
+}
+
+// rpmNotFoundMessages are printed by yum and dnf when no repository has the
+// requested package version.
+var rpmNotFoundMessages = []string{"No package", "No match for argument", "Unable to find a match"}
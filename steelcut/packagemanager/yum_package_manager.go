@@ -2,7 +2,6 @@ package packagemanager
 
 import (
 	"context"
-	"strings"
 
 	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
 )
@@ -12,7 +11,7 @@ type YumPackageManager struct {
 }
 
 func (ypm *YumPackageManager) ListPackages() ([]string, error) {
-	output, err := ypm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+	lines, err := cm.RunCommandLines(context.TODO(), ypm.CommandManager, cm.CommandConfig{
 		Command: "yum",
 		Args:    []string{"list", "installed"},
 	})
@@ -20,15 +19,10 @@ func (ypm *YumPackageManager) ListPackages() ([]string, error) {
 		return nil, err
 	}
 
-	lines := strings.Split(output.STDOUT, "\n")
-	var packages []string
-	for _, line := range lines[1:] { // Skipping the header line
-		parts := strings.Fields(line)
-		if len(parts) > 0 {
-			packages = append(packages, parts[0])
-		}
+	if len(lines) > 0 {
+		lines = lines[1:] // Skipping the header line
 	}
-	return packages, nil
+	return firstFields(lines), nil
 }
 
 func (ypm *YumPackageManager) AddPackage(pkg string) error {
@@ -40,6 +34,16 @@ func (ypm *YumPackageManager) AddPackage(pkg string) error {
 	return err
 }
 
+// AddPackageVersion installs pkg-version.
+func (ypm *YumPackageManager) AddPackageVersion(pkg, version string) error {
+	result, err := ypm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "yum",
+		Sudo:    true,
+		Args:    []string{"install", "-y", pkg + "-" + version},
+	})
+	return checkVersionInstall(pkg, version, result, err, rpmNotFoundMessages)
+}
+
 func (ypm *YumPackageManager) RemovePackage(pkg string) error {
 	_, err := ypm.CommandManager.Run(context.TODO(), cm.CommandConfig{
 		Command: "yum",
@@ -59,7 +63,7 @@ func (ypm *YumPackageManager) UpgradePackage(pkg string) error {
 }
 
 func (ypm *YumPackageManager) CheckOSUpdates() ([]string, error) {
-	output, err := ypm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+	result, err := ypm.CommandManager.Run(context.TODO(), cm.CommandConfig{
 		Command: "yum",
 		Args:    []string{"list", "updates"},
 	})
@@ -67,15 +71,7 @@ func (ypm *YumPackageManager) CheckOSUpdates() ([]string, error) {
 		return nil, err
 	}
 
-	lines := strings.Split(output.STDOUT, "\n")
-	var updates []string
-	for _, line := range lines[1:] { // Skipping the header line
-		parts := strings.Fields(line)
-		if len(parts) > 0 {
-			updates = append(updates, parts[0])
-		}
-	}
-	return updates, nil
+	return parseRPMList("yum list updates", result.STDOUT, cm.StrictParsing(ypm.CommandManager))
 }
 
 func (ypm *YumPackageManager) UpgradeAll() ([]string, error) {
@@ -90,6 +86,61 @@ func (ypm *YumPackageManager) UpgradeAll() ([]string, error) {
 	return ypm.CheckOSUpdates()
 }
 
+// CleanCache clears the package cache and reports the bytes freed.
+func (ypm *YumPackageManager) CleanCache() (int64, error) {
+	return cleanCache(ypm.CommandManager, "/var/cache/yum", cm.CommandConfig{
+		Command: "yum",
+		Sudo:    true,
+		Args:    []string{"clean", "all"},
+	})
+}
+
+// PackageInfo describes pkg using yum info.
+func (ypm *YumPackageManager) PackageInfo(pkg string) (PackageDetails, error) {
+	return rpmPackageInfo(ypm.CommandManager, "yum", pkg)
+}
+
+// SearchPackages searches package names and summaries with yum search.
+func (ypm *YumPackageManager) SearchPackages(query string) ([]string, error) {
+	return rpmSearch(ypm.CommandManager, "yum", query)
+}
+
+// PlanAddPackage reports what installing pkg would change. yum needs
+// root to resolve the transaction, which is then declined with --assumeno.
+func (ypm *YumPackageManager) PlanAddPackage(pkg string) ([]Update, error) {
+	return rpmPlan(ypm.CommandManager, "yum", "install", pkg)
+}
+
+// PlanUpgradePackage reports what upgrading pkg would change.
+func (ypm *YumPackageManager) PlanUpgradePackage(pkg string) ([]Update, error) {
+	return rpmPlan(ypm.CommandManager, "yum", "update", pkg)
+}
+
+// PlanUpgradeAll reports what UpgradeAll would change.
+func (ypm *YumPackageManager) PlanUpgradeAll() ([]Update, error) {
+	return rpmPlan(ypm.CommandManager, "yum", "update")
+}
+
+// HoldPackage locks pkg at its installed version with the versionlock
+// plugin, which must be installed.
+func (ypm *YumPackageManager) HoldPackage(pkg string) error {
+	_, err := ypm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "yum",
+		Sudo:    true,
+		Args:    []string{"versionlock", "add", pkg},
+	})
+	return err
+}
+
+func (ypm *YumPackageManager) UnholdPackage(pkg string) error {
+	return rpmUnlock(ypm.CommandManager, "yum", pkg)
+}
+
+func (ypm *YumPackageManager) ListHeldPackages() ([]string, error) {
+	_, names, err := rpmVersionlocks(ypm.CommandManager, "yum")
+	return names, err
+}
+
 func (ypm *YumPackageManager) EnsurePackagePresent(pkg string) error {
 	packages, err := ypm.ListPackages()
 	if err != nil {
@@ -0,0 +1,271 @@
+package packagemanager
+
+import (
+	"context"
+	"strings"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// zypperNoMatches is the exit code zypper uses when a search finds nothing.
+const zypperNoMatches = 104
+
+type ZypperPackageManager struct {
+	CommandManager cm.CommandManager
+}
+
+// Update describes a package with a newer version available.
+type Update struct {
+	Name             string
+	CurrentVersion   string
+	AvailableVersion string
+	Arch             string
+	Repository       string
+}
+
+// updateNames returns the names of the packages in updates.
+func updateNames(updates []Update) []string {
+	var names []string
+	for _, update := range updates {
+		names = append(names, update.Name)
+	}
+	return names
+}
+
+func (zpm *ZypperPackageManager) ListPackages() ([]string, error) {
+	result, err := zpm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "zypper",
+		Args:    []string{"--non-interactive", "search", "--installed-only"},
+	})
+	if result.ExitCode == zypperNoMatches {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := parseZypperTable("zypper search --installed-only", result.STDOUT, cm.StrictParsing(zpm.CommandManager))
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, row := range rows {
+		// Patterns and patches are listed alongside packages
+		if kind, ok := row["Type"]; ok && kind != "package" {
+			continue
+		}
+		names = append(names, row["Name"])
+	}
+	return names, nil
+}
+
+func (zpm *ZypperPackageManager) AddPackage(pkg string) error {
+	_, err := zpm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "zypper",
+		Sudo:    true,
+		Args:    []string{"--non-interactive", "install", "-y", pkg},
+	})
+	return err
+}
+
+// AddPackageVersion installs pkg=version.
+func (zpm *ZypperPackageManager) AddPackageVersion(pkg, version string) error {
+	result, err := zpm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "zypper",
+		Sudo:    true,
+		Args:    []string{"--non-interactive", "install", "-y", pkg + "=" + version},
+	})
+	return checkVersionInstall(pkg, version, result, err, []string{"No provider of", "not found in package names"})
+}
+
+func (zpm *ZypperPackageManager) RemovePackage(pkg string) error {
+	_, err := zpm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "zypper",
+		Sudo:    true,
+		Args:    []string{"--non-interactive", "remove", "-y", pkg},
+	})
+	return err
+}
+
+func (zpm *ZypperPackageManager) UpgradePackage(pkg string) error {
+	_, err := zpm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "zypper",
+		Sudo:    true,
+		Args:    []string{"--non-interactive", "update", "-y", pkg},
+	})
+	return err
+}
+
+// ListUpdates returns the packages "zypper list-updates" reports.
+func (zpm *ZypperPackageManager) ListUpdates() ([]Update, error) {
+	result, err := zpm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "zypper",
+		Args:    []string{"--non-interactive", "list-updates"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return parseZypperUpdates(result.STDOUT, cm.StrictParsing(zpm.CommandManager))
+}
+
+func (zpm *ZypperPackageManager) CheckOSUpdates() ([]string, error) {
+	updates, err := zpm.ListUpdates()
+	return updateNames(updates), err
+}
+
+// UpgradeAll refreshes the repositories, upgrades every package and returns
+// the packages ListUpdates reported before the upgrade.
+func (zpm *ZypperPackageManager) UpgradeAll() ([]string, error) {
+	_, err := zpm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "zypper",
+		Sudo:    true,
+		Args:    []string{"--non-interactive", "refresh"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	updates, err := zpm.ListUpdates()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = zpm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "zypper",
+		Sudo:    true,
+		Args:    []string{"--non-interactive", "update", "-y"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updateNames(updates), nil
+}
+
+// CleanCache clears the package cache and reports the bytes freed.
+func (zpm *ZypperPackageManager) CleanCache() (int64, error) {
+	return cleanCache(zpm.CommandManager, "/var/cache/zypp", cm.CommandConfig{
+		Command: "zypper",
+		Sudo:    true,
+		Args:    []string{"--non-interactive", "clean", "--all"},
+	})
+}
+
+// PackageInfo describes pkg using zypper info.
+func (zpm *ZypperPackageManager) PackageInfo(pkg string) (PackageDetails, error) {
+	result, err := zpm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "zypper",
+		Args:    []string{"--non-interactive", "info", pkg},
+	})
+	if err != nil && result.ExitCode != zypperNoMatches {
+		return PackageDetails{}, err
+	}
+	fields := infoFields(strings.Split(result.STDOUT, "\n"))
+	if fields["Name"] == "" {
+		return PackageDetails{}, packageNotFound(pkg)
+	}
+	return PackageDetails{
+		Name:        fields["Name"],
+		Version:     fields["Version"],
+		Description: fields["Summary"],
+		Installed:   strings.HasPrefix(fields["Installed"], "Yes"),
+	}, nil
+}
+
+// SearchPackages searches the repositories with zypper search.
+func (zpm *ZypperPackageManager) SearchPackages(query string) ([]string, error) {
+	result, err := zpm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "zypper",
+		Args:    []string{"--non-interactive", "search", query},
+	})
+	if result.ExitCode == zypperNoMatches {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := parseZypperTable("zypper search", result.STDOUT, cm.StrictParsing(zpm.CommandManager))
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, row := range rows {
+		if kind, ok := row["Type"]; ok && kind != "package" {
+			continue
+		}
+		names = append(names, row["Name"])
+	}
+	return names, nil
+}
+
+// HoldPackage adds a package lock for pkg.
+func (zpm *ZypperPackageManager) HoldPackage(pkg string) error {
+	_, err := zpm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "zypper",
+		Sudo:    true,
+		Args:    []string{"--non-interactive", "addlock", pkg},
+	})
+	return err
+}
+
+func (zpm *ZypperPackageManager) UnholdPackage(pkg string) error {
+	_, err := zpm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "zypper",
+		Sudo:    true,
+		Args:    []string{"--non-interactive", "removelock", pkg},
+	})
+	return err
+}
+
+func (zpm *ZypperPackageManager) ListHeldPackages() ([]string, error) {
+	result, err := zpm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "zypper",
+		Args:    []string{"--non-interactive", "locks"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := parseZypperTable("zypper locks", result.STDOUT, cm.StrictParsing(zpm.CommandManager))
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, row := range rows {
+		names = append(names, row["Name"])
+	}
+	return names, nil
+}
+
+func (zpm *ZypperPackageManager) EnsurePackagePresent(pkg string) error {
+	packages, err := zpm.ListPackages()
+	if err != nil {
+		return err
+	}
+
+	for _, installedPkg := range packages {
+		if installedPkg == pkg {
+			// Package is already installed; return without taking action
+			return nil
+		}
+	}
+
+	// Package is not installed; proceed with installation
+	return zpm.AddPackage(pkg)
+}
+
+func (zpm *ZypperPackageManager) EnsurePackageAbsent(pkg string) error {
+	packages, err := zpm.ListPackages()
+	if err != nil {
+		return err
+	}
+
+	for _, installedPkg := range packages {
+		if installedPkg == pkg {
+			// Package is installed; proceed with removal
+			return zpm.RemovePackage(pkg)
+		}
+	}
+
+	// Package is not installed; return without taking action
+	return nil
+}
@@ -3,6 +3,7 @@ package servicemanager
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
 
 	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
@@ -28,6 +29,38 @@ func (dsm *DarwinServiceManager) DisableService(serviceName string) error {
 	return err
 }
 
+// EnableAtBoot loads the job's plist and clears its disabled override, so
+// that launchd starts it at boot. Loading starts a RunAtLoad job now too.
+func (dsm *DarwinServiceManager) EnableAtBoot(serviceName string) error {
+	_, err := dsm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "launchctl",
+		Args:    []string{"load", "-w", fmt.Sprintf("/Library/LaunchDaemons/%s.plist", serviceName)},
+		Sudo:    true,
+	})
+	return err
+}
+
+// DisableAtBoot unloads the job, which stops it, and sets its disabled
+// override.
+func (dsm *DarwinServiceManager) DisableAtBoot(serviceName string) error {
+	_, err := dsm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "launchctl",
+		Args:    []string{"unload", "-w", fmt.Sprintf("/Library/LaunchDaemons/%s.plist", serviceName)},
+		Sudo:    true,
+	})
+	return err
+}
+
+// MaskService is not supported on macOS, which has no systemd.
+func (dsm *DarwinServiceManager) MaskService(serviceName string) error {
+	return ErrNotSupported
+}
+
+// UnmaskService is not supported on macOS, which has no systemd.
+func (dsm *DarwinServiceManager) UnmaskService(serviceName string) error {
+	return ErrNotSupported
+}
+
 func (dsm *DarwinServiceManager) StartService(serviceName string) error {
 	_, err := dsm.CommandManager.Run(context.TODO(), cm.CommandConfig{
 		Command: "launchctl",
@@ -54,19 +87,31 @@ func (dsm *DarwinServiceManager) ReloadService(serviceName string) error {
 	return dsm.RestartService(serviceName)
 }
 
-func (dsm *DarwinServiceManager) CheckServiceStatus(serviceName string) (ServiceStatus, error) {
+// CheckServiceStatus reports whether the job is running and whether it is
+// enabled in the system domain.
+func (dsm *DarwinServiceManager) CheckServiceStatus(serviceName string) (ServiceState, error) {
 	output, err := dsm.CommandManager.Run(context.TODO(), cm.CommandConfig{
 		Command: "launchctl",
 		Args:    []string{"print", fmt.Sprintf("system/%s", serviceName)},
 	})
 	if err != nil {
-		return "", err
+		return ServiceState{}, err
 	}
+	state := ServiceState{Status: Inactive}
 	if strings.Contains(output.STDOUT, "running") {
-		return Active, nil
-	} else {
-		return Inactive, nil
+		state.Status = Active
 	}
+
+	output, err = dsm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "launchctl",
+		Args:    []string{"print-disabled", "system"},
+		Sudo:    true,
+	})
+	if err != nil {
+		return ServiceState{}, err
+	}
+	state.Enabled = !parseLaunchctlDisabled(output.STDOUT)[serviceName]
+	return state, nil
 }
 
 func (dsm *DarwinServiceManager) IsServiceEnabled(serviceName string) (bool, error) {
@@ -81,3 +126,164 @@ func (dsm *DarwinServiceManager) IsServiceEnabled(serviceName string) (bool, err
 	}
 	return strings.Contains(output.STDOUT, serviceName), nil
 }
+
+// ListServices returns the services loaded in launchd's system domain. A
+// service is enabled unless launchctl print-disabled lists it as disabled.
+func (dsm *DarwinServiceManager) ListServices() ([]ServiceEntry, error) {
+	result, err := dsm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "launchctl",
+		Args:    []string{"list"},
+		Sudo:    true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	services := parseLaunchctlList(result.STDOUT)
+
+	result, err = dsm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "launchctl",
+		Args:    []string{"print-disabled", "system"},
+		Sudo:    true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	disabled := parseLaunchctlDisabled(result.STDOUT)
+	for i := range services {
+		services[i].Enabled = !disabled[services[i].Name]
+	}
+	return services, nil
+}
+
+// parseLaunchctlList parses "launchctl list" output, "PID Status Label"
+// columns where the PID is "-" for a job that is not running and Status is
+// its last exit status.
+func parseLaunchctlList(output string) []ServiceEntry {
+	var services []ServiceEntry
+	for _, line := range cm.Lines(output) {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || fields[0] == "PID" {
+			continue
+		}
+		info := ServiceInfo{Name: fields[2], LoadState: "loaded", ActiveState: "active", SubState: "running"}
+		if fields[0] == "-" {
+			info.ActiveState, info.SubState = "inactive", "dead"
+			if fields[1] != "0" {
+				info.ActiveState, info.SubState = "failed", "failed"
+			}
+		}
+		services = append(services, ServiceEntry{ServiceInfo: info})
+	}
+	return services
+}
+
+// parseLaunchctlDisabled parses "launchctl print-disabled" output, lines
+// such as "\"com.example.job\" => disabled". Older releases print true for
+// disabled and false for enabled.
+func parseLaunchctlDisabled(output string) map[string]bool {
+	disabled := make(map[string]bool)
+	for _, line := range cm.Lines(output) {
+		label, state, ok := strings.Cut(line, "=>")
+		if !ok {
+			continue
+		}
+		state = strings.TrimSpace(state)
+		disabled[strings.Trim(strings.TrimSpace(label), `"`)] = state == "disabled" || state == "true"
+	}
+	return disabled
+}
+
+// ServiceLogs returns the last lines lines the service wrote to the
+// standard output and error files named in its launchd job, or, for a job
+// without them, its last hour of unified log entries. A non-positive lines
+// returns everything.
+func (dsm *DarwinServiceManager) ServiceLogs(serviceName string, lines int) (string, error) {
+	paths, err := dsm.logPaths(serviceName)
+	if err != nil {
+		return "", err
+	}
+
+	config := cm.CommandConfig{
+		Command: "log",
+		Args:    []string{"show", "--style", "compact", "--last", "1h", "--predicate", logPredicate(serviceName)},
+	}
+	if len(paths) > 0 {
+		config = cm.CommandConfig{Command: "cat", Args: paths, Sudo: true}
+	}
+	result, err := dsm.CommandManager.Run(context.TODO(), config)
+	if err != nil {
+		return "", err
+	}
+	return lastLines(result.STDOUT, lines), nil
+}
+
+// FollowServiceLogs calls onLine with each line the service logs until ctx
+// is cancelled. See ServiceLogs for where logs are read from.
+func (dsm *DarwinServiceManager) FollowServiceLogs(ctx context.Context, serviceName string, onLine func(line string)) error {
+	paths, err := dsm.logPaths(serviceName)
+	if err != nil {
+		return err
+	}
+
+	config := cm.CommandConfig{
+		Command: "log",
+		Args:    []string{"stream", "--style", "compact", "--predicate", logPredicate(serviceName)},
+	}
+	if len(paths) > 0 {
+		config = cm.CommandConfig{Command: "tail", Args: append([]string{"-n", "0", "-F"}, paths...), Sudo: true}
+	}
+	return cm.RunCommandStream(ctx, dsm.CommandManager, config, func(stream, line string) {
+		if stream == cm.StreamStdout {
+			onLine(line)
+		}
+	})
+}
+
+// logPaths returns the distinct stdout and stderr paths of the launchd job.
+func (dsm *DarwinServiceManager) logPaths(serviceName string) ([]string, error) {
+	output, err := dsm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "launchctl",
+		Args:    []string{"print", fmt.Sprintf("system/%s", serviceName)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, line := range cm.Lines(output.STDOUT) {
+		key, value, ok := strings.Cut(line, " = ")
+		if !ok || (key != "stdout path" && key != "stderr path") {
+			continue
+		}
+		if len(paths) == 0 || paths[0] != value {
+			paths = append(paths, value)
+		}
+	}
+	return paths, nil
+}
+
+// logPredicate matches unified log entries from the job's process or
+// subsystem.
+func logPredicate(serviceName string) string {
+	name := strconv.Quote(serviceName)
+	return fmt.Sprintf("process == %s OR subsystem == %s", name, name)
+}
+
+// lastLines returns the last n lines of output, or all of it for n <= 0.
+func lastLines(output string, n int) string {
+	lines := strings.SplitAfter(strings.TrimSuffix(output, "\n"), "\n")
+	if n <= 0 || len(lines) <= n {
+		return output
+	}
+	return strings.Join(lines[len(lines)-n:], "") + "\n"
+}
+
+// FailedUnits is not supported on macOS, which has no systemd.
+func (dsm *DarwinServiceManager) FailedUnits() ([]ServiceInfo, error) {
+	return nil, ErrNotSupported
+}
+
+// ResetFailed is not supported on macOS, which has no systemd.
+func (dsm *DarwinServiceManager) ResetFailed(unit string) error {
+	return ErrNotSupported
+}
@@ -0,0 +1,78 @@
+package servicemanager
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// FailedUnits returns the units systemd reports as failed.
+func (lsm *LinuxServiceManager) FailedUnits() ([]ServiceInfo, error) {
+	result, err := lsm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "systemctl",
+		Args:    []string{"list-units", "--state=failed", "--no-pager", "--plain", "--no-legend"},
+	})
+	if err := systemctlError(result, err); err != nil {
+		return nil, err
+	}
+	return parseListUnits(result.STDOUT), nil
+}
+
+// ResetFailed clears the failed state of unit after it has been remediated.
+func (lsm *LinuxServiceManager) ResetFailed(unit string) error {
+	result, err := lsm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "systemctl",
+		Args:    []string{"reset-failed", unit},
+		Sudo:    true,
+	})
+	return systemctlError(result, err)
+}
+
+// systemctlError maps a missing or unbooted systemd to ErrNotSupported.
+func systemctlError(result cm.CommandResult, err error) error {
+	if strings.Contains(result.STDERR, "not found") ||
+		strings.Contains(result.STDERR, "System has not been booted with systemd") {
+		return ErrNotSupported
+	}
+	if err != nil {
+		return err
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("systemctl failed: %s", strings.TrimSpace(result.STDERR))
+	}
+	return nil
+}
+
+// parseListUnits parses "systemctl list-units --plain" output, e.g.
+// "nginx.service loaded failed failed A high performance web server".
+// The column header and the legend that follows the first blank line are
+// skipped when present.
+func parseListUnits(output string) []ServiceInfo {
+	var units []ServiceInfo
+	started := false
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "●"))
+		if line == "" {
+			if started {
+				break
+			}
+			continue
+		}
+		started = true
+
+		fields := strings.Fields(line)
+		if len(fields) < 4 || fields[0] == "UNIT" {
+			continue
+		}
+		units = append(units, ServiceInfo{
+			Name:        fields[0],
+			LoadState:   fields[1],
+			ActiveState: fields[2],
+			SubState:    fields[3],
+			Description: strings.Join(fields[4:], " "),
+		})
+	}
+	return units
+}
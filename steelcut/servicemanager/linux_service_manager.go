@@ -2,6 +2,7 @@ package servicemanager
 
 import (
 	"context"
+	"fmt"
 	"strings"
 
 	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
@@ -27,6 +28,35 @@ func (lsm *LinuxServiceManager) DisableService(serviceName string) error {
 	return err
 }
 
+// EnableAtBoot enables the unit without starting it.
+func (lsm *LinuxServiceManager) EnableAtBoot(serviceName string) error {
+	return lsm.systemctl("enable", serviceName)
+}
+
+// DisableAtBoot disables the unit without stopping it.
+func (lsm *LinuxServiceManager) DisableAtBoot(serviceName string) error {
+	return lsm.systemctl("disable", serviceName)
+}
+
+// MaskService links the unit to /dev/null so that it cannot be started,
+// even as a dependency of another unit.
+func (lsm *LinuxServiceManager) MaskService(serviceName string) error {
+	return lsm.systemctl("mask", serviceName)
+}
+
+func (lsm *LinuxServiceManager) UnmaskService(serviceName string) error {
+	return lsm.systemctl("unmask", serviceName)
+}
+
+func (lsm *LinuxServiceManager) systemctl(args ...string) error {
+	result, err := lsm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "systemctl",
+		Args:    args,
+		Sudo:    true,
+	})
+	return systemctlError(result, err)
+}
+
 func (lsm *LinuxServiceManager) StartService(serviceName string) error {
 	_, err := lsm.CommandManager.Run(context.TODO(), cm.CommandConfig{
 		Command: "systemctl",
@@ -59,24 +89,44 @@ func (lsm *LinuxServiceManager) ReloadService(serviceName string) error {
 	return err
 }
 
-func (lsm *LinuxServiceManager) CheckServiceStatus(serviceName string) (ServiceStatus, error) {
+// CheckServiceStatus reports whether the service is running and whether it
+// is enabled or masked.
+func (lsm *LinuxServiceManager) CheckServiceStatus(serviceName string) (ServiceState, error) {
+	// Both commands exit non-zero for inactive or disabled units but still
+	// print the state
 	output, err := lsm.CommandManager.Run(context.TODO(), cm.CommandConfig{
 		Command: "systemctl",
 		Args:    []string{"is-active", serviceName},
 	})
-	if err != nil {
-		return "", err
+	if err != nil && !cm.IsExitError(err) {
+		return ServiceState{}, err
 	}
+	var state ServiceState
 	switch strings.TrimSpace(output.STDOUT) {
 	case "active":
-		return Active, nil
+		state.Status = Active
 	case "inactive":
-		return Inactive, nil
+		state.Status = Inactive
 	case "failed":
-		return Failed, nil
+		state.Status = Failed
 	default:
-		return "", err
+		return ServiceState{}, fmt.Errorf("unexpected state for %s: %s", serviceName, strings.TrimSpace(output.STDOUT+output.STDERR))
+	}
+
+	output, err = lsm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "systemctl",
+		Args:    []string{"is-enabled", serviceName},
+	})
+	if err != nil && !cm.IsExitError(err) {
+		return ServiceState{}, err
+	}
+	switch strings.TrimSpace(output.STDOUT) {
+	case "enabled", "enabled-runtime":
+		state.Enabled = true
+	case "masked", "masked-runtime":
+		state.Masked = true
 	}
+	return state, nil
 }
 
 func (lsm *LinuxServiceManager) IsServiceEnabled(serviceName string) (bool, error) {
@@ -84,7 +134,8 @@ func (lsm *LinuxServiceManager) IsServiceEnabled(serviceName string) (bool, erro
 		Command: "systemctl",
 		Args:    []string{"is-enabled", serviceName},
 	})
-	if err != nil {
+	// is-enabled exits 1 for disabled units but still prints the state
+	if err != nil && !cm.IsExitError(err) {
 		return false, err
 	}
 	return strings.TrimSpace(output.STDOUT) == "enabled", nil
@@ -0,0 +1,535 @@
+package servicemanager
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+type MockCommandManager struct {
+	Outputs map[string]cm.CommandResult
+	Errors  map[string]error
+	Calls   []cm.CommandConfig
+}
+
+func (m *MockCommandManager) RunLocal(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
+	return m.Run(ctx, config)
+}
+
+func (m *MockCommandManager) RunRemote(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
+	return m.Run(ctx, config)
+}
+
+// Run answers with the output keyed by the full command line, e.g.
+// "systemctl list-unit-files", if any, and otherwise by the command alone.
+func (m *MockCommandManager) Run(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
+	m.Calls = append(m.Calls, config)
+	if config.OnLine != nil {
+		for _, line := range cm.Lines(m.Outputs[config.Command].STDOUT) {
+			config.OnLine(cm.StreamStdout, line)
+		}
+	}
+	for line := strings.Join(append([]string{config.Command}, config.Args...), " "); line != config.Command; {
+		if result, ok := m.Outputs[line]; ok {
+			return result, m.Errors[line]
+		}
+		line = line[:strings.LastIndex(line, " ")]
+	}
+	return m.Outputs[config.Command], m.Errors[config.Command]
+}
+
+func (m *MockCommandManager) ran(command string, arg string) bool {
+	for _, call := range m.Calls {
+		if call.Command == command && strings.Contains(strings.Join(call.Args, " "), arg) {
+			return true
+		}
+	}
+	return false
+}
+
+// written returns the content streamed to the last override write.
+func (m *MockCommandManager) written() (string, bool) {
+	for i := len(m.Calls) - 1; i >= 0; i-- {
+		call := m.Calls[i]
+		if call.Command == "sh" && call.Stdin != nil {
+			data, _ := io.ReadAll(call.Stdin)
+			return string(data), true
+		}
+	}
+	return "", false
+}
+
+func TestSetUnitOverrideCreates(t *testing.T) {
+	mockCmd := &MockCommandManager{}
+	manager := LinuxServiceManager{CommandManager: mockCmd}
+
+	changed, err := manager.SetUnitOverride("nginx", "Service", "LimitNOFILE", "65536")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !changed {
+		t.Errorf("Expected override creation to report a change")
+	}
+	if !mockCmd.ran("sh", "/etc/systemd/system/nginx.service.d/override.conf") {
+		t.Errorf("Expected override to be written, calls: %v", mockCmd.Calls)
+	}
+	if content, ok := mockCmd.written(); !ok || content != "[Service]\nLimitNOFILE=65536\n" {
+		t.Errorf("Expected the drop-in streamed over stdin, got %q", content)
+	}
+	if !mockCmd.ran("systemctl", "daemon-reload") {
+		t.Errorf("Expected daemon-reload after change")
+	}
+}
+
+func TestSetUnitOverrideReadError(t *testing.T) {
+	mockCmd := &MockCommandManager{
+		Outputs: map[string]cm.CommandResult{
+			"sh": {STDERR: "cat: override.conf: Permission denied", ExitCode: 1},
+		},
+		Errors: map[string]error{
+			"sh": &cm.CommandError{Command: "sh", ExitCode: 1, Stderr: "cat: override.conf: Permission denied"},
+		},
+	}
+	manager := LinuxServiceManager{CommandManager: mockCmd}
+
+	if _, err := manager.SetUnitOverride("nginx", "Service", "LimitNOFILE", "65536"); err == nil {
+		t.Fatal("Expected an unreadable drop-in to fail")
+	}
+	if _, ok := mockCmd.written(); ok {
+		t.Errorf("Expected no write after a failed read")
+	}
+}
+
+func TestOverrideScripts(t *testing.T) {
+	overridePath := filepath.Join(t.TempDir(), "nginx.service.d", overrideFileName)
+	manager := &cm.UnixCommandManager{Hostname: "localhost"}
+
+	result, err := manager.Run(context.Background(), cm.CommandConfig{
+		Command: "sh",
+		Args:    []string{"-c", readOverrideScript, "sh", overridePath},
+	})
+	if err != nil || result.STDOUT != "" {
+		t.Fatalf("Expected a missing drop-in to read as empty, got %q, %v", result.STDOUT, err)
+	}
+
+	content := "[Service]\nEnvironment='GREETING=hello world' HOME=$HOME\n"
+	write := cm.ReplaceFile(overridePath, []byte(content))
+	write.Sudo = false
+	_, err = manager.Run(context.Background(), write)
+	if err != nil {
+		t.Fatalf("Expected the drop-in to be written, got: %v", err)
+	}
+	if data, _ := os.ReadFile(overridePath); string(data) != content {
+		t.Errorf("Expected %q, got %q", content, data)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(overridePath))
+	if len(entries) != 1 {
+		t.Errorf("Expected no temporary file left behind, got %d entries", len(entries))
+	}
+
+	result, err = manager.Run(context.Background(), cm.CommandConfig{
+		Command: "sh",
+		Args:    []string{"-c", readOverrideScript, "sh", overridePath},
+	})
+	if err != nil || result.STDOUT != content {
+		t.Errorf("Expected %q, got %q, %v", content, result.STDOUT, err)
+	}
+}
+
+func TestSetUnitOverrideChangeDetection(t *testing.T) {
+	existing := "[Service]\nLimitNOFILE=65536\n"
+	mockCmd := &MockCommandManager{
+		Outputs: map[string]cm.CommandResult{"sh": {STDOUT: existing}},
+	}
+	manager := LinuxServiceManager{CommandManager: mockCmd}
+
+	changed, err := manager.SetUnitOverride("nginx.service", "Service", "LimitNOFILE", "65536")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if changed {
+		t.Errorf("Expected no change for identical value")
+	}
+	if mockCmd.ran("systemctl", "daemon-reload") {
+		t.Errorf("Expected no daemon-reload when nothing changed")
+	}
+
+	changed, err = manager.SetUnitOverride("nginx.service", "Service", "LimitNOFILE", "1024")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !changed {
+		t.Errorf("Expected a change for a new value")
+	}
+	if !mockCmd.ran("systemctl", "daemon-reload") {
+		t.Errorf("Expected daemon-reload after change")
+	}
+}
+
+func TestSetUnitDirective(t *testing.T) {
+	tests := []struct {
+		content  string
+		expected string
+	}{
+		{"", "[Service]\nRestart=always\n"},
+		{"[Service]\nRestart=no\nUser=www\n", "[Service]\nRestart=always\nUser=www\n"},
+		{"[Service]\nUser=www\n\n[Install]\nWantedBy=multi-user.target\n", "[Service]\nUser=www\nRestart=always\n\n[Install]\nWantedBy=multi-user.target\n"},
+		{"[Unit]\nAfter=network.target\n", "[Unit]\nAfter=network.target\n\n[Service]\nRestart=always\n"},
+	}
+
+	for _, tt := range tests {
+		got := setUnitDirective(tt.content, "Service", "Restart", "always")
+		if got != tt.expected {
+			t.Errorf("Expected %q, got %q", tt.expected, got)
+		}
+	}
+}
+
+func TestSetUnitDirectiveRepeatedKeys(t *testing.T) {
+	tests := []struct {
+		content, key, value string
+		expected            string
+	}{
+		{
+			"[Service]\nEnvironment=A=1\nUser=www\nEnvironment=B=2\n", "Environment", "C=3",
+			"[Service]\nEnvironment=C=3\nUser=www\n",
+		},
+		{
+			"", "ExecStart", "/usr/bin/app --fast",
+			"[Service]\nExecStart=\nExecStart=/usr/bin/app --fast\n",
+		},
+		{
+			"[Service]\nExecStart=\nExecStart=/usr/bin/app\nUser=www\n", "ExecStart", "/usr/bin/app --fast",
+			"[Service]\nExecStart=\nExecStart=/usr/bin/app --fast\nUser=www\n",
+		},
+	}
+
+	for _, tt := range tests {
+		got := setUnitDirective(tt.content, "Service", tt.key, tt.value)
+		if got != tt.expected {
+			t.Errorf("Expected %q, got %q", tt.expected, got)
+		}
+		if again := setUnitDirective(got, "Service", tt.key, tt.value); again != got {
+			t.Errorf("Expected setting %s again to change nothing, got %q", tt.key, again)
+		}
+	}
+}
+
+func TestRemoveUnitOverride(t *testing.T) {
+	mockCmd := &MockCommandManager{}
+	manager := LinuxServiceManager{CommandManager: mockCmd}
+
+	if err := manager.RemoveUnitOverride("nginx"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !mockCmd.ran("sh", removeOverrideScript+" sh /etc/systemd/system/nginx.service.d/override.conf") {
+		t.Errorf("Expected only override.conf to be removed, calls: %v", mockCmd.Calls)
+	}
+	if !mockCmd.ran("systemctl", "daemon-reload") {
+		t.Errorf("Expected daemon-reload after removal")
+	}
+}
+
+func TestRemoveOverrideScript(t *testing.T) {
+	manager := &cm.UnixCommandManager{Hostname: "localhost"}
+	remove := func(overridePath string) error {
+		_, err := manager.Run(context.Background(), cm.CommandConfig{
+			Command: "sh",
+			Args:    []string{"-c", removeOverrideScript, "sh", overridePath},
+		})
+		return err
+	}
+
+	// Neither the drop-in nor its directory exists
+	dir := filepath.Join(t.TempDir(), "nginx.service.d")
+	if err := remove(filepath.Join(dir, overrideFileName)); err != nil {
+		t.Errorf("Expected removing a missing override to succeed, got: %v", err)
+	}
+
+	// Other drop-ins keep the directory
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{overrideFileName, "limits.conf"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("[Service]\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := remove(filepath.Join(dir, overrideFileName)); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 || entries[0].Name() != "limits.conf" {
+		t.Errorf("Expected only limits.conf to remain, got %v", entries)
+	}
+
+	// The last drop-in takes the directory with it
+	if err := remove(filepath.Join(dir, "limits.conf")); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("Expected the empty directory to be removed, got: %v", err)
+	}
+}
+
+func TestParseListUnits(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		expected []ServiceInfo
+	}{
+		{"none failed", "", nil},
+		{
+			"none failed with legend",
+			"UNIT LOAD ACTIVE SUB DESCRIPTION\n\n0 loaded units listed.\n",
+			nil,
+		},
+		{
+			"several failed",
+			"nginx.service         loaded failed failed A high performance web server\n" +
+				"certbot.timer         loaded failed failed Run certbot twice daily\n" +
+				"● ghost.service       not-found failed failed ghost.service\n",
+			[]ServiceInfo{
+				{"nginx.service", "loaded", "failed", "failed", "A high performance web server"},
+				{"certbot.timer", "loaded", "failed", "failed", "Run certbot twice daily"},
+				{"ghost.service", "not-found", "failed", "failed", "ghost.service"},
+			},
+		},
+		{
+			"with legend",
+			"UNIT          LOAD   ACTIVE SUB    DESCRIPTION\n" +
+				"nginx.service loaded failed failed A high performance web server\n" +
+				"\n" +
+				"LOAD   = Reflects whether the unit definition was properly loaded.\n" +
+				"ACTIVE = The high-level unit activation state, i.e. generalization of SUB.\n" +
+				"\n" +
+				"1 loaded units listed.\n",
+			[]ServiceInfo{{"nginx.service", "loaded", "failed", "failed", "A high performance web server"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			units := parseListUnits(tt.output)
+			if !reflect.DeepEqual(units, tt.expected) {
+				t.Errorf("Expected %+v, got: %+v", tt.expected, units)
+			}
+		})
+	}
+}
+
+func TestFailedUnitsNotSupported(t *testing.T) {
+	mockCmd := &MockCommandManager{
+		Outputs: map[string]cm.CommandResult{
+			"systemctl": {STDERR: "System has not been booted with systemd as init system (PID 1). Can't operate.", ExitCode: 1},
+		},
+	}
+	manager := LinuxServiceManager{CommandManager: mockCmd}
+
+	if _, err := manager.FailedUnits(); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("Expected ErrNotSupported, got: %v", err)
+	}
+
+	mockCmd.Outputs = nil
+	if err := manager.ResetFailed("nginx.service"); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+	if !mockCmd.ran("systemctl", "reset-failed nginx.service") {
+		t.Errorf("Expected systemctl reset-failed, calls: %v", mockCmd.Calls)
+	}
+}
+
+func TestListServices(t *testing.T) {
+	mockCmd := &MockCommandManager{
+		Outputs: map[string]cm.CommandResult{
+			"systemctl list-units": {STDOUT: "nginx.service          loaded    active   running A high performance web server\n" +
+				"getty@tty1.service     loaded    active   running Getty on tty1\n" +
+				"● ghost.service        not-found inactive dead    ghost.service\n"},
+			"systemctl list-unit-files": {STDOUT: "nginx.service enabled enabled\n" +
+				"getty@.service enabled enabled\n" +
+				"ssh.service disabled enabled\n"},
+		},
+	}
+	manager := LinuxServiceManager{CommandManager: mockCmd}
+
+	services, err := manager.ListServices()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	expected := []ServiceEntry{
+		{ServiceInfo{"nginx.service", "loaded", "active", "running", "A high performance web server"}, true},
+		{ServiceInfo{"getty@tty1.service", "loaded", "active", "running", "Getty on tty1"}, true},
+		{ServiceInfo{"ghost.service", "not-found", "inactive", "dead", "ghost.service"}, false},
+	}
+	if !reflect.DeepEqual(services, expected) {
+		t.Errorf("Expected %+v, got: %+v", expected, services)
+	}
+	if !mockCmd.ran("systemctl", "list-units --type=service --all") {
+		t.Errorf("Expected every service unit to be listed, calls: %v", mockCmd.Calls)
+	}
+}
+
+func TestDarwinListServices(t *testing.T) {
+	mockCmd := &MockCommandManager{
+		Outputs: map[string]cm.CommandResult{
+			"launchctl list": {STDOUT: "PID\tStatus\tLabel\n" +
+				"412\t0\tcom.example.web\n" +
+				"-\t0\tcom.example.backup\n" +
+				"-\t78\tcom.example.broken\n"},
+			"launchctl print-disabled": {STDOUT: "disabled services = {\n" +
+				"\t\"com.example.backup\" => disabled\n" +
+				"\t\"com.example.web\" => enabled\n" +
+				"}\n"},
+		},
+	}
+	manager := DarwinServiceManager{CommandManager: mockCmd}
+
+	services, err := manager.ListServices()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	expected := []ServiceEntry{
+		{ServiceInfo{Name: "com.example.web", LoadState: "loaded", ActiveState: "active", SubState: "running"}, true},
+		{ServiceInfo{Name: "com.example.backup", LoadState: "loaded", ActiveState: "inactive", SubState: "dead"}, false},
+		{ServiceInfo{Name: "com.example.broken", LoadState: "loaded", ActiveState: "failed", SubState: "failed"}, true},
+	}
+	if !reflect.DeepEqual(services, expected) {
+		t.Errorf("Expected %+v, got: %+v", expected, services)
+	}
+}
+
+func TestServiceLogs(t *testing.T) {
+	mockCmd := &MockCommandManager{
+		Outputs: map[string]cm.CommandResult{
+			"journalctl": {STDOUT: "Jun 10 06:13:20 web1 nginx[812]: started\nJun 10 06:14:02 web1 nginx[812]: reloading\n"},
+		},
+	}
+	manager := LinuxServiceManager{CommandManager: mockCmd}
+
+	logs, err := manager.ServiceLogs("nginx", 50)
+	if err != nil || !strings.Contains(logs, "reloading") {
+		t.Errorf("Unexpected logs %q, %v", logs, err)
+	}
+	if !reflect.DeepEqual(mockCmd.Calls[0].Args, []string{"-u", "nginx", "--no-pager", "-n", "50"}) {
+		t.Errorf("Unexpected journalctl arguments %v", mockCmd.Calls[0].Args)
+	}
+
+	var followed []string
+	err = manager.FollowServiceLogs(context.Background(), "nginx", func(line string) { followed = append(followed, line) })
+	if err != nil || len(followed) != 2 || !mockCmd.ran("journalctl", "-f") {
+		t.Errorf("Unexpected followed lines %v, %v", followed, err)
+	}
+}
+
+func TestDarwinServiceLogs(t *testing.T) {
+	mockCmd := &MockCommandManager{
+		Outputs: map[string]cm.CommandResult{
+			"launchctl": {STDOUT: "system/com.example.web = {\n\tstdout path = /var/log/web.log\n\tstderr path = /var/log/web.log\n}\n"},
+			"cat":       {STDOUT: "one\ntwo\nthree\n"},
+		},
+	}
+	manager := DarwinServiceManager{CommandManager: mockCmd}
+
+	logs, err := manager.ServiceLogs("com.example.web", 2)
+	if err != nil || logs != "two\nthree\n" {
+		t.Errorf("Unexpected logs %q, %v", logs, err)
+	}
+	if call := mockCmd.Calls[1]; !reflect.DeepEqual(call.Args, []string{"/var/log/web.log"}) {
+		t.Errorf("Expected the job's log file to be read once, got %v", call.Args)
+	}
+
+	// A job without log files falls back to the unified log
+	mockCmd = &MockCommandManager{Outputs: map[string]cm.CommandResult{"log": {STDOUT: "entry\n"}}}
+	manager = DarwinServiceManager{CommandManager: mockCmd}
+	if logs, err := manager.ServiceLogs("com.example.web", 10); err != nil || logs != "entry\n" {
+		t.Errorf("Unexpected logs %q, %v", logs, err)
+	}
+	if !mockCmd.ran("log", `process == "com.example.web"`) {
+		t.Errorf("Expected log show with a predicate, calls: %v", mockCmd.Calls)
+	}
+}
+
+func TestCheckServiceStatus(t *testing.T) {
+	mockCmd := &MockCommandManager{
+		Outputs: map[string]cm.CommandResult{
+			"systemctl is-active":  {STDOUT: "inactive\n", ExitCode: 3},
+			"systemctl is-enabled": {STDOUT: "masked\n", ExitCode: 1},
+		},
+		Errors: map[string]error{
+			"systemctl is-active":  &cm.CommandError{Command: "systemctl", ExitCode: 3},
+			"systemctl is-enabled": &cm.CommandError{Command: "systemctl", ExitCode: 1},
+		},
+	}
+	manager := LinuxServiceManager{CommandManager: mockCmd}
+
+	state, err := manager.CheckServiceStatus("nginx")
+	if err != nil || state != (ServiceState{Status: Inactive, Masked: true}) {
+		t.Errorf("Unexpected state %+v, %v", state, err)
+	}
+
+	mockCmd = &MockCommandManager{
+		Outputs: map[string]cm.CommandResult{
+			"systemctl is-active":  {STDOUT: "active\n"},
+			"systemctl is-enabled": {STDOUT: "enabled\n"},
+		},
+	}
+	manager = LinuxServiceManager{CommandManager: mockCmd}
+	state, err = manager.CheckServiceStatus("nginx")
+	if err != nil || state != (ServiceState{Status: Active, Enabled: true}) {
+		t.Errorf("Unexpected state %+v, %v", state, err)
+	}
+}
+
+func TestBootPersistence(t *testing.T) {
+	mockCmd := &MockCommandManager{}
+	manager := LinuxServiceManager{CommandManager: mockCmd}
+	for _, op := range []func(string) error{manager.EnableAtBoot, manager.DisableAtBoot, manager.MaskService, manager.UnmaskService} {
+		if err := op("nginx"); err != nil {
+			t.Errorf("Expected no error, got: %v", err)
+		}
+	}
+	for i, verb := range []string{"enable", "disable", "mask", "unmask"} {
+		if call := mockCmd.Calls[i]; !call.Sudo || !reflect.DeepEqual(call.Args, []string{verb, "nginx"}) {
+			t.Errorf("Expected sudo systemctl %s nginx, got %+v", verb, call)
+		}
+	}
+
+	darwinCmd := &MockCommandManager{}
+	darwin := DarwinServiceManager{CommandManager: darwinCmd}
+	if err := darwin.EnableAtBoot("com.example.web"); err != nil || !darwinCmd.ran("launchctl", "load -w /Library/LaunchDaemons/com.example.web.plist") {
+		t.Errorf("Expected launchctl load -w, got %v, calls: %v", err, darwinCmd.Calls)
+	}
+	if err := darwin.MaskService("com.example.web"); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("Expected ErrNotSupported, got: %v", err)
+	}
+}
+
+func TestIsServiceEnabledDisabledUnit(t *testing.T) {
+	mockCmd := &MockCommandManager{
+		Outputs: map[string]cm.CommandResult{
+			"systemctl is-enabled": {STDOUT: "disabled\n", ExitCode: 1},
+		},
+		Errors: map[string]error{
+			"systemctl is-enabled": &cm.CommandError{Command: "systemctl", ExitCode: 1},
+		},
+	}
+	manager := LinuxServiceManager{CommandManager: mockCmd}
+
+	enabled, err := manager.IsServiceEnabled("nginx")
+	if err != nil || enabled {
+		t.Errorf("Expected a disabled unit to report false without error, got %v, %v", enabled, err)
+	}
+
+	// An escalation failure carries an exit code but is not a command exit
+	mockCmd.Outputs["systemctl is-enabled"] = cm.CommandResult{ExitCode: 1}
+	mockCmd.Errors["systemctl is-enabled"] = cm.ErrSudoAuth
+	if _, err := manager.IsServiceEnabled("nginx"); !errors.Is(err, cm.ErrSudoAuth) {
+		t.Errorf("Expected ErrSudoAuth, got: %v", err)
+	}
+}
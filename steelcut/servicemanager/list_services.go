@@ -0,0 +1,60 @@
+package servicemanager
+
+import (
+	"context"
+	"strings"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// ListServices returns every service unit systemd knows of, loaded or not,
+// with whether its unit file is enabled.
+func (lsm *LinuxServiceManager) ListServices() ([]ServiceEntry, error) {
+	result, err := lsm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "systemctl",
+		Args:    []string{"list-units", "--type=service", "--all", "--no-pager", "--plain", "--no-legend"},
+	})
+	if err := systemctlError(result, err); err != nil {
+		return nil, err
+	}
+	units := parseListUnits(result.STDOUT)
+
+	result, err = lsm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "systemctl",
+		Args:    []string{"list-unit-files", "--type=service", "--no-pager", "--plain", "--no-legend"},
+	})
+	if err := systemctlError(result, err); err != nil {
+		return nil, err
+	}
+	states := parseUnitFileStates(result.STDOUT)
+
+	services := make([]ServiceEntry, 0, len(units))
+	for _, unit := range units {
+		state, ok := states[unit.Name]
+		if !ok {
+			// Instances such as getty@tty1.service take the template's state
+			if prefix, _, found := strings.Cut(unit.Name, "@"); found {
+				state = states[prefix+"@.service"]
+			}
+		}
+		services = append(services, ServiceEntry{
+			ServiceInfo: unit,
+			Enabled:     state == "enabled" || state == "enabled-runtime",
+		})
+	}
+	return services, nil
+}
+
+// parseUnitFileStates parses "systemctl list-unit-files --plain" output,
+// e.g. "nginx.service enabled enabled", into each unit file's state.
+func parseUnitFileStates(output string) map[string]string {
+	states := make(map[string]string)
+	for _, line := range cm.Lines(output) {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] == "UNIT" {
+			continue
+		}
+		states[fields[0]] = fields[1]
+	}
+	return states
+}
@@ -0,0 +1,38 @@
+package servicemanager
+
+import (
+	"context"
+	"strconv"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// ServiceLogs returns the last lines journal entries for the service. A
+// non-positive lines returns the whole journal.
+func (lsm *LinuxServiceManager) ServiceLogs(serviceName string, lines int) (string, error) {
+	args := []string{"-u", serviceName, "--no-pager"}
+	if lines > 0 {
+		args = append(args, "-n", strconv.Itoa(lines))
+	}
+	result, err := lsm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "journalctl",
+		Args:    args,
+	})
+	if err != nil {
+		return "", err
+	}
+	return result.STDOUT, nil
+}
+
+// FollowServiceLogs calls onLine with each new journal entry for the
+// service until ctx is cancelled.
+func (lsm *LinuxServiceManager) FollowServiceLogs(ctx context.Context, serviceName string, onLine func(line string)) error {
+	return cm.RunCommandStream(ctx, lsm.CommandManager, cm.CommandConfig{
+		Command: "journalctl",
+		Args:    []string{"-u", serviceName, "--no-pager", "-n", "0", "-f"},
+	}, func(stream, line string) {
+		if stream == cm.StreamStdout {
+			onLine(line)
+		}
+	})
+}
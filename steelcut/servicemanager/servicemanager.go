@@ -1,5 +1,13 @@
 package servicemanager
 
+import (
+	"context"
+	"errors"
+)
+
+// ErrNotSupported is returned when an operation is not available on the host.
+var ErrNotSupported = errors.New("operation not supported on this host")
+
 type ServiceStatus string
 
 const (
@@ -8,14 +16,49 @@ const (
 	Failed   ServiceStatus = "failed"
 )
 
+// ServiceState is a service's current run state and whether it starts at
+// boot.
+type ServiceState struct {
+	Status  ServiceStatus
+	Enabled bool
+	Masked  bool // systemd only: the unit cannot be started at all
+}
+
 // ServiceManager represents operations that can be performed on system services.
 type ServiceManager interface {
+	// Deprecated: EnableService loads and starts the service on macOS but
+	// only enables it on Linux; use EnableAtBoot and StartService.
 	EnableService(serviceName string) error
+	// Deprecated: use DisableAtBoot and StopService.
 	DisableService(serviceName string) error
+	EnableAtBoot(serviceName string) error  // Start the service at boot
+	DisableAtBoot(serviceName string) error // Stop starting the service at boot
+	MaskService(serviceName string) error
+	UnmaskService(serviceName string) error
 	StartService(serviceName string) error
 	StopService(serviceName string) error
 	RestartService(serviceName string) error
 	ReloadService(serviceName string) error
-	CheckServiceStatus(serviceName string) (ServiceStatus, error)
+	CheckServiceStatus(serviceName string) (ServiceState, error)
 	IsServiceEnabled(serviceName string) (bool, error)
+	ListServices() ([]ServiceEntry, error)
+	ServiceLogs(serviceName string, lines int) (string, error)
+	FollowServiceLogs(ctx context.Context, serviceName string, onLine func(line string)) error
+	FailedUnits() ([]ServiceInfo, error)
+	ResetFailed(unit string) error
+}
+
+// ServiceInfo describes a unit as listed by systemctl.
+type ServiceInfo struct {
+	Name        string
+	LoadState   string // e.g. "loaded", "not-found"
+	ActiveState string // e.g. "active", "failed"
+	SubState    string // e.g. "running", "exited"
+	Description string
+}
+
+// ServiceEntry is a service as listed by ListServices.
+type ServiceEntry struct {
+	ServiceInfo
+	Enabled bool // whether the service starts at boot
 }
@@ -0,0 +1,169 @@
+package servicemanager
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+const (
+	systemdUnitDir   = "/etc/systemd/system"
+	overrideFileName = "override.conf"
+)
+
+// readOverrideScript prints the file "$1" if it exists, and nothing
+// otherwise, so a missing drop-in reads as empty without parsing errors.
+const readOverrideScript = `if [ -e "$1" ]; then cat "$1"; fi`
+
+// removeOverrideScript deletes the file "$1" and then its directory, but only
+// if the directory exists and no other drop-ins remain in it.
+const removeOverrideScript = `rm -f "$1" || exit 1
+d=$(dirname "$1")
+if [ -d "$d" ]; then rmdir --ignore-fail-on-non-empty "$d"; fi`
+
+// unitOverridePath returns the path of the steelcut-managed drop-in for unit.
+func unitOverridePath(unit string) string {
+	if !strings.Contains(unit, ".") {
+		unit += ".service"
+	}
+	return path.Join(systemdUnitDir, unit+".d", overrideFileName)
+}
+
+// SetUnitOverride sets key=value in section of the unit's override.conf
+// drop-in, creating the drop-in if needed. systemd is reloaded only when the
+// file changes, and changed reports whether it did so callers can restart the
+// unit only when needed. Other drop-ins for the unit are left untouched.
+func (lsm *LinuxServiceManager) SetUnitOverride(unit, section, key, value string) (bool, error) {
+	overridePath := unitOverridePath(unit)
+
+	current, err := lsm.readOverride(overridePath)
+	if err != nil {
+		return false, err
+	}
+
+	updated := setUnitDirective(current, section, key, value)
+	if updated == current {
+		return false, nil
+	}
+
+	// The drop-in is replaced atomically, so systemd never reads a partial
+	// one; the temporary name doesn't end in .conf, so systemd ignores it.
+	result, err := lsm.CommandManager.Run(context.TODO(), cm.ReplaceFile(overridePath, []byte(updated)))
+	if err != nil {
+		return false, err
+	}
+	if result.ExitCode != 0 {
+		return false, fmt.Errorf("failed to write %s: %s", overridePath, result.STDERR)
+	}
+
+	return true, lsm.daemonReload()
+}
+
+// RemoveUnitOverride deletes the unit's override.conf drop-in and reloads
+// systemd. Other drop-ins in the unit's directory are preserved. Removing an
+// override that doesn't exist succeeds.
+func (lsm *LinuxServiceManager) RemoveUnitOverride(unit string) error {
+	overridePath := unitOverridePath(unit)
+
+	result, err := lsm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "sh",
+		Args:    []string{"-c", removeOverrideScript, "sh", overridePath},
+		Sudo:    true,
+	})
+	if err != nil {
+		return err
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("failed to remove %s: %s", overridePath, result.STDERR)
+	}
+
+	return lsm.daemonReload()
+}
+
+func (lsm *LinuxServiceManager) readOverride(overridePath string) (string, error) {
+	result, err := lsm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "sh",
+		Args:    []string{"-c", readOverrideScript, "sh", overridePath},
+	})
+	if err != nil {
+		return "", err
+	}
+	if result.ExitCode != 0 {
+		return "", fmt.Errorf("failed to read %s: %s", overridePath, result.STDERR)
+	}
+	return result.STDOUT, nil
+}
+
+func (lsm *LinuxServiceManager) daemonReload() error {
+	_, err := lsm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "systemctl",
+		Args:    []string{"daemon-reload"},
+		Sudo:    true,
+	})
+	return err
+}
+
+// setUnitDirective returns content with key set to value in section. Every
+// existing assignment of key in the section is replaced, in place of the
+// first, so that list-type keys such as Environment= end up with exactly
+// value; otherwise the key is appended to the section, which is created if
+// missing. Exec* keys are preceded by an empty assignment, which drops the
+// commands of the unit itself rather than adding to them.
+func setUnitDirective(content, section, key, value string) string {
+	directives := []string{key + "=" + value}
+	if strings.HasPrefix(key, "Exec") && value != "" {
+		directives = []string{key + "=", key + "=" + value}
+	}
+	header := "[" + section + "]"
+
+	var lines []string
+	if content != "" {
+		lines = strings.Split(strings.TrimRight(content, "\n"), "\n")
+	}
+
+	var out []string
+	inSection, replaced := false, false
+	sectionEnd := -1
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+			inSection = trimmed == header
+			out = append(out, line)
+			if inSection {
+				sectionEnd = len(out) - 1
+			}
+			continue
+		}
+		if !inSection || trimmed == "" {
+			out = append(out, line)
+			continue
+		}
+		if k, _, ok := strings.Cut(trimmed, "="); ok && strings.TrimSpace(k) == key {
+			if !replaced {
+				out = append(out, directives...)
+				replaced = true
+				sectionEnd = len(out) - 1
+			}
+			continue
+		}
+		out = append(out, line)
+		sectionEnd = len(out) - 1
+	}
+
+	switch {
+	case replaced:
+	case sectionEnd == -1:
+		if len(out) > 0 {
+			out = append(out, "")
+		}
+		out = append(out, header)
+		out = append(out, directives...)
+	default:
+		out = append(out[:sectionEnd+1], append(directives, out[sectionEnd+1:]...)...)
+	}
+
+	return strings.Join(out, "\n") + "\n"
+}
@@ -1,6 +1,8 @@
 package steelcut
 
 import (
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"net"
 	"os"
@@ -17,7 +19,11 @@ type SSHKeyManager interface {
 }
 
 // FileSSHKeyManager is an implementation of SSHKeyManager that reads SSH keys from disk.
-type FileSSHKeyManager struct{}
+type FileSSHKeyManager struct {
+	// Paths are the key files to read. Empty reads ~/.ssh/id_*; listed
+	// files that do not exist are skipped.
+	Paths []string
+}
 
 // AgentSSHKeyManager is an implementation of SSHKeyManager that reads SSH keys from an SSH agent.
 type AgentSSHKeyManager struct{}
@@ -48,53 +54,75 @@ func (km AgentSSHKeyManager) ReadPrivateKeys(_ string) ([]ssh.Signer, error) {
 	return signers, nil
 }
 
-// ReadPrivateKeys reads private keys from the user's home directory.
+// defaultKeyFiles are the keys under ~/.ssh that FileSSHKeyManager tries
+// when no Paths are given, in the order ssh tries them.
+var defaultKeyFiles = []string{"id_ed25519", "id_ecdsa", "id_rsa"}
+
+// ReadPrivateKeys reads the private keys at Paths, or the default keys in the
+// user's ~/.ssh directory, skipping files that do not exist. Encrypted keys
+// are decrypted with keyPassphrase. Every key that can be loaded is returned;
+// if none can, the error names each file and why it failed, e.g. a wrong
+// passphrase.
 func (km FileSSHKeyManager) ReadPrivateKeys(keyPassphrase string) ([]ssh.Signer, error) {
-	// Find possible key files
-	files, err := filepath.Glob(os.Getenv("HOME") + "/.ssh/id_*")
-	if err != nil {
-		return nil, err
+	files := km.Paths
+	if len(files) == 0 {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range defaultKeyFiles {
+			files = append(files, filepath.Join(home, ".ssh", name))
+		}
 	}
 
-	signers := []ssh.Signer{}
-
-	// Try each key file
+	var signers []ssh.Signer
+	var errs []error
 	for _, file := range files {
 		// Skip public keys
 		if strings.HasSuffix(file, ".pub") {
 			continue
 		}
 
-		// Read private key file
 		keyBytes, err := os.ReadFile(file)
+		if os.IsNotExist(err) {
+			continue
+		}
 		if err != nil {
 			return nil, err
 		}
 
-		var signer ssh.Signer
-
-		// Parse private key
-		if keyPassphrase != "" {
-			signer, err = ssh.ParsePrivateKeyWithPassphrase(keyBytes, []byte(keyPassphrase))
-			if err != nil {
-				// Failed to parse with passphrase, try next key
-				continue
-			}
-		} else {
-			signer, err = ssh.ParsePrivateKey(keyBytes)
-			if err != nil {
-				// Failed to parse without passphrase, try next key
-				continue
-			}
+		signer, err := parsePrivateKey(keyBytes, keyPassphrase)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", file, err))
+			continue
 		}
-
 		signers = append(signers, signer)
 	}
 
 	if len(signers) == 0 {
-		// We didn't manage to parse any key files
-		return nil, err
+		if len(errs) > 0 {
+			return nil, fmt.Errorf("no usable private key: %w", errors.Join(errs...))
+		}
+		return nil, fmt.Errorf("no private key found in %s", strings.Join(files, ", "))
 	}
-
 	return signers, nil
 }
+
+// parsePrivateKey parses a private key of any type ssh supports, decrypting
+// it with passphrase if it is encrypted.
+func parsePrivateKey(keyBytes []byte, passphrase string) (ssh.Signer, error) {
+	signer, err := ssh.ParsePrivateKey(keyBytes)
+	var missing *ssh.PassphraseMissingError
+	if !errors.As(err, &missing) {
+		return signer, err
+	}
+	if passphrase == "" {
+		return nil, errors.New("key is encrypted and no passphrase was given")
+	}
+
+	signer, err = ssh.ParsePrivateKeyWithPassphrase(keyBytes, []byte(passphrase))
+	if errors.Is(err, x509.IncorrectPasswordError) {
+		return nil, fmt.Errorf("wrong passphrase: %w", err)
+	}
+	return signer, err
+}
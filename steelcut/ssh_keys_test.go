@@ -0,0 +1,88 @@
+package steelcut
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func writeKey(t *testing.T, path string, key interface{}, passphrase string) {
+	t.Helper()
+	var block *pem.Block
+	var err error
+	if passphrase != "" {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(key, "", []byte(passphrase))
+	} else {
+		block, err = ssh.MarshalPrivateKey(key, "")
+	}
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+}
+
+func TestFileSSHKeyManagerDefaultKeys(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dir := filepath.Join(home, ".ssh")
+	if err := os.Mkdir(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	writeKey(t, filepath.Join(dir, "id_ed25519"), edKey, "secret")
+	writeKey(t, filepath.Join(dir, "id_ecdsa"), ecKey, "secret")
+	writeKey(t, filepath.Join(dir, "id_rsa"), rsaKey, "")
+
+	signers, err := FileSSHKeyManager{}.ReadPrivateKeys("secret")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var types []string
+	for _, signer := range signers {
+		types = append(types, signer.PublicKey().Type())
+	}
+	if got := strings.Join(types, " "); got != "ssh-ed25519 ecdsa-sha2-nistp256 ssh-rsa" {
+		t.Errorf("Expected all three keys in order, got %s", got)
+	}
+
+	// The unencrypted RSA key still loads with a wrong passphrase
+	signers, err = FileSSHKeyManager{}.ReadPrivateKeys("wrong")
+	if err != nil || len(signers) != 1 {
+		t.Errorf("Expected only the unencrypted key, got %d keys, %v", len(signers), err)
+	}
+}
+
+func TestFileSSHKeyManagerWrongPassphrase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "deploy_ed25519")
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+	writeKey(t, path, key, "secret")
+
+	_, err := FileSSHKeyManager{Paths: []string{path}}.ReadPrivateKeys("wrong")
+	if err == nil || !strings.Contains(err.Error(), path+": wrong passphrase") {
+		t.Errorf("Expected the key and the wrong passphrase to be named, got %v", err)
+	}
+
+	_, err = FileSSHKeyManager{Paths: []string{path}}.ReadPrivateKeys("")
+	if err == nil || !strings.Contains(err.Error(), "no passphrase") {
+		t.Errorf("Expected a missing passphrase error, got %v", err)
+	}
+
+	_, err = FileSSHKeyManager{Paths: []string{path + ".missing"}}.ReadPrivateKeys("secret")
+	if err == nil || !strings.Contains(err.Error(), "no private key found") {
+		t.Errorf("Expected a no key error, got %v", err)
+	}
+}
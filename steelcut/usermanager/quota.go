@@ -0,0 +1,225 @@
+package usermanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// ErrQuotasNotEnabled is returned when no filesystem on the host has quotas
+// enabled, or none apply to the user.
+var ErrQuotasNotEnabled = errors.New("disk quotas are not enabled")
+
+// QuotaInfo describes a user's usage and limits on one filesystem. Block
+// values are in 1 KiB blocks and a zero limit means unlimited. Grace is only
+// set while usage is over the soft limit.
+type QuotaInfo struct {
+	User       string
+	Filesystem string
+	BlocksUsed int64
+	BlocksSoft int64
+	BlocksHard int64
+	BlockGrace string
+	InodesUsed int64
+	InodesSoft int64
+	InodesHard int64
+	InodeGrace string
+}
+
+// OverSoftLimit reports whether block or inode usage exceeds a soft limit.
+func (q QuotaInfo) OverSoftLimit() bool {
+	return (q.BlocksSoft > 0 && q.BlocksUsed > q.BlocksSoft) || (q.InodesSoft > 0 && q.InodesUsed > q.InodesSoft)
+}
+
+var quotaNotEnabledMessages = []string{
+	"No filesystem with quota",
+	"Cannot find any quota file",
+	"Quota file not found",
+	"not using quota",
+	"quotas not enabled",
+}
+
+// UserQuota returns the quota of user on the first filesystem that reports one.
+func (l *LinuxUserManager) UserQuota(user string) (QuotaInfo, error) {
+	result, err := l.runQuotaCommand("quota", "-u", user)
+	if err != nil {
+		return QuotaInfo{}, err
+	}
+
+	quotas, err := parseQuota(user, result.STDOUT)
+	if err != nil {
+		return QuotaInfo{}, err
+	}
+	if len(quotas) == 0 {
+		return QuotaInfo{}, ErrQuotasNotEnabled
+	}
+	return quotas[0], nil
+}
+
+// RepquotaAll returns the user quotas of every quota-enabled filesystem.
+func (l *LinuxUserManager) RepquotaAll() ([]QuotaInfo, error) {
+	result, err := l.runQuotaCommand("repquota", "-a")
+	if err != nil {
+		return nil, err
+	}
+
+	quotas, err := parseRepquota(result.STDOUT)
+	if err != nil {
+		return nil, err
+	}
+	if len(quotas) == 0 {
+		return nil, ErrQuotasNotEnabled
+	}
+	return quotas, nil
+}
+
+func (l *LinuxUserManager) runQuotaCommand(command string, args ...string) (cm.CommandResult, error) {
+	result, err := l.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: command,
+		Args:    args,
+		Sudo:    true,
+	})
+	for _, msg := range quotaNotEnabledMessages {
+		if strings.Contains(result.STDERR, msg) {
+			return result, ErrQuotasNotEnabled
+		}
+	}
+	// quota exits 1 when a user is over quota but still prints the report
+	if err != nil && !cm.IsExitError(err) {
+		return result, err
+	}
+	if result.ExitCode != 0 && result.STDOUT == "" {
+		return result, fmt.Errorf("%s failed: %s", command, strings.TrimSpace(result.STDERR))
+	}
+	return result, nil
+}
+
+// parseQuota parses "quota -u" output. Long device names are printed on a line
+// of their own with the numbers wrapped onto the next line, so the output
+// after the header is treated as one stream of fields. A grace column follows
+// the block or inode triple only when its usage is marked with "*".
+func parseQuota(user, output string) ([]QuotaInfo, error) {
+	lines := strings.Split(output, "\n")
+
+	header := -1
+	for i, line := range lines {
+		if strings.HasSuffix(strings.TrimSpace(line), ": none") {
+			return nil, nil
+		}
+		if strings.Contains(line, "Filesystem") {
+			header = i
+			break
+		}
+	}
+	if header == -1 {
+		return nil, nil
+	}
+
+	fields := strings.Fields(strings.Join(lines[header+1:], " "))
+	var quotas []QuotaInfo
+	for len(fields) > 0 {
+		q := QuotaInfo{User: user, Filesystem: fields[0]}
+		fields = fields[1:]
+
+		var err error
+		if fields, q.BlocksUsed, q.BlocksSoft, q.BlocksHard, q.BlockGrace, err = parseQuotaLimits(fields); err != nil {
+			return nil, fmt.Errorf("error parsing block quota for %s: %v", q.Filesystem, err)
+		}
+		if fields, q.InodesUsed, q.InodesSoft, q.InodesHard, q.InodeGrace, err = parseQuotaLimits(fields); err != nil {
+			return nil, fmt.Errorf("error parsing inode quota for %s: %v", q.Filesystem, err)
+		}
+		quotas = append(quotas, q)
+	}
+	return quotas, nil
+}
+
+// parseQuotaLimits consumes "used soft hard [grace]" from fields, where grace
+// is present only when used carries a trailing "*".
+func parseQuotaLimits(fields []string) (rest []string, used, soft, hard int64, grace string, err error) {
+	if len(fields) < 3 {
+		return nil, 0, 0, 0, "", fmt.Errorf("expected used, soft and hard values, got %v", fields)
+	}
+
+	over := strings.HasSuffix(fields[0], "*")
+	values := make([]int64, 3)
+	for i, field := range fields[:3] {
+		if values[i], err = strconv.ParseInt(strings.TrimSuffix(field, "*"), 10, 64); err != nil {
+			return nil, 0, 0, 0, "", err
+		}
+	}
+	rest = fields[3:]
+
+	if over {
+		if len(rest) == 0 {
+			return nil, 0, 0, 0, "", errors.New("missing grace period")
+		}
+		grace, rest = rest[0], rest[1:]
+	}
+	return rest, values[0], values[1], values[2], grace, nil
+}
+
+// parseRepquota parses "repquota -a" output. Each row's two-character flag
+// column marks with "+" which of the block and inode limits is exceeded, and
+// hence which grace columns are present.
+func parseRepquota(output string) ([]QuotaInfo, error) {
+	var quotas []QuotaInfo
+	var device string
+	inTable := false
+
+	err := cm.EachLine(output, func(line string) error {
+		if strings.HasPrefix(line, "***") {
+			if _, dev, ok := strings.Cut(line, " on device "); ok {
+				device = strings.TrimSpace(dev)
+			}
+			inTable = false
+			return nil
+		}
+		if strings.HasPrefix(line, "---") {
+			inTable = true
+			return nil
+		}
+		if !inTable {
+			return nil
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 || len(fields[1]) != 2 {
+			return fmt.Errorf("unexpected repquota line: %q", line)
+		}
+		flags := fields[1]
+
+		// Mark used values the same way quota does so the limits parse alike
+		rest := fields[2:]
+		if flags[0] == '+' && len(rest) > 0 {
+			rest[0] += "*"
+		}
+		if flags[1] == '+' {
+			offset := 3
+			if flags[0] == '+' {
+				offset = 4
+			}
+			if len(rest) > offset {
+				rest[offset] += "*"
+			}
+		}
+
+		q := QuotaInfo{User: fields[0], Filesystem: device}
+		var err error
+		if rest, q.BlocksUsed, q.BlocksSoft, q.BlocksHard, q.BlockGrace, err = parseQuotaLimits(rest); err != nil {
+			return fmt.Errorf("error parsing repquota line %q: %v", line, err)
+		}
+		if _, q.InodesUsed, q.InodesSoft, q.InodesHard, q.InodeGrace, err = parseQuotaLimits(rest); err != nil {
+			return fmt.Errorf("error parsing repquota line %q: %v", line, err)
+		}
+		quotas = append(quotas, q)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return quotas, nil
+}
@@ -0,0 +1,161 @@
+package usermanager
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+type MockCommandManager struct {
+	Result cm.CommandResult
+	Err    error
+}
+
+func (m *MockCommandManager) RunLocal(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
+	return m.Result, m.Err
+}
+
+func (m *MockCommandManager) RunRemote(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
+	return m.Result, m.Err
+}
+
+func (m *MockCommandManager) Run(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
+	return m.Result, m.Err
+}
+
+const quotaFixture = `Disk quotas for user alice (uid 1001): 
+     Filesystem  blocks   quota   limit   grace   files   quota   limit   grace
+      /dev/sda1   10240*  10000   12000   6days     120     500     600        
+`
+
+const quotaWrappedFixture = `Disk quotas for user alice (uid 1001): 
+     Filesystem  blocks   quota   limit   grace   files   quota   limit   grace
+/dev/mapper/vg0-home
+                   4096   20000   25000            1200*   1000    2000  23:59
+      /dev/sdb1       8       0       0               2       0       0        
+`
+
+const repquotaFixture = `*** Report for user quotas on device /dev/sda1
+Block grace time: 7days; Inode grace time: 7days
+                        Block limits                File limits
+User            used    soft    hard  grace    used  soft  hard  grace
+----------------------------------------------------------------------
+root      --      20       0       0              2     0     0       
+alice     +-   10240   10000   12000  6days     120     0     0       
+bob       ++   30000   20000   40000   none     900   500  1000  3days
+
+*** Report for user quotas on device /dev/mapper/vg0-home
+Block grace time: 7days; Inode grace time: 7days
+                        Block limits                File limits
+User            used    soft    hard  grace    used  soft  hard  grace
+----------------------------------------------------------------------
+carol     -+     100       0       0            700   500   800  1day
+`
+
+func TestParseQuota(t *testing.T) {
+	quotas, err := parseQuota("alice", quotaFixture)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	expected := []QuotaInfo{{
+		User: "alice", Filesystem: "/dev/sda1",
+		BlocksUsed: 10240, BlocksSoft: 10000, BlocksHard: 12000, BlockGrace: "6days",
+		InodesUsed: 120, InodesSoft: 500, InodesHard: 600,
+	}}
+	if !reflect.DeepEqual(quotas, expected) {
+		t.Errorf("Expected %+v, got: %+v", expected, quotas)
+	}
+	if !quotas[0].OverSoftLimit() {
+		t.Errorf("Expected alice to be over the soft limit")
+	}
+}
+
+func TestParseQuotaWrappedDevice(t *testing.T) {
+	quotas, err := parseQuota("alice", quotaWrappedFixture)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	expected := []QuotaInfo{
+		{
+			User: "alice", Filesystem: "/dev/mapper/vg0-home",
+			BlocksUsed: 4096, BlocksSoft: 20000, BlocksHard: 25000,
+			InodesUsed: 1200, InodesSoft: 1000, InodesHard: 2000, InodeGrace: "23:59",
+		},
+		{User: "alice", Filesystem: "/dev/sdb1", BlocksUsed: 8, InodesUsed: 2},
+	}
+	if !reflect.DeepEqual(quotas, expected) {
+		t.Errorf("Expected %+v, got: %+v", expected, quotas)
+	}
+}
+
+func TestParseQuotaMalformed(t *testing.T) {
+	output := "Disk quotas for user alice (uid 1001):\n Filesystem blocks quota limit grace files quota limit grace\n /dev/sda1 lots 0 0 1 0 0\n"
+	if _, err := parseQuota("alice", output); err == nil || errors.Is(err, ErrQuotasNotEnabled) {
+		t.Errorf("Expected a parse error, got: %v", err)
+	}
+}
+
+func TestParseRepquota(t *testing.T) {
+	quotas, err := parseRepquota(repquotaFixture)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(quotas) != 4 {
+		t.Fatalf("Expected 4 quotas, got: %+v", quotas)
+	}
+
+	bob := QuotaInfo{
+		User: "bob", Filesystem: "/dev/sda1",
+		BlocksUsed: 30000, BlocksSoft: 20000, BlocksHard: 40000, BlockGrace: "none",
+		InodesUsed: 900, InodesSoft: 500, InodesHard: 1000, InodeGrace: "3days",
+	}
+	if quotas[2] != bob {
+		t.Errorf("Expected %+v, got: %+v", bob, quotas[2])
+	}
+
+	carol := QuotaInfo{
+		User: "carol", Filesystem: "/dev/mapper/vg0-home",
+		BlocksUsed: 100, InodesUsed: 700, InodesSoft: 500, InodesHard: 800, InodeGrace: "1day",
+	}
+	if quotas[3] != carol {
+		t.Errorf("Expected %+v, got: %+v", carol, quotas[3])
+	}
+}
+
+func TestQuotasNotEnabled(t *testing.T) {
+	manager := LinuxUserManager{CommandManager: &MockCommandManager{
+		Result: cm.CommandResult{STDERR: "repquota: Cannot find any quota file to work on.\n", ExitCode: 1},
+	}}
+	if _, err := manager.RepquotaAll(); !errors.Is(err, ErrQuotasNotEnabled) {
+		t.Errorf("Expected ErrQuotasNotEnabled, got: %v", err)
+	}
+
+	manager.CommandManager = &MockCommandManager{
+		Result: cm.CommandResult{STDOUT: "Disk quotas for user alice (uid 1001): none\n"},
+	}
+	if _, err := manager.UserQuota("alice"); !errors.Is(err, ErrQuotasNotEnabled) {
+		t.Errorf("Expected ErrQuotasNotEnabled for a user without quotas, got: %v", err)
+	}
+}
+
+func TestUserQuotaOverQuota(t *testing.T) {
+	manager := LinuxUserManager{CommandManager: &MockCommandManager{
+		Result: cm.CommandResult{STDOUT: quotaFixture, ExitCode: 1},
+		Err:    &cm.CommandError{Command: "quota", ExitCode: 1},
+	}}
+	quota, err := manager.UserQuota("alice")
+	if err != nil || quota.User != "alice" {
+		t.Errorf("Expected the report of a user over quota, got %+v, %v", quota, err)
+	}
+
+	manager = LinuxUserManager{CommandManager: &MockCommandManager{
+		Result: cm.CommandResult{STDOUT: quotaFixture, ExitCode: 1},
+		Err:    cm.ErrDirNotFound,
+	}}
+	if _, err := manager.UserQuota("alice"); !errors.Is(err, cm.ErrDirNotFound) {
+		t.Errorf("Expected ErrDirNotFound, got: %v", err)
+	}
+}
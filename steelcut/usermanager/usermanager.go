@@ -26,4 +26,10 @@ type UserManager interface {
 
 	// Lists all users
 	ListUsers() ([]User, error)
+
+	// Reports a user's disk quota
+	UserQuota(user string) (QuotaInfo, error)
+
+	// Reports the user quotas of all quota-enabled filesystems
+	RepquotaAll() ([]QuotaInfo, error)
 }